@@ -7,6 +7,7 @@ toolchain go1.24.1
 require (
 	github.com/HORNET-Storage/Scionic-Merkle-Tree/v2 v2.2.6
 	github.com/HORNET-Storage/hdk-nostr-go v1.1.4
+	github.com/btcsuite/btcd/btcutil v1.1.5
 	github.com/dgraph-io/badger/v4 v4.8.0
 	github.com/fsnotify/fsnotify v1.7.0
 	github.com/fxamacker/cbor/v2 v2.9.0
@@ -35,7 +36,6 @@ require (
 require (
 	github.com/benbjohnson/clock v1.3.5 // indirect
 	github.com/beorn7/perks v1.0.1 // indirect
-	github.com/btcsuite/btcd/btcutil v1.1.5 // indirect
 	github.com/cespare/xxhash/v2 v2.3.0 // indirect
 	github.com/containerd/cgroups v1.1.0 // indirect
 	github.com/coreos/go-systemd/v22 v22.5.0 // indirect
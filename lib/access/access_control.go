@@ -11,6 +11,7 @@ import (
 
 	merkle_dag "github.com/HORNET-Storage/Scionic-Merkle-Tree/v2/dag"
 	"github.com/HORNET-Storage/hdk-nostr-go/lib/signing"
+	"github.com/HORNET-Storage/hornet-storage/lib/backfill"
 	"github.com/HORNET-Storage/hornet-storage/lib/config"
 	"github.com/HORNET-Storage/hornet-storage/lib/logging"
 	"github.com/HORNET-Storage/hornet-storage/lib/stores"
@@ -61,6 +62,9 @@ type AccessControl struct {
 	// Blacklist holds per-repository blocked pubkeys.
 	// Used to deny writes from blacklisted users before any permission checks.
 	Blacklist *RepoBlacklist
+
+	// relayWot is the relay-wide web of trust consulted in wot mode.
+	relayWot relayWot
 }
 
 // NewAccessControl creates a new access control instance
@@ -86,6 +90,11 @@ func (ac *AccessControl) CanWriteEvent(event *nostr.Event, store stores.Store) e
 		return fmt.Errorf("event is required")
 	}
 
+	// Invite requests come from pubkeys that cannot write yet, the handler checks the claim itself
+	if event.Kind == InviteRequestKind {
+		return nil
+	}
+
 	writeErr := ac.CanWrite(event.PubKey)
 	if writeErr == nil {
 		return nil
@@ -987,6 +996,12 @@ func (ac *AccessControl) isAllowedUncached(readOrWrite string, hex string, requi
 		return fmt.Errorf("user does not have permission")
 	}
 
+	// Pubkeys within the relay web of trust are allowed, anyone else may still be added manually
+	if readOrWrite == accessWotUsers && ac.isWithinRelayWot(hex) {
+		logging.Debugf("[ACCESS CONTROL] User %s is within the relay web of trust, granting access", hex)
+		return nil
+	}
+
 	// Get the allowed user from the database
 	user, err := ac.statsStore.GetAllowedUser(hex)
 	if err != nil {
@@ -1000,6 +1015,12 @@ func (ac *AccessControl) isAllowedUncached(readOrWrite string, hex string, requi
 		return fmt.Errorf("user does not have permission to read")
 	}
 
+	// Time-limited grants stop working as soon as they lapse, before the sweep removes them
+	if user.Expired(time.Now()) {
+		logging.Debugf("[ACCESS CONTROL] User %s grant expired at %v", hex, user.ExpiresAt)
+		return fmt.Errorf("user access has expired")
+	}
+
 	if requireWriteCapability && user.ReadOnly {
 		logging.Debugf("[ACCESS CONTROL] User %s is allowed for read but not write", hex)
 		return fmt.Errorf("user does not have permission to write")
@@ -1064,7 +1085,14 @@ func (ac *AccessControl) AddAllowedUser(npub string, read bool, write bool, tier
 	if !read && !write {
 		canWrite = false
 	}
-	return ac.statsStore.AddAllowedUser(npub, canWrite, tier, createdBy)
+	if err := ac.statsStore.AddAllowedUser(npub, canWrite, tier, createdBy); err != nil {
+		return err
+	}
+
+	if canWrite {
+		backfill.Trigger(npub, "allowed_user")
+	}
+	return nil
 }
 
 func (ac *AccessControl) RemoveAllowedUser(npub string) error {
@@ -1120,8 +1148,8 @@ func (ac *AccessControl) ValidateSettings(settings *types.AllowedUsersSettings)
 	logging.Debugf("Write setting %s", write)
 	// This ensures the correct options are selected for each mode and sets defaults when incorrect values are set
 	// Not all read/write values are valid for each mode so this ensures that the read/write values are in line with the selected mode
-	// mode: 		only-me, invite_only, public, subscription
-	// read/write: 	all_users, paid_users, allowed_users, only-me
+	// mode: 		only-me, invite_only, public, subscription, wot
+	// read/write: 	all_users, paid_users, allowed_users, wot_users, only-me
 
 	switch mode {
 	case "only-me":
@@ -1152,12 +1180,30 @@ func (ac *AccessControl) ValidateSettings(settings *types.AllowedUsersSettings)
 		default:
 			read = "paid_users"
 		}
+	case accessModeWot:
+		write = accessWotUsers
+		switch read {
+		case "all_users":
+		case accessWotUsers:
+		default:
+			read = "all_users"
+		}
+		if settings.WotHops <= 0 {
+			settings.WotHops = wot.DefaultMaxHops
+		}
+		if settings.WotHops > wot.MaxAllowedHops {
+			settings.WotHops = wot.MaxAllowedHops
+		}
 	default:
 		mode = "only-me"
 		read = "only-me"
 		write = "only-me"
 	}
 
+	if err := validateWritePolicies(settings); err != nil {
+		return err
+	}
+
 	settings.Mode = mode
 	settings.Read = read
 	settings.Write = write
@@ -1189,6 +1235,8 @@ func (ac *AccessControl) UpdateSettings(settings *types.AllowedUsersSettings) {
 	ac.settings = settings
 	// Invalidate all cached access results since settings changed
 	ac.InvalidateCache()
+	// Seeds, hops, write policies or the mode itself may have changed
+	ac.refreshRelayWot()
 }
 
 // InvalidateCache clears all cached access check results.
@@ -6,6 +6,7 @@ import (
 	"path/filepath"
 	"testing"
 
+	merkle_dag "github.com/HORNET-Storage/Scionic-Merkle-Tree/v2/dag"
 	"github.com/HORNET-Storage/hdk-nostr-go/lib/signing"
 	"github.com/HORNET-Storage/hornet-storage/lib/access"
 	"github.com/HORNET-Storage/hornet-storage/lib/stores/badgerhold"
@@ -255,7 +256,7 @@ func TestCanReadDagAllowsMaintainerWhenBundleTagResolvesRepo(t *testing.T) {
 		t.Fatalf("expected maintainer to be allowed to read repo event directly: %v", err)
 	}
 
-	if err := accessControl.CanReadDag(bundleRoot, maintainerPub, hex.EncodeToString(signature.Serialize()), store); err != nil {
+	if err := accessControl.CanReadDag(&merkle_dag.DagLeaf{Hash: bundleRoot}, maintainerPub, hex.EncodeToString(signature.Serialize()), store); err != nil {
 		t.Fatalf("expected maintainer to be allowed to read bundle DAG: %v", err)
 	}
 }
@@ -322,7 +323,7 @@ func TestRepositoryReadOverrideDisabledInOnlyMeMode(t *testing.T) {
 		t.Fatal("expected repo read override to be disabled in only-me mode")
 	}
 
-	if err := accessControl.CanReadDag(bundleRoot, readerPub, hex.EncodeToString(signature.Serialize()), store); err == nil {
+	if err := accessControl.CanReadDag(&merkle_dag.DagLeaf{Hash: bundleRoot}, readerPub, hex.EncodeToString(signature.Serialize()), store); err == nil {
 		t.Fatal("expected DAG read override to be disabled in only-me mode")
 	}
 }
@@ -0,0 +1,95 @@
+package access
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/HORNET-Storage/hornet-storage/lib/logging"
+	"github.com/HORNET-Storage/hornet-storage/lib/subscription"
+	"github.com/HORNET-Storage/hornet-storage/lib/types"
+)
+
+const (
+	// InviteRequestKind is the NIP-43 join request kind. Its claim tag carries the invite code.
+	InviteRequestKind = 28934
+
+	// DefaultExpirySweepInterval is how often lapsed allowed-user grants are revoked
+	DefaultExpirySweepInterval = time.Minute
+)
+
+// RedeemInvite grants pubkey the tier attached to an invite code and clears the
+// access cache so the grant applies immediately.
+func (ac *AccessControl) RedeemInvite(code string, pubkey string) (*types.AllowedUser, error) {
+	code = strings.TrimSpace(code)
+	if code == "" {
+		return nil, types.ErrInviteNotFound
+	}
+	if !isValidHexPubkey(pubkey) {
+		return nil, fmt.Errorf("invalid public key format: expected 64-character hex string")
+	}
+	pubkey = strings.ToLower(pubkey)
+
+	user, err := ac.statsStore.RedeemInviteCode(code, pubkey, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	ac.InvalidateCache()
+
+	logging.Infof("[ACCESS CONTROL] User %s redeemed an invite for tier %s", pubkey, user.Tier)
+
+	// Reflect the new tier in the user's kind 11888 event, as for an admin-added user
+	if ac.settings != nil && normalizeAccessSetting(ac.settings.Mode) == "invite-only" {
+		go func() {
+			if manager := subscription.GetGlobalManager(); manager != nil {
+				if err := manager.UpdateUserSubscriptionFromDatabase(pubkey); err != nil {
+					logging.Infof("Error updating subscription event for invited user %s: %v", pubkey, err)
+				}
+			}
+		}()
+	}
+
+	return user, nil
+}
+
+// StartExpirySweep revokes lapsed time-limited grants every interval until ctx is done
+func (ac *AccessControl) StartExpirySweep(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultExpirySweepInterval
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				ac.SweepExpiredUsers(time.Now())
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// SweepExpiredUsers removes allowed users whose grant lapsed before now and
+// invalidates the access cache if any were removed.
+func (ac *AccessControl) SweepExpiredUsers(now time.Time) []string {
+	if ac.statsStore == nil {
+		return nil
+	}
+
+	revoked, err := ac.statsStore.RemoveExpiredAllowedUsers(now)
+	if err != nil {
+		logging.Infof("Error revoking expired allowed users: %v", err)
+		return nil
+	}
+
+	if len(revoked) > 0 {
+		ac.InvalidateCache()
+		logging.Infof("[ACCESS CONTROL] Revoked %d expired allowed users", len(revoked))
+	}
+	return revoked
+}
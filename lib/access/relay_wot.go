@@ -0,0 +1,179 @@
+package access
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/nbd-wtf/go-nostr/nip19"
+
+	"github.com/HORNET-Storage/hornet-storage/lib/eventbus"
+	"github.com/HORNET-Storage/hornet-storage/lib/logging"
+	"github.com/HORNET-Storage/hornet-storage/lib/stores"
+	"github.com/HORNET-Storage/hornet-storage/lib/wot"
+)
+
+const (
+	// accessModeWot grants access by follow distance from the relay owner or seed pubkeys
+	accessModeWot = "wot"
+
+	// accessWotUsers is the read/write setting used by the wot mode
+	accessWotUsers = "wot_users"
+)
+
+// relayWot holds the relay-wide web of trust used by the wot allowed users mode
+type relayWot struct {
+	mu    sync.Mutex
+	store stores.Store
+	graph *wot.RelayGraph
+	sub   *eventbus.Subscription
+}
+
+// StartRelayWot gives access control the event store it builds the relay-wide
+// web of trust from. The graph is only built while the relay is in wot mode or a
+// write policy rule requires a follow distance, and is kept up to date from
+// contact and mute lists published on the event bus.
+func (ac *AccessControl) StartRelayWot(store stores.Store) {
+	ac.relayWot.mu.Lock()
+	ac.relayWot.store = store
+	ac.relayWot.mu.Unlock()
+
+	ac.refreshRelayWot()
+}
+
+// refreshRelayWot rebuilds the relay graph when something needs it and drops it otherwise
+func (ac *AccessControl) refreshRelayWot() {
+	ac.relayWot.mu.Lock()
+	defer ac.relayWot.mu.Unlock()
+
+	if ac.relayWot.store == nil {
+		return
+	}
+
+	hops := ac.relayWotHops()
+	if hops == 0 {
+		if ac.relayWot.sub != nil {
+			ac.relayWot.sub.Close()
+			ac.relayWot.sub = nil
+		}
+		if ac.relayWot.graph != nil {
+			ac.relayWot.graph = nil
+			ac.WotCache.Invalidate(wot.RelayGraphKey)
+		}
+		return
+	}
+
+	store := ac.relayWot.store
+	seeds := ac.RelayWotSeeds()
+	if len(seeds) == 0 {
+		logging.Warnf("[WOT] Relay graph has no owner or wot_seeds configured, only the owner is within it")
+	}
+
+	graph := wot.NewRelayGraph(ac.WotCache, seeds, hops)
+	graph.SetBlocked(func(pubkey string) bool {
+		blocked, err := store.IsBlockedPubkey(pubkey)
+		return err == nil && blocked
+	})
+
+	events, err := stores.QueryAllEvents(store, nostr.Filter{Kinds: []int{wot.ContactListKind, wot.MuteListKind}})
+	if err != nil {
+		logging.Errorf("[WOT] Failed to load contact lists for relay graph: %v", err)
+	}
+	graph.Load(events)
+	graph.OnChange(ac.InvalidateCache)
+
+	ac.relayWot.graph = graph
+	ac.InvalidateCache()
+
+	if ac.relayWot.sub == nil {
+		ac.relayWot.sub = eventbus.Subscribe("relay-wot", nostr.Filters{{Kinds: []int{wot.ContactListKind, wot.MuteListKind}}},
+			func(event *nostr.Event) error {
+				ac.relayWot.mu.Lock()
+				graph := ac.relayWot.graph
+				ac.relayWot.mu.Unlock()
+
+				if graph != nil {
+					graph.Apply(event)
+				}
+				return nil
+			},
+			func(reason string) {
+				// The graph missed updates, rebuild it from the store
+				logging.Warnf("[WOT] Relay graph subscription closed (%s), rebuilding", reason)
+				ac.relayWot.mu.Lock()
+				ac.relayWot.sub = nil
+				ac.relayWot.mu.Unlock()
+				go ac.refreshRelayWot()
+			})
+	}
+
+	logging.Infof("[WOT] Relay graph built from %d contact and mute lists (%d seeds, %d hops)",
+		len(events), len(seeds), graph.MaxHops())
+}
+
+// relayWotHops returns how far the relay graph must reach for the wot mode and any
+// write policy rules, or 0 when nothing uses it
+func (ac *AccessControl) relayWotHops() int {
+	if ac.settings == nil {
+		return 0
+	}
+
+	hops := ac.maxPolicyWotHops()
+	if normalizeAccessSetting(ac.settings.Mode) == accessModeWot {
+		modeHops := ac.settings.WotHops
+		if modeHops <= 0 {
+			modeHops = wot.DefaultMaxHops
+		}
+		hops = max(hops, modeHops)
+	}
+	return hops
+}
+
+// RelayWotSeeds returns the configured seed pubkeys as hex, falling back to the relay owner
+func (ac *AccessControl) RelayWotSeeds() []string {
+	var seeds []string
+	for _, seed := range ac.settings.WotSeeds {
+		seed = strings.TrimSpace(seed)
+		if strings.HasPrefix(seed, "npub1") {
+			_, decoded, err := nip19.Decode(seed)
+			if err != nil {
+				logging.Warnf("[WOT] Ignoring invalid wot seed %s: %v", seed, err)
+				continue
+			}
+			seed = decoded.(string)
+		}
+		if !isValidHexPubkey(seed) {
+			logging.Warnf("[WOT] Ignoring invalid wot seed %s", seed)
+			continue
+		}
+		seeds = append(seeds, strings.ToLower(seed))
+	}
+
+	if len(seeds) == 0 && ac.statsStore != nil {
+		if owner, err := ac.statsStore.GetRelayOwner(); err == nil && owner != nil && isValidHexPubkey(owner.Npub) {
+			seeds = append(seeds, strings.ToLower(owner.Npub))
+		}
+	}
+
+	return seeds
+}
+
+// isWithinRelayWot reports whether hex is within the wot mode hops of the relay graph
+func (ac *AccessControl) isWithinRelayWot(hex string) bool {
+	return ac.isWithinRelayWotHops(hex, ac.settings.WotHops)
+}
+
+// isWithinRelayWotHops reports whether hex is within hops of the relay graph seeds
+func (ac *AccessControl) isWithinRelayWotHops(hex string, hops int) bool {
+	ac.relayWot.mu.Lock()
+	graph := ac.relayWot.graph
+	ac.relayWot.mu.Unlock()
+
+	if graph == nil {
+		return false
+	}
+	if hops <= 0 || hops > graph.MaxHops() {
+		hops = graph.MaxHops()
+	}
+	return ac.WotCache.IsWithinHops(wot.RelayGraphKey, hex, hops)
+}
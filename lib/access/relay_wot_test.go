@@ -0,0 +1,54 @@
+package access_test
+
+import (
+	"testing"
+
+	"github.com/HORNET-Storage/hornet-storage/lib/access"
+	"github.com/HORNET-Storage/hornet-storage/lib/stores"
+	"github.com/HORNET-Storage/hornet-storage/lib/types"
+	"github.com/HORNET-Storage/hornet-storage/lib/wot"
+	"github.com/nbd-wtf/go-nostr"
+)
+
+func TestRelayWotLoadsMoreListsThanTheQueryCap(t *testing.T) {
+	store := newAccessTestStore(t)
+	defer store.Cleanup()
+
+	owner := newAccessTestPubkey(t)
+	followed := newAccessTestPubkey(t)
+
+	// The owner's list is the oldest, so a single capped query would only see the others
+	if err := store.StoreEvent(&nostr.Event{
+		ID:        accessTestEventID(1),
+		PubKey:    owner,
+		Kind:      wot.ContactListKind,
+		CreatedAt: 1,
+		Tags:      nostr.Tags{{"p", followed}},
+	}); err != nil {
+		t.Fatalf("StoreEvent(owner): %v", err)
+	}
+	for i := 0; i < stores.DefaultMaxLimit+100; i++ {
+		if err := store.StoreEvent(&nostr.Event{
+			ID:        accessTestEventID(i + 2),
+			PubKey:    newAccessTestPubkey(t),
+			Kind:      wot.ContactListKind,
+			CreatedAt: nostr.Timestamp(1000 + i),
+			Tags:      nostr.Tags{{"p", newAccessTestPubkey(t)}},
+		}); err != nil {
+			t.Fatalf("StoreEvent(%d): %v", i, err)
+		}
+	}
+
+	accessControl := access.NewAccessControl(store.GetStatsStore(), &types.AllowedUsersSettings{
+		Mode:     "wot",
+		Read:     "wot_users",
+		Write:    "wot_users",
+		WotHops:  1,
+		WotSeeds: []string{owner},
+	})
+	accessControl.StartRelayWot(store)
+
+	if err := accessControl.CanRead(followed); err != nil {
+		t.Fatalf("expected a pubkey followed by the owner to be within the relay web of trust: %v", err)
+	}
+}
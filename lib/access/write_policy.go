@@ -0,0 +1,309 @@
+package access
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/nbd-wtf/go-nostr"
+
+	lib_nostr "github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr"
+	"github.com/HORNET-Storage/hornet-storage/lib/logging"
+	"github.com/HORNET-Storage/hornet-storage/lib/stores"
+	"github.com/HORNET-Storage/hornet-storage/lib/types"
+	"github.com/HORNET-Storage/hornet-storage/lib/wot"
+)
+
+// Machine readable OK prefixes used for write policy rejections (NIP-01, NIP-42)
+const (
+	policyPrefixBlocked      = "blocked"
+	policyPrefixRestricted   = "restricted"
+	policyPrefixAuthRequired = "auth-required"
+	policyPrefixInvalid      = "invalid"
+	policyPrefixPow          = "pow"
+)
+
+// Rule names for the relay-wide requirements checked alongside the write policy rules
+const (
+	powRuleName           = "proof_of_work"
+	blockedPubkeyRuleName = "blocked_pubkey"
+	writeAccessRuleName   = "write_access"
+	kindWhitelistRuleName = "kind_whitelist"
+)
+
+// PolicyViolation is a write policy requirement an event did not meet
+type PolicyViolation struct {
+	Rule        string `json:"rule"`
+	Requirement string `json:"requirement"`
+	Prefix      string `json:"prefix"`
+	Reason      string `json:"reason"`
+}
+
+// Error formats the violation as an OK message
+func (v *PolicyViolation) Error() string {
+	return v.Prefix + ": " + v.Reason
+}
+
+// PolicyResult is the outcome of evaluating an event against the write policy
+type PolicyResult struct {
+	Allowed      bool              `json:"allowed"`
+	MatchedRules []string          `json:"matched_rules"`
+	Violations   []PolicyViolation `json:"violations"`
+}
+
+// CheckWritePolicy returns the first write policy requirement the event fails, or nil.
+// authPubkey is the NIP-42 authenticated pubkey of the connection, empty if unauthenticated.
+func (ac *AccessControl) CheckWritePolicy(event *nostr.Event, authPubkey string) error {
+	result := ac.EvaluateWritePolicy(event, authPubkey)
+	if result.Allowed {
+		return nil
+	}
+
+	violation := result.Violations[0]
+	logging.Infof("[ACCESS CONTROL] Write policy %q rejected kind %d from %s: %s",
+		violation.Rule, event.Kind, event.PubKey, violation.Reason)
+	return &violation
+}
+
+// EvaluateEventWrite checks the event the way the relay does when it is published:
+// blocked pubkeys, write access and the kind whitelist come first, then the write policy.
+func (ac *AccessControl) EvaluateEventWrite(event *nostr.Event, authPubkey string, store stores.Store) *PolicyResult {
+	var violations []PolicyViolation
+
+	if store != nil && event != nil {
+		if blocked, err := store.IsBlockedPubkey(event.PubKey); err == nil && blocked {
+			violations = append(violations, PolicyViolation{
+				Rule:        blockedPubkeyRuleName,
+				Requirement: "not_blocked",
+				Prefix:      policyPrefixBlocked,
+				Reason:      "pubkey is blocked",
+			})
+		}
+	}
+
+	if err := ac.CanWriteEvent(event, store); err != nil {
+		violations = append(violations, PolicyViolation{
+			Rule:        writeAccessRuleName,
+			Requirement: ac.settings.Write,
+			Prefix:      policyPrefixRestricted,
+			Reason:      "write access denied",
+		})
+	}
+
+	if event != nil && !lib_nostr.IsKindAllowed(event.Kind) {
+		violations = append(violations, PolicyViolation{
+			Rule:        kindWhitelistRuleName,
+			Requirement: "kind_whitelist",
+			Prefix:      policyPrefixRestricted,
+			Reason:      fmt.Sprintf("kind %d not allowed", event.Kind),
+		})
+	}
+
+	result := ac.EvaluateWritePolicy(event, authPubkey)
+	result.Violations = append(violations, result.Violations...)
+	result.Allowed = len(result.Violations) == 0
+	return result
+}
+
+// EvaluateWritePolicy checks the event against the relay proof of work requirement
+// and every rule matching its kind, and collects all violations. The relay owner
+// is exempt from the policy.
+func (ac *AccessControl) EvaluateWritePolicy(event *nostr.Event, authPubkey string) *PolicyResult {
+	result := &PolicyResult{Allowed: true, MatchedRules: []string{}, Violations: []PolicyViolation{}}
+	if event == nil || ac.settings == nil {
+		return result
+	}
+
+	required := lib_nostr.RequiredPowDifficulty(event.Kind, false)
+	if required == 0 && len(ac.settings.WritePolicies) == 0 {
+		return result
+	}
+
+	if ac.isOwner(event.PubKey) {
+		return result
+	}
+
+	if required > 0 && ac.isTrustedAuthor(event.PubKey, authPubkey) {
+		required = lib_nostr.RequiredPowDifficulty(event.Kind, true)
+	}
+	if err := lib_nostr.CheckProofOfWork(event, required); err != nil {
+		result.Violations = append(result.Violations, powViolation(powRuleName, err))
+	}
+
+	for i, rule := range ac.settings.WritePolicies {
+		if !writePolicyMatchesKind(rule, event.Kind) {
+			continue
+		}
+
+		name := writePolicyRuleName(rule, i)
+		result.MatchedRules = append(result.MatchedRules, name)
+
+		violate := func(requirement string, prefix string, format string, args ...interface{}) {
+			result.Violations = append(result.Violations, PolicyViolation{
+				Rule:        name,
+				Requirement: requirement,
+				Prefix:      prefix,
+				Reason:      fmt.Sprintf(format, args...),
+			})
+		}
+
+		if rule.AuthRequired && authPubkey == "" {
+			violate("auth_required", policyPrefixAuthRequired, "kind %d requires an authenticated connection", event.Kind)
+		}
+
+		if rule.MaxContentLength > 0 && len(event.Content) > rule.MaxContentLength {
+			violate("max_content_length", policyPrefixInvalid, "content is %d bytes, the limit for kind %d is %d",
+				len(event.Content), event.Kind, rule.MaxContentLength)
+		}
+
+		if rule.MaxTags > 0 && len(event.Tags) > rule.MaxTags {
+			violate("max_tags", policyPrefixInvalid, "event has %d tags, the limit for kind %d is %d",
+				len(event.Tags), event.Kind, rule.MaxTags)
+		}
+
+		if err := lib_nostr.CheckProofOfWork(event, rule.MinPowDifficulty); err != nil {
+			result.Violations = append(result.Violations, powViolation(name, err))
+		}
+
+		if rule.MinTier != "" {
+			required := ac.tierRank(rule.MinTier)
+			tier, rank := ac.userTier(event.PubKey)
+			if rank < required {
+				if tier == "" {
+					violate("min_tier", policyPrefixRestricted, "kind %d requires the %s tier or higher", event.Kind, rule.MinTier)
+				} else {
+					violate("min_tier", policyPrefixRestricted, "kind %d requires the %s tier or higher, you have %s",
+						event.Kind, rule.MinTier, tier)
+				}
+			}
+		}
+
+		if rule.MaxWotHops > 0 && !ac.isWithinRelayWotHops(event.PubKey, rule.MaxWotHops) {
+			violate("max_wot_hops", policyPrefixRestricted, "kind %d requires being within %d hops of the relay web of trust",
+				event.Kind, rule.MaxWotHops)
+		}
+	}
+
+	result.Allowed = len(result.Violations) == 0
+	return result
+}
+
+// isTrustedAuthor reports whether the author may use the lower trusted proof of work
+// bar: the connection must be authenticated as the author, who must also be an allowed
+// user or an active paid subscriber
+func (ac *AccessControl) isTrustedAuthor(pubkey string, authPubkey string) bool {
+	if authPubkey == "" || authPubkey != pubkey || ac.statsStore == nil {
+		return false
+	}
+
+	now := time.Now()
+	if subscriber, err := ac.statsStore.GetPaidSubscriberByNpub(pubkey); err == nil && subscriber != nil && now.Before(subscriber.ExpirationDate) {
+		return true
+	}
+	user, err := ac.statsStore.GetAllowedUser(pubkey)
+	return err == nil && user != nil && !user.Expired(now)
+}
+
+// powViolation converts a proof of work check error into a violation
+func powViolation(rule string, err error) PolicyViolation {
+	prefix, reason, found := strings.Cut(err.Error(), ": ")
+	if !found {
+		prefix, reason = policyPrefixPow, err.Error()
+	}
+	return PolicyViolation{
+		Rule:        rule,
+		Requirement: "min_pow_difficulty",
+		Prefix:      prefix,
+		Reason:      reason,
+	}
+}
+
+// validateWritePolicies rejects rules that could never be evaluated as intended
+func validateWritePolicies(settings *types.AllowedUsersSettings) error {
+	for i, rule := range settings.WritePolicies {
+		name := writePolicyRuleName(rule, i)
+
+		for _, kindRange := range rule.KindRanges {
+			if kindRange.From < 0 || kindRange.To < kindRange.From {
+				return fmt.Errorf("write policy %q has an invalid kind range %d-%d", name, kindRange.From, kindRange.To)
+			}
+		}
+
+		if rule.MaxWotHops < 0 || rule.MaxWotHops > wot.MaxAllowedHops {
+			return fmt.Errorf("write policy %q max_wot_hops must be between 0 and %d", name, wot.MaxAllowedHops)
+		}
+
+		if rule.MaxContentLength < 0 || rule.MaxTags < 0 || rule.MinPowDifficulty < 0 || rule.MinPowDifficulty > 256 {
+			return fmt.Errorf("write policy %q has an out of range limit", name)
+		}
+
+		if rule.MinTier != "" && types.TierRank(settings.Tiers, rule.MinTier) < 0 {
+			return fmt.Errorf("write policy %q requires unknown tier %s", name, rule.MinTier)
+		}
+	}
+
+	return nil
+}
+
+// maxPolicyWotHops returns the largest follow distance required by any write policy rule
+func (ac *AccessControl) maxPolicyWotHops() int {
+	hops := 0
+	if ac.settings == nil {
+		return hops
+	}
+	for _, rule := range ac.settings.WritePolicies {
+		hops = max(hops, rule.MaxWotHops)
+	}
+	return hops
+}
+
+// userTier returns the pubkey's active tier and its rank, preferring a valid paid
+// subscription over an allowed users grant. The rank is -1 without a known tier.
+func (ac *AccessControl) userTier(pubkey string) (string, int64) {
+	if ac.statsStore == nil {
+		return "", -1
+	}
+
+	now := time.Now()
+	tier := ""
+	if subscriber, err := ac.statsStore.GetPaidSubscriberByNpub(pubkey); err == nil && subscriber != nil && now.Before(subscriber.ExpirationDate) {
+		tier = subscriber.Tier
+	}
+	if tier == "" {
+		if user, err := ac.statsStore.GetAllowedUser(pubkey); err == nil && user != nil && !user.Expired(now) {
+			tier = user.Tier
+		}
+	}
+
+	if tier == "" {
+		return "", -1
+	}
+	return tier, ac.tierRank(tier)
+}
+
+// tierRank ranks a tier by the storage it grants, -1 for a tier that is not configured
+func (ac *AccessControl) tierRank(name string) int64 {
+	return types.TierRank(ac.settings.Tiers, name)
+}
+
+func writePolicyMatchesKind(rule types.WritePolicyRule, kind int) bool {
+	if len(rule.Kinds) == 0 && len(rule.KindRanges) == 0 {
+		return true
+	}
+	if containsKind(rule.Kinds, kind) {
+		return true
+	}
+	for _, kindRange := range rule.KindRanges {
+		if kind >= kindRange.From && kind <= kindRange.To {
+			return true
+		}
+	}
+	return false
+}
+
+func writePolicyRuleName(rule types.WritePolicyRule, index int) string {
+	if rule.Name != "" {
+		return rule.Name
+	}
+	return fmt.Sprintf("rule %d", index+1)
+}
@@ -0,0 +1,272 @@
+package access_test
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/spf13/viper"
+
+	"github.com/HORNET-Storage/hornet-storage/lib/access"
+	"github.com/HORNET-Storage/hornet-storage/lib/config"
+	"github.com/HORNET-Storage/hornet-storage/lib/stores/statistics"
+	"github.com/HORNET-Storage/hornet-storage/lib/stores/statistics/gorm/sqlite"
+	"github.com/HORNET-Storage/hornet-storage/lib/types"
+)
+
+func newPolicyStatsStore(t *testing.T) statistics.StatisticsStore {
+	t.Helper()
+
+	statsStore, err := sqlite.InitStore(filepath.Join(t.TempDir(), "stats.db"))
+	if err != nil {
+		t.Fatalf("InitStore: %v", err)
+	}
+	return statsStore
+}
+
+// setProofOfWork requires difficulty bits on every event, relaxed to nothing for trusted authors
+func setProofOfWork(t *testing.T, difficulty int) {
+	t.Helper()
+
+	viper.Reset()
+	viper.Set("event_filtering.proof_of_work.min_difficulty", difficulty)
+	viper.Set("event_filtering.proof_of_work.relax_for_trusted", true)
+	viper.Set("event_filtering.proof_of_work.trusted_min_difficulty", 0)
+	config.InitConfigForTesting()
+	t.Cleanup(func() {
+		viper.Reset()
+		config.InitConfigForTesting()
+	})
+}
+
+func TestTrustedProofOfWorkRequiresAuthenticatedMember(t *testing.T) {
+	setProofOfWork(t, 8)
+	statsStore := newPolicyStatsStore(t)
+
+	allowed := newAccessTestPubkey(t)
+	paid := newAccessTestPubkey(t)
+	lapsed := newAccessTestPubkey(t)
+	stranger := newAccessTestPubkey(t)
+
+	if err := statsStore.AddAllowedUser(allowed, true, "", "test"); err != nil {
+		t.Fatalf("AddAllowedUser: %v", err)
+	}
+	for pubkey, expires := range map[string]time.Time{
+		paid:   time.Now().Add(time.Hour),
+		lapsed: time.Now().Add(-time.Hour),
+	} {
+		if err := statsStore.SavePaidSubscriber(&types.PaidSubscriber{Npub: pubkey, Tier: "basic", ExpirationDate: expires}); err != nil {
+			t.Fatalf("SavePaidSubscriber: %v", err)
+		}
+	}
+
+	accessControl := access.NewAccessControl(statsStore, &types.AllowedUsersSettings{
+		Mode:  "public",
+		Read:  "all_users",
+		Write: "all_users",
+	})
+
+	tests := []struct {
+		name       string
+		author     string
+		authPubkey string
+		allowed    bool
+	}{
+		{"allowed user authenticated as the author", allowed, allowed, true},
+		{"paid subscriber authenticated as the author", paid, paid, true},
+		{"allowed user without authentication", allowed, "", false},
+		{"allowed user's event on another pubkey's connection", allowed, stranger, false},
+		{"paid subscriber's event on another member's connection", paid, allowed, false},
+		{"lapsed subscriber authenticated as the author", lapsed, lapsed, false},
+		{"stranger authenticated as the author", stranger, stranger, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			event := &nostr.Event{PubKey: test.author, Kind: 1, CreatedAt: nostr.Now(), Tags: nostr.Tags{}, Content: "unmined"}
+			event.ID = event.GetID()
+
+			result := accessControl.EvaluateWritePolicy(event, test.authPubkey)
+			if result.Allowed != test.allowed {
+				t.Fatalf("expected allowed=%v, got %+v", test.allowed, result)
+			}
+			if !test.allowed && result.Violations[0].Requirement != "min_pow_difficulty" {
+				t.Errorf("expected a proof of work violation, got %+v", result.Violations)
+			}
+		})
+	}
+}
+
+func TestEvaluateWritePolicy(t *testing.T) {
+	viper.Reset()
+	viper.Set("event_filtering.proof_of_work.kind_difficulty", map[string]int{"7": 8})
+	config.InitConfigForTesting()
+	t.Cleanup(func() {
+		viper.Reset()
+		config.InitConfigForTesting()
+	})
+	statsStore := newPolicyStatsStore(t)
+
+	proSubscriber := newAccessTestPubkey(t)
+	basicSubscriber := newAccessTestPubkey(t)
+	plusUser := newAccessTestPubkey(t)
+	expiredUser := newAccessTestPubkey(t)
+	stranger := newAccessTestPubkey(t)
+
+	for pubkey, tier := range map[string]string{proSubscriber: "Pro", basicSubscriber: "Basic"} {
+		if err := statsStore.SavePaidSubscriber(&types.PaidSubscriber{Npub: pubkey, Tier: tier, ExpirationDate: time.Now().Add(time.Hour)}); err != nil {
+			t.Fatalf("SavePaidSubscriber: %v", err)
+		}
+	}
+	if err := statsStore.AddAllowedUser(plusUser, true, "Plus", "test"); err != nil {
+		t.Fatalf("AddAllowedUser: %v", err)
+	}
+	expired := time.Now().Add(-time.Hour)
+	if err := statsStore.AddAllowedUserUntil(expiredUser, true, "Plus", "test", &expired); err != nil {
+		t.Fatalf("AddAllowedUserUntil: %v", err)
+	}
+
+	// Tiers rank by the storage they grant, not by their position in the list
+	accessControl := access.NewAccessControl(statsStore, &types.AllowedUsersSettings{
+		Mode:  "public",
+		Read:  "all_users",
+		Write: "all_users",
+		Tiers: []types.SubscriptionTier{
+			{Name: "Pro", Unlimited: true},
+			{Name: "Plus", MonthlyLimitBytes: 5 << 30},
+			{Name: "Basic", MonthlyLimitBytes: 1 << 30},
+		},
+		WritePolicies: []types.WritePolicyRule{
+			{Name: "long-form", Kinds: []int{30023}, MinTier: "plus"},
+			{Name: "dms", Kinds: []int{4}, AuthRequired: true},
+			{Name: "notes", KindRanges: []types.KindRange{{From: 1, To: 1}}, MaxContentLength: 10, MaxTags: 1},
+		},
+	})
+
+	tests := []struct {
+		name       string
+		author     string
+		authPubkey string
+		kind       int
+		content    string
+		tags       nostr.Tags
+		rules      []string
+		violations []string
+	}{
+		{"unlimited tier listed first", proSubscriber, "", 30023, "", nil, []string{"long-form"}, nil},
+		{"lower tier", basicSubscriber, "", 30023, "", nil, []string{"long-form"}, []string{"min_tier"}},
+		{"tier from an allowed user grant", plusUser, "", 30023, "", nil, []string{"long-form"}, nil},
+		{"expired allowed user grant", expiredUser, "", 30023, "", nil, []string{"long-form"}, []string{"min_tier"}},
+		{"no tier", stranger, "", 30023, "", nil, []string{"long-form"}, []string{"min_tier"}},
+		{"unauthenticated", stranger, "", 4, "", nil, []string{"dms"}, []string{"auth_required"}},
+		{"authenticated", stranger, stranger, 4, "", nil, []string{"dms"}, nil},
+		{"within limits", stranger, "", 1, "short", nostr.Tags{{"t", "a"}}, []string{"notes"}, nil},
+		{"every limit exceeded", stranger, "", 1, "far too long", nostr.Tags{{"t", "a"}, {"t", "b"}}, []string{"notes"}, []string{"max_content_length", "max_tags"}},
+		{"kind without rules", stranger, "", 3, "", nil, nil, nil},
+		{"kind proof of work", stranger, "", 7, "", nil, nil, []string{"min_pow_difficulty"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			event := &nostr.Event{PubKey: test.author, Kind: test.kind, CreatedAt: nostr.Now(), Tags: test.tags, Content: test.content}
+			if event.Tags == nil {
+				event.Tags = nostr.Tags{}
+			}
+			event.ID = event.GetID()
+
+			result := accessControl.EvaluateWritePolicy(event, test.authPubkey)
+
+			if len(result.MatchedRules) != len(test.rules) || (len(test.rules) > 0 && result.MatchedRules[0] != test.rules[0]) {
+				t.Errorf("expected matched rules %v, got %v", test.rules, result.MatchedRules)
+			}
+			requirements := []string{}
+			for _, violation := range result.Violations {
+				requirements = append(requirements, violation.Requirement)
+			}
+			if len(requirements) != len(test.violations) {
+				t.Fatalf("expected violations %v, got %+v", test.violations, result.Violations)
+			}
+			for i := range requirements {
+				if requirements[i] != test.violations[i] {
+					t.Fatalf("expected violations %v, got %+v", test.violations, result.Violations)
+				}
+			}
+			if result.Allowed != (len(test.violations) == 0) {
+				t.Errorf("expected allowed=%v, got %v", len(test.violations) == 0, result.Allowed)
+			}
+		})
+	}
+}
+
+func TestEvaluateEventWrite(t *testing.T) {
+	viper.Reset()
+	viper.Set("event_filtering.registered_kinds", []int{1, 30023})
+	viper.Set("event_filtering.kind_whitelist", []string{"kind1"})
+	viper.Set("event_filtering.allow_unregistered_kinds", false)
+	config.InitConfigForTesting()
+	t.Cleanup(func() {
+		viper.Reset()
+		config.InitConfigForTesting()
+	})
+
+	store := newAccessTestStore(t)
+	defer store.Cleanup()
+
+	member := newAccessTestPubkey(t)
+	blocked := newAccessTestPubkey(t)
+	stranger := newAccessTestPubkey(t)
+
+	for _, pubkey := range []string{member, blocked} {
+		if err := store.GetStatsStore().AddAllowedUser(pubkey, true, "", "test"); err != nil {
+			t.Fatalf("AddAllowedUser: %v", err)
+		}
+	}
+	if err := store.BlockPubkey(blocked, "spam"); err != nil {
+		t.Fatalf("BlockPubkey: %v", err)
+	}
+
+	accessControl := access.NewAccessControl(store.GetStatsStore(), &types.AllowedUsersSettings{
+		Mode:  "invite-only",
+		Read:  "all_users",
+		Write: "allowed_users",
+	})
+
+	tests := []struct {
+		name       string
+		author     string
+		kind       int
+		violations []string
+	}{
+		{"allowed user", member, 1, nil},
+		{"blocked pubkey", blocked, 1, []string{"blocked_pubkey"}},
+		{"not an allowed user", stranger, 1, []string{"write_access"}},
+		{"kind outside the whitelist", member, 30023, []string{"kind_whitelist"}},
+		{"every check failing", stranger, 30023, []string{"write_access", "kind_whitelist"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			event := &nostr.Event{PubKey: test.author, Kind: test.kind, CreatedAt: nostr.Now(), Tags: nostr.Tags{}}
+			event.ID = event.GetID()
+
+			result := accessControl.EvaluateEventWrite(event, "", store)
+
+			rules := []string{}
+			for _, violation := range result.Violations {
+				rules = append(rules, violation.Rule)
+			}
+			if len(rules) != len(test.violations) {
+				t.Fatalf("expected violations %v, got %+v", test.violations, result.Violations)
+			}
+			for i := range rules {
+				if rules[i] != test.violations[i] {
+					t.Fatalf("expected violations %v, got %+v", test.violations, result.Violations)
+				}
+			}
+			if result.Allowed != (len(test.violations) == 0) {
+				t.Errorf("expected allowed=%v, got %v", len(test.violations) == 0, result.Allowed)
+			}
+		})
+	}
+}
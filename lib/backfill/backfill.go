@@ -0,0 +1,300 @@
+// Package backfill imports a user's existing history from their other relays when
+// they gain write access here. Jobs read the user's NIP-65 relay list, reconcile
+// with NIP-77 negentropy where the remote supports it or page through REQ otherwise,
+// and store every verified event through the normal kind handlers.
+package backfill
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/HORNET-Storage/hdk-nostr-go/lib/signing"
+	"github.com/nbd-wtf/go-nostr"
+
+	"github.com/HORNET-Storage/hornet-storage/lib/config"
+	"github.com/HORNET-Storage/hornet-storage/lib/logging"
+	"github.com/HORNET-Storage/hornet-storage/lib/stores"
+)
+
+const (
+	JobStatusQueued    = "queued"
+	JobStatusRunning   = "running"
+	JobStatusCompleted = "completed"
+	JobStatusFailed    = "failed"
+
+	MethodNegentropy = "negentropy"
+	MethodREQ        = "req"
+
+	queueSize  = 100
+	maxHistory = 200 // Finished jobs kept for the panel
+)
+
+// QuotaTracker reports how much storage a subscriber has used and may use. The
+// subscription manager implements it; it is injected so this package does not depend on it.
+type QuotaTracker interface {
+	// StorageQuota returns the used and allowed bytes, with limited false when
+	// nothing caps the pubkey's storage
+	StorageQuota(npub string) (used int64, limit int64, limited bool, err error)
+}
+
+// WritePolicy judges an event the way live writes are judged, including proof of
+// work. Access control implements it; it is injected because access control
+// triggers backfills and so cannot be imported here.
+type WritePolicy interface {
+	CheckWritePolicy(event *nostr.Event, authPubkey string) error
+}
+
+// RelayProgress is how far a job got with one source relay
+type RelayProgress struct {
+	Relay   string `json:"relay"`
+	Method  string `json:"method,omitempty"`
+	Fetched int    `json:"fetched"`
+	Error   string `json:"error,omitempty"`
+}
+
+// Job is one backfill run for a pubkey
+type Job struct {
+	Pubkey     string          `json:"pubkey"`
+	Reason     string          `json:"reason"`
+	Status     string          `json:"status"`
+	Relays     []RelayProgress `json:"relays"`
+	Stored     int             `json:"stored"`
+	Duplicates int             `json:"duplicates"`
+	Rejected   int             `json:"rejected"`
+	Bytes      int64           `json:"bytes"`
+	CapReached bool            `json:"cap_reached"`
+	Error      string          `json:"error,omitempty"`
+	QueuedAt   time.Time       `json:"queued_at"`
+	StartedAt  *time.Time      `json:"started_at,omitempty"`
+	FinishedAt *time.Time      `json:"finished_at,omitempty"`
+}
+
+func (j *Job) active() bool {
+	return j.Status == JobStatusQueued || j.Status == JobStatusRunning
+}
+
+func (j *Job) snapshot() Job {
+	copied := *j
+	copied.Relays = append([]RelayProgress(nil), j.Relays...)
+	return copied
+}
+
+// BackfillService runs backfill jobs one at a time from a queue
+type BackfillService struct {
+	store  stores.Store
+	quota  QuotaTracker
+	policy WritePolicy
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+	queue  chan *Job
+
+	mutex sync.RWMutex
+	jobs  []*Job // Oldest first
+}
+
+// NewBackfillService creates the service. quota may be nil to skip storage checks
+// and policy may be nil to skip the write policy.
+func NewBackfillService(store stores.Store, quota QuotaTracker, policy WritePolicy) *BackfillService {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &BackfillService{
+		store:  store,
+		quota:  quota,
+		policy: policy,
+		ctx:    ctx,
+		cancel: cancel,
+		queue:  make(chan *Job, queueSize),
+	}
+}
+
+// Start starts the job worker
+func (bs *BackfillService) Start() {
+	bs.wg.Add(1)
+	go bs.run()
+}
+
+// Stop cancels the running job and stops the worker. Queued jobs are dropped.
+func (bs *BackfillService) Stop() {
+	bs.cancel()
+	bs.wg.Wait()
+}
+
+func (bs *BackfillService) run() {
+	defer bs.wg.Done()
+
+	for {
+		select {
+		case <-bs.ctx.Done():
+			return
+		case job := <-bs.queue:
+			bs.runJob(bs.ctx, job)
+		}
+	}
+}
+
+// Enqueue queues a backfill for pubkey (hex or npub). A pubkey that already has a
+// queued or running job is not queued twice; the existing job is returned instead.
+func (bs *BackfillService) Enqueue(pubkey string, reason string) (Job, error) {
+	cfg, err := config.GetConfig()
+	if err != nil {
+		return Job{}, fmt.Errorf("failed to get config: %w", err)
+	}
+	if !cfg.Backfill.Enabled {
+		return Job{}, fmt.Errorf("backfill is disabled")
+	}
+
+	hexKey, err := normalizePubkey(pubkey)
+	if err != nil {
+		return Job{}, err
+	}
+
+	bs.mutex.Lock()
+	defer bs.mutex.Unlock()
+
+	for _, job := range bs.jobs {
+		if job.Pubkey == hexKey && job.active() {
+			return job.snapshot(), nil
+		}
+	}
+
+	job := &Job{
+		Pubkey:   hexKey,
+		Reason:   reason,
+		Status:   JobStatusQueued,
+		QueuedAt: time.Now(),
+	}
+
+	select {
+	case bs.queue <- job:
+	default:
+		return Job{}, fmt.Errorf("backfill queue is full")
+	}
+
+	bs.jobs = append(bs.jobs, job)
+	bs.pruneLocked()
+
+	logging.Infof("Queued history backfill for %s (%s)", hexKey, reason)
+	return job.snapshot(), nil
+}
+
+// Trigger queues a backfill after write access was granted, if automatic backfill is enabled.
+// Failures are logged rather than returned so they never interrupt the caller.
+func (bs *BackfillService) Trigger(pubkey string, reason string) {
+	cfg, err := config.GetConfig()
+	if err != nil || !cfg.Backfill.Enabled || !cfg.Backfill.AutoTrigger {
+		return
+	}
+
+	if _, err := bs.Enqueue(pubkey, reason); err != nil {
+		logging.Infof("Warning: failed to queue history backfill for %s: %v", pubkey, err)
+	}
+}
+
+// Jobs returns every tracked job, newest first
+func (bs *BackfillService) Jobs() []Job {
+	bs.mutex.RLock()
+	defer bs.mutex.RUnlock()
+
+	jobs := make([]Job, 0, len(bs.jobs))
+	for i := len(bs.jobs) - 1; i >= 0; i-- {
+		jobs = append(jobs, bs.jobs[i].snapshot())
+	}
+	return jobs
+}
+
+// LatestJob returns the most recent job for pubkey (hex or npub)
+func (bs *BackfillService) LatestJob(pubkey string) (Job, bool) {
+	hexKey, err := normalizePubkey(pubkey)
+	if err != nil {
+		return Job{}, false
+	}
+
+	bs.mutex.RLock()
+	defer bs.mutex.RUnlock()
+
+	for i := len(bs.jobs) - 1; i >= 0; i-- {
+		if bs.jobs[i].Pubkey == hexKey {
+			return bs.jobs[i].snapshot(), true
+		}
+	}
+	return Job{}, false
+}
+
+// update applies a change to a job under the service lock so snapshots stay consistent
+func (bs *BackfillService) update(job *Job, change func(job *Job)) {
+	bs.mutex.Lock()
+	defer bs.mutex.Unlock()
+	change(job)
+}
+
+// pruneLocked drops the oldest finished jobs beyond the history limit. Must be called with bs.mutex held.
+func (bs *BackfillService) pruneLocked() {
+	excess := len(bs.jobs) - maxHistory
+	if excess <= 0 {
+		return
+	}
+
+	kept := bs.jobs[:0]
+	for _, job := range bs.jobs {
+		if excess > 0 && !job.active() {
+			excess--
+			continue
+		}
+		kept = append(kept, job)
+	}
+	bs.jobs = kept
+}
+
+func normalizePubkey(pubkey string) (string, error) {
+	keyBytes, err := signing.DecodeKey(pubkey)
+	if err != nil || len(keyBytes) != 32 {
+		return "", fmt.Errorf("invalid pubkey: %s", pubkey)
+	}
+	return hex.EncodeToString(keyBytes), nil
+}
+
+// Global service instance
+var globalBackfillService *BackfillService
+var serviceMutex sync.RWMutex
+
+// InitGlobalBackfillService initializes and starts the global backfill service
+func InitGlobalBackfillService(store stores.Store, quota QuotaTracker, policy WritePolicy) error {
+	serviceMutex.Lock()
+	defer serviceMutex.Unlock()
+
+	if globalBackfillService != nil {
+		return fmt.Errorf("backfill service already initialized")
+	}
+
+	globalBackfillService = NewBackfillService(store, quota, policy)
+	globalBackfillService.Start()
+	return nil
+}
+
+// GetGlobalBackfillService returns the global backfill service instance
+func GetGlobalBackfillService() *BackfillService {
+	serviceMutex.RLock()
+	defer serviceMutex.RUnlock()
+	return globalBackfillService
+}
+
+// StopGlobalBackfillService stops the global backfill service
+func StopGlobalBackfillService() {
+	serviceMutex.Lock()
+	defer serviceMutex.Unlock()
+
+	if globalBackfillService != nil {
+		globalBackfillService.Stop()
+		globalBackfillService = nil
+	}
+}
+
+// Trigger queues an automatic backfill on the global service, if it is running
+func Trigger(pubkey string, reason string) {
+	if service := GetGlobalBackfillService(); service != nil {
+		service.Trigger(pubkey, reason)
+	}
+}
@@ -0,0 +1,365 @@
+package backfill_test
+
+import (
+	"encoding/hex"
+	"fmt"
+	"net"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gofiber/contrib/websocket"
+	"github.com/gofiber/fiber/v2"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/spf13/viper"
+
+	"github.com/HORNET-Storage/hornet-storage/lib/backfill"
+	"github.com/HORNET-Storage/hornet-storage/lib/config"
+	lib_nostr "github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr"
+	"github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr/universal"
+	"github.com/HORNET-Storage/hornet-storage/lib/negentropy"
+	"github.com/HORNET-Storage/hornet-storage/lib/stores"
+	"github.com/HORNET-Storage/hornet-storage/lib/stores/badgerhold"
+)
+
+// startFakeRelay serves events over REQ, and over NIP-77 when supportsNegentropy is
+// set. Without it NEG-OPEN gets a NOTICE like a relay that does not know the verb.
+func startFakeRelay(t *testing.T, events []*nostr.Event, supportsNegentropy bool) string {
+	app := fiber.New(fiber.Config{DisableStartupMessage: true})
+	app.Get("/", websocket.New(func(c *websocket.Conn) {
+		var mu sync.Mutex
+		sessions := map[string]*negentropy.Negentropy{}
+
+		send := func(message ...interface{}) {
+			data, _ := jsoniter.Marshal(message)
+			mu.Lock()
+			defer mu.Unlock()
+			c.WriteMessage(websocket.TextMessage, data)
+		}
+
+		for {
+			_, data, err := c.ReadMessage()
+			if err != nil {
+				return
+			}
+
+			var message []jsoniter.RawMessage
+			if jsoniter.Unmarshal(data, &message) != nil || len(message) < 2 {
+				continue
+			}
+			var label, subID string
+			jsoniter.Unmarshal(message[0], &label)
+			jsoniter.Unmarshal(message[1], &subID)
+
+			switch label {
+			case "REQ":
+				var filter nostr.Filter
+				jsoniter.Unmarshal(message[2], &filter)
+				for _, event := range matching(events, filter) {
+					send("EVENT", subID, event)
+				}
+				send("EOSE", subID)
+
+			case "NEG-OPEN":
+				if !supportsNegentropy {
+					send("NOTICE", "unknown message type NEG-OPEN")
+					continue
+				}
+				var filter nostr.Filter
+				jsoniter.Unmarshal(message[2], &filter)
+				var items []negentropy.Item
+				for _, event := range matching(events, filter) {
+					item := negentropy.Item{Timestamp: uint64(event.CreatedAt)}
+					raw, _ := hex.DecodeString(event.ID)
+					copy(item.ID[:], raw)
+					items = append(items, item)
+				}
+				sessions[subID] = negentropy.New(items)
+				respond(sessions[subID], message[3], subID, send)
+
+			case "NEG-MSG":
+				if session := sessions[subID]; session != nil {
+					respond(session, message[2], subID, send)
+				}
+
+			case "NEG-CLOSE":
+				delete(sessions, subID)
+			}
+		}
+	}))
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	go app.Listener(listener)
+	t.Cleanup(func() { app.Shutdown() })
+
+	return "ws://" + listener.Addr().String()
+}
+
+func respond(session *negentropy.Negentropy, raw jsoniter.RawMessage, subID string, send func(...interface{})) {
+	var payload string
+	jsoniter.Unmarshal(raw, &payload)
+	query, _ := hex.DecodeString(payload)
+
+	response, _, _, err := session.Reconcile(query)
+	if err != nil {
+		send("NEG-ERR", subID, "error: "+err.Error())
+		return
+	}
+	send("NEG-MSG", subID, hex.EncodeToString(response))
+}
+
+// matching returns the events a filter selects, newest first and capped at its limit
+func matching(events []*nostr.Event, filter nostr.Filter) []*nostr.Event {
+	var matched []*nostr.Event
+	for _, event := range events {
+		if filter.Matches(event) {
+			matched = append(matched, event)
+		}
+	}
+	sort.Slice(matched, func(i, j int) bool { return matched[i].CreatedAt > matched[j].CreatedAt })
+	if filter.Limit > 0 && len(matched) > filter.Limit {
+		matched = matched[:filter.Limit]
+	}
+	return matched
+}
+
+func setupBackfillTest(t *testing.T) (stores.Store, string, func(kind int, content string, createdAt nostr.Timestamp, tags ...nostr.Tag) *nostr.Event) {
+	viper.Reset()
+	viper.Set("event_filtering.allow_unregistered_kinds", true)
+	viper.Set("backfill.enabled", true)
+	viper.Set("backfill.negentropy", true)
+	viper.Set("backfill.max_relays", 5)
+	viper.Set("backfill.relay_timeout_seconds", 5)
+	config.InitConfigForTesting()
+	t.Cleanup(viper.Reset)
+
+	tempDir := t.TempDir()
+	store, err := badgerhold.InitStore(filepath.Join(tempDir, "store"), filepath.Join(tempDir, "stats.db"))
+	if err != nil {
+		t.Fatalf("InitStore: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := store.Cleanup(); err != nil {
+			t.Fatalf("Cleanup: %v", err)
+		}
+	})
+
+	lib_nostr.ClearHandlers()
+	lib_nostr.RegisterHandler("universal", universal.BuildUniversalHandler(store))
+	t.Cleanup(lib_nostr.ClearHandlers)
+
+	authorKey := nostr.GeneratePrivateKey()
+	author, _ := nostr.GetPublicKey(authorKey)
+
+	sign := func(kind int, content string, createdAt nostr.Timestamp, tags ...nostr.Tag) *nostr.Event {
+		event := &nostr.Event{Kind: kind, Content: content, CreatedAt: createdAt, Tags: tags}
+		if err := event.Sign(authorKey); err != nil {
+			t.Fatalf("Sign: %v", err)
+		}
+		return event
+	}
+
+	return store, author, sign
+}
+
+// runBackfill queues a job on a fresh service and waits for it to finish
+func runBackfill(t *testing.T, store stores.Store, pubkey string) backfill.Job {
+	return runBackfillWith(t, store, pubkey, nil, nil)
+}
+
+func runBackfillWith(t *testing.T, store stores.Store, pubkey string, quota backfill.QuotaTracker, policy backfill.WritePolicy) backfill.Job {
+	service := backfill.NewBackfillService(store, quota, policy)
+	service.Start()
+	t.Cleanup(service.Stop)
+
+	if _, err := service.Enqueue(pubkey, "test"); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+
+	deadline := time.Now().Add(30 * time.Second)
+	for time.Now().Before(deadline) {
+		if job, ok := service.LatestJob(pubkey); ok && job.FinishedAt != nil {
+			return job
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Fatal("backfill did not finish")
+	return backfill.Job{}
+}
+
+func TestBackfillPrefersNegentropyAndFallsBackToREQ(t *testing.T) {
+	store, author, sign := setupBackfillTest(t)
+
+	var notes []*nostr.Event
+	for i := 0; i < 30; i++ {
+		notes = append(notes, sign(1, fmt.Sprintf("note %d", i), nostr.Timestamp(1700000000+i/3)))
+	}
+	var extra []*nostr.Event
+	for i := 0; i < 3; i++ {
+		extra = append(extra, sign(1, fmt.Sprintf("only on the second relay %d", i), nostr.Timestamp(1600000000+i)))
+	}
+
+	// Tampered after signing, so it no longer matches its id
+	tampered := sign(1, "original", 1700000100)
+	tampered.Content = "tampered"
+
+	negentropyRelay := startFakeRelay(t, append(append([]*nostr.Event{}, notes...), tampered), true)
+	reqRelay := startFakeRelay(t, append(append([]*nostr.Event{}, notes...), extra...), false)
+
+	// The first five notes are already here, so negentropy should not fetch them
+	for _, note := range notes[:5] {
+		if err := store.StoreEvent(note); err != nil {
+			t.Fatalf("StoreEvent: %v", err)
+		}
+	}
+	relayList := sign(10002, "", nostr.Now(),
+		nostr.Tag{"r", negentropyRelay}, nostr.Tag{"r", reqRelay, "write"}, nostr.Tag{"r", "wss://read-only.example.com", "read"})
+	if err := store.StoreEvent(relayList); err != nil {
+		t.Fatalf("StoreEvent: %v", err)
+	}
+
+	job := runBackfill(t, store, author)
+
+	if job.Status != backfill.JobStatusCompleted {
+		t.Fatalf("expected the job to complete, got %s (%s)", job.Status, job.Error)
+	}
+	if len(job.Relays) != 2 {
+		t.Fatalf("expected the two write relays as sources, got %+v", job.Relays)
+	}
+	if job.Relays[0].Method != backfill.MethodNegentropy || job.Relays[0].Fetched != 26 {
+		t.Errorf("expected negentropy to fetch only the 26 missing events, got %+v", job.Relays[0])
+	}
+	if job.Relays[1].Method != backfill.MethodREQ || job.Relays[1].Fetched != 33 {
+		t.Errorf("expected REQ to page through all 33 events, got %+v", job.Relays[1])
+	}
+	if job.Stored != 28 || job.Duplicates != 30 || job.Rejected != 1 {
+		t.Errorf("expected 28 stored, 30 duplicates and 1 rejected, got %d, %d and %d", job.Stored, job.Duplicates, job.Rejected)
+	}
+
+	for _, event := range append(notes, extra...) {
+		if stored, _ := store.QueryEvents(nostr.Filter{IDs: []string{event.ID}}); len(stored) != 1 {
+			t.Errorf("expected event %s to be stored", event.ID)
+		}
+	}
+	if stored, _ := store.QueryEvents(nostr.Filter{IDs: []string{tampered.ID}}); len(stored) != 0 {
+		t.Error("expected the tampered event to be rejected")
+	}
+}
+
+func TestBackfillStopsAtEventCap(t *testing.T) {
+	store, author, sign := setupBackfillTest(t)
+	viper.Set("backfill.max_events", 10)
+	viper.Set("backfill.negentropy", false)
+	config.InitConfigForTesting()
+
+	var notes []*nostr.Event
+	for i := 0; i < 25; i++ {
+		notes = append(notes, sign(1, fmt.Sprintf("note %d", i), nostr.Timestamp(1700000000+i)))
+	}
+	relay := startFakeRelay(t, notes, true)
+	viper.Set("backfill.fallback_relays", []string{relay})
+	config.InitConfigForTesting()
+
+	job := runBackfill(t, store, author)
+
+	if job.Status != backfill.JobStatusCompleted || !job.CapReached || job.Stored != 10 {
+		t.Fatalf("expected the job to stop after 10 events, got %+v", job)
+	}
+	if job.Relays[0].Method != backfill.MethodREQ {
+		t.Errorf("expected REQ when negentropy is disabled, got %s", job.Relays[0].Method)
+	}
+}
+
+// fixedQuota reports the same usage and limit for every pubkey
+type fixedQuota struct {
+	used    int64
+	limit   int64
+	limited bool
+}
+
+func (q fixedQuota) StorageQuota(npub string) (int64, int64, bool, error) {
+	return q.used, q.limit, q.limited, nil
+}
+
+func TestBackfillStopsAtStorageQuota(t *testing.T) {
+	for _, test := range []struct {
+		name   string
+		quota  fixedQuota
+		stored int
+	}{
+		{"limited", fixedQuota{used: 1000, limited: true}, 4},
+		{"unlimited", fixedQuota{used: 1000}, 10},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			store, author, sign := setupBackfillTest(t)
+			viper.Set("backfill.negentropy", false)
+
+			var notes []*nostr.Event
+			for i := 0; i < 10; i++ {
+				notes = append(notes, sign(1, fmt.Sprintf("note %d", i), nostr.Timestamp(1700000000+i)))
+			}
+			relay := startFakeRelay(t, notes, false)
+			viper.Set("backfill.fallback_relays", []string{relay})
+			config.InitConfigForTesting()
+
+			// Every note encodes to the same size, so the quota has room for exactly four
+			encoded, _ := jsoniter.Marshal(nostr.EventEnvelope{Event: *notes[0]})
+			test.quota.limit = test.quota.used + 4*int64(len(encoded))
+
+			job := runBackfillWith(t, store, author, test.quota, nil)
+
+			if job.Status != backfill.JobStatusCompleted || job.Stored != test.stored || job.CapReached != test.quota.limited {
+				t.Fatalf("expected %d stored events, got %+v", test.stored, job)
+			}
+		})
+	}
+}
+
+// keywordPolicy rejects events containing a keyword and records the pubkeys it judged as authenticated
+type keywordPolicy struct {
+	keyword string
+	mutex   sync.Mutex
+	authed  map[string]bool
+}
+
+func (p *keywordPolicy) CheckWritePolicy(event *nostr.Event, authPubkey string) error {
+	p.mutex.Lock()
+	p.authed[authPubkey] = true
+	p.mutex.Unlock()
+
+	if strings.Contains(event.Content, p.keyword) {
+		return fmt.Errorf("pow: difficulty too low")
+	}
+	return nil
+}
+
+func TestBackfillAppliesWritePolicy(t *testing.T) {
+	store, author, sign := setupBackfillTest(t)
+	viper.Set("backfill.negentropy", false)
+
+	allowed := sign(1, "hello", 1700000000)
+	rejected := sign(1, "needs more work", 1700000001)
+	relay := startFakeRelay(t, []*nostr.Event{allowed, rejected}, false)
+	viper.Set("backfill.fallback_relays", []string{relay})
+	config.InitConfigForTesting()
+
+	policy := &keywordPolicy{keyword: "work", authed: map[string]bool{}}
+	job := runBackfillWith(t, store, author, nil, policy)
+
+	if job.Stored != 1 || job.Rejected != 1 {
+		t.Fatalf("expected 1 stored and 1 rejected, got %+v", job)
+	}
+	if stored, _ := store.QueryEvents(nostr.Filter{IDs: []string{rejected.ID}}); len(stored) != 0 {
+		t.Error("expected the event failing the write policy not to be stored")
+	}
+	if len(policy.authed) != 1 || !policy.authed[author] {
+		t.Errorf("expected events to be judged as authenticated by the author, got %v", policy.authed)
+	}
+}
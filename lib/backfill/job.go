@@ -0,0 +1,345 @@
+package backfill
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/nbd-wtf/go-nostr"
+
+	"github.com/HORNET-Storage/hornet-storage/lib/config"
+	lib_nostr "github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr"
+	"github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr/kind10002"
+	"github.com/HORNET-Storage/hornet-storage/lib/logging"
+	"github.com/HORNET-Storage/hornet-storage/lib/negentropy"
+	"github.com/HORNET-Storage/hornet-storage/lib/types"
+)
+
+const defaultRelayTimeout = 60 * time.Second
+
+// errStop ends a job early once a cap or the user's storage quota is reached
+var errStop = errors.New("backfill stopped")
+
+// jobRun is the working state of a job that is not shown on the panel
+type jobRun struct {
+	job    *Job
+	config types.BackfillConfig
+	known  map[string]bool // Ids stored locally, including those stored by this job
+	stop   error
+
+	// Storage used before the job started and the user's limit. Stored events are
+	// charged asynchronously, so the job adds its own bytes rather than re-reading usage.
+	usedBytes  int64
+	limitBytes int64
+	limited    bool
+}
+
+func (bs *BackfillService) runJob(ctx context.Context, job *Job) {
+	cfg, err := config.GetConfig()
+	if err != nil {
+		bs.finish(job, err)
+		return
+	}
+
+	started := time.Now()
+	bs.update(job, func(job *Job) {
+		job.Status = JobStatusRunning
+		job.StartedAt = &started
+	})
+	logging.Infof("Starting history backfill for %s", job.Pubkey)
+
+	run := &jobRun{job: job, config: cfg.Backfill}
+
+	if bs.quota != nil {
+		run.usedBytes, run.limitBytes, run.limited, err = bs.quota.StorageQuota(job.Pubkey)
+		if err != nil {
+			bs.finish(job, fmt.Errorf("failed to read storage quota: %w", err))
+			return
+		}
+	}
+
+	local, err := bs.localItems(job.Pubkey)
+	if err != nil {
+		bs.finish(job, fmt.Errorf("failed to read local history: %w", err))
+		return
+	}
+	run.known = make(map[string]bool, len(local))
+	for _, item := range local {
+		run.known[hex.EncodeToString(item.ID[:])] = true
+	}
+
+	relays := bs.sourceRelays(ctx, run)
+	if len(relays) == 0 {
+		bs.finish(job, fmt.Errorf("no relays to backfill from"))
+		return
+	}
+
+	bs.update(job, func(job *Job) {
+		for _, relay := range relays {
+			job.Relays = append(job.Relays, RelayProgress{Relay: relay})
+		}
+	})
+
+	for i, relay := range relays {
+		if ctx.Err() != nil || run.stop != nil {
+			break
+		}
+		bs.backfillRelay(ctx, run, i, relay, local)
+	}
+
+	if run.stop != nil && !errors.Is(run.stop, errStop) {
+		bs.finish(job, run.stop)
+		return
+	}
+	bs.finish(job, ctx.Err())
+}
+
+func (bs *BackfillService) finish(job *Job, err error) {
+	finished := time.Now()
+	bs.update(job, func(job *Job) {
+		job.FinishedAt = &finished
+		job.Status = JobStatusCompleted
+		if err != nil {
+			job.Status = JobStatusFailed
+			job.Error = err.Error()
+		}
+	})
+
+	if err != nil {
+		logging.Infof("History backfill for %s failed: %v", job.Pubkey, err)
+		return
+	}
+	logging.Infof("History backfill for %s completed: %d stored, %d duplicates, %d rejected",
+		job.Pubkey, job.Stored, job.Duplicates, job.Rejected)
+}
+
+// backfillRelay imports from one relay, preferring negentropy and falling back to paged REQ
+func (bs *BackfillService) backfillRelay(ctx context.Context, run *jobRun, index int, relay string, local []negentropy.Item) {
+	progress := func(change func(progress *RelayProgress)) {
+		bs.update(run.job, func(job *Job) { change(&job.Relays[index]) })
+	}
+	handle := func(event *nostr.Event) bool {
+		progress(func(progress *RelayProgress) { progress.Fetched++ })
+		return bs.ingest(run, event)
+	}
+
+	r, err := dialRemote(ctx, relay, run.relayTimeout())
+	if err != nil {
+		progress(func(progress *RelayProgress) { progress.Error = err.Error() })
+		return
+	}
+	defer r.close()
+
+	if run.config.Negentropy {
+		need, err := r.reconcile(ctx, nostr.Filter{Authors: []string{run.job.Pubkey}}, local)
+		if err == nil {
+			progress(func(progress *RelayProgress) { progress.Method = MethodNegentropy })
+			if _, err := r.fetchIDs(ctx, need, handle); err != nil {
+				progress(func(progress *RelayProgress) { progress.Error = err.Error() })
+			}
+			return
+		}
+		if !errors.Is(err, errNegentropyUnsupported) {
+			progress(func(progress *RelayProgress) { progress.Error = err.Error() })
+			return
+		}
+		logging.Infof("Relay %s does not support negentropy, backfilling with REQ", relay)
+	}
+
+	progress(func(progress *RelayProgress) { progress.Method = MethodREQ })
+	if _, err := r.paginate(ctx, run.job.Pubkey, handle); err != nil {
+		progress(func(progress *RelayProgress) { progress.Error = err.Error() })
+	}
+}
+
+// ingest verifies an event and stores it through its kind handler. It returns false once the job must stop.
+func (bs *BackfillService) ingest(run *jobRun, event *nostr.Event) bool {
+	job := run.job
+	reject := func(reason string) bool {
+		logging.Infof("Backfill rejected event %s for %s: %s", event.ID, job.Pubkey, reason)
+		bs.update(job, func(job *Job) { job.Rejected++ })
+		return true
+	}
+
+	if event.PubKey != job.Pubkey {
+		return reject("event is by another author")
+	}
+	if event.GetID() != event.ID {
+		return reject("event id does not match its content")
+	}
+	if ok, err := event.CheckSignature(); err != nil || !ok {
+		return reject("invalid signature")
+	}
+	if event.Kind >= 20000 && event.Kind < 30000 {
+		return reject("ephemeral events are not stored")
+	}
+
+	if run.known[event.ID] {
+		bs.update(job, func(job *Job) { job.Duplicates++ })
+		return true
+	}
+
+	envelope := nostr.EventEnvelope{Event: *event}
+	data, err := json.Marshal(envelope)
+	if err != nil {
+		return reject("failed to encode event")
+	}
+	size := int64(len(data))
+
+	if run.config.MaxEvents > 0 && job.Stored >= run.config.MaxEvents ||
+		run.config.MaxBytes > 0 && job.Bytes+size > run.config.MaxBytes {
+		bs.update(job, func(job *Job) { job.CapReached = true })
+		run.stop = errStop
+		return false
+	}
+
+	// Stored events are charged to the subscriber by the statistics store, so only check for room here
+	if run.limited && run.usedBytes+job.Bytes+size > run.limitBytes {
+		bs.update(job, func(job *Job) { job.CapReached = true })
+		run.stop = errStop
+		return false
+	}
+
+	// The job runs for the user who was granted write access, so their events are
+	// judged as if they had authenticated to publish them
+	if bs.policy != nil {
+		if err := bs.policy.CheckWritePolicy(event, job.Pubkey); err != nil {
+			return reject(err.Error())
+		}
+	}
+
+	handler := lib_nostr.GetHandler(fmt.Sprintf("kind/%d", event.Kind))
+	if handler == nil && lib_nostr.IsKindAllowed(event.Kind) {
+		handler = lib_nostr.GetHandler("universal")
+	}
+	if handler == nil || !lib_nostr.IsKindAllowed(event.Kind) {
+		return reject(fmt.Sprintf("kind %d not allowed", event.Kind))
+	}
+
+	var reason string
+	write, accepted := lib_nostr.TrackAccepted(func(messageType string, params ...interface{}) {
+		if flat := lib_nostr.ExtractInterfaceValues(params...); len(flat) > 0 {
+			reason = fmt.Sprint(flat[len(flat)-1])
+		}
+	})
+
+	// History is not published to the event bus, so it is not mirrored or pushed again
+	handler(func() ([]byte, error) { return data, nil }, write)
+
+	if !accepted() {
+		return reject(reason)
+	}
+
+	run.known[event.ID] = true
+	bs.update(job, func(job *Job) {
+		job.Stored++
+		job.Bytes += size
+	})
+	return true
+}
+
+// sourceRelays returns the user's NIP-65 write relays, looking the list up on the
+// fallback relays when it is not stored locally, or the fallback relays themselves
+func (bs *BackfillService) sourceRelays(ctx context.Context, run *jobRun) []string {
+	relayList, err := kind10002.LatestRelayList(bs.store, run.job.Pubkey)
+	if err != nil {
+		logging.Infof("Warning: failed to read relay list for %s: %v", run.job.Pubkey, err)
+	}
+	if relayList == nil {
+		relayList = bs.fetchRelayList(ctx, run)
+	}
+
+	candidates := kind10002.WriteRelays(relayList)
+	if len(candidates) == 0 {
+		candidates = run.config.FallbackRelays
+	}
+
+	var relays []string
+	for _, relay := range candidates {
+		normalized := nostr.NormalizeURL(relay)
+		if normalized == "" || slices.Contains(relays, normalized) {
+			continue
+		}
+		relays = append(relays, normalized)
+		if run.config.MaxRelays > 0 && len(relays) >= run.config.MaxRelays {
+			break
+		}
+	}
+	return relays
+}
+
+// fetchRelayList asks the fallback relays for the user's newest kind 10002
+func (bs *BackfillService) fetchRelayList(ctx context.Context, run *jobRun) *nostr.Event {
+	var latest *nostr.Event
+	for _, relay := range run.config.FallbackRelays {
+		r, err := dialRemote(ctx, nostr.NormalizeURL(relay), run.relayTimeout())
+		if err != nil {
+			continue
+		}
+
+		r.query(ctx, nostr.Filter{Kinds: []int{10002}, Authors: []string{run.job.Pubkey}, Limit: 1}, func(event *nostr.Event) bool {
+			if event.PubKey != run.job.Pubkey || event.Kind != 10002 {
+				return true
+			}
+			if ok, _ := event.CheckSignature(); ok && (latest == nil || event.CreatedAt > latest.CreatedAt) {
+				latest = event
+			}
+			return true
+		})
+		r.close()
+	}
+	return latest
+}
+
+// localItems lists the author's stored events as negentropy items, paging by
+// created_at because unbounded queries are capped
+func (bs *BackfillService) localItems(pubkey string) ([]negentropy.Item, error) {
+	seen := make(map[string]bool)
+	var items []negentropy.Item
+	var until *nostr.Timestamp
+
+	for {
+		events, err := bs.store.QueryEvents(nostr.Filter{Authors: []string{pubkey}, Limit: reqPageSize, Until: until})
+		if err != nil {
+			return nil, err
+		}
+
+		var oldest nostr.Timestamp
+		fresh := 0
+		for _, event := range events {
+			if seen[event.ID] {
+				continue
+			}
+			seen[event.ID] = true
+
+			raw, err := hex.DecodeString(event.ID)
+			if err != nil || len(raw) != negentropy.IDSize {
+				continue
+			}
+			item := negentropy.Item{Timestamp: uint64(event.CreatedAt)}
+			copy(item.ID[:], raw)
+			items = append(items, item)
+
+			fresh++
+			if oldest == 0 || event.CreatedAt < oldest {
+				oldest = event.CreatedAt
+			}
+		}
+
+		if fresh == 0 {
+			return items, nil
+		}
+		next := oldest
+		until = &next
+	}
+}
+
+func (run *jobRun) relayTimeout() time.Duration {
+	if run.config.RelayTimeoutSeconds <= 0 {
+		return defaultRelayTimeout
+	}
+	return time.Duration(run.config.RelayTimeoutSeconds) * time.Second
+}
@@ -0,0 +1,301 @@
+package backfill
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/nbd-wtf/go-nostr"
+
+	"github.com/HORNET-Storage/hornet-storage/lib/negentropy"
+)
+
+const (
+	reqPageSize = 500
+	idChunkSize = 100
+)
+
+// errNegentropyUnsupported means the remote refused NEG-OPEN, so REQ is used instead
+var errNegentropyUnsupported = errors.New("negentropy not supported")
+
+var json = jsoniter.ConfigCompatibleWithStandardLibrary
+
+var subscriptionCounter atomic.Int64
+
+func nextSubscriptionID(prefix string) string {
+	return fmt.Sprintf("%s-%d", prefix, subscriptionCounter.Add(1))
+}
+
+// remote is a raw connection to a source relay. go-nostr's Relay drops NEG-MSG
+// frames, so messages are read directly and handed over on a channel.
+type remote struct {
+	conn     *nostr.Connection
+	timeout  time.Duration // Longest wait for any single message
+	messages chan []jsoniter.RawMessage
+	done     chan struct{}
+	readErr  error
+}
+
+func dialRemote(ctx context.Context, url string, timeout time.Duration) (*remote, error) {
+	dialCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	conn, err := nostr.NewConnection(dialCtx, url, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &remote{
+		conn:     conn,
+		timeout:  timeout,
+		messages: make(chan []jsoniter.RawMessage, 64),
+		done:     make(chan struct{}),
+	}
+	go r.read()
+	return r, nil
+}
+
+func (r *remote) read() {
+	defer close(r.messages)
+
+	for {
+		var buf bytes.Buffer
+		if err := r.conn.ReadMessage(context.Background(), &buf); err != nil {
+			r.readErr = err
+			return
+		}
+
+		var message []jsoniter.RawMessage
+		if err := json.Unmarshal(buf.Bytes(), &message); err != nil || len(message) == 0 {
+			continue
+		}
+
+		select {
+		case r.messages <- message:
+		case <-r.done:
+			return
+		}
+	}
+}
+
+func (r *remote) close() {
+	close(r.done)
+	r.conn.Close()
+}
+
+func (r *remote) send(message ...interface{}) error {
+	data, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	return r.conn.WriteMessage(data)
+}
+
+// next waits for the next message and returns its label with the raw elements
+func (r *remote) next(ctx context.Context) (string, []jsoniter.RawMessage, error) {
+	timer := time.NewTimer(r.timeout)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return "", nil, ctx.Err()
+	case <-timer.C:
+		return "", nil, fmt.Errorf("no response within %s", r.timeout)
+	case message, ok := <-r.messages:
+		if !ok {
+			if r.readErr != nil {
+				return "", nil, r.readErr
+			}
+			return "", nil, errors.New("connection closed")
+		}
+		var label string
+		if err := json.Unmarshal(message[0], &label); err != nil {
+			return "", nil, fmt.Errorf("invalid message label: %w", err)
+		}
+		return label, message, nil
+	}
+}
+
+// reconcile runs NIP-77 against the remote for filter and returns the ids it has that local lacks
+func (r *remote) reconcile(ctx context.Context, filter nostr.Filter, local []negentropy.Item) ([]string, error) {
+	client := negentropy.New(local)
+	subID := nextSubscriptionID("backfill-neg")
+
+	if err := r.send("NEG-OPEN", subID, filter, hex.EncodeToString(client.Initiate())); err != nil {
+		return nil, err
+	}
+
+	var need []string
+	for {
+		label, message, err := r.next(ctx)
+		if err != nil {
+			if len(need) == 0 {
+				// A relay that silently ignores NEG-OPEN never answers
+				return nil, fmt.Errorf("%w: %v", errNegentropyUnsupported, err)
+			}
+			return nil, err
+		}
+
+		switch label {
+		case "NEG-MSG":
+			if len(message) < 3 || !matchesSubscription(message[1], subID) {
+				continue
+			}
+			var payload string
+			if err := json.Unmarshal(message[2], &payload); err != nil {
+				return nil, fmt.Errorf("invalid NEG-MSG: %w", err)
+			}
+			query, err := hex.DecodeString(payload)
+			if err != nil {
+				return nil, fmt.Errorf("invalid NEG-MSG: %w", err)
+			}
+
+			response, _, needIDs, err := client.Reconcile(query)
+			if err != nil {
+				r.send("NEG-CLOSE", subID)
+				return nil, err
+			}
+			for _, id := range needIDs {
+				need = append(need, hex.EncodeToString(id[:]))
+			}
+
+			if response == nil {
+				r.send("NEG-CLOSE", subID)
+				return need, nil
+			}
+			if err := r.send("NEG-MSG", subID, hex.EncodeToString(response)); err != nil {
+				return nil, err
+			}
+
+		case "NEG-ERR", "CLOSED":
+			if len(message) >= 2 && matchesSubscription(message[1], subID) {
+				return nil, fmt.Errorf("%w: %s", errNegentropyUnsupported, reasonOf(message))
+			}
+
+		case "NOTICE":
+			// Relays without NIP-77 answer an unknown verb with a notice
+			return nil, fmt.Errorf("%w: %s", errNegentropyUnsupported, reasonOf(message))
+		}
+	}
+}
+
+// query sends one REQ and passes each matching event to handle until EOSE.
+// handle returns false to stop early. It returns the number of events received.
+func (r *remote) query(ctx context.Context, filter nostr.Filter, handle func(event *nostr.Event) bool) (int, error) {
+	subID := nextSubscriptionID("backfill")
+	if err := r.send("REQ", subID, filter); err != nil {
+		return 0, err
+	}
+	defer r.send("CLOSE", subID)
+
+	received := 0
+	for {
+		label, message, err := r.next(ctx)
+		if err != nil {
+			return received, err
+		}
+		if len(message) < 2 || !matchesSubscription(message[1], subID) {
+			continue
+		}
+
+		switch label {
+		case "EVENT":
+			if len(message) < 3 {
+				continue
+			}
+			var event nostr.Event
+			if err := json.Unmarshal(message[2], &event); err != nil {
+				continue
+			}
+			received++
+			if !handle(&event) {
+				return received, nil
+			}
+
+		case "EOSE":
+			return received, nil
+
+		case "CLOSED":
+			return received, fmt.Errorf("subscription closed: %s", reasonOf(message))
+		}
+	}
+}
+
+// fetchIDs requests specific events in chunks
+func (r *remote) fetchIDs(ctx context.Context, ids []string, handle func(event *nostr.Event) bool) (int, error) {
+	received := 0
+	for start := 0; start < len(ids); start += idChunkSize {
+		chunk := ids[start:min(start+idChunkSize, len(ids))]
+
+		stopped := false
+		count, err := r.query(ctx, nostr.Filter{IDs: chunk, Limit: len(chunk)}, func(event *nostr.Event) bool {
+			if !handle(event) {
+				stopped = true
+				return false
+			}
+			return true
+		})
+		received += count
+		if err != nil || stopped {
+			return received, err
+		}
+	}
+	return received, nil
+}
+
+// paginate pages backwards through the author's history with until/limit until a
+// page brings nothing new. Pages overlap by one second so events sharing a timestamp
+// across a page boundary are not missed.
+func (r *remote) paginate(ctx context.Context, pubkey string, handle func(event *nostr.Event) bool) (int, error) {
+	seen := make(map[string]bool)
+	received := 0
+	var until *nostr.Timestamp
+
+	for {
+		filter := nostr.Filter{Authors: []string{pubkey}, Limit: reqPageSize, Until: until}
+
+		var oldest nostr.Timestamp
+		fresh := 0
+		stopped := false
+		_, err := r.query(ctx, filter, func(event *nostr.Event) bool {
+			if seen[event.ID] {
+				return true
+			}
+			seen[event.ID] = true
+			fresh++
+			received++
+			if oldest == 0 || event.CreatedAt < oldest {
+				oldest = event.CreatedAt
+			}
+			if !handle(event) {
+				stopped = true
+				return false
+			}
+			return true
+		})
+		if err != nil || stopped || fresh == 0 {
+			return received, err
+		}
+
+		next := oldest
+		until = &next
+	}
+}
+
+func matchesSubscription(raw jsoniter.RawMessage, subID string) bool {
+	var id string
+	return json.Unmarshal(raw, &id) == nil && id == subID
+}
+
+func reasonOf(message []jsoniter.RawMessage) string {
+	var reason string
+	if len(message) > 0 {
+		json.Unmarshal(message[len(message)-1], &reason)
+	}
+	return reason
+}
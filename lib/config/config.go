@@ -930,19 +930,33 @@ func setDefaults() {
 	viper.SetDefault("relay.software", "HORNETS")
 	viper.SetDefault("relay.version", "0.0.1")
 	viper.SetDefault("relay.service_tag", "hornet-storage-service")
-	viper.SetDefault("relay.supported_nips", []int{1, 2, 9, 11, 18, 23, 24, 25, 42, 45, 50, 51, 56, 57, 65, 116, 555, 888})
+	viper.SetDefault("relay.supported_nips", []int{1, 2, 9, 11, 13, 18, 23, 24, 25, 42, 45, 50, 51, 56, 57, 65, 66, 116, 555, 888})
 	viper.SetDefault("relay.secret_key", "hornets-secret-key")
 	viper.SetDefault("relay.private_key", "")
 	viper.SetDefault("relay.public_key", "")
 	viper.SetDefault("relay.dht_seed", "")
 	viper.SetDefault("relay.dht_public_key", "")
 	viper.SetDefault("relay.dht_private_key", "")
+	viper.SetDefault("relay.banner", "")
+	viper.SetDefault("relay.terms_of_service", "")
+	viper.SetDefault("relay.posting_policy", "")
+	viper.SetDefault("relay.limitation.max_message_length", 0) // Unenforced unless configured
+	viper.SetDefault("relay.limitation.max_subscriptions", 0)
+	viper.SetDefault("relay.discovery.enabled", false)
+	viper.SetDefault("relay.discovery.url", "")
+	viper.SetDefault("relay.discovery.topics", []string{})
+	viper.SetDefault("relay.discovery.bootstrap_relays", []string{})
+	viper.SetDefault("relay.discovery.refresh_interval_seconds", 3600)
 
 	// Content filtering defaults
 	viper.SetDefault("content_filtering.text_filter.enabled", true)
 	viper.SetDefault("content_filtering.text_filter.cache_size", 10000)
 	viper.SetDefault("content_filtering.text_filter.cache_ttl_seconds", 60)
 	viper.SetDefault("content_filtering.text_filter.full_text_search_kinds", []int{1})
+	viper.SetDefault("content_filtering.text_filter.latency_budget_ms", 2000)
+	viper.SetDefault("content_filtering.text_filter.fail_mode", "open")
+	viper.SetDefault("content_filtering.text_filter.precompute_workers", 2)
+	viper.SetDefault("content_filtering.text_filter.paid_only", true)
 
 	viper.SetDefault("content_filtering.image_moderation.enabled", true)
 	viper.SetDefault("content_filtering.image_moderation.mode", "full")
@@ -950,6 +964,26 @@ func setDefaults() {
 	viper.SetDefault("content_filtering.image_moderation.timeout_seconds", 600)
 	viper.SetDefault("content_filtering.image_moderation.check_interval_seconds", 30)
 	viper.SetDefault("content_filtering.image_moderation.concurrency", 5)
+	viper.SetDefault("content_filtering.image_moderation.providers", []string{"phash", "api"})
+	viper.SetDefault("content_filtering.image_moderation.combine", "first_block")
+	viper.SetDefault("content_filtering.image_moderation.hash_threshold", 10)
+	viper.SetDefault("content_filtering.image_moderation.verdict_cache", true)
+	viper.SetDefault("content_filtering.image_moderation.verdict_cache_ttl_hours", 720)
+	viper.SetDefault("content_filtering.image_moderation.blossom_upload", "async")
+
+	viper.SetDefault("content_filtering.reports.enabled", true)
+	viper.SetDefault("content_filtering.reports.wot_root", "")
+	viper.SetDefault("content_filtering.reports.weights.base", 1.0)
+	viper.SetDefault("content_filtering.reports.weights.allowed_user", 2.0)
+	viper.SetDefault("content_filtering.reports.weights.paid_subscriber", 3.0)
+	viper.SetDefault("content_filtering.reports.weights.wot_hops", []float64{5.0, 3.0, 2.0})
+	viper.SetDefault("content_filtering.reports.thresholds", map[string]interface{}{
+		"default": map[string]interface{}{"score": 10.0, "action": "pending"},
+		"illegal": map[string]interface{}{"score": 5.0, "action": "block"},
+		"nudity":  map[string]interface{}{"score": 6.0, "action": "pending"},
+	})
+	viper.SetDefault("content_filtering.reports.pubkey_threshold.score", 25.0)
+	viper.SetDefault("content_filtering.reports.pubkey_threshold.action", "notify")
 
 	// Event filtering defaults
 	viper.SetDefault("event_filtering.allow_unregistered_kinds", false) // Default to false for security
@@ -967,17 +1001,22 @@ func setDefaults() {
 		31415, 16630, // Parameterized replaceable kinds (repository permissions), branch metadata
 		19841, 19842, 19843, // Subscription kinds
 		22242,               // Auth kind
+		28934,               // NIP-43 join request (invite codes)
 		30000, 30008, 30009, // Parameterized replaceable kinds
 		30023, 30078, 30079, 30301, 30302, // Long-form content kinds
 		31416,                              // Release artifact sets (parameterized replaceable)
 		30303,                              // Repository blacklist (parameterized replaceable)
 	})
 	viper.SetDefault("event_filtering.moderation_mode", "strict")
-	viper.SetDefault("event_filtering.kind_whitelist", []string{"kind0", "kind1", "kind22242", "kind10010", "kind19841", "kind19842", "kind19843", "kind10002", "kind1111", "kind1808", "kind1809", "kind443", "kind444", "kind445", "kind1059", "kind10051", "kind72", "kind73", "kind74", "kind75", "kind76", "kind77", "kind6927", "kind7007", "kind31415", "kind16630", "kind31416", "kind30078", "kind30301", "kind30302", "kind30303"})
+	viper.SetDefault("event_filtering.kind_whitelist", []string{"kind0", "kind1", "kind22242", "kind28934", "kind10010", "kind19841", "kind19842", "kind19843", "kind10002", "kind1111", "kind1808", "kind1809", "kind443", "kind444", "kind445", "kind1059", "kind10051", "kind72", "kind73", "kind74", "kind75", "kind76", "kind77", "kind6927", "kind7007", "kind31415", "kind16630", "kind31416", "kind30078", "kind30301", "kind30302", "kind30303"})
 	viper.SetDefault("event_filtering.dynamic_kinds.enabled", false)
 	viper.SetDefault("event_filtering.dynamic_kinds.allowed_kinds", []int{})
 	viper.SetDefault("event_filtering.protocols.enabled", false)
 	viper.SetDefault("event_filtering.protocols.allowed_protocols", []string{})
+	viper.SetDefault("event_filtering.proof_of_work.min_difficulty", 0)
+	viper.SetDefault("event_filtering.proof_of_work.kind_difficulty", map[string]int{})
+	viper.SetDefault("event_filtering.proof_of_work.relax_for_trusted", false)
+	viper.SetDefault("event_filtering.proof_of_work.trusted_min_difficulty", 0)
 
 	// Media definitions defaults
 	viper.SetDefault("event_filtering.media_definitions.image.mime_patterns", []string{"image/*"})
@@ -1007,6 +1046,9 @@ func setDefaults() {
 	viper.SetDefault("allowed_users.repo_access_override_kinds", []int{72, 73, 74, 75, 76, 77, 1111, 6927, 7007, 31415, 16630, 31416, 30078, 30301, 30302, 30303})
 	viper.SetDefault("allowed_users.last_updated", 0)
 	viper.SetDefault("allowed_users.batch_update_on_startup", false) // Disable batch update by default for performance
+	viper.SetDefault("allowed_users.wot_hops", 3)
+	viper.SetDefault("allowed_users.wot_seeds", []string{})
+	viper.SetDefault("allowed_users.write_policies", []types.WritePolicyRule{})
 
 	// Default free tier with 100MB monthly storage
 	viper.SetDefault("allowed_users.tiers", []map[string]interface{}{
@@ -1104,6 +1146,7 @@ func setDefaults() {
 		"10022": "51",  // Additional list type
 		"9803":  "84",  // Additional highlight type
 		"22242": "42",  // Client Authentication
+		"28934": "43",  // Relay join request
 		"19841": "888", // Payment subscription
 		"19842": "888", // Payment subscription
 		"19843": "888", // Payment subscription
@@ -1125,12 +1168,73 @@ func setDefaults() {
 	viper.SetDefault("push_notifications.fcm.project_id", "")
 	viper.SetDefault("push_notifications.fcm.credentials_path", "")
 
+	// Web Push Configuration defaults
+	viper.SetDefault("push_notifications.web_push.enabled", false)
+	viper.SetDefault("push_notifications.web_push.subject", "")
+	viper.SetDefault("push_notifications.web_push.key_path", "")
+
 	// Service Configuration defaults
 	viper.SetDefault("push_notifications.service.queue_size", 1000)
 	viper.SetDefault("push_notifications.service.worker_count", 10)
 	viper.SetDefault("push_notifications.service.batch_size", 100)
 	viper.SetDefault("push_notifications.service.retry_attempts", 3)
 	viper.SetDefault("push_notifications.service.retry_delay", "5s")
+	viper.SetDefault("push_notifications.service.coalesce_window", "60s")
+	viper.SetDefault("push_notifications.service.rate_limit_per_minute", 10)
+	viper.SetDefault("push_notifications.service.rate_limit_burst", 5)
+
+	// Lightning payment defaults
+	viper.SetDefault("lightning.enabled", false)
+	viper.SetDefault("lightning.backend", "lnd")
+	viper.SetDefault("lightning.invoice_expiry_seconds", 3600)
+	viper.SetDefault("lightning.poll_interval_seconds", 10)
+	viper.SetDefault("lightning.lnd.url", "https://localhost:8080")
+	viper.SetDefault("lightning.lnd.macaroon_path", "")
+	viper.SetDefault("lightning.lnd.tls_cert_path", "")
+	viper.SetDefault("lightning.lnbits.url", "")
+	viper.SetDefault("lightning.lnbits.api_key", "")
+	viper.SetDefault("lightning.nwc.connection_uri", "")
+	viper.SetDefault("lightning.zaps.enabled", false)
+	viper.SetDefault("lightning.zaps.zapper_pubkeys", []string{})
+
+	// On-chain payment confirmation defaults
+	viper.SetDefault("onchain_payments.confirmation_bands", []map[string]interface{}{
+		{"max_sats": 100000, "confirmations": 0},
+		{"max_sats": 1000000, "confirmations": 1},
+		{"max_sats": 0, "confirmations": 3},
+	})
+
+	// Outbound mirroring defaults
+	viper.SetDefault("mirroring.enabled", false)
+	viper.SetDefault("mirroring.pubkeys", []string{})
+	viper.SetDefault("mirroring.kinds", []int{})
+	viper.SetDefault("mirroring.fallback_relays", []string{})
+	viper.SetDefault("mirroring.max_relays", 10)
+	viper.SetDefault("mirroring.max_attempts", 10)
+	viper.SetDefault("mirroring.retry_base_seconds", 30)
+	viper.SetDefault("mirroring.retry_max_seconds", 3600)
+	viper.SetDefault("mirroring.history_days", 7)
+
+	// History backfill defaults
+	viper.SetDefault("backfill.enabled", false)
+	viper.SetDefault("backfill.auto_trigger", true)
+	viper.SetDefault("backfill.fallback_relays", []string{})
+	viper.SetDefault("backfill.max_relays", 5)
+	viper.SetDefault("backfill.max_events", 10000)
+	viper.SetDefault("backfill.max_bytes", 50*1024*1024)
+	viper.SetDefault("backfill.relay_timeout_seconds", 60)
+	viper.SetDefault("backfill.negentropy", true)
+
+	// Subscription lifecycle notification defaults
+	viper.SetDefault("subscription_notifications.enabled", false)
+	viper.SetDefault("subscription_notifications.push", false)
+	viper.SetDefault("subscription_notifications.expiring_lead_days", []int{7, 1})
+	viper.SetDefault("subscription_notifications.quota_threshold_percent", 90)
+	viper.SetDefault("subscription_notifications.templates.expiring", "Your {tier} subscription on {relay} expires in {days} day(s), on {expires}. Renew to keep your storage.")
+	viper.SetDefault("subscription_notifications.templates.expired", "Your {tier} subscription on {relay} expired on {expires}.")
+	viper.SetDefault("subscription_notifications.templates.payment_received", "Payment of {amount} sats received. Your {tier} subscription on {relay} is active until {expires}.")
+	viper.SetDefault("subscription_notifications.templates.credit_added", "{amount} sats were added to your credit on {relay}. Your credit is now {credit} sats.")
+	viper.SetDefault("subscription_notifications.templates.quota_warning", "You have used {percent}% of your storage on {relay} ({used} of {limit}).")
 }
 
 // GetAllSettingsAsMap returns all configuration settings as a map
@@ -1186,21 +1290,35 @@ func GetAllSettingsAsMap() (map[string]interface{}, error) {
 
 	// Relay settings
 	settings["relay"] = map[string]interface{}{
-		"name":            cfg.Relay.Name,
-		"description":     cfg.Relay.Description,
-		"contact":         cfg.Relay.Contact,
-		"icon":            cfg.Relay.Icon,
-		"software":        cfg.Relay.Software,
-		"version":         cfg.Relay.Version,
-		"service_tag":     cfg.Relay.ServiceTag,
-		"supported_nips":  cfg.Relay.SupportedNIPs,
-		"secret_key":      cfg.Relay.SecretKey,
-		"private_key":     cfg.Relay.PrivateKey,
-		"public_key":      cfg.Relay.PublicKey,
-		"dht_seed":        cfg.Relay.DHTSeed,
-		"dht_public_key":  cfg.Relay.DHTPublicKey,
-		"dht_private_key": cfg.Relay.DHTPrivateKey,
-		"dht_key":         cfg.Relay.DHTSeed,
+		"name":             cfg.Relay.Name,
+		"description":      cfg.Relay.Description,
+		"contact":          cfg.Relay.Contact,
+		"icon":             cfg.Relay.Icon,
+		"software":         cfg.Relay.Software,
+		"version":          cfg.Relay.Version,
+		"service_tag":      cfg.Relay.ServiceTag,
+		"supported_nips":   cfg.Relay.SupportedNIPs,
+		"secret_key":       cfg.Relay.SecretKey,
+		"private_key":      cfg.Relay.PrivateKey,
+		"public_key":       cfg.Relay.PublicKey,
+		"dht_seed":         cfg.Relay.DHTSeed,
+		"dht_public_key":   cfg.Relay.DHTPublicKey,
+		"dht_private_key":  cfg.Relay.DHTPrivateKey,
+		"dht_key":          cfg.Relay.DHTSeed,
+		"banner":           cfg.Relay.Banner,
+		"terms_of_service": cfg.Relay.TermsOfService,
+		"posting_policy":   cfg.Relay.PostingPolicy,
+		"limitation": map[string]interface{}{
+			"max_message_length": cfg.Relay.Limitation.MaxMessageLength,
+			"max_subscriptions":  cfg.Relay.Limitation.MaxSubscriptions,
+		},
+		"discovery": map[string]interface{}{
+			"enabled":                  cfg.Relay.Discovery.Enabled,
+			"url":                      cfg.Relay.Discovery.URL,
+			"topics":                   cfg.Relay.Discovery.Topics,
+			"bootstrap_relays":         cfg.Relay.Discovery.BootstrapRelays,
+			"refresh_interval_seconds": cfg.Relay.Discovery.RefreshIntervalSeconds,
+		},
 	}
 
 	// Content filtering settings
@@ -1210,14 +1328,39 @@ func GetAllSettingsAsMap() (map[string]interface{}, error) {
 			"cache_size":             cfg.ContentFiltering.TextFilter.CacheSize,
 			"cache_ttl_seconds":      cfg.ContentFiltering.TextFilter.CacheTTLSeconds,
 			"full_text_search_kinds": cfg.ContentFiltering.TextFilter.FullTextSearchKinds,
+			"latency_budget_ms":      cfg.ContentFiltering.TextFilter.LatencyBudgetMs,
+			"fail_mode":              cfg.ContentFiltering.TextFilter.FailMode,
+			"precompute_workers":     cfg.ContentFiltering.TextFilter.PrecomputeWorkers,
+			"paid_only":              cfg.ContentFiltering.TextFilter.PaidOnly,
 		},
 		"image_moderation": map[string]interface{}{
-			"enabled":                cfg.ContentFiltering.ImageModeration.Enabled,
-			"mode":                   cfg.ContentFiltering.ImageModeration.Mode,
-			"threshold":              cfg.ContentFiltering.ImageModeration.Threshold,
-			"timeout_seconds":        cfg.ContentFiltering.ImageModeration.TimeoutSeconds,
-			"check_interval_seconds": cfg.ContentFiltering.ImageModeration.CheckIntervalSeconds,
-			"concurrency":            cfg.ContentFiltering.ImageModeration.Concurrency,
+			"enabled":                 cfg.ContentFiltering.ImageModeration.Enabled,
+			"mode":                    cfg.ContentFiltering.ImageModeration.Mode,
+			"threshold":               cfg.ContentFiltering.ImageModeration.Threshold,
+			"timeout_seconds":         cfg.ContentFiltering.ImageModeration.TimeoutSeconds,
+			"check_interval_seconds":  cfg.ContentFiltering.ImageModeration.CheckIntervalSeconds,
+			"concurrency":             cfg.ContentFiltering.ImageModeration.Concurrency,
+			"providers":               cfg.ContentFiltering.ImageModeration.Providers,
+			"combine":                 cfg.ContentFiltering.ImageModeration.Combine,
+			"hash_threshold":          cfg.ContentFiltering.ImageModeration.HashThreshold,
+			"verdict_cache":           cfg.ContentFiltering.ImageModeration.VerdictCache,
+			"verdict_cache_ttl_hours": cfg.ContentFiltering.ImageModeration.VerdictCacheTTLHours,
+			"blossom_upload":          cfg.ContentFiltering.ImageModeration.BlossomUpload,
+		},
+		"reports": map[string]interface{}{
+			"enabled":  cfg.ContentFiltering.Reports.Enabled,
+			"wot_root": cfg.ContentFiltering.Reports.WotRoot,
+			"weights": map[string]interface{}{
+				"base":            cfg.ContentFiltering.Reports.Weights.Base,
+				"allowed_user":    cfg.ContentFiltering.Reports.Weights.AllowedUser,
+				"paid_subscriber": cfg.ContentFiltering.Reports.Weights.PaidSubscriber,
+				"wot_hops":        cfg.ContentFiltering.Reports.Weights.WotHops,
+			},
+			"thresholds": cfg.ContentFiltering.Reports.Thresholds,
+			"pubkey_threshold": map[string]interface{}{
+				"score":  cfg.ContentFiltering.Reports.PubkeyThreshold.Score,
+				"action": cfg.ContentFiltering.Reports.PubkeyThreshold.Action,
+			},
 		},
 	}
 
@@ -1236,6 +1379,12 @@ func GetAllSettingsAsMap() (map[string]interface{}, error) {
 			"enabled":           cfg.EventFiltering.Protocols.Enabled,
 			"allowed_protocols": cfg.EventFiltering.Protocols.AllowedProtocols,
 		},
+		"proof_of_work": map[string]interface{}{
+			"min_difficulty":         cfg.EventFiltering.ProofOfWork.MinDifficulty,
+			"kind_difficulty":        cfg.EventFiltering.ProofOfWork.KindDifficulty,
+			"relax_for_trusted":      cfg.EventFiltering.ProofOfWork.RelaxForTrusted,
+			"trusted_min_difficulty": cfg.EventFiltering.ProofOfWork.TrustedMinDifficulty,
+		},
 	}
 
 	// Allowed users settings
@@ -1246,6 +1395,9 @@ func GetAllSettingsAsMap() (map[string]interface{}, error) {
 		"repo_access_override_kinds": cfg.AllowedUsersSettings.RepoAccessOverrideKinds,
 		"tiers":                      cfg.AllowedUsersSettings.Tiers,
 		"last_updated":               cfg.AllowedUsersSettings.LastUpdated,
+		"wot_hops":                   cfg.AllowedUsersSettings.WotHops,
+		"wot_seeds":                  cfg.AllowedUsersSettings.WotSeeds,
+		"write_policies":             cfg.AllowedUsersSettings.WritePolicies,
 	}
 
 	// Push notifications settings
@@ -1264,13 +1416,88 @@ func GetAllSettingsAsMap() (map[string]interface{}, error) {
 			"project_id":       cfg.PushNotifications.FCM.ProjectID,
 			"credentials_path": cfg.PushNotifications.FCM.CredentialsPath,
 		},
+		"web_push": map[string]interface{}{
+			"enabled":  cfg.PushNotifications.WebPush.Enabled,
+			"subject":  cfg.PushNotifications.WebPush.Subject,
+			"key_path": cfg.PushNotifications.WebPush.KeyPath,
+		},
 		"service": map[string]interface{}{
-			"queue_size":     cfg.PushNotifications.Service.QueueSize,
-			"worker_count":   cfg.PushNotifications.Service.WorkerCount,
-			"batch_size":     cfg.PushNotifications.Service.BatchSize,
-			"retry_attempts": cfg.PushNotifications.Service.RetryAttempts,
-			"retry_delay":    cfg.PushNotifications.Service.RetryDelay,
+			"queue_size":            cfg.PushNotifications.Service.QueueSize,
+			"worker_count":          cfg.PushNotifications.Service.WorkerCount,
+			"batch_size":            cfg.PushNotifications.Service.BatchSize,
+			"retry_attempts":        cfg.PushNotifications.Service.RetryAttempts,
+			"retry_delay":           cfg.PushNotifications.Service.RetryDelay,
+			"coalesce_window":       cfg.PushNotifications.Service.CoalesceWindow,
+			"rate_limit_per_minute": cfg.PushNotifications.Service.RateLimitPerMinute,
+			"rate_limit_burst":      cfg.PushNotifications.Service.RateLimitBurst,
+		},
+	}
+
+	// Lightning payment settings
+	settings["lightning"] = map[string]interface{}{
+		"enabled":                cfg.Lightning.Enabled,
+		"backend":                cfg.Lightning.Backend,
+		"invoice_expiry_seconds": cfg.Lightning.InvoiceExpirySeconds,
+		"poll_interval_seconds":  cfg.Lightning.PollIntervalSeconds,
+		"lnd": map[string]interface{}{
+			"url":           cfg.Lightning.LND.URL,
+			"macaroon_path": cfg.Lightning.LND.MacaroonPath,
+			"tls_cert_path": cfg.Lightning.LND.TLSCertPath,
+		},
+		"lnbits": map[string]interface{}{
+			"url":     cfg.Lightning.LNbits.URL,
+			"api_key": cfg.Lightning.LNbits.APIKey,
+		},
+		"nwc": map[string]interface{}{
+			"connection_uri": cfg.Lightning.NWC.ConnectionURI,
 		},
+		"zaps": map[string]interface{}{
+			"enabled":        cfg.Lightning.Zaps.Enabled,
+			"zapper_pubkeys": cfg.Lightning.Zaps.ZapperPubkeys,
+		},
+	}
+
+	settings["onchain_payments"] = map[string]interface{}{
+		"confirmation_bands": cfg.OnchainPayments.ConfirmationBands,
+	}
+
+	settings["subscription_notifications"] = map[string]interface{}{
+		"enabled":                 cfg.SubscriptionNotifications.Enabled,
+		"push":                    cfg.SubscriptionNotifications.Push,
+		"expiring_lead_days":      cfg.SubscriptionNotifications.ExpiringLeadDays,
+		"quota_threshold_percent": cfg.SubscriptionNotifications.QuotaThresholdPercent,
+		"templates": map[string]interface{}{
+			"expiring":         cfg.SubscriptionNotifications.Templates.Expiring,
+			"expired":          cfg.SubscriptionNotifications.Templates.Expired,
+			"payment_received": cfg.SubscriptionNotifications.Templates.PaymentReceived,
+			"credit_added":     cfg.SubscriptionNotifications.Templates.CreditAdded,
+			"quota_warning":    cfg.SubscriptionNotifications.Templates.QuotaWarning,
+		},
+	}
+
+	// Outbound mirroring settings
+	settings["mirroring"] = map[string]interface{}{
+		"enabled":            cfg.Mirroring.Enabled,
+		"pubkeys":            cfg.Mirroring.Pubkeys,
+		"kinds":              cfg.Mirroring.Kinds,
+		"fallback_relays":    cfg.Mirroring.FallbackRelays,
+		"max_relays":         cfg.Mirroring.MaxRelays,
+		"max_attempts":       cfg.Mirroring.MaxAttempts,
+		"retry_base_seconds": cfg.Mirroring.RetryBaseSeconds,
+		"retry_max_seconds":  cfg.Mirroring.RetryMaxSeconds,
+		"history_days":       cfg.Mirroring.HistoryDays,
+	}
+
+	// History backfill settings
+	settings["backfill"] = map[string]interface{}{
+		"enabled":               cfg.Backfill.Enabled,
+		"auto_trigger":          cfg.Backfill.AutoTrigger,
+		"fallback_relays":       cfg.Backfill.FallbackRelays,
+		"max_relays":            cfg.Backfill.MaxRelays,
+		"max_events":            cfg.Backfill.MaxEvents,
+		"max_bytes":             cfg.Backfill.MaxBytes,
+		"relay_timeout_seconds": cfg.Backfill.RelayTimeoutSeconds,
+		"negentropy":            cfg.Backfill.Negentropy,
 	}
 
 	// Add NIP mappings separately as they're not in the Config struct
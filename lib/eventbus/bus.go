@@ -0,0 +1,230 @@
+// Package eventbus is the in-process pub/sub that carries accepted events to live
+// subscriptions on every transport (WebSocket, DHT streams and internal services).
+package eventbus
+
+import (
+	"sync"
+
+	"github.com/nbd-wtf/go-nostr"
+
+	"github.com/HORNET-Storage/hornet-storage/lib/logging"
+)
+
+const (
+	// DefaultQueueSize is the number of undelivered events a subscription may hold
+	// before it is closed as too slow
+	DefaultQueueSize = 256
+
+	// publishQueueSize buffers published events ahead of the dispatcher
+	publishQueueSize = 1000
+
+	// ClosedTooSlow is the reason given when a subscription falls too far behind
+	ClosedTooSlow = "error: subscription could not keep up and was closed, please resubscribe"
+)
+
+// Sink delivers a matched event to a subscriber. Returning an error closes the subscription.
+type Sink func(event *nostr.Event) error
+
+// Hook sees every published event before it is queued for subscriptions. Hooks run on
+// the publisher's goroutine, so consumers that must never miss an event (such as a
+// persistent queue) can record it before a slow subscription could drop it.
+type Hook func(event *nostr.Event)
+
+// Subscription is a live filter registered on the bus. Matching events are queued
+// and handed to the sink one at a time, in the order they were published.
+type Subscription struct {
+	ID      string
+	Filters nostr.Filters
+
+	bus     *Bus
+	sink    Sink
+	onClose func(reason string)
+	queue   chan *nostr.Event
+	done    chan struct{}
+	once    sync.Once
+}
+
+// Bus fans published events out to matching subscriptions
+type Bus struct {
+	mu        sync.RWMutex
+	subs      map[*Subscription]struct{}
+	hooks     map[string]Hook
+	events    chan *nostr.Event
+	queueSize int
+}
+
+var globalBus = New(DefaultQueueSize)
+
+// New creates a bus and starts its dispatcher. queueSize bounds each subscription's backlog.
+func New(queueSize int) *Bus {
+	if queueSize <= 0 {
+		queueSize = DefaultQueueSize
+	}
+
+	b := &Bus{
+		subs:      make(map[*Subscription]struct{}),
+		hooks:     make(map[string]Hook),
+		events:    make(chan *nostr.Event, publishQueueSize),
+		queueSize: queueSize,
+	}
+	go b.dispatch()
+	return b
+}
+
+// Publish runs the hooks and queues an accepted event for delivery to every matching
+// subscription. It only blocks if the dispatcher itself is behind, which slows
+// publishers down rather than dropping events for every subscriber.
+func (b *Bus) Publish(event *nostr.Event) {
+	copied := *event
+
+	b.mu.RLock()
+	hooks := make([]Hook, 0, len(b.hooks))
+	for _, hook := range b.hooks {
+		hooks = append(hooks, hook)
+	}
+	b.mu.RUnlock()
+
+	for _, hook := range hooks {
+		hook(&copied)
+	}
+
+	b.events <- &copied
+}
+
+// AddHook registers a hook under id, replacing any hook already registered under it
+func (b *Bus) AddHook(id string, hook Hook) {
+	b.mu.Lock()
+	b.hooks[id] = hook
+	b.mu.Unlock()
+}
+
+// RemoveHook unregisters the hook registered under id
+func (b *Bus) RemoveHook(id string) {
+	b.mu.Lock()
+	delete(b.hooks, id)
+	b.mu.Unlock()
+}
+
+// Subscribe registers filters and starts delivering matching events to sink.
+// onClose, if set, is called with a reason when the bus closes the subscription
+// because its sink fell behind. It runs on its own goroutine after the subscription
+// has been removed, so it may block or resubscribe. It is not called for Close.
+func (b *Bus) Subscribe(id string, filters nostr.Filters, sink Sink, onClose func(reason string)) *Subscription {
+	sub := &Subscription{
+		ID:      id,
+		Filters: filters,
+		bus:     b,
+		sink:    sink,
+		onClose: onClose,
+		queue:   make(chan *nostr.Event, b.queueSize),
+		done:    make(chan struct{}),
+	}
+
+	b.mu.Lock()
+	b.subs[sub] = struct{}{}
+	b.mu.Unlock()
+
+	go sub.run()
+	return sub
+}
+
+// Size returns the number of live subscriptions
+func (b *Bus) Size() int {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return len(b.subs)
+}
+
+// dispatch matches each published event against the subscriptions in publish order.
+// Queues are never waited on, so one slow subscriber cannot stall the others.
+func (b *Bus) dispatch() {
+	for event := range b.events {
+		var overflowed []*Subscription
+
+		b.mu.RLock()
+		for sub := range b.subs {
+			if !sub.Filters.Match(event) {
+				continue
+			}
+			select {
+			case sub.queue <- event:
+			default:
+				overflowed = append(overflowed, sub)
+			}
+		}
+		b.mu.RUnlock()
+
+		for _, sub := range overflowed {
+			logging.Infof("Closing slow subscription %s: %d undelivered events", sub.ID, len(sub.queue))
+			sub.close(ClosedTooSlow)
+		}
+	}
+}
+
+func (b *Bus) remove(sub *Subscription) {
+	b.mu.Lock()
+	delete(b.subs, sub)
+	b.mu.Unlock()
+}
+
+// Close stops delivery to the subscription. Safe to call more than once.
+func (s *Subscription) Close() {
+	s.close("")
+}
+
+// Done is closed once the subscription stops delivering events
+func (s *Subscription) Done() <-chan struct{} {
+	return s.done
+}
+
+// Pending returns the number of queued events that have not been delivered.
+// After the subscription is done these events are dropped.
+func (s *Subscription) Pending() int {
+	return len(s.queue)
+}
+
+func (s *Subscription) close(reason string) {
+	s.once.Do(func() {
+		s.bus.remove(s)
+		close(s.done)
+		// The dispatcher calls close for slow subscriptions, so the callback must
+		// not hold it up with client writes or resubscribes
+		if reason != "" && s.onClose != nil {
+			go s.onClose(reason)
+		}
+	})
+}
+
+func (s *Subscription) run() {
+	for {
+		select {
+		case event := <-s.queue:
+			if err := s.sink(event); err != nil {
+				s.close("")
+				return
+			}
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// Publish queues an event on the relay-wide bus
+func Publish(event *nostr.Event) {
+	globalBus.Publish(event)
+}
+
+// AddHook registers a hook on the relay-wide bus
+func AddHook(id string, hook Hook) {
+	globalBus.AddHook(id, hook)
+}
+
+// RemoveHook unregisters a hook from the relay-wide bus
+func RemoveHook(id string) {
+	globalBus.RemoveHook(id)
+}
+
+// Subscribe registers a live subscription on the relay-wide bus
+func Subscribe(id string, filters nostr.Filters, sink Sink, onClose func(reason string)) *Subscription {
+	return globalBus.Subscribe(id, filters, sink, onClose)
+}
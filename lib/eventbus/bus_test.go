@@ -0,0 +1,239 @@
+package eventbus
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/nbd-wtf/go-nostr"
+)
+
+func TestSubscriptionReceivesMatchingEventsInOrder(t *testing.T) {
+	bus := New(DefaultQueueSize)
+
+	received := make(chan string, 100)
+	sub := bus.Subscribe("notes", nostr.Filters{{Kinds: []int{1}}}, func(event *nostr.Event) error {
+		received <- event.ID
+		return nil
+	}, nil)
+	defer sub.Close()
+
+	for i := 0; i < 50; i++ {
+		kind := 1
+		if i%5 == 0 {
+			kind = 7 // Not matched by the subscription
+		}
+		bus.Publish(&nostr.Event{ID: fmt.Sprintf("%d", i), Kind: kind})
+	}
+
+	for i := 0; i < 50; i++ {
+		if i%5 == 0 {
+			continue
+		}
+		select {
+		case id := <-received:
+			if id != fmt.Sprintf("%d", i) {
+				t.Fatalf("expected event %d, got %s", i, id)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("timed out waiting for event %d", i)
+		}
+	}
+
+	select {
+	case id := <-received:
+		t.Fatalf("unexpected extra event %s", id)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestSlowSubscriptionIsClosedWithoutBlockingOthers(t *testing.T) {
+	bus := New(2)
+
+	release := make(chan struct{})
+	closed := make(chan string, 1)
+	slow := bus.Subscribe("slow", nostr.Filters{{}}, func(event *nostr.Event) error {
+		<-release
+		return nil
+	}, func(reason string) {
+		closed <- reason
+	})
+	defer close(release)
+
+	fastCount := make(chan struct{}, 100)
+	fast := bus.Subscribe("fast", nostr.Filters{{}}, func(event *nostr.Event) error {
+		fastCount <- struct{}{}
+		return nil
+	}, nil)
+	defer fast.Close()
+
+	// The fast subscriber keeps up with every event while the slow one's queue fills
+	for i := 0; i < 10; i++ {
+		bus.Publish(&nostr.Event{ID: fmt.Sprintf("%d", i), Kind: 1})
+		select {
+		case <-fastCount:
+		case <-time.After(2 * time.Second):
+			t.Fatalf("fast subscription only received %d events", i)
+		}
+	}
+
+	select {
+	case reason := <-closed:
+		if reason != ClosedTooSlow {
+			t.Errorf("unexpected close reason %q", reason)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected the slow subscription to be closed")
+	}
+
+	select {
+	case <-slow.Done():
+	default:
+		t.Error("expected the slow subscription to be done")
+	}
+
+	if size := bus.Size(); size != 1 {
+		t.Errorf("expected only the fast subscription to remain, got %d", size)
+	}
+}
+
+func TestClosedSubscriptionStopsDelivery(t *testing.T) {
+	bus := New(DefaultQueueSize)
+
+	received := make(chan string, 10)
+	sub := bus.Subscribe("closed", nostr.Filters{{}}, func(event *nostr.Event) error {
+		received <- event.ID
+		return nil
+	}, func(reason string) {
+		t.Errorf("onClose should not be called for Close, got %q", reason)
+	})
+	sub.Close()
+	sub.Close()
+
+	bus.Publish(&nostr.Event{ID: "after-close", Kind: 1})
+
+	select {
+	case id := <-received:
+		t.Fatalf("unexpected event %s after Close", id)
+	case <-time.After(50 * time.Millisecond):
+	}
+	if size := bus.Size(); size != 0 {
+		t.Errorf("expected no subscriptions, got %d", size)
+	}
+}
+
+func TestBlockingOnCloseDoesNotStallDispatch(t *testing.T) {
+	bus := New(1)
+
+	release := make(chan struct{})
+	defer close(release)
+
+	closing := make(chan struct{}, 10)
+	for i := 0; i < 3; i++ {
+		bus.Subscribe(fmt.Sprintf("stuck-%d", i), nostr.Filters{{}}, func(event *nostr.Event) error {
+			<-release
+			return nil
+		}, func(reason string) {
+			closing <- struct{}{}
+			<-release // A client write that never completes
+		})
+	}
+
+	received := make(chan struct{}, 100)
+	fast := bus.Subscribe("fast", nostr.Filters{{}}, func(event *nostr.Event) error {
+		received <- struct{}{}
+		return nil
+	}, nil)
+	defer fast.Close()
+
+	for i := 0; i < 20; i++ {
+		bus.Publish(&nostr.Event{ID: fmt.Sprintf("%d", i), Kind: 1})
+		select {
+		case <-received:
+		case <-time.After(2 * time.Second):
+			t.Fatalf("dispatch stalled after %d events", i)
+		}
+	}
+
+	for i := 0; i < 3; i++ {
+		select {
+		case <-closing:
+		case <-time.After(2 * time.Second):
+			t.Fatalf("expected every stuck subscription to be closed, got %d", i)
+		}
+	}
+}
+
+func TestHooksSeeEventsBeforeSlowSubscriptionsDropThem(t *testing.T) {
+	bus := New(1)
+
+	release := make(chan struct{})
+	defer close(release)
+	slow := bus.Subscribe("slow", nostr.Filters{{}}, func(event *nostr.Event) error {
+		<-release
+		return nil
+	}, nil)
+	defer slow.Close()
+
+	var hooked []string
+	bus.AddHook("queue", func(event *nostr.Event) {
+		hooked = append(hooked, event.ID)
+	})
+
+	for i := 0; i < 10; i++ {
+		bus.Publish(&nostr.Event{ID: fmt.Sprintf("%d", i), Kind: 1})
+	}
+
+	// Hooks run on the publisher, so every event was seen by the time Publish returned
+	if len(hooked) != 10 {
+		t.Fatalf("expected the hook to see all 10 events, got %v", hooked)
+	}
+
+	bus.RemoveHook("queue")
+	bus.Publish(&nostr.Event{ID: "after", Kind: 1})
+	if len(hooked) != 10 {
+		t.Fatalf("expected a removed hook to see no more events, got %v", hooked)
+	}
+}
+
+func TestDurableSubscriptionResubscribesWhenTooSlow(t *testing.T) {
+	bus := New(1)
+
+	release := make(chan struct{})
+	received := make(chan string, 100)
+	durable := bus.SubscribeDurable("durable", nostr.Filters{{}}, func(event *nostr.Event) error {
+		if event.ID == "0" {
+			<-release // Fall behind until the bus closes the subscription
+		}
+		received <- event.ID
+		return nil
+	})
+
+	for i := 0; i < 5; i++ {
+		bus.Publish(&nostr.Event{ID: fmt.Sprintf("%d", i), Kind: 1})
+	}
+	close(release)
+
+	// Once the bus has closed the slow subscription a new one takes its place
+	deadline := time.After(2 * time.Second)
+	for {
+		bus.Publish(&nostr.Event{ID: "after", Kind: 1})
+		select {
+		case id := <-received:
+			if id != "after" {
+				continue
+			}
+		case <-deadline:
+			t.Fatal("expected the durable subscription to resubscribe")
+		case <-time.After(10 * time.Millisecond):
+			continue
+		}
+		break
+	}
+
+	durable.Close()
+	durable.Close()
+	if size := bus.Size(); size != 0 {
+		t.Errorf("expected Close to remove the subscription, got %d", size)
+	}
+}
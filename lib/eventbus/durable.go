@@ -0,0 +1,92 @@
+package eventbus
+
+import (
+	"sync"
+
+	"github.com/nbd-wtf/go-nostr"
+
+	"github.com/HORNET-Storage/hornet-storage/lib/logging"
+)
+
+// DurableSubscription is a subscription for internal services that must keep receiving
+// events: whenever the bus closes it for falling behind, the dropped events are logged
+// and a new subscription takes its place.
+type DurableSubscription struct {
+	ID string
+
+	bus     *Bus
+	filters nostr.Filters
+	sink    Sink
+
+	mu          sync.Mutex
+	sub         *Subscription
+	stopped     bool
+	resubscribe chan string   // Close reasons from the bus, handled by watch
+	done        chan struct{} // Closed by Close
+}
+
+// SubscribeDurable registers filters like Subscribe, resubscribing whenever the bus
+// closes the subscription as too slow. A sink error ends it like Close would.
+func (b *Bus) SubscribeDurable(id string, filters nostr.Filters, sink Sink) *DurableSubscription {
+	d := &DurableSubscription{
+		ID:          id,
+		bus:         b,
+		filters:     filters,
+		sink:        sink,
+		resubscribe: make(chan string, 1),
+		done:        make(chan struct{}),
+	}
+
+	d.mu.Lock()
+	d.subscribe()
+	d.mu.Unlock()
+
+	go d.watch()
+	return d
+}
+
+// subscribe must be called with d.mu held
+func (d *DurableSubscription) subscribe() {
+	d.sub = d.bus.Subscribe(d.ID, d.filters, d.sink, func(reason string) {
+		// Never take d.mu here, Close holds it while closing the subscription
+		select {
+		case d.resubscribe <- reason:
+		default: // A resubscribe is already pending
+		}
+	})
+}
+
+// watch replaces the subscription each time the bus closes it, until Close
+func (d *DurableSubscription) watch() {
+	for {
+		select {
+		case <-d.done:
+			return
+		case reason := <-d.resubscribe:
+			d.mu.Lock()
+			if !d.stopped {
+				logging.Infof("Event subscription %s closed (%s), dropped %d events, resubscribing", d.ID, reason, d.sub.Pending())
+				d.subscribe()
+			}
+			d.mu.Unlock()
+		}
+	}
+}
+
+// Close stops delivery for good. Safe to call more than once.
+func (d *DurableSubscription) Close() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.stopped {
+		return
+	}
+	d.stopped = true
+	close(d.done)
+	d.sub.Close()
+}
+
+// SubscribeDurable registers a durable subscription on the relay-wide bus
+func SubscribeDurable(id string, filters nostr.Filters, sink Sink) *DurableSubscription {
+	return globalBus.SubscribeDurable(id, filters, sink)
+}
@@ -43,6 +43,12 @@ func (s *Server) UploadBlobHandler() fiber.Handler {
 
 func (s *Server) getBlob(c *fiber.Ctx) error {
 	hash := c.Params("hash")
+
+	// Blobs blocked by moderation stay stored for review but aren't served
+	if blockedVerdict(hash) != nil {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"message": "Blob has been blocked by moderation"})
+	}
+
 	data, err := s.storage.GetBlob(hash)
 	if err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"message": "Blob not found"})
@@ -83,6 +89,27 @@ func (s *Server) uploadBlob(c *fiber.Ctx) error {
 	// Use hash as filename for content-addressed storage
 	name := encodedHash
 
+	// Refuse files that moderation has already blocked, and judge new ones before storing
+	// them in sync mode
+	service, moderationMode := uploadModerator(mtype)
+	if service != nil {
+		response := blockedVerdict(encodedHash)
+		if response == nil && moderationMode == uploadModerationSync {
+			var err error
+			if response, err = service.ModerateData(data, mtype.Extension()); err != nil {
+				// Fail open like event moderation, the blob can still be caught when referenced
+				logging.Infof("Blossom upload moderation failed for %s: %v", encodedHash, err)
+			}
+		}
+
+		if response != nil && response.ShouldBlock() {
+			logging.Infof("Blossom upload rejected by moderation - Author: %s, Hash: %s, Reason: %s", pubkey, encodedHash, response.Explanation)
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"message": "File was blocked by content moderation: " + response.Explanation,
+			})
+		}
+	}
+
 	logging.Infof("Blossom upload: Storing blob - Author: %s, Hash: %s", pubkey, encodedHash)
 
 	// Store the blob
@@ -94,6 +121,11 @@ func (s *Server) uploadBlob(c *fiber.Ctx) error {
 	// Store the file in the statistics database
 	s.storage.GetStatsStore().SaveFile("blossom", encodedHash, name, mtype.String(), 0, int64(len(data)))
 
+	if service != nil && moderationMode == uploadModerationAsync {
+		// Fiber reuses the request body once the handler returns
+		go s.moderateStoredBlob(service, encodedHash, append([]byte(nil), data...), mtype, pubkey)
+	}
+
 	// Update subscription storage usage for the file upload asynchronously
 	go func(pk string, size int64) {
 		subManager := subscription.GetGlobalManager()
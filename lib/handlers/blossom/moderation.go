@@ -0,0 +1,96 @@
+package blossom
+
+import (
+	"strings"
+	"time"
+
+	"github.com/gabriel-vasile/mimetype"
+	"github.com/spf13/viper"
+
+	"github.com/HORNET-Storage/hornet-storage/lib"
+	"github.com/HORNET-Storage/hornet-storage/lib/logging"
+	"github.com/HORNET-Storage/hornet-storage/lib/moderation"
+	"github.com/HORNET-Storage/hornet-storage/lib/moderation/image"
+)
+
+// Upload moderation modes
+const (
+	uploadModerationOff   = "off"   // Uploads are only moderated once an event references them
+	uploadModerationSync  = "sync"  // Blocked uploads are refused before they are stored
+	uploadModerationAsync = "async" // Uploads are stored, then quarantined if blocked
+)
+
+// uploadModerator returns the moderation service and mode for a Blossom upload, or nil
+// when uploads of this type aren't moderated
+func uploadModerator(mtype *mimetype.MIME) (*image.ModerationService, string) {
+	service := moderation.GetService()
+	if service == nil || !service.IsEnabled() || !isModeratedMedia(mtype) {
+		return nil, uploadModerationOff
+	}
+
+	mode := viper.GetString("content_filtering.image_moderation.blossom_upload")
+	switch mode {
+	case uploadModerationSync, uploadModerationAsync:
+		return service, mode
+	default:
+		return service, uploadModerationOff
+	}
+}
+
+// blockedVerdict returns the BLOCK verdict for a blob, if its bytes have been blocked
+// anywhere on the relay
+func blockedVerdict(hash string) *image.ModerationResponse {
+	service := moderation.GetService()
+	if service == nil || !service.IsEnabled() {
+		return nil
+	}
+
+	if response := service.GetVerdict(hash); response != nil && response.ShouldBlock() {
+		return response
+	}
+	return nil
+}
+
+// moderateStoredBlob moderates an upload that has already been stored. A BLOCK verdict
+// quarantines the blob, as downloads check the verdict cache, and tells the uploader why.
+func (s *Server) moderateStoredBlob(service *image.ModerationService, hash string, data []byte, mtype *mimetype.MIME, pubkey string) {
+	response, err := service.ModerateData(data, mtype.Extension())
+	if err != nil {
+		logging.Infof("Blossom upload moderation failed for %s: %v", hash, err)
+		return
+	}
+
+	if !response.ShouldBlock() {
+		return
+	}
+
+	logging.Infof("Blossom blob %s from %s quarantined by moderation: %s", hash, pubkey, response.Explanation)
+
+	statsStore := s.storage.GetStatsStore()
+	if statsStore == nil {
+		return
+	}
+
+	// Blobs have no event, so the blob hash identifies the notification
+	notification := &lib.ModerationNotification{
+		PubKey:      pubkey,
+		EventID:     hash,
+		Reason:      response.Explanation,
+		CreatedAt:   time.Now(),
+		ContentType: mediaCategory(mtype),
+		MediaURL:    "/blossom/" + hash,
+	}
+	if err := statsStore.CreateModerationNotification(notification); err != nil {
+		logging.Infof("Error creating moderation notification for blob %s: %v", hash, err)
+	}
+}
+
+func isModeratedMedia(mtype *mimetype.MIME) bool {
+	category := mediaCategory(mtype)
+	return category == "image" || category == "video"
+}
+
+func mediaCategory(mtype *mimetype.MIME) string {
+	category, _, _ := strings.Cut(mtype.String(), "/")
+	return category
+}
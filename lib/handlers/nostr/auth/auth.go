@@ -153,6 +153,8 @@ func accessDeniedMessage(settings *types.AllowedUsersSettings) string {
 		return "restricted: This relay is private and only accessible to the owner."
 	case "subscription":
 		return "restricted: This relay requires a paid subscription. Go to the subscription page to subscribe."
+	case "wot":
+		return "restricted: This relay only accepts users within the owner's web of trust."
 	default:
 		return "restricted: Authentication failed - access denied."
 	}
@@ -0,0 +1,254 @@
+package contentfilter
+
+import (
+	"context"
+	"time"
+
+	"github.com/HORNET-Storage/hornet-storage/lib/logging"
+	"github.com/nbd-wtf/go-nostr"
+)
+
+const (
+	// DefaultLatencyBudget is how long a feed waits for verdicts by default
+	DefaultLatencyBudget = 2 * time.Second
+
+	// Backoff after Ollama fails before it is tried again
+	unavailableBackoff = 30 * time.Second
+
+	// Instruction sets are precomputed for as long as they were used this recently
+	activeInstructionsWindow = 15 * time.Minute
+	maxActiveInstructions    = 100
+
+	precomputeQueueSize = 1000
+)
+
+// activeInstructions is an instruction set some user filtered a feed with recently
+type activeInstructions struct {
+	instructions string
+	lastUsed     time.Time
+}
+
+// precomputeJob judges an event for one instruction set ahead of time
+type precomputeJob struct {
+	event        *nostr.Event
+	instructions string
+}
+
+// FilterFeed returns the events that pass the instructions, in their original order.
+// Verdicts that aren't ready within the latency budget, or that fail, follow the fail
+// policy; their Ollama calls carry on in the background and fill the cache for next time.
+func (s *Service) FilterFeed(events []*nostr.Event, instructions string) []*nostr.Event {
+	if !s.enabled || instructions == "" || len(events) == 0 {
+		return events
+	}
+
+	s.trackInstructions(instructions)
+	instructionsHash := GenerateInstructionsHash(instructions)
+
+	type verdict struct {
+		index int
+		pass  bool
+		ok    bool
+	}
+
+	// 1 passes, -1 is filtered, 0 has no verdict yet
+	decisions := make([]int, len(events))
+	pending := 0
+	verdicts := make(chan verdict, len(events))
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.latencyBudget)
+	defer cancel()
+
+	for i, event := range events {
+		if !s.ShouldFilterKind(event.Kind) {
+			decisions[i] = 1
+			continue
+		}
+		if result, found := s.cache.Get(event.ID, instructionsHash); found {
+			decisions[i] = decision(result.Pass)
+			continue
+		}
+
+		pending++
+		go func(index int, e *nostr.Event) {
+			result, err := s.filterWithin(ctx, e, instructions)
+			verdicts <- verdict{index: index, pass: result.Pass, ok: err == nil}
+		}(i, event)
+	}
+
+	for pending > 0 {
+		select {
+		case v := <-verdicts:
+			pending--
+			if v.ok {
+				decisions[v.index] = decision(v.pass)
+			}
+		case <-ctx.Done():
+			logging.Infof(ColorYellow+"[CONTENT FILTER] %d verdicts not ready within %s, failing %s"+ColorReset, pending, s.latencyBudget, s.failPolicy())
+			pending = 0
+		}
+	}
+
+	filtered := make([]*nostr.Event, 0, len(events))
+	for i, event := range events {
+		if decisions[i] == 1 || (decisions[i] == 0 && !s.failClosed) {
+			filtered = append(filtered, event)
+		}
+	}
+	return filtered
+}
+
+// Allow decides whether a single live event passes the instructions. It runs on the
+// delivery path, so only verdicts that are already cached count; on a miss the fail
+// policy applies at once and the event is judged in the background for next time.
+func (s *Service) Allow(event *nostr.Event, instructions string) bool {
+	if !s.enabled || instructions == "" || !s.ShouldFilterKind(event.Kind) {
+		return true
+	}
+
+	s.trackInstructions(instructions)
+	if result, found := s.cache.Get(event.ID, GenerateInstructionsHash(instructions)); found {
+		return result.Pass
+	}
+
+	// Only judge if a concurrency slot is free right now, a burst of live events
+	// must not pile up goroutines waiting on Ollama
+	select {
+	case s.inflight <- struct{}{}:
+		go func() {
+			defer func() { <-s.inflight }()
+			if _, err := s.FilterEvent(event, instructions); err != nil {
+				logging.Debugf("[CONTENT FILTER] Live verdict for event %s failed: %v", event.ID, err)
+			}
+		}()
+	default:
+	}
+	return !s.failClosed
+}
+
+// Precompute queues a new event to be judged for every instruction set in recent use, so
+// feeds and live delivery find the verdict cached. Never blocks; jobs are dropped when
+// the queue is full.
+func (s *Service) Precompute(event *nostr.Event) {
+	if s.precomputeQueue == nil || !s.ShouldFilterKind(event.Kind) {
+		return
+	}
+
+	for _, instructions := range s.activeInstructionSets() {
+		select {
+		case s.precomputeQueue <- precomputeJob{event: event, instructions: instructions}:
+		default:
+			return
+		}
+	}
+}
+
+// Stop ends the precompute workers
+func (s *Service) Stop() {
+	s.stopOnce.Do(func() { close(s.stop) })
+}
+
+// filterWithin runs FilterEvent once a concurrency slot is free, giving up if none frees
+// up before ctx ends. Once started, the call completes so its verdict gets cached.
+func (s *Service) filterWithin(ctx context.Context, event *nostr.Event, instructions string) (FilterResult, error) {
+	select {
+	case s.inflight <- struct{}{}:
+	case <-ctx.Done():
+		return FilterResult{}, ctx.Err()
+	}
+	defer func() { <-s.inflight }()
+
+	return s.FilterEvent(event, instructions)
+}
+
+func (s *Service) startPrecompute(workers int) {
+	s.precomputeQueue = make(chan precomputeJob, precomputeQueueSize)
+	for i := 0; i < workers; i++ {
+		go func() {
+			for {
+				select {
+				case job := <-s.precomputeQueue:
+					if _, err := s.FilterEvent(job.event, job.instructions); err != nil {
+						logging.Debugf("[CONTENT FILTER] Precompute for event %s failed: %v", job.event.ID, err)
+					}
+				case <-s.stop:
+					return
+				}
+			}
+		}()
+	}
+}
+
+// trackInstructions remembers an instruction set so new events get precomputed for it
+func (s *Service) trackInstructions(instructions string) {
+	if s.precomputeQueue == nil {
+		return
+	}
+
+	s.activeMu.Lock()
+	defer s.activeMu.Unlock()
+
+	hash := GenerateInstructionsHash(instructions)
+	if _, ok := s.active[hash]; !ok && len(s.active) >= maxActiveInstructions {
+		s.pruneActive(true)
+	}
+	s.active[hash] = activeInstructions{instructions: instructions, lastUsed: time.Now()}
+}
+
+func (s *Service) activeInstructionSets() []string {
+	s.activeMu.Lock()
+	defer s.activeMu.Unlock()
+
+	s.pruneActive(false)
+	sets := make([]string, 0, len(s.active))
+	for _, active := range s.active {
+		sets = append(sets, active.instructions)
+	}
+	return sets
+}
+
+// pruneActive forgets instruction sets that haven't been used recently. If evict is set
+// and none have expired, the least recently used one goes instead. Must hold activeMu.
+func (s *Service) pruneActive(evict bool) {
+	var oldestHash string
+	var oldest time.Time
+	for hash, active := range s.active {
+		if time.Since(active.lastUsed) > activeInstructionsWindow {
+			delete(s.active, hash)
+			evict = false
+			continue
+		}
+		if oldestHash == "" || active.lastUsed.Before(oldest) {
+			oldestHash, oldest = hash, active.lastUsed
+		}
+	}
+	if evict && oldestHash != "" {
+		delete(s.active, oldestHash)
+	}
+}
+
+func (s *Service) unavailable() bool {
+	s.unavailableMu.Lock()
+	defer s.unavailableMu.Unlock()
+	return time.Now().Before(s.unavailableUntil)
+}
+
+func (s *Service) markUnavailable() {
+	s.unavailableMu.Lock()
+	defer s.unavailableMu.Unlock()
+	s.unavailableUntil = time.Now().Add(unavailableBackoff)
+}
+
+func (s *Service) failPolicy() string {
+	if s.failClosed {
+		return "closed"
+	}
+	return "open"
+}
+
+func decision(pass bool) int {
+	if pass {
+		return 1
+	}
+	return -1
+}
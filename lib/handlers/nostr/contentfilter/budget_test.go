@@ -0,0 +1,222 @@
+package contentfilter
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nbd-wtf/go-nostr"
+)
+
+const testInstructions = "Only show posts about gardening"
+
+// stubOllama answers like Ollama's generate endpoint: "false" for content containing
+// [spam], a 500 for [broken], and "true" for anything else, after a delay for [slow]
+type stubOllama struct {
+	*httptest.Server
+	calls atomic.Int32
+	delay time.Duration
+}
+
+func newStubOllama(t *testing.T, delay time.Duration) *stubOllama {
+	stub := &stubOllama{delay: delay}
+	stub.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		stub.calls.Add(1)
+
+		var req OllamaRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if strings.Contains(req.Prompt, "[slow]") {
+			time.Sleep(stub.delay)
+		}
+		if strings.Contains(req.Prompt, "[broken]") {
+			http.Error(w, "model not loaded", http.StatusInternalServerError)
+			return
+		}
+
+		answer := "true"
+		if strings.Contains(req.Prompt, "[spam]") {
+			answer = "false"
+		}
+		json.NewEncoder(w).Encode(OllamaResponse{Model: req.Model, Response: answer})
+	}))
+	t.Cleanup(stub.Close)
+	return stub
+}
+
+func newTestService(stub *stubOllama, config ServiceConfig) *Service {
+	config.APIURL = stub.URL + "/api/generate"
+	config.Enabled = true
+	return NewService(config)
+}
+
+func testEvent(id, content string) *nostr.Event {
+	return &nostr.Event{ID: id, PubKey: "author", Kind: 1, Content: content}
+}
+
+func eventIDs(events []*nostr.Event) string {
+	ids := make([]string, len(events))
+	for i, event := range events {
+		ids[i] = event.ID
+	}
+	return strings.Join(ids, ",")
+}
+
+func TestFilterFeedKeepsOrderAndDropsRejected(t *testing.T) {
+	stub := newStubOllama(t, 0)
+	service := newTestService(stub, ServiceConfig{LatencyBudget: time.Second})
+
+	events := []*nostr.Event{
+		testEvent("a", "tomatoes"),
+		testEvent("b", "buy now [spam]"),
+		{ID: "c", PubKey: "author", Kind: 7, Content: "[spam]"},
+		testEvent("d", "roses"),
+	}
+
+	if got := eventIDs(service.FilterFeed(events, testInstructions)); got != "a,c,d" {
+		t.Errorf("expected a,c,d, got %s", got)
+	}
+	if calls := stub.calls.Load(); calls != 3 {
+		t.Errorf("expected only the 3 kind 1 events to be judged, got %d calls", calls)
+	}
+
+	// The second request is answered from the cache
+	service.FilterFeed(events, testInstructions)
+	if calls := stub.calls.Load(); calls != 3 {
+		t.Errorf("expected cached verdicts to be reused, got %d calls", calls)
+	}
+}
+
+func TestFilterFeedAppliesFailPolicyAfterBudget(t *testing.T) {
+	stub := newStubOllama(t, 300*time.Millisecond)
+	events := []*nostr.Event{testEvent("fast", "tomatoes"), testEvent("slow", "roses [slow]")}
+
+	open := newTestService(stub, ServiceConfig{LatencyBudget: 50 * time.Millisecond})
+	start := time.Now()
+	if got := eventIDs(open.FilterFeed(events, testInstructions)); got != "fast,slow" {
+		t.Errorf("expected fail open to pass the slow event, got %s", got)
+	}
+	if elapsed := time.Since(start); elapsed > 250*time.Millisecond {
+		t.Errorf("expected the feed to return after the budget, took %s", elapsed)
+	}
+
+	closed := newTestService(stub, ServiceConfig{LatencyBudget: 50 * time.Millisecond, FailClosed: true})
+	if got := eventIDs(closed.FilterFeed(events, testInstructions)); got != "fast" {
+		t.Errorf("expected fail closed to drop the slow event, got %s", got)
+	}
+
+	// The slow call finishes in the background and is cached for the next request
+	time.Sleep(400 * time.Millisecond)
+	if _, found := closed.cache.Get("slow", GenerateInstructionsHash(testInstructions)); !found {
+		t.Fatalf("expected the slow verdict to be cached once it arrived")
+	}
+	if got := eventIDs(closed.FilterFeed(events, testInstructions)); got != "fast,slow" {
+		t.Errorf("expected the cached verdict to be used, got %s", got)
+	}
+}
+
+// waitForVerdict waits until the service has cached a verdict for the event
+func waitForVerdict(t *testing.T, service *Service, id string) {
+	t.Helper()
+	hash := GenerateInstructionsHash(testInstructions)
+	deadline := time.Now().Add(time.Second)
+	for {
+		if _, found := service.cache.Get(id, hash); found {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected a verdict for event %s to be cached", id)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestAllowOnlyUsesCachedVerdicts(t *testing.T) {
+	stub := newStubOllama(t, 500*time.Millisecond)
+
+	// A miss follows the fail policy at once instead of waiting on Ollama
+	open := newTestService(stub, ServiceConfig{})
+	start := time.Now()
+	if !open.Allow(testEvent("a", "buy now [spam] [slow]"), testInstructions) {
+		t.Errorf("expected fail open to deliver an event without a verdict")
+	}
+	if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+		t.Errorf("expected Allow not to wait for Ollama, took %s", elapsed)
+	}
+
+	closed := newTestService(stub, ServiceConfig{FailClosed: true})
+	if closed.Allow(testEvent("b", "roses"), testInstructions) {
+		t.Errorf("expected fail closed to hold back an event without a verdict")
+	}
+
+	// The verdicts are judged in the background and used for later deliveries
+	waitForVerdict(t, open, "a")
+	if open.Allow(testEvent("a", "buy now [spam] [slow]"), testInstructions) {
+		t.Errorf("expected the cached rejection to hold the event back")
+	}
+	waitForVerdict(t, closed, "b")
+	if !closed.Allow(testEvent("b", "roses"), testInstructions) {
+		t.Errorf("expected the cached verdict to deliver the event")
+	}
+}
+
+func TestAllowBacksOffWhenOllamaFails(t *testing.T) {
+	stub := newStubOllama(t, 0)
+	service := newTestService(stub, ServiceConfig{})
+
+	service.Allow(testEvent("a", "[broken]"), testInstructions)
+	deadline := time.Now().Add(time.Second)
+	for !service.unavailable() {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the failed call to mark Ollama unavailable")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// After a failure Ollama is left alone for a while instead of being called per event
+	calls := stub.calls.Load()
+	if !service.Allow(testEvent("d", "tomatoes"), testInstructions) {
+		t.Errorf("expected fail open to deliver the event")
+	}
+	time.Sleep(50 * time.Millisecond)
+	if stub.calls.Load() != calls {
+		t.Errorf("expected no calls while Ollama is backing off")
+	}
+}
+
+func TestPrecomputeFillsCacheForActiveInstructions(t *testing.T) {
+	stub := newStubOllama(t, 0)
+	service := newTestService(stub, ServiceConfig{PrecomputeWorkers: 1})
+	defer service.Stop()
+
+	// Nothing is precomputed until some feed uses the instructions
+	service.Precompute(testEvent("early", "tomatoes"))
+	service.FilterFeed([]*nostr.Event{testEvent("a", "tomatoes")}, testInstructions)
+	service.Precompute(testEvent("new", "buy now [spam]"))
+
+	hash := GenerateInstructionsHash(testInstructions)
+	deadline := time.Now().Add(time.Second)
+	for {
+		if result, found := service.cache.Get("new", hash); found {
+			if result.Pass {
+				t.Errorf("expected the precomputed verdict to reject the event")
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the new event to be precomputed")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if _, found := service.cache.Get("early", hash); found {
+		t.Errorf("expected events published before the instructions were used not to be judged")
+	}
+}
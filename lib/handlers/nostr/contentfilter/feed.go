@@ -0,0 +1,223 @@
+package contentfilter
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nbd-wtf/go-nostr"
+
+	"github.com/HORNET-Storage/hornet-storage/lib/eventbus"
+	"github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr/kind10010"
+	"github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr/kind19842"
+	"github.com/HORNET-Storage/hornet-storage/lib/logging"
+	"github.com/HORNET-Storage/hornet-storage/lib/stores"
+)
+
+const (
+	// How long a user's filter preference is reused before it is read from the store again
+	preferenceTTL = 30 * time.Second
+
+	// Expired preferences are swept once this many viewers are cached
+	maxCachedPreferences = 10000
+)
+
+// Feed applies each user's kind 10010 filter preference to the events they are sent:
+// mute words for everyone, and AI instructions through the Service
+type Feed struct {
+	service     *Service
+	preferences func(pubkey string) (*kind10010.FilterPreference, error)
+
+	prefsMu sync.Mutex
+	prefs   map[string]cachedPreference
+
+	subMu        sync.Mutex
+	subscription *eventbus.DurableSubscription
+}
+
+type cachedPreference struct {
+	preference *kind10010.FilterPreference
+	loadedAt   time.Time
+}
+
+var feed *Feed
+
+// SetFeed makes a feed the one used for REQ results and live delivery
+func SetFeed(f *Feed) {
+	feed = f
+}
+
+// GetFeed returns the feed set with SetFeed, or nil
+func GetFeed() *Feed {
+	return feed
+}
+
+// NewFeed creates a feed reading preferences from the store. With paidOnly set, AI
+// instructions are only honoured for paid subscribers; mute words apply to everyone.
+func NewFeed(service *Service, store stores.Store, paidOnly bool) *Feed {
+	return newFeed(service, func(pubkey string) (*kind10010.FilterPreference, error) {
+		pref, err := kind10010.GetUserFilterPreference(store, pubkey)
+		if err != nil || !paidOnly || pref.Instructions == "" {
+			return pref, err
+		}
+
+		paid, err := kind19842.IsPaidSubscriber(store, pubkey)
+		if err != nil {
+			logging.Infof("[CONTENT FILTER] Error checking paid subscription for %s: %v", pubkey, err)
+		}
+		if !paid {
+			pref.Instructions = ""
+		}
+		return pref, nil
+	})
+}
+
+func newFeed(service *Service, preferences func(pubkey string) (*kind10010.FilterPreference, error)) *Feed {
+	return &Feed{
+		service:     service,
+		preferences: preferences,
+		prefs:       make(map[string]cachedPreference),
+	}
+}
+
+// Start precomputes verdicts for new events and picks up preference changes as they are
+// published
+func (f *Feed) Start() {
+	f.subMu.Lock()
+	defer f.subMu.Unlock()
+
+	f.subscription = eventbus.SubscribeDurable("content-filter", nostr.Filters{{}}, func(event *nostr.Event) error {
+		if event.Kind == 10010 {
+			f.forgetPreference(event.PubKey)
+			return nil
+		}
+		f.service.Precompute(event)
+		return nil
+	})
+}
+
+// Stop ends the event subscription and the service's precompute workers
+func (f *Feed) Stop() {
+	f.subMu.Lock()
+	if f.subscription != nil {
+		f.subscription.Close()
+		f.subscription = nil
+	}
+	f.subMu.Unlock()
+
+	f.service.Stop()
+}
+
+// FilterEvents returns the events the viewer wants to see, in their original order.
+// Anonymous viewers, the viewer's own events and kinds that aren't filtered always pass.
+func (f *Feed) FilterEvents(viewer string, events []*nostr.Event) []*nostr.Event {
+	pref := f.preference(viewer)
+	if pref == nil {
+		return events
+	}
+
+	var candidates []*nostr.Event
+	muted := 0
+	for _, event := range events {
+		if !f.filterable(viewer, event) {
+			continue
+		}
+		if ContainsMuteWord(event.Content, pref.MuteWords) {
+			muted++
+			continue
+		}
+		candidates = append(candidates, event)
+	}
+
+	passed := make(map[string]bool, len(candidates))
+	for _, event := range f.service.FilterFeed(candidates, pref.Instructions) {
+		passed[event.ID] = true
+	}
+
+	filtered := make([]*nostr.Event, 0, len(events))
+	for _, event := range events {
+		if !f.filterable(viewer, event) || passed[event.ID] {
+			filtered = append(filtered, event)
+		}
+	}
+
+	logging.Debugf("[CONTENT FILTER] %d/%d events passed for %s (%d muted)", len(filtered), len(events), viewer, muted)
+	return filtered
+}
+
+// AllowLive decides whether a live event should be delivered to the viewer. It runs in
+// the bus sink, so AI instructions only apply through verdicts that are already cached.
+func (f *Feed) AllowLive(viewer string, event *nostr.Event) bool {
+	if !f.filterable(viewer, event) {
+		return true
+	}
+
+	pref := f.preference(viewer)
+	if pref == nil {
+		return true
+	}
+	if ContainsMuteWord(event.Content, pref.MuteWords) {
+		return false
+	}
+	return f.service.Allow(event, pref.Instructions)
+}
+
+// ContainsMuteWord reports whether content contains any of the words, ignoring case
+func ContainsMuteWord(content string, words []string) bool {
+	content = strings.ToLower(content)
+	for _, word := range words {
+		word = strings.TrimSpace(word)
+		if word != "" && strings.Contains(content, strings.ToLower(word)) {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *Feed) filterable(viewer string, event *nostr.Event) bool {
+	return viewer != "" && event.PubKey != viewer && f.service.ShouldFilterKind(event.Kind)
+}
+
+// preference returns the viewer's enabled filter preference, or nil if nothing should
+// be filtered for them
+func (f *Feed) preference(viewer string) *kind10010.FilterPreference {
+	if viewer == "" {
+		return nil
+	}
+
+	f.prefsMu.Lock()
+	cached, ok := f.prefs[viewer]
+	f.prefsMu.Unlock()
+
+	if !ok || time.Since(cached.loadedAt) > preferenceTTL {
+		pref, err := f.preferences(viewer)
+		if err != nil {
+			logging.Infof("[CONTENT FILTER] Error loading filter preference for %s: %v", viewer, err)
+			return nil
+		}
+
+		cached = cachedPreference{preference: pref, loadedAt: time.Now()}
+		f.prefsMu.Lock()
+		if len(f.prefs) >= maxCachedPreferences {
+			for pubkey, entry := range f.prefs {
+				if time.Since(entry.loadedAt) > preferenceTTL {
+					delete(f.prefs, pubkey)
+				}
+			}
+		}
+		f.prefs[viewer] = cached
+		f.prefsMu.Unlock()
+	}
+
+	pref := cached.preference
+	if pref == nil || !pref.Enabled || (len(pref.MuteWords) == 0 && pref.Instructions == "") {
+		return nil
+	}
+	return pref
+}
+
+func (f *Feed) forgetPreference(pubkey string) {
+	f.prefsMu.Lock()
+	defer f.prefsMu.Unlock()
+	delete(f.prefs, pubkey)
+}
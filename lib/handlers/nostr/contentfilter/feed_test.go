@@ -0,0 +1,64 @@
+package contentfilter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nbd-wtf/go-nostr"
+
+	"github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr/kind10010"
+)
+
+func TestFeedAppliesViewerPreferences(t *testing.T) {
+	stub := newStubOllama(t, 0)
+	service := newTestService(stub, ServiceConfig{LatencyBudget: time.Second})
+
+	loads := 0
+	feed := newFeed(service, func(pubkey string) (*kind10010.FilterPreference, error) {
+		loads++
+		if pubkey != "viewer" {
+			return &kind10010.FilterPreference{}, nil
+		}
+		return &kind10010.FilterPreference{Enabled: true, Instructions: testInstructions, MuteWords: []string{"Crypto"}}, nil
+	})
+
+	events := []*nostr.Event{
+		testEvent("a", "tomatoes"),
+		testEvent("b", "crypto giveaway"),
+		testEvent("c", "buy now [spam]"),
+		{ID: "d", PubKey: "viewer", Kind: 1, Content: "my own crypto [spam]"},
+	}
+
+	if got := eventIDs(feed.FilterEvents("viewer", events)); got != "a,d" {
+		t.Errorf("expected a,d, got %s", got)
+	}
+	if got := eventIDs(feed.FilterEvents("", events)); got != "a,b,c,d" {
+		t.Errorf("expected anonymous viewers to see everything, got %s", got)
+	}
+	if got := eventIDs(feed.FilterEvents("someone", events)); got != "a,b,c,d" {
+		t.Errorf("expected viewers without a preference to see everything, got %s", got)
+	}
+
+	if feed.AllowLive("viewer", testEvent("e", "CRYPTO")) {
+		t.Errorf("expected a muted live event to be held back")
+	}
+	if !feed.AllowLive("viewer", testEvent("f", "roses")) {
+		t.Errorf("expected a matching live event to be delivered")
+	}
+
+	if loads != 2 {
+		t.Errorf("expected preferences to be cached per viewer, loaded %d times", loads)
+	}
+}
+
+func TestFeedAppliesMuteWordsWithoutAI(t *testing.T) {
+	service := NewService(ServiceConfig{Enabled: false})
+	feed := newFeed(service, func(pubkey string) (*kind10010.FilterPreference, error) {
+		return &kind10010.FilterPreference{Enabled: true, Instructions: testInstructions, MuteWords: []string{"crypto"}}, nil
+	})
+
+	events := []*nostr.Event{testEvent("a", "tomatoes"), testEvent("b", "crypto giveaway")}
+	if got := eventIDs(feed.FilterEvents("viewer", events)); got != "a" {
+		t.Errorf("expected mute words to apply with AI filtering disabled, got %s", got)
+	}
+}
@@ -42,6 +42,21 @@ type Service struct {
 	cache       *Cache
 	enabled     bool
 	filterKind  []int // Event kinds that should be filtered
+
+	latencyBudget time.Duration // How long a feed waits for verdicts before applying the fail policy
+	failClosed    bool          // Drop events without a verdict instead of passing them
+	inflight      chan struct{} // Bounds concurrent Ollama calls made for feeds
+
+	// Ollama calls are skipped until unavailableUntil after a failure, so a missing
+	// Ollama doesn't cost every request a connection attempt per event
+	unavailableMu    sync.Mutex
+	unavailableUntil time.Time
+
+	precomputeQueue chan precomputeJob
+	activeMu        sync.Mutex
+	active          map[string]activeInstructions // Instruction sets seen recently, by hash
+	stop            chan struct{}
+	stopOnce        sync.Once
 }
 
 // ServiceConfig defines the configuration options for the filter service
@@ -53,6 +68,11 @@ type ServiceConfig struct {
 	CacheTTL   time.Duration
 	FilterKind []int
 	Enabled    bool
+
+	LatencyBudget     time.Duration // Time a REQ waits for verdicts, live delivery never waits
+	FailClosed        bool          // Drop events whose verdict isn't ready in time
+	MaxConcurrent     int           // Concurrent Ollama calls for feeds
+	PrecomputeWorkers int           // Workers judging new events ahead of time, 0 disables
 }
 
 // NewService creates a new content filter service
@@ -73,15 +93,36 @@ func NewService(config ServiceConfig) *Service {
 	if config.Model == "" {
 		config.Model = "gemma3:1b" // Default model - smaller and more efficient
 	}
+	if config.LatencyBudget == 0 {
+		config.LatencyBudget = DefaultLatencyBudget
+	}
+	if config.MaxConcurrent <= 0 {
+		config.MaxConcurrent = 10
+	}
 
-	return &Service{
-		ollamaURL:   config.APIURL,
-		ollamaModel: config.Model,
-		client:      &http.Client{Timeout: config.Timeout},
-		cache:       NewCache(config.CacheSize, config.CacheTTL),
-		enabled:     config.Enabled,
-		filterKind:  config.FilterKind,
+	s := &Service{
+		ollamaURL:     config.APIURL,
+		ollamaModel:   config.Model,
+		client:        &http.Client{Timeout: config.Timeout},
+		cache:         NewCache(config.CacheSize, config.CacheTTL),
+		enabled:       config.Enabled,
+		filterKind:    config.FilterKind,
+		latencyBudget: config.LatencyBudget,
+		failClosed:    config.FailClosed,
+		inflight:      make(chan struct{}, config.MaxConcurrent),
+		active:        make(map[string]activeInstructions),
+		stop:          make(chan struct{}),
 	}
+
+	if config.Enabled && config.PrecomputeWorkers > 0 {
+		s.startPrecompute(config.PrecomputeWorkers)
+	}
+	return s
+}
+
+// Enabled reports whether instruction-based filtering is turned on
+func (s *Service) Enabled() bool {
+	return s.enabled
 }
 
 // ShouldFilterKind checks if a given event kind should be filtered
@@ -110,12 +151,17 @@ func (s *Service) FilterEvent(event *nostr.Event, instructions string) (FilterRe
 		return result, nil
 	}
 
+	if s.unavailable() {
+		return FilterResult{Pass: true, Reason: "API unavailable"}, fmt.Errorf("ollama API unavailable, retrying after backoff")
+	}
+
 	// Build prompt for Ollama
 	prompt := BuildPrompt(event.Content, instructions)
 
 	// Call Ollama directly
 	result, err := s.callOllama(event, prompt)
 	if err != nil {
+		s.markUnavailable()
 		return FilterResult{Pass: true, Reason: "API error"}, fmt.Errorf("error calling Ollama API: %v", err)
 	}
 
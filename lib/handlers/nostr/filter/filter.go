@@ -1,8 +1,6 @@
 package filter
 
 import (
-	"strings"
-
 	"github.com/HORNET-Storage/hornet-storage/lib/logging"
 	"github.com/HORNET-Storage/hornet-storage/lib/sessions"
 	"github.com/HORNET-Storage/hornet-storage/lib/stores"
@@ -13,7 +11,7 @@ import (
 	"github.com/spf13/viper"
 
 	lib_nostr "github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr"
-	"github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr/kind10010"
+	"github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr/contentfilter"
 	"github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr/search"
 )
 
@@ -294,81 +292,9 @@ func BuildFilterHandler(store stores.Store) func(read lib_nostr.KindReader, writ
 		// Add detailed logging
 		addLogging(&request, connPubkey)
 
-		// Apply mute word filtering if the user is authenticated
-		if connPubkey != "" {
-			// Get user's filter preferences
-			pref, err := kind10010.GetUserFilterPreference(store, connPubkey)
-
-			// Check if filtering is enabled
-			if err == nil && pref.Enabled {
-				// Separate filterable (kind 1) and non-filterable events
-				var filterableEvents []*nostr.Event
-				var nonFilterableEvents []*nostr.Event
-
-				for _, e := range uniqueEvents {
-					if e.PubKey == connPubkey {
-						nonFilterableEvents = append(nonFilterableEvents, e)
-						logging.Debugf("[CONTENT FILTER] EXEMPT EVENT: ID=%s, Kind=%d, PubKey=%s (authored by requester)",
-							e.ID, e.Kind, e.PubKey)
-					} else if e.Kind == 1 { // Only filter kind 1 (text notes)
-						filterableEvents = append(filterableEvents, e)
-					} else {
-						nonFilterableEvents = append(nonFilterableEvents, e)
-						logging.Debugf("[CONTENT FILTER] EXEMPT EVENT: ID=%s, Kind=%d, PubKey=%s (non-filterable event kind)",
-							e.ID, e.Kind, e.PubKey)
-					}
-				}
-
-				// Store the original count for proper logging
-				originalCount := len(filterableEvents)
-
-				// Log event details before filtering for diagnostics
-				logging.Debugf("[CONTENT FILTER] PROCESSING: %d filterable events for user %s", originalCount, connPubkey)
-				for _, e := range filterableEvents {
-					logging.Debugf("[CONTENT FILTER] EVENT TO FILTER: ID=%s, Kind=%d, PubKey=%s, Content: %s",
-						e.ID, e.Kind, e.PubKey, truncateString(e.Content, 50))
-				}
-
-				// Apply mute word filtering if mute words are present
-				if len(pref.MuteWords) > 0 {
-					logging.Debugf("[CONTENT FILTER] APPLYING MUTE WORD FILTER FOR USER: %s", connPubkey)
-
-					// Create a filtered list that excludes events with muted words
-					var muteFilteredEvents []*nostr.Event
-
-					for _, e := range filterableEvents {
-						containsMutedWord := false
-
-						// Check if event content contains any muted word
-						for _, muteWord := range pref.MuteWords {
-							if muteWord != "" && strings.Contains(strings.ToLower(e.Content), strings.ToLower(muteWord)) {
-								logging.Debugf("[CONTENT FILTER] MUTED WORD: '%s' found in event ID=%s",
-									muteWord, e.ID)
-								containsMutedWord = true
-								break
-							}
-						}
-
-						// Only keep events that don't contain muted words
-						if !containsMutedWord {
-							muteFilteredEvents = append(muteFilteredEvents, e)
-						}
-					}
-
-					// Replace the filterable events with the mute-filtered list
-					filterableEvents = muteFilteredEvents
-
-					logging.Debugf("[CONTENT FILTER] MUTE FILTER: %d/%d events passed mute word filtering",
-						len(filterableEvents), originalCount)
-				}
-
-				// Combine filtered events with non-filterable events
-				uniqueEvents = append(nonFilterableEvents, filterableEvents...)
-				logging.Debugf("[CONTENT FILTER] MUTE-ONLY FILTER: %d events passed mute filtering, %d exempt events",
-					len(filterableEvents), len(nonFilterableEvents))
-			} else {
-				logging.Debugf("Content filtering not enabled for user %s", connPubkey)
-			}
+		// Apply the user's mute words and AI filter instructions
+		if feed := contentfilter.GetFeed(); feed != nil {
+			uniqueEvents = feed.FilterEvents(connPubkey, uniqueEvents)
 		}
 
 		// Send each unique event to the client
@@ -488,14 +414,6 @@ func BuildFilterHandler(store stores.Store) func(read lib_nostr.KindReader, writ
 	return handler
 }
 
-// truncateString limits the length of a string for logging purposes
-func truncateString(s string, maxLength int) string {
-	if len(s) <= maxLength {
-		return s
-	}
-	return s[:maxLength] + "..."
-}
-
 func deduplicateEvents(events []*nostr.Event) []*nostr.Event {
 	seen := make(map[string]struct{})
 	var uniqueEvents []*nostr.Event
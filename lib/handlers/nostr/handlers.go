@@ -2,6 +2,9 @@ package nostr
 
 var KindHandlers map[string]KindHandler
 
+// privateKinds are accepted by their handlers but never published to live subscriptions
+var privateKinds = map[int]bool{}
+
 type KindWriter func(messageType string, params ...interface{})
 type KindReader func() ([]byte, error)
 
@@ -31,6 +34,34 @@ func GetHandlers() map[string]KindHandler {
 	return KindHandlers
 }
 
+// RegisterPrivateKind marks a kind whose events carry data meant only for the relay,
+// such as invite codes, so transports never deliver them to subscribers
+func RegisterPrivateKind(kind int) {
+	privateKinds[kind] = true
+}
+
+// IsPrivateKind reports whether events of kind must not be published
+func IsPrivateKind(kind int) bool {
+	return privateKinds[kind]
+}
+
 func ClearHandlers() {
 	KindHandlers = make(map[string]KindHandler)
 }
+
+// TrackAccepted wraps a writer and reports whether the handler acknowledged the event
+// with a successful OK, so transports only publish events that were actually accepted
+func TrackAccepted(write KindWriter) (KindWriter, func() bool) {
+	accepted := false
+	tracked := func(messageType string, params ...interface{}) {
+		if messageType == "OK" {
+			if flat := ExtractInterfaceValues(params...); len(flat) >= 2 {
+				if ok, _ := flat[1].(bool); ok {
+					accepted = true
+				}
+			}
+		}
+		write(messageType, params...)
+	}
+	return tracked, func() bool { return accepted }
+}
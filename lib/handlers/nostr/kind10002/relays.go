@@ -0,0 +1,42 @@
+package kind10002
+
+import (
+	"github.com/nbd-wtf/go-nostr"
+
+	"github.com/HORNET-Storage/hornet-storage/lib/stores"
+)
+
+// LatestRelayList returns the newest stored kind 10002 for pubkey, or nil if there is none
+func LatestRelayList(store stores.Store, pubkey string) (*nostr.Event, error) {
+	events, err := store.QueryEvents(nostr.Filter{Kinds: []int{10002}, Authors: []string{pubkey}})
+	if err != nil || len(events) == 0 {
+		return nil, err
+	}
+
+	latest := events[0]
+	for _, event := range events[1:] {
+		if event.CreatedAt > latest.CreatedAt {
+			latest = event
+		}
+	}
+	return latest, nil
+}
+
+// WriteRelays returns the relays a NIP-65 list marks for writing. Unmarked relays are used for both.
+func WriteRelays(event *nostr.Event) []string {
+	if event == nil {
+		return nil
+	}
+
+	var relays []string
+	for _, tag := range event.Tags {
+		if len(tag) < 2 || tag[0] != "r" {
+			continue
+		}
+		if len(tag) >= 3 && tag[2] != "write" {
+			continue
+		}
+		relays = append(relays, tag[1])
+	}
+	return relays
+}
@@ -39,13 +39,19 @@ type RelayInfo struct {
 	Version           string                 `json:"version"`
 	DHTkey            string                 `json:"dhtkey,omitempty"`
 	SubscriptionTiers []SubscriptionTierInfo `json:"subscription_tiers,omitempty"`
+	PaymentMethods    []string               `json:"payment_methods,omitempty"`
+	LightningEndpoint string                 `json:"lightning_invoice_endpoint,omitempty"` // Panel path for requesting tier invoices
 }
 
 type SubscriptionTierInfo struct {
+	Tier      string `json:"tier,omitempty"` // Tier name to request a Lightning invoice for
 	DataLimit string `json:"datalimit"`
 	Price     string `json:"price"`
 }
 
+// LightningInvoiceEndpoint is the panel path clients use to request subscription invoices
+const LightningInvoiceEndpoint = "/api/lightning/invoice"
+
 func formatDataLimit(bytes int64, unlimited bool) string {
 	if unlimited {
 		return "Unlimited"
@@ -78,12 +84,9 @@ func CreateKind10411Event(privateKey *secp256k1.PrivateKey, publicKey *secp256k1
 			continue
 		}
 
-		// Convert bytes to human-readable format for the datalimit field
-		datalimit := formatDataLimit(tier.MonthlyLimitBytes, tier.Unlimited)
-
 		// Create a custom tier structure that matches the expected format
 		tiers = append(tiers, types.SubscriptionTier{
-			Name:              datalimit,
+			Name:              tier.Name,
 			PriceSats:         tier.PriceSats,
 			MonthlyLimitBytes: tier.MonthlyLimitBytes,
 			Unlimited:         tier.Unlimited,
@@ -112,7 +115,8 @@ func CreateKind10411Event(privateKey *secp256k1.PrivateKey, publicKey *secp256k1
 	var tierInfos []SubscriptionTierInfo
 	for _, tier := range tiers {
 		tierInfos = append(tierInfos, SubscriptionTierInfo{
-			DataLimit: tier.Name, // We stored the formatted data limit in Name
+			Tier:      tier.Name,
+			DataLimit: formatDataLimit(tier.MonthlyLimitBytes, tier.Unlimited),
 			Price:     fmt.Sprintf("%d", tier.PriceSats),
 		})
 	}
@@ -155,6 +159,18 @@ func CreateKind10411Event(privateKey *secp256k1.PrivateKey, publicKey *secp256k1
 		SubscriptionTiers: tierInfos,
 	}
 
+	// Advertise how paid tiers can be purchased
+	if len(tierInfos) > 0 {
+		relayInfo.PaymentMethods = []string{"bitcoin"}
+		if cfg.Lightning.Enabled {
+			relayInfo.PaymentMethods = append(relayInfo.PaymentMethods, "lightning")
+			relayInfo.LightningEndpoint = LightningInvoiceEndpoint
+		}
+		if cfg.Lightning.Zaps.Enabled && len(cfg.Lightning.Zaps.ZapperPubkeys) > 0 {
+			relayInfo.PaymentMethods = append(relayInfo.PaymentMethods, "zap")
+		}
+	}
+
 	// Convert relay info to JSON
 	content, err := json.Marshal(relayInfo)
 	if err != nil {
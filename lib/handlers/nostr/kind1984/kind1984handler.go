@@ -1,18 +1,25 @@
 package kind1984
 
 import (
+	"fmt"
+
 	jsoniter "github.com/json-iterator/go"
 
 	"github.com/HORNET-Storage/hornet-storage/lib"
 	"github.com/HORNET-Storage/hornet-storage/lib/logging"
+	"github.com/HORNET-Storage/hornet-storage/lib/moderation/reports"
 	"github.com/HORNET-Storage/hornet-storage/lib/stores"
+	"github.com/HORNET-Storage/hornet-storage/lib/wot"
 	"github.com/nbd-wtf/go-nostr"
 
 	lib_nostr "github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr"
 )
 
 // BuildKind1984Handler constructs and returns a handler function for kind 1984 (Report) events.
-func BuildKind1984Handler(store stores.Store) func(read lib_nostr.KindReader, write lib_nostr.KindWriter) {
+// wotCache lets reporters be weighted by their follow distance from the relay's graph and may be nil.
+func BuildKind1984Handler(store stores.Store, wotCache *wot.Cache) func(read lib_nostr.KindReader, write lib_nostr.KindWriter) {
+	aggregator := reports.NewAggregator(store, wotCache)
+
 	handler := func(read lib_nostr.KindReader, write lib_nostr.KindWriter) {
 		var json = jsoniter.ConfigCompatibleWithStandardLibrary
 
@@ -44,6 +51,7 @@ func BuildKind1984Handler(store stores.Store) func(read lib_nostr.KindReader, wr
 		}
 
 		// If an event ID is provided, verify that the event exists in our database
+		var reportedEvent *nostr.Event
 		if reportedEventID != "" {
 			filter := nostr.Filter{
 				IDs: []string{reportedEventID},
@@ -53,11 +61,22 @@ func BuildKind1984Handler(store stores.Store) func(read lib_nostr.KindReader, wr
 				write("OK", env.Event.ID, false, "Reported event not found in our database")
 				return
 			}
+			reportedEvent = events[0]
+		}
+
+		// Record the report once per reporter and apply any threshold actions
+		result, err := aggregator.Submit(&env.Event, reportedEvent, reportedPubkey, reportType)
+		if err != nil {
+			logging.Infof("Error aggregating report: %v", err)
+			write("OK", env.Event.ID, false, fmt.Sprintf("Failed to process report: %v", err))
+			return
+		}
 
+		if reportedEvent != nil {
 			// Check if the event is already blocked or pending moderation
 			isBlocked, _ := store.IsEventBlocked(reportedEventID)
 			isPending, _ := store.IsPendingModeration(reportedEventID)
-			if isBlocked || isPending {
+			if (isBlocked || isPending) && result.EventAction == reports.ActionNone {
 				// We acknowledge the report but don't create a notification since
 				// the event is already being handled by moderation
 				write("OK", env.Event.ID, true, "Event already being processed by moderation")
@@ -78,15 +97,17 @@ func BuildKind1984Handler(store stores.Store) func(read lib_nostr.KindReader, wr
 			}
 
 			if existingNotification != nil {
-				// Increment the report count
-				err = store.GetStatsStore().UpdateReportCount(reportedEventID)
-				if err != nil {
-					logging.Infof("Error updating report count: %v", err)
+				// Only count each reporter once per event, whatever types they report it as
+				if result.NewReporter {
+					err = store.GetStatsStore().UpdateReportCount(reportedEventID)
+					if err != nil {
+						logging.Infof("Error updating report count: %v", err)
+					}
 				}
 			} else {
 				// Create a new report notification
 				notification := &lib.ReportNotification{
-					PubKey:         reportedPubkey,
+					PubKey:         reportedEvent.PubKey,
 					EventID:        reportedEventID,
 					ReportType:     reportType,
 					ReportContent:  env.Event.Content,
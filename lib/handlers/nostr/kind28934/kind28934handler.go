@@ -0,0 +1,91 @@
+package kind28934
+
+import (
+	"errors"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/nbd-wtf/go-nostr"
+
+	"github.com/HORNET-Storage/hornet-storage/lib/access"
+	"github.com/HORNET-Storage/hornet-storage/lib/logging"
+	"github.com/HORNET-Storage/hornet-storage/lib/types"
+
+	lib_nostr "github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr"
+)
+
+// maxRequestAge bounds how old a join request may be, so captured requests cannot be replayed later
+const maxRequestAge = 10 * time.Minute
+
+// BuildKind28934Handler constructs a handler for NIP-43 join requests. The event's
+// claim tag is redeemed as an invite code for the signing pubkey. Join requests
+// are ephemeral and never stored.
+func BuildKind28934Handler(accessControl *access.AccessControl) func(read lib_nostr.KindReader, write lib_nostr.KindWriter) {
+	handler := func(read lib_nostr.KindReader, write lib_nostr.KindWriter) {
+		var json = jsoniter.ConfigCompatibleWithStandardLibrary
+
+		data, err := read()
+		if err != nil {
+			write("NOTICE", "Error reading from stream.")
+			return
+		}
+
+		var env nostr.EventEnvelope
+		if err := json.Unmarshal(data, &env); err != nil {
+			write("NOTICE", "Error unmarshaling event.")
+			return
+		}
+
+		// Check relay settings for allowed events whilst also verifying signatures and kind number
+		success := lib_nostr.ValidateEvent(write, env, access.InviteRequestKind)
+		if !success {
+			return
+		}
+
+		if time.Since(env.Event.CreatedAt.Time()) > maxRequestAge {
+			write("OK", env.Event.ID, false, "invalid: join request is too old")
+			return
+		}
+
+		claim := env.Event.Tags.GetFirst([]string{"claim"})
+		if claim == nil || len(*claim) < 2 || (*claim)[1] == "" {
+			write("OK", env.Event.ID, false, "invalid: missing claim tag")
+			return
+		}
+
+		if accessControl == nil {
+			write("OK", env.Event.ID, false, "error: access control is not available")
+			return
+		}
+
+		user, err := accessControl.RedeemInvite((*claim)[1], env.Event.PubKey)
+		if err != nil {
+			logging.Infof("Invite redemption failed for %s: %v", env.Event.PubKey, err)
+			write("OK", env.Event.ID, false, inviteErrorMessage(err))
+			return
+		}
+
+		message := "invite accepted"
+		if user.ExpiresAt != nil {
+			message += ", access expires " + user.ExpiresAt.UTC().Format(time.RFC3339)
+		}
+		write("OK", env.Event.ID, true, message)
+	}
+
+	return handler
+}
+
+// inviteErrorMessage maps a redemption failure to a machine-readable OK message
+func inviteErrorMessage(err error) string {
+	switch {
+	case errors.Is(err, types.ErrInviteNotFound),
+		errors.Is(err, types.ErrInviteRevoked),
+		errors.Is(err, types.ErrInviteExpired),
+		errors.Is(err, types.ErrInviteExhausted):
+		return "restricted: " + err.Error()
+	case errors.Is(err, types.ErrInviteAlreadyRedeemed):
+		return "duplicate: " + err.Error()
+	default:
+		return "error: could not redeem invite"
+	}
+}
@@ -0,0 +1,157 @@
+package kind28934
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/spf13/viper"
+
+	"github.com/HORNET-Storage/hornet-storage/lib/access"
+	"github.com/HORNET-Storage/hornet-storage/lib/config"
+	lib_nostr "github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr"
+	"github.com/HORNET-Storage/hornet-storage/lib/stores/badgerhold"
+	"github.com/HORNET-Storage/hornet-storage/lib/types"
+)
+
+func TestInviteRedemptionAndExpiry(t *testing.T) {
+	viper.Reset()
+	viper.Set("event_filtering.registered_kinds", []int{access.InviteRequestKind})
+	viper.Set("event_filtering.kind_whitelist", []string{"kind28934"})
+	config.InitConfigForTesting()
+	t.Cleanup(viper.Reset)
+
+	tempDir := t.TempDir()
+	store, err := badgerhold.InitStore(filepath.Join(tempDir, "store"), filepath.Join(tempDir, "stats.db"))
+	if err != nil {
+		t.Fatalf("InitStore: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := store.Cleanup(); err != nil {
+			t.Fatalf("Cleanup: %v", err)
+		}
+	})
+
+	statsStore := store.GetStatsStore()
+	if err := statsStore.CreateInviteCode(&types.InviteCode{
+		Code:          "community-invite",
+		Tier:          "Basic",
+		MaxUses:       1,
+		GrantDuration: int64(time.Hour / time.Second),
+	}); err != nil {
+		t.Fatalf("CreateInviteCode: %v", err)
+	}
+
+	ac := access.NewAccessControl(statsStore, &types.AllowedUsersSettings{
+		Mode:  "invite-only",
+		Read:  "all_users",
+		Write: "allowed_users",
+	})
+	handler := BuildKind28934Handler(ac)
+
+	redeem := func(claim string) (string, bool, string) {
+		privateKey := nostr.GeneratePrivateKey()
+		event := nostr.Event{
+			Kind:      access.InviteRequestKind,
+			CreatedAt: nostr.Now(),
+			Tags:      nostr.Tags{{"claim", claim}},
+		}
+		if err := event.Sign(privateKey); err != nil {
+			t.Fatalf("Sign: %v", err)
+		}
+
+		data, _ := jsoniter.Marshal(nostr.EventEnvelope{Event: event})
+		var accepted bool
+		var message string
+		handler(func() ([]byte, error) { return data, nil }, func(messageType string, params ...interface{}) {
+			flat := lib_nostr.ExtractInterfaceValues(params...)
+			if messageType == "OK" && len(flat) >= 3 {
+				accepted, _ = flat[1].(bool)
+				message, _ = flat[2].(string)
+			}
+		})
+		return event.PubKey, accepted, message
+	}
+
+	invited, accepted, message := redeem("community-invite")
+	if !accepted {
+		t.Fatalf("expected the invite to be accepted, got %q", message)
+	}
+	if err := ac.CanWrite(invited); err != nil {
+		t.Fatalf("expected the invited user to be able to write: %v", err)
+	}
+
+	if _, accepted, message := redeem("community-invite"); accepted || message != "restricted: "+types.ErrInviteExhausted.Error() {
+		t.Errorf("expected the single-use invite to be exhausted, got %t %q", accepted, message)
+	}
+	if _, accepted, _ := redeem("unknown-code"); accepted {
+		t.Error("expected an unknown code to be rejected")
+	}
+
+	user, err := statsStore.GetAllowedUser(invited)
+	if err != nil || user.ExpiresAt == nil || user.Tier != "Basic" {
+		t.Fatalf("expected a time-limited Basic grant, got %+v (%v)", user, err)
+	}
+
+	// The sweep revokes the grant once it lapses and clears the cached result
+	if revoked := ac.SweepExpiredUsers(time.Now()); len(revoked) != 0 {
+		t.Fatalf("expected nothing to be revoked yet, got %v", revoked)
+	}
+	if revoked := ac.SweepExpiredUsers(user.ExpiresAt.Add(time.Second)); len(revoked) != 1 || revoked[0] != invited {
+		t.Fatalf("expected the invited user to be revoked, got %v", revoked)
+	}
+	if err := ac.CanWrite(invited); err == nil {
+		t.Error("expected write access to be revoked after expiry")
+	}
+}
+
+func TestInviteNeverNarrowsAnExistingGrant(t *testing.T) {
+	viper.Reset()
+	viper.Set("allowed_users.tiers", []map[string]interface{}{
+		{"name": "Basic", "price_sats": 0, "monthly_limit_bytes": 104857600},
+		{"name": "Premium", "price_sats": 5000, "monthly_limit_bytes": 10737418240},
+	})
+	config.InitConfigForTesting()
+	t.Cleanup(viper.Reset)
+
+	tempDir := t.TempDir()
+	store, err := badgerhold.InitStore(filepath.Join(tempDir, "store"), filepath.Join(tempDir, "stats.db"))
+	if err != nil {
+		t.Fatalf("InitStore: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := store.Cleanup(); err != nil {
+			t.Fatalf("Cleanup: %v", err)
+		}
+	})
+
+	statsStore := store.GetStatsStore()
+	if err := statsStore.CreateInviteCode(&types.InviteCode{
+		Code:          "read-only-trial",
+		Tier:          "Basic",
+		ReadOnly:      true,
+		GrantDuration: int64(time.Hour / time.Second),
+	}); err != nil {
+		t.Fatalf("CreateInviteCode: %v", err)
+	}
+
+	pubkey, _ := nostr.GetPublicKey(nostr.GeneratePrivateKey())
+	if err := statsStore.AddAllowedUser(pubkey, true, "Premium", "admin"); err != nil {
+		t.Fatalf("AddAllowedUser: %v", err)
+	}
+
+	granted, err := statsStore.RedeemInviteCode("read-only-trial", pubkey, time.Now())
+	if err != nil {
+		t.Fatalf("RedeemInviteCode: %v", err)
+	}
+	if granted.Tier != "Premium" || granted.ReadOnly || granted.ExpiresAt != nil || granted.CreatedBy != "admin" {
+		t.Fatalf("expected the permanent Premium write grant to be kept, got %+v", granted)
+	}
+
+	user, err := statsStore.GetAllowedUser(pubkey)
+	if err != nil || user.Tier != "Premium" || user.ReadOnly || user.ExpiresAt != nil {
+		t.Fatalf("expected the stored grant to be unchanged, got %+v (%v)", user, err)
+	}
+}
@@ -0,0 +1,211 @@
+package kind30166
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/nbd-wtf/go-nostr"
+
+	"github.com/HORNET-Storage/hornet-storage/lib/config"
+	"github.com/HORNET-Storage/hornet-storage/lib/logging"
+	"github.com/HORNET-Storage/hornet-storage/lib/stores"
+	"github.com/HORNET-Storage/hornet-storage/lib/transports/websocket"
+	"github.com/HORNET-Storage/hornet-storage/lib/types"
+)
+
+const (
+	// RelayDiscoveryKind is the NIP-66 relay discovery event kind
+	RelayDiscoveryKind = 30166
+
+	// DefaultRefreshInterval is how often the announcement is re-signed when none is configured
+	DefaultRefreshInterval = time.Hour
+
+	publishTimeout = 15 * time.Second
+)
+
+// CreateKind30166Event signs a NIP-66 discovery event describing this relay, replaces
+// the previously stored one and returns it. It returns nil when discovery is disabled.
+func CreateKind30166Event(privateKey *secp256k1.PrivateKey, publicKey *secp256k1.PublicKey, store stores.Store) (*nostr.Event, error) {
+	cfg, err := config.GetConfig()
+	if err != nil {
+		return nil, fmt.Errorf("error getting config: %v", err)
+	}
+
+	discovery := cfg.Relay.Discovery
+	if !discovery.Enabled {
+		return nil, nil
+	}
+	if strings.TrimSpace(discovery.URL) == "" {
+		return nil, fmt.Errorf("relay.discovery.url must be set to announce the relay")
+	}
+
+	info := websocket.GetRelayInfo()
+	content, err := json.Marshal(info)
+	if err != nil {
+		return nil, fmt.Errorf("error marshaling relay info: %v", err)
+	}
+
+	event := &nostr.Event{
+		CreatedAt: nostr.Now(),
+		Kind:      RelayDiscoveryKind,
+		Tags:      BuildDiscoveryTags(cfg, info),
+		Content:   string(content),
+	}
+	if err := event.Sign(hex.EncodeToString(privateKey.Serialize())); err != nil {
+		return nil, fmt.Errorf("error signing kind 30166 event: %v", err)
+	}
+
+	// Only the latest announcement is kept
+	existingEvents, err := store.QueryEvents(nostr.Filter{
+		Kinds:   []int{RelayDiscoveryKind},
+		Authors: []string{event.PubKey},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error querying existing kind 30166 events: %v", err)
+	}
+	for _, oldEvent := range existingEvents {
+		if err := store.DeleteEvent(oldEvent.ID); err != nil {
+			return nil, fmt.Errorf("error deleting old kind 30166 event %s: %v", oldEvent.ID, err)
+		}
+	}
+
+	if err := store.StoreEvent(event); err != nil {
+		return nil, fmt.Errorf("error storing kind 30166 event: %v", err)
+	}
+
+	logging.Infof("Kind 30166 discovery event created for %s", discovery.URL)
+	return event, nil
+}
+
+// BuildDiscoveryTags returns the NIP-66 tags for the relay: the d tag with its
+// normalized url, N tags for supported NIPs, R tags for requirements, k tags for
+// the kind whitelist and t tags for the configured topics.
+func BuildDiscoveryTags(cfg *types.Config, info websocket.NIP11RelayInfo) nostr.Tags {
+	tags := nostr.Tags{
+		{"d", nostr.NormalizeURL(cfg.Relay.Discovery.URL)},
+		{"n", "clearnet"},
+	}
+
+	for _, nip := range info.SupportedNIPs {
+		tags = append(tags, nostr.Tag{"N", strconv.Itoa(nip)})
+	}
+
+	if limitation := info.Limitation; limitation != nil {
+		tags = append(tags,
+			requirementTag("auth", limitation.AuthRequired),
+			requirementTag("payment", limitation.PaymentRequired),
+			requirementTag("writes", limitation.RestrictedWrites),
+			requirementTag("pow", limitation.MinPowDifficulty > 0),
+		)
+	}
+
+	for _, kind := range whitelistedKinds(cfg.EventFiltering.KindWhitelist) {
+		tags = append(tags, nostr.Tag{"k", strconv.Itoa(kind)})
+	}
+
+	for _, topic := range cfg.Relay.Discovery.Topics {
+		if topic = strings.ToLower(strings.TrimSpace(topic)); topic != "" {
+			tags = append(tags, nostr.Tag{"t", topic})
+		}
+	}
+
+	return tags
+}
+
+// PublishKind30166Event sends the announcement to each relay, logging failures
+func PublishKind30166Event(ctx context.Context, event *nostr.Event, relays []string) {
+	for _, url := range relays {
+		if url = strings.TrimSpace(url); url == "" {
+			continue
+		}
+
+		publishCtx, cancel := context.WithTimeout(ctx, publishTimeout)
+		relay, err := nostr.RelayConnect(publishCtx, url)
+		if err != nil {
+			logging.Infof("Failed to connect to %s to publish kind 30166: %v", url, err)
+			cancel()
+			continue
+		}
+
+		if err := relay.Publish(publishCtx, *event); err != nil {
+			logging.Infof("Failed to publish kind 30166 to %s: %v", url, err)
+		}
+		relay.Close()
+		cancel()
+	}
+}
+
+// RefreshKind30166Event regenerates the announcement and pushes it to the bootstrap relays
+func RefreshKind30166Event(ctx context.Context, privateKey *secp256k1.PrivateKey, publicKey *secp256k1.PublicKey, store stores.Store) error {
+	event, err := CreateKind30166Event(privateKey, publicKey, store)
+	if err != nil || event == nil {
+		return err
+	}
+
+	cfg, err := config.GetConfig()
+	if err != nil {
+		return nil
+	}
+	PublishKind30166Event(ctx, event, cfg.Relay.Discovery.BootstrapRelays)
+	return nil
+}
+
+// StartDiscoveryAnnouncer publishes the announcement now and then every refresh
+// interval until ctx is done, so liveness monitors see a recent created_at.
+func StartDiscoveryAnnouncer(ctx context.Context, privateKey *secp256k1.PrivateKey, publicKey *secp256k1.PublicKey, store stores.Store) {
+	go func() {
+		for {
+			if err := RefreshKind30166Event(ctx, privateKey, publicKey, store); err != nil {
+				logging.Infof("Error refreshing kind 30166 event: %v", err)
+			}
+
+			select {
+			case <-time.After(refreshInterval()):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+func refreshInterval() time.Duration {
+	cfg, err := config.GetConfig()
+	if err != nil || cfg.Relay.Discovery.RefreshIntervalSeconds <= 0 {
+		return DefaultRefreshInterval
+	}
+	return time.Duration(cfg.Relay.Discovery.RefreshIntervalSeconds) * time.Second
+}
+
+// requirementTag formats a NIP-66 R tag, negated with ! when the requirement is absent
+func requirementTag(requirement string, required bool) nostr.Tag {
+	if required {
+		return nostr.Tag{"R", requirement}
+	}
+	return nostr.Tag{"R", "!" + requirement}
+}
+
+// whitelistedKinds parses the "kindN" whitelist entries into sorted unique kinds
+func whitelistedKinds(whitelist []string) []int {
+	seen := make(map[int]struct{})
+	kinds := make([]int, 0, len(whitelist))
+	for _, entry := range whitelist {
+		kind, err := strconv.Atoi(strings.TrimPrefix(entry, "kind"))
+		if err != nil {
+			continue
+		}
+		if _, ok := seen[kind]; ok {
+			continue
+		}
+		seen[kind] = struct{}{}
+		kinds = append(kinds, kind)
+	}
+	sort.Ints(kinds)
+	return kinds
+}
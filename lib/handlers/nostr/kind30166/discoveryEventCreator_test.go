@@ -0,0 +1,89 @@
+package kind30166
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/spf13/viper"
+
+	"github.com/HORNET-Storage/hornet-storage/lib/config"
+	"github.com/HORNET-Storage/hornet-storage/lib/stores/badgerhold"
+)
+
+func TestCreateKind30166Event(t *testing.T) {
+	viper.Reset()
+	viper.Set("relay.discovery.enabled", true)
+	viper.Set("relay.discovery.url", "wss://relay.example.com")
+	viper.Set("relay.discovery.topics", []string{"Storage", " "})
+	viper.Set("relay.supported_nips", []int{1, 11, 66})
+	viper.Set("event_filtering.kind_whitelist", []string{"kind7", "kind1", "kind1", "invalid"})
+	viper.Set("event_filtering.proof_of_work.min_difficulty", 16)
+	viper.Set("allowed_users.mode", "public")
+	viper.Set("allowed_users.read", "all_users")
+	viper.Set("allowed_users.write", "all_users")
+	config.InitConfigForTesting()
+	t.Cleanup(viper.Reset)
+
+	tempDir := t.TempDir()
+	store, err := badgerhold.InitStore(filepath.Join(tempDir, "store"), filepath.Join(tempDir, "stats.db"))
+	if err != nil {
+		t.Fatalf("InitStore: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := store.Cleanup(); err != nil {
+			t.Fatalf("Cleanup: %v", err)
+		}
+	})
+
+	privateKey, err := secp256k1.GeneratePrivateKey()
+	if err != nil {
+		t.Fatalf("GeneratePrivateKey: %v", err)
+	}
+
+	event, err := CreateKind30166Event(privateKey, privateKey.PubKey(), store)
+	if err != nil || event == nil {
+		t.Fatalf("CreateKind30166Event: %v", err)
+	}
+	if ok, err := event.CheckSignature(); !ok || err != nil {
+		t.Fatalf("expected a valid signature: %v", err)
+	}
+
+	expected := nostr.Tags{
+		{"d", "wss://relay.example.com"},
+		{"n", "clearnet"},
+		{"N", "1"}, {"N", "11"}, {"N", "66"},
+		{"R", "!auth"}, {"R", "!payment"}, {"R", "writes"}, {"R", "pow"},
+		{"k", "1"}, {"k", "7"},
+		{"t", "storage"},
+	}
+	if len(event.Tags) != len(expected) {
+		t.Fatalf("expected tags %v, got %v", expected, event.Tags)
+	}
+	for i, tag := range expected {
+		if !slices.Equal(tag, event.Tags[i]) {
+			t.Errorf("tag %d: expected %v, got %v", i, tag, event.Tags[i])
+		}
+	}
+
+	// Refreshing replaces the stored announcement
+	refreshed, err := CreateKind30166Event(privateKey, privateKey.PubKey(), store)
+	if err != nil {
+		t.Fatalf("CreateKind30166Event: %v", err)
+	}
+	stored, err := store.QueryEvents(nostr.Filter{Kinds: []int{RelayDiscoveryKind}})
+	if err != nil {
+		t.Fatalf("QueryEvents: %v", err)
+	}
+	if len(stored) != 1 || stored[0].ID != refreshed.ID {
+		t.Fatalf("expected only the refreshed announcement to be stored, got %d events", len(stored))
+	}
+
+	viper.Set("relay.discovery.enabled", false)
+	config.InitConfigForTesting()
+	if event, err := CreateKind30166Event(privateKey, privateKey.PubKey(), store); event != nil || err != nil {
+		t.Errorf("expected nothing to be announced when discovery is disabled, got %v (%v)", event, err)
+	}
+}
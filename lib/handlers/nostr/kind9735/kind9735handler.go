@@ -5,7 +5,10 @@ import (
 
 	jsoniter "github.com/json-iterator/go"
 
+	"github.com/HORNET-Storage/hornet-storage/lib/logging"
 	"github.com/HORNET-Storage/hornet-storage/lib/stores"
+	"github.com/HORNET-Storage/hornet-storage/lib/subscription"
+	"github.com/HORNET-Storage/hornet-storage/services/lightning"
 	"github.com/nbd-wtf/go-nostr"
 
 	lib_nostr "github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr"
@@ -42,6 +45,15 @@ func BuildKind9735Handler(store stores.Store) func(read lib_nostr.KindReader, wr
 			return
 		}
 
+		// Zaps to the relay pubkey pay for the sender's subscription
+		var processor lightning.PaymentProcessor
+		if manager := subscription.GetGlobalManager(); manager != nil {
+			processor = manager
+		}
+		if _, err := lightning.ProcessZapReceipt(store, processor, &env.Event); err != nil {
+			logging.Infof("Zap receipt %s not credited: %v", env.Event.ID, err)
+		}
+
 		// Successfully processed event
 		write("OK", env.Event.ID, true, "Event stored successfully")
 	}
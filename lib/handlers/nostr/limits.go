@@ -0,0 +1,35 @@
+package nostr
+
+import (
+	"fmt"
+
+	"github.com/HORNET-Storage/hornet-storage/lib/config"
+)
+
+// MaxMessageLength returns the largest client message in bytes, 0 when unlimited
+func MaxMessageLength() int {
+	settings, err := config.GetConfig()
+	if err != nil {
+		return 0
+	}
+	return max(settings.Relay.Limitation.MaxMessageLength, 0)
+}
+
+// MaxSubscriptions returns how many subscriptions one connection may hold open, 0 when unlimited
+func MaxSubscriptions() int {
+	settings, err := config.GetConfig()
+	if err != nil {
+		return 0
+	}
+	return max(settings.Relay.Limitation.MaxSubscriptions, 0)
+}
+
+// SubscriptionLimitReason returns the CLOSED reason when a connection that already
+// holds open subscriptions may not open another, or an empty string if it may
+func SubscriptionLimitReason(open int) string {
+	limit := MaxSubscriptions()
+	if limit == 0 || open < limit {
+		return ""
+	}
+	return fmt.Sprintf("error: too many open subscriptions, the limit is %d", limit)
+}
@@ -0,0 +1,72 @@
+package nostr
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/nbd-wtf/go-nostr/nip13"
+
+	"github.com/HORNET-Storage/hornet-storage/lib/config"
+)
+
+// MinPowDifficulty returns the relay-wide NIP-13 difficulty advertised in NIP-11
+func MinPowDifficulty() int {
+	settings, err := config.GetConfig()
+	if err != nil {
+		return 0
+	}
+	return max(settings.EventFiltering.ProofOfWork.MinDifficulty, 0)
+}
+
+// RequiredPowDifficulty returns the NIP-13 difficulty an event of kind must meet.
+// Trusted authors (authenticated or paid) get the lower trusted bar when it is enabled.
+func RequiredPowDifficulty(kind int, trusted bool) int {
+	settings, err := config.GetConfig()
+	if err != nil {
+		return 0
+	}
+	pow := settings.EventFiltering.ProofOfWork
+
+	required := pow.MinDifficulty
+	if difficulty, ok := pow.KindDifficulty[strconv.Itoa(kind)]; ok {
+		required = difficulty
+	}
+	if trusted && pow.RelaxForTrusted {
+		required = min(required, pow.TrustedMinDifficulty)
+	}
+	return max(required, 0)
+}
+
+// CheckProofOfWork verifies that the event id has at least minDifficulty leading
+// zero bits and that its nonce tag commits to at least that target, so ids that
+// are lucky at a lower target are rejected. Errors are formatted as OK messages.
+func CheckProofOfWork(event *nostr.Event, minDifficulty int) error {
+	if minDifficulty <= 0 {
+		return nil
+	}
+
+	// The difficulty is only meaningful if the id really is the event hash
+	if event.GetID() != event.ID {
+		return fmt.Errorf("invalid: event id does not match its content")
+	}
+
+	nonce := event.Tags.GetFirst([]string{"nonce"})
+	if nonce == nil || len(*nonce) < 3 {
+		return fmt.Errorf("pow: missing nonce tag committing to difficulty %d", minDifficulty)
+	}
+
+	target, err := strconv.Atoi((*nonce)[2])
+	if err != nil {
+		return fmt.Errorf("pow: invalid nonce target %q", (*nonce)[2])
+	}
+	if target < minDifficulty {
+		return fmt.Errorf("pow: committed target %d is less than %d", target, minDifficulty)
+	}
+
+	if difficulty := nip13.Difficulty(event.ID); difficulty < minDifficulty {
+		return fmt.Errorf("pow: difficulty %d is less than %d", max(difficulty, 0), minDifficulty)
+	}
+
+	return nil
+}
@@ -0,0 +1,90 @@
+package nostr
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/nbd-wtf/go-nostr/nip13"
+	"github.com/spf13/viper"
+
+	"github.com/HORNET-Storage/hornet-storage/lib/config"
+)
+
+func TestRequiredPowDifficulty(t *testing.T) {
+	viper.Reset()
+	viper.Set("event_filtering.proof_of_work.min_difficulty", 16)
+	viper.Set("event_filtering.proof_of_work.kind_difficulty", map[string]int{"0": 0, "1": 20})
+	viper.Set("event_filtering.proof_of_work.relax_for_trusted", true)
+	viper.Set("event_filtering.proof_of_work.trusted_min_difficulty", 8)
+	config.InitConfigForTesting()
+	t.Cleanup(viper.Reset)
+
+	cases := []struct {
+		kind     int
+		trusted  bool
+		expected int
+	}{
+		{kind: 7, expected: 16},
+		{kind: 1, expected: 20},
+		{kind: 0, expected: 0},
+		{kind: 1, trusted: true, expected: 8},
+		{kind: 0, trusted: true, expected: 0},
+	}
+	for _, c := range cases {
+		if got := RequiredPowDifficulty(c.kind, c.trusted); got != c.expected {
+			t.Errorf("kind %d trusted %t: expected %d, got %d", c.kind, c.trusted, c.expected, got)
+		}
+	}
+	if got := MinPowDifficulty(); got != 16 {
+		t.Errorf("expected advertised difficulty 16, got %d", got)
+	}
+}
+
+func TestCheckProofOfWork(t *testing.T) {
+	privateKey := nostr.GeneratePrivateKey()
+	sign := func(event *nostr.Event) *nostr.Event {
+		if err := event.Sign(privateKey); err != nil {
+			t.Fatalf("Sign: %v", err)
+		}
+		return event
+	}
+
+	pubkey, _ := nostr.GetPublicKey(privateKey)
+	mined, err := nip13.Generate(&nostr.Event{PubKey: pubkey, Kind: 1, Content: "mined", Tags: nostr.Tags{}}, 8, 10*time.Second)
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	sign(mined)
+
+	if err := CheckProofOfWork(mined, 8); err != nil {
+		t.Errorf("expected mined event to pass, got %v", err)
+	}
+	if err := CheckProofOfWork(mined, 0); err != nil {
+		t.Errorf("expected no requirement to pass, got %v", err)
+	}
+	if err := CheckProofOfWork(mined, 12); err == nil || !strings.HasPrefix(err.Error(), "pow: committed target 8") {
+		t.Errorf("expected the commitment to be checked, got %v", err)
+	}
+
+	unmined := sign(&nostr.Event{Kind: 1, Content: "plain", CreatedAt: nostr.Now(), Tags: nostr.Tags{}})
+	if err := CheckProofOfWork(unmined, 8); err == nil || !strings.HasPrefix(err.Error(), "pow: missing nonce tag") {
+		t.Errorf("expected a missing nonce to be rejected, got %v", err)
+	}
+
+	// A nonce that commits to the target without doing the work is rejected on the id
+	lucky := &nostr.Event{Kind: 1, Content: "claimed", CreatedAt: nostr.Now(), Tags: nostr.Tags{{"nonce", "1", "8"}}}
+	for sign(lucky); nip13.Difficulty(lucky.ID) >= 8; sign(lucky) {
+		lucky.Tags[0][1] += "1"
+	}
+	if err := CheckProofOfWork(lucky, 8); err == nil || !strings.HasPrefix(err.Error(), "pow: difficulty") {
+		t.Errorf("expected insufficient work to be rejected, got %v", err)
+	}
+
+	forged := *mined
+	forged.Content = "changed after mining"
+	if err := CheckProofOfWork(&forged, 8); err == nil || !strings.HasPrefix(err.Error(), "invalid: ") {
+		t.Errorf("expected an id that does not match the content to be rejected, got %v", err)
+	}
+}
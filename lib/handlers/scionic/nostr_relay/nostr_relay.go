@@ -6,12 +6,15 @@ import (
 	"encoding/hex"
 	"fmt"
 	"io"
+	"sync"
 
 	jsoniter "github.com/json-iterator/go"
 	"github.com/nbd-wtf/go-nostr"
 
+	"github.com/HORNET-Storage/hornet-storage/lib/eventbus"
 	lib_nostr "github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr"
 	nostr_auth "github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr/auth"
+	"github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr/contentfilter"
 	"github.com/HORNET-Storage/hornet-storage/lib/logging"
 	"github.com/HORNET-Storage/hornet-storage/lib/stores"
 	ws "github.com/HORNET-Storage/hornet-storage/lib/transports/websocket"
@@ -21,10 +24,88 @@ import (
 )
 
 type dhtAuthState struct {
+	mu            sync.RWMutex
 	pubkey        string
 	authenticated bool
 }
 
+func (a *dhtAuthState) get() (string, bool) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.pubkey, a.authenticated
+}
+
+func (a *dhtAuthState) set(pubkey string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.pubkey = pubkey
+	a.authenticated = true
+}
+
+// dhtSubscriptions tracks the live event bus subscriptions opened on one stream
+type dhtSubscriptions struct {
+	mu   sync.Mutex
+	subs map[string]*eventbus.Subscription
+}
+
+// set registers a subscription, closing any previous one with the same ID
+func (d *dhtSubscriptions) set(id string, sub *eventbus.Subscription) {
+	d.mu.Lock()
+	previous := d.subs[id]
+	d.subs[id] = sub
+	d.mu.Unlock()
+
+	if previous != nil {
+		previous.Close()
+	}
+}
+
+// open counts the open subscriptions other than id
+func (d *dhtSubscriptions) open(id string) int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	open := len(d.subs)
+	if _, exists := d.subs[id]; exists {
+		open--
+	}
+	return open
+}
+
+// remove closes and forgets a subscription. Returns false if it was not open.
+func (d *dhtSubscriptions) remove(id string) bool {
+	d.mu.Lock()
+	sub, ok := d.subs[id]
+	delete(d.subs, id)
+	d.mu.Unlock()
+
+	if ok {
+		sub.Close()
+	}
+	return ok
+}
+
+// forget drops a subscription the bus already closed, unless it has been replaced
+func (d *dhtSubscriptions) forget(id string, sub *eventbus.Subscription) {
+	d.mu.Lock()
+	if d.subs[id] == sub {
+		delete(d.subs, id)
+	}
+	d.mu.Unlock()
+}
+
+// closeAll closes every subscription when the stream ends
+func (d *dhtSubscriptions) closeAll() {
+	d.mu.Lock()
+	subs := d.subs
+	d.subs = make(map[string]*eventbus.Subscription)
+	d.mu.Unlock()
+
+	for _, sub := range subs {
+		sub.Close()
+	}
+}
+
 // AddNostrRelayHandler registers the /nostr protocol on the hyperswarm listener.
 // This creates a single bidirectional stream per client that speaks full Nostr
 // protocol (EVENT, REQ, CLOSE, AUTH, COUNT) — exactly like a WebSocket connection
@@ -57,9 +138,26 @@ func buildNostrStreamHandler(store stores.Store) hsListener.StreamHandler {
 
 		var json = jsoniter.ConfigCompatibleWithStandardLibrary
 		authState := &dhtAuthState{}
+		subscriptions := &dhtSubscriptions{subs: make(map[string]*eventbus.Subscription)}
+		defer subscriptions.closeAll()
+
+		// Live events are written from event bus goroutines, so all writes are serialised
+		var writeMu sync.Mutex
+		send := func(messageType string, out []byte) {
+			logging.Infof("/nostr: SENDING %s (%d bytes): %s", messageType, len(out), string(out[:min(len(out), 200)]))
+			writeMu.Lock()
+			defer writeMu.Unlock()
+			if _, err := stream.Write(out); err != nil {
+				logging.Errorf("/nostr: write error sending %s: %v", messageType, err)
+			}
+		}
 		scanner := bufio.NewScanner(stream)
-		// Allow messages up to 2MB (matches go-nostr's 33MB but reasonable for DHT)
-		scanner.Buffer(make([]byte, 0, 64*1024), 2*1024*1024)
+		// Allow messages up to max_message_length, or 2MB when unlimited
+		maxMessageLength := lib_nostr.MaxMessageLength()
+		if maxMessageLength == 0 {
+			maxMessageLength = 2 * 1024 * 1024
+		}
+		scanner.Buffer(make([]byte, 0, min(64*1024, maxMessageLength)), maxMessageLength)
 
 		// writeFn sends a Nostr protocol response back on the stream.
 		//
@@ -145,10 +243,7 @@ func buildNostrStreamHandler(store stores.Store) hsListener.StreamHandler {
 			}
 
 			if len(out) > 0 {
-				logging.Infof("/nostr: SENDING %s (%d bytes): %s", messageType, len(out), string(out[:min(len(out), 200)]))
-				if _, err := stream.Write(out); err != nil {
-					logging.Errorf("/nostr: write error sending %s: %v", messageType, err)
-				}
+				send(messageType, out)
 			}
 		}
 
@@ -167,24 +262,31 @@ func buildNostrStreamHandler(store stores.Store) hsListener.StreamHandler {
 			switch env := envelope.(type) {
 			case *nostr.EventEnvelope:
 				logging.Infof("/nostr: EVENT kind=%d id=%s", env.Kind, env.Event.ID[:16])
-				handleEvent(env, writeFn, store, json)
+				handleEvent(env, writeFn, store, json, authState)
 
 			case *nostr.ReqEnvelope:
 				logging.Infof("/nostr: REQ sub=%s filters=%d", env.SubscriptionID, len(env.Filters))
+				if reason := lib_nostr.SubscriptionLimitReason(subscriptions.open(env.SubscriptionID)); reason != "" {
+					writeFn("CLOSED", env.SubscriptionID, reason)
+					continue
+				}
+				subscribeLive(env, subscriptions, authState, writeFn)
 				handleReq(env, writeFn, json, authState)
 
 			case *nostr.CountEnvelope:
 				handleCount(env, writeFn, json, authState)
 
 			case *nostr.CloseEnvelope:
-				logging.Infof("/nostr: CLOSE %s", string(*env))
+				subscriptionID := string(*env)
+				logging.Infof("/nostr: CLOSE %s", subscriptionID)
+				subscriptions.remove(subscriptionID)
+				writeFn("CLOSED", subscriptionID, "Subscription closed successfully.")
 
 			case *nostr.AuthEnvelope:
 				logging.Infof("/nostr: AUTH received, event_id=%s", env.Event.ID[:16])
 				result, message, ok := nostr_auth.AuthenticateEvent(&env.Event, challenge, store, ws.GetAccessControl())
 				if ok {
-					authState.pubkey = result.PubKey
-					authState.authenticated = true
+					authState.set(result.PubKey)
 				}
 				writeFn("OK", env.Event.ID, ok, message)
 
@@ -201,8 +303,16 @@ func buildNostrStreamHandler(store stores.Store) hsListener.StreamHandler {
 	}
 }
 
-// handleEvent dispatches an EVENT message to the appropriate kind handler.
-func handleEvent(env *nostr.EventEnvelope, writeFn lib_nostr.KindWriter, store stores.Store, json jsoniter.API) {
+// handleEvent dispatches an EVENT message to the appropriate kind handler and
+// publishes it to live subscribers on every transport once it is accepted.
+func handleEvent(env *nostr.EventEnvelope, writeFn lib_nostr.KindWriter, store stores.Store, json jsoniter.API, authState *dhtAuthState) {
+	writeFn, accepted := lib_nostr.TrackAccepted(writeFn)
+	defer func() {
+		if accepted() && !lib_nostr.IsPrivateKind(env.Event.Kind) {
+			eventbus.Publish(&env.Event)
+		}
+	}()
+
 	// Check blocked pubkeys
 	if store != nil {
 		isBlocked, err := store.IsBlockedPubkey(env.Event.PubKey)
@@ -221,6 +331,12 @@ func handleEvent(env *nostr.EventEnvelope, writeFn lib_nostr.KindWriter, store s
 			writeFn("OK", env.Event.ID, false, "Event rejected: Write access denied")
 			return
 		}
+
+		authPubkey, _ := authState.get()
+		if err := accessControl.CheckWritePolicy(&env.Event, authPubkey); err != nil {
+			writeFn("OK", env.Event.ID, false, err.Error())
+			return
+		}
 	}
 
 	// Find handler for this kind
@@ -262,6 +378,7 @@ func handleReq(env *nostr.ReqEnvelope, writeFn lib_nostr.KindWriter, json jsonit
 		return
 	}
 
+	pubkey, authenticated := authState.get()
 	read := func() ([]byte, error) {
 		wrapper := struct {
 			Request         *nostr.ReqEnvelope `json:"request"`
@@ -269,8 +386,8 @@ func handleReq(env *nostr.ReqEnvelope, writeFn lib_nostr.KindWriter, json jsonit
 			IsAuthenticated bool               `json:"is_authenticated"`
 		}{
 			Request:         env,
-			AuthPubkey:      authState.pubkey,
-			IsAuthenticated: authState.authenticated,
+			AuthPubkey:      pubkey,
+			IsAuthenticated: authenticated,
 		}
 		return json.Marshal(wrapper)
 	}
@@ -278,6 +395,34 @@ func handleReq(env *nostr.ReqEnvelope, writeFn lib_nostr.KindWriter, json jsonit
 	handler(read, writeFn)
 }
 
+// subscribeLive registers the REQ's filters on the event bus so events accepted on any
+// transport after the stored results are streamed to the client until it sends CLOSE.
+func subscribeLive(env *nostr.ReqEnvelope, subscriptions *dhtSubscriptions, authState *dhtAuthState, writeFn lib_nostr.KindWriter) {
+	subscriptionID := env.SubscriptionID
+
+	var sub *eventbus.Subscription
+	sub = eventbus.Subscribe(subscriptionID, env.Filters, func(event *nostr.Event) error {
+		// Live events are only delivered once the stream has authenticated, as on WebSocket
+		pubkey, authenticated := authState.get()
+		if !authenticated {
+			return nil
+		}
+		if feed := contentfilter.GetFeed(); feed != nil && !feed.AllowLive(pubkey, event) {
+			return nil
+		}
+		eventJSON, err := event.MarshalJSON()
+		if err != nil {
+			return nil
+		}
+		writeFn("EVENT", subscriptionID, string(eventJSON))
+		return nil
+	}, func(reason string) {
+		subscriptions.forget(subscriptionID, sub)
+		writeFn("CLOSED", subscriptionID, reason)
+	})
+	subscriptions.set(subscriptionID, sub)
+}
+
 // handleCount dispatches a COUNT message to the count handler.
 func handleCount(env *nostr.CountEnvelope, writeFn lib_nostr.KindWriter, json jsoniter.API, authState *dhtAuthState) {
 	handler := lib_nostr.GetHandler("count")
@@ -286,6 +431,7 @@ func handleCount(env *nostr.CountEnvelope, writeFn lib_nostr.KindWriter, json js
 		return
 	}
 
+	pubkey, authenticated := authState.get()
 	read := func() ([]byte, error) {
 		wrapper := struct {
 			Request         *nostr.CountEnvelope `json:"request"`
@@ -293,8 +439,8 @@ func handleCount(env *nostr.CountEnvelope, writeFn lib_nostr.KindWriter, json js
 			IsAuthenticated bool                 `json:"is_authenticated"`
 		}{
 			Request:         env,
-			AuthPubkey:      authState.pubkey,
-			IsAuthenticated: authState.authenticated,
+			AuthPubkey:      pubkey,
+			IsAuthenticated: authenticated,
 		}
 		return json.Marshal(wrapper)
 	}
@@ -1,6 +1,8 @@
 package nostr_relay
 
 import (
+	"path/filepath"
+	"strings"
 	"testing"
 
 	jsoniter "github.com/json-iterator/go"
@@ -9,6 +11,8 @@ import (
 
 	"github.com/HORNET-Storage/hornet-storage/lib/config"
 	lib_nostr "github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr"
+	"github.com/HORNET-Storage/hornet-storage/lib/stores/badgerhold"
+	ws "github.com/HORNET-Storage/hornet-storage/lib/transports/websocket"
 )
 
 func TestHandleEventRoutesConfigAllowedKindToUniversal(t *testing.T) {
@@ -42,7 +46,7 @@ func TestHandleEventRoutesConfigAllowedKindToUniversal(t *testing.T) {
 		if messageType == "OK" && len(params) >= 2 {
 			ok, _ = params[1].(bool)
 		}
-	}, nil, jsoniter.ConfigCompatibleWithStandardLibrary)
+	}, nil, jsoniter.ConfigCompatibleWithStandardLibrary, &dhtAuthState{})
 
 	if !calledUniversal {
 		t.Fatal("expected config-allowed kind without a specific handler to use universal handler")
@@ -51,3 +55,91 @@ func TestHandleEventRoutesConfigAllowedKindToUniversal(t *testing.T) {
 		t.Fatal("expected universal handler acknowledgement")
 	}
 }
+
+func TestHandleEventAppliesWritePolicy(t *testing.T) {
+	viper.Reset()
+	lib_nostr.ClearHandlers()
+	t.Cleanup(func() {
+		viper.Reset()
+		lib_nostr.ClearHandlers()
+		config.InitConfigForTesting()
+	})
+
+	viper.Set("event_filtering.registered_kinds", []int{1, 7, 30023})
+	viper.Set("event_filtering.kind_whitelist", []string{"kind1", "kind7", "kind30023"})
+	viper.Set("allowed_users.mode", "public")
+	viper.Set("allowed_users.read", "all_users")
+	viper.Set("allowed_users.write", "all_users")
+	viper.Set("allowed_users.tiers", []map[string]interface{}{
+		{"name": "Basic", "price_sats": 0},
+		{"name": "Pro", "price_sats": 1000},
+	})
+	viper.Set("allowed_users.write_policies", []map[string]interface{}{
+		{"name": "notes", "kinds": []int{1}, "auth_required": true, "max_content_length": 10},
+		{"name": "long-form", "kind_ranges": []map[string]interface{}{{"from": 30000, "to": 39999}}, "min_tier": "Pro"},
+	})
+	config.InitConfigForTesting()
+
+	tempDir := t.TempDir()
+	store, err := badgerhold.InitStore(filepath.Join(tempDir, "store"), filepath.Join(tempDir, "stats.db"))
+	if err != nil {
+		t.Fatalf("InitStore: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := store.Cleanup(); err != nil {
+			t.Fatalf("Cleanup: %v", err)
+		}
+	})
+	if err := ws.InitializeAccessControl(store.GetStatsStore()); err != nil {
+		t.Fatalf("InitializeAccessControl: %v", err)
+	}
+
+	lib_nostr.RegisterHandler("universal", func(read lib_nostr.KindReader, write lib_nostr.KindWriter) {
+		write("OK", "event-id", true, "")
+	})
+
+	privateKey := nostr.GeneratePrivateKey()
+	pubkey, _ := nostr.GetPublicKey(privateKey)
+	send := func(kind int, content string, authState *dhtAuthState) (bool, string) {
+		event := nostr.Event{Kind: kind, Content: content, CreatedAt: nostr.Now(), Tags: nostr.Tags{}}
+		if err := event.Sign(privateKey); err != nil {
+			t.Fatalf("Sign: %v", err)
+		}
+
+		var ok bool
+		var message string
+		handleEvent(&nostr.EventEnvelope{Event: event}, func(messageType string, params ...interface{}) {
+			if messageType == "OK" && len(params) >= 3 {
+				ok, _ = params[1].(bool)
+				message, _ = params[2].(string)
+			}
+		}, store, jsoniter.ConfigCompatibleWithStandardLibrary, authState)
+		return ok, message
+	}
+
+	if ok, message := send(1, "hello", &dhtAuthState{}); ok || !strings.HasPrefix(message, "auth-required: ") {
+		t.Errorf("expected unauthenticated note to need auth, got %t %q", ok, message)
+	}
+
+	authenticated := &dhtAuthState{}
+	authenticated.set(pubkey)
+	if ok, message := send(1, "this note is far too long", authenticated); ok || !strings.HasPrefix(message, "invalid: ") {
+		t.Errorf("expected oversized note to be invalid, got %t %q", ok, message)
+	}
+	if ok, message := send(1, "hello", authenticated); !ok {
+		t.Errorf("expected short authenticated note to be accepted, got %q", message)
+	}
+	if ok, message := send(7, "+", &dhtAuthState{}); !ok {
+		t.Errorf("expected kinds without a rule to be unaffected, got %q", message)
+	}
+
+	if ok, message := send(30023, "article", &dhtAuthState{}); ok || !strings.HasPrefix(message, "restricted: ") {
+		t.Errorf("expected long-form without a tier to be restricted, got %t %q", ok, message)
+	}
+	if err := store.GetStatsStore().AddAllowedUser(pubkey, true, "Pro", "test"); err != nil {
+		t.Fatalf("AddAllowedUser: %v", err)
+	}
+	if ok, message := send(30023, "article", &dhtAuthState{}); !ok {
+		t.Errorf("expected a Pro user to publish long-form, got %q", message)
+	}
+}
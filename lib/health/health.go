@@ -0,0 +1,252 @@
+// Package health aggregates per-component checks into liveness and readiness reports,
+// so an orchestrator can restart a wedged node or stop routing traffic to a broken one.
+package health
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Status is the state of a single component or of the whole node
+type Status string
+
+const (
+	StatusOK       Status = "ok"
+	StatusDegraded Status = "degraded" // Working, but something needs attention
+	StatusDown     Status = "down"
+)
+
+// DefaultCheckTimeout bounds each check; a check that runs longer is reported down
+const DefaultCheckTimeout = 3 * time.Second
+
+// DefaultCacheTTL is how long a report is reused, so frequent probes don't hammer the stores
+const DefaultCacheTTL = 2 * time.Second
+
+// Result is what a check reports about its component
+type Result struct {
+	Status  Status
+	Error   string
+	Details map[string]interface{}
+}
+
+// Check probes one component. It should respect ctx, which carries the check timeout.
+type Check func(ctx context.Context) Result
+
+// Component is a named check. A critical component that is down makes the node not ready;
+// a liveness component that is down means the process should be restarted.
+type Component struct {
+	Name     string
+	Critical bool
+	Liveness bool
+	Check    Check
+}
+
+// ComponentReport is the outcome of one check
+type ComponentReport struct {
+	Status    Status                 `json:"status"`
+	Critical  bool                   `json:"critical"`
+	LatencyMs int64                  `json:"latency_ms"`
+	Error     string                 `json:"error,omitempty"`
+	Details   map[string]interface{} `json:"details,omitempty"`
+}
+
+// Report is the aggregated state of the node
+type Report struct {
+	Status        Status                     `json:"status"`
+	CheckedAt     time.Time                  `json:"checked_at"`
+	UptimeSeconds int64                      `json:"uptime_seconds"`
+	Components    map[string]ComponentReport `json:"components"`
+}
+
+// Healthy reports whether the report should be answered with a success status code
+func (r *Report) Healthy() bool {
+	return r.Status != StatusDown
+}
+
+// OK reports a healthy component
+func OK(details map[string]interface{}) Result {
+	return Result{Status: StatusOK, Details: details}
+}
+
+// Degraded reports a component that works but needs attention
+func Degraded(message string, details map[string]interface{}) Result {
+	return Result{Status: StatusDegraded, Error: message, Details: details}
+}
+
+// Down reports a component that is not working
+func Down(err error, details map[string]interface{}) Result {
+	return Result{Status: StatusDown, Error: err.Error(), Details: details}
+}
+
+// Registry holds the registered components and the most recent reports
+type Registry struct {
+	mu         sync.Mutex
+	components []Component
+	timeout    time.Duration
+	cacheTTL   time.Duration
+	started    time.Time
+	cached     map[bool]*Report // Keyed by whether the report is a liveness report
+	now        func() time.Time
+}
+
+// NewRegistry creates an empty registry. Zero durations use the defaults; a negative
+// cacheTTL disables caching.
+func NewRegistry(timeout, cacheTTL time.Duration) *Registry {
+	if timeout <= 0 {
+		timeout = DefaultCheckTimeout
+	}
+	if cacheTTL == 0 {
+		cacheTTL = DefaultCacheTTL
+	}
+
+	return &Registry{
+		timeout:  timeout,
+		cacheTTL: cacheTTL,
+		started:  time.Now(),
+		cached:   make(map[bool]*Report),
+		now:      time.Now,
+	}
+}
+
+// Register adds a component, replacing any existing one with the same name
+func (r *Registry) Register(component Component) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, existing := range r.components {
+		if existing.Name == component.Name {
+			r.components[i] = component
+			r.cached = make(map[bool]*Report)
+			return
+		}
+	}
+	r.components = append(r.components, component)
+	r.cached = make(map[bool]*Report)
+}
+
+// Liveness runs only the liveness components. With none registered the node is live as
+// long as it can answer.
+func (r *Registry) Liveness(ctx context.Context) *Report {
+	return r.report(ctx, true)
+}
+
+// Readiness runs every component
+func (r *Registry) Readiness(ctx context.Context) *Report {
+	return r.report(ctx, false)
+}
+
+func (r *Registry) report(ctx context.Context, liveness bool) *Report {
+	r.mu.Lock()
+	if cached := r.cached[liveness]; cached != nil && r.now().Sub(cached.CheckedAt) < r.cacheTTL {
+		r.mu.Unlock()
+		return cached
+	}
+
+	var components []Component
+	for _, component := range r.components {
+		if !liveness || component.Liveness {
+			components = append(components, component)
+		}
+	}
+	r.mu.Unlock()
+
+	report := &Report{
+		Status:     StatusOK,
+		Components: make(map[string]ComponentReport, len(components)),
+	}
+
+	var wg sync.WaitGroup
+	var reportMu sync.Mutex
+	for _, component := range components {
+		wg.Add(1)
+		go func(component Component) {
+			defer wg.Done()
+
+			componentReport := r.run(ctx, component)
+
+			reportMu.Lock()
+			report.Components[component.Name] = componentReport
+			reportMu.Unlock()
+		}(component)
+	}
+	wg.Wait()
+
+	for _, component := range report.Components {
+		report.Status = worst(report.Status, overall(component))
+	}
+
+	report.CheckedAt = r.now()
+	report.UptimeSeconds = int64(report.CheckedAt.Sub(r.started).Seconds())
+
+	r.mu.Lock()
+	r.cached[liveness] = report
+	r.mu.Unlock()
+
+	return report
+}
+
+// run executes one check under the registry timeout. A check that does not return in
+// time is reported down; it keeps running in the background until it finishes.
+func (r *Registry) run(ctx context.Context, component Component) ComponentReport {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	started := r.now()
+	results := make(chan Result, 1)
+	go func() {
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				results <- Result{Status: StatusDown, Error: "check panicked"}
+			}
+		}()
+		results <- component.Check(ctx)
+	}()
+
+	var result Result
+	select {
+	case result = <-results:
+	case <-ctx.Done():
+		result = Result{Status: StatusDown, Error: "check timed out"}
+	}
+	if result.Status == "" {
+		result.Status = StatusOK
+	}
+
+	return ComponentReport{
+		Status:    result.Status,
+		Critical:  component.Critical,
+		LatencyMs: r.now().Sub(started).Milliseconds(),
+		Error:     result.Error,
+		Details:   result.Details,
+	}
+}
+
+// overall is a component's contribution to the node status: only critical components
+// can take the node down, anything else that fails only degrades it
+func overall(component ComponentReport) Status {
+	if component.Status == StatusDown && !component.Critical {
+		return StatusDegraded
+	}
+	return component.Status
+}
+
+func worst(a, b Status) Status {
+	rank := map[Status]int{StatusOK: 0, StatusDegraded: 1, StatusDown: 2}
+	if rank[b] > rank[a] {
+		return b
+	}
+	return a
+}
+
+var defaultRegistry = NewRegistry(DefaultCheckTimeout, DefaultCacheTTL)
+
+// Register adds a component to the default registry
+func Register(component Component) {
+	defaultRegistry.Register(component)
+}
+
+// Default returns the registry the web endpoints report on
+func Default() *Registry {
+	return defaultRegistry
+}
@@ -0,0 +1,94 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func staticCheck(result Result) Check {
+	return func(ctx context.Context) Result {
+		return result
+	}
+}
+
+func TestReadinessAggregatesComponents(t *testing.T) {
+	registry := NewRegistry(time.Second, -1)
+	registry.Register(Component{Name: "store", Critical: true, Liveness: true, Check: staticCheck(OK(nil))})
+	registry.Register(Component{Name: "push", Check: staticCheck(OK(nil))})
+
+	report := registry.Readiness(context.Background())
+	if report.Status != StatusOK || !report.Healthy() || len(report.Components) != 2 {
+		t.Fatalf("expected a healthy report with 2 components, got %+v", report)
+	}
+
+	// A failing non-critical component only degrades the node
+	registry.Register(Component{Name: "push", Check: staticCheck(Down(errors.New("queue stuck"), nil))})
+	report = registry.Readiness(context.Background())
+	if report.Status != StatusDegraded || !report.Healthy() {
+		t.Fatalf("expected a degraded but healthy report, got %+v", report)
+	}
+	if push := report.Components["push"]; push.Status != StatusDown || push.Error != "queue stuck" {
+		t.Errorf("expected the push component to be reported down, got %+v", push)
+	}
+
+	// A failing critical component takes the node out of rotation
+	registry.Register(Component{Name: "sidecar", Critical: true, Check: staticCheck(Down(errors.New("lost"), nil))})
+	report = registry.Readiness(context.Background())
+	if report.Status != StatusDown || report.Healthy() {
+		t.Fatalf("expected an unhealthy report, got %+v", report)
+	}
+
+	// Liveness ignores components a restart would not fix
+	live := registry.Liveness(context.Background())
+	if live.Status != StatusOK || len(live.Components) != 1 {
+		t.Errorf("expected liveness to run only the store check, got %+v", live)
+	}
+}
+
+func TestSlowChecksAreReportedDown(t *testing.T) {
+	registry := NewRegistry(20*time.Millisecond, -1)
+	registry.Register(Component{Name: "store", Critical: true, Check: func(ctx context.Context) Result {
+		time.Sleep(time.Second)
+		return OK(nil)
+	}})
+	registry.Register(Component{Name: "broken", Check: func(ctx context.Context) Result {
+		panic("boom")
+	}})
+
+	started := time.Now()
+	report := registry.Readiness(context.Background())
+	if elapsed := time.Since(started); elapsed > 500*time.Millisecond {
+		t.Errorf("expected the report to respect the check timeout, took %s", elapsed)
+	}
+	if store := report.Components["store"]; store.Status != StatusDown || store.Error != "check timed out" {
+		t.Errorf("expected a timed out check to be down, got %+v", store)
+	}
+	if broken := report.Components["broken"]; broken.Status != StatusDown {
+		t.Errorf("expected a panicking check to be down, got %+v", broken)
+	}
+}
+
+func TestReportsAreCached(t *testing.T) {
+	var calls atomic.Int32
+	registry := NewRegistry(time.Second, time.Minute)
+	registry.Register(Component{Name: "store", Check: func(ctx context.Context) Result {
+		calls.Add(1)
+		return OK(nil)
+	}})
+
+	registry.Readiness(context.Background())
+	registry.Readiness(context.Background())
+	if calls.Load() != 1 {
+		t.Fatalf("expected the second report to be served from cache, checks ran %d times", calls.Load())
+	}
+
+	now := time.Now()
+	registry.now = func() time.Time { return now.Add(2 * time.Minute) }
+	registry.Readiness(context.Background())
+	if calls.Load() != 2 {
+		t.Errorf("expected an expired report to be refreshed, checks ran %d times", calls.Load())
+	}
+}
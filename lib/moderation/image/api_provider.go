@@ -0,0 +1,263 @@
+package image
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/HORNET-Storage/hornet-storage/lib/logging"
+)
+
+// APIProvider sends media to the external moderation API as a multipart upload
+type APIProvider struct {
+	Endpoint  string       // URL of the moderation API's /moderate route
+	Threshold float64      // Confidence threshold for moderation
+	Mode      string       // Moderation mode (full, fast, etc.)
+	Client    *http.Client // HTTP client for API requests
+}
+
+// NewAPIProvider creates a provider for the moderation API at endpoint
+func NewAPIProvider(endpoint string, threshold float64, mode string, timeout time.Duration) *APIProvider {
+	// Make sure the endpoint is properly formatted
+	if !strings.HasPrefix(endpoint, "http") {
+		endpoint = "http://" + endpoint
+	}
+
+	// Default to port 8000 if not specified
+	if !strings.Contains(endpoint, ":") {
+		if strings.HasPrefix(endpoint, "https") {
+			endpoint = endpoint + ":443"
+		} else {
+			endpoint = endpoint + ":8000"
+		}
+	}
+
+	// Ensure endpoint points to /moderate
+	if !strings.HasSuffix(endpoint, "/moderate") {
+		endpoint = strings.TrimSuffix(endpoint, "/") + "/moderate"
+	}
+
+	return &APIProvider{
+		Endpoint:  endpoint,
+		Threshold: threshold,
+		Mode:      mode,
+		Client:    &http.Client{Timeout: timeout},
+	}
+}
+
+// Name identifies the provider in logs and configuration
+func (p *APIProvider) Name() string {
+	return "api"
+}
+
+// ModerateFile sends a local image file to the moderation API
+func (p *APIProvider) ModerateFile(filePath string) (*ModerationResponse, error) {
+	fileInfo, err := os.Stat(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat image file: %w", err)
+	}
+
+	imgType, err := validateImageFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("file validation failed: %w", err)
+	}
+
+	// Get the absolute path to the file for debugging
+	absPath, err := filepath.Abs(filePath)
+	if err != nil {
+		absPath = filePath // Fallback to relative path if abs fails
+	}
+
+	// Log file information for debugging
+	logging.Infof("Uploading image: %s (type: %s, size: %d bytes, path: %s)",
+		filepath.Base(filePath), imgType, fileInfo.Size(), absPath)
+
+	// Read the entire file into memory
+	fileData, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read image file: %w", err)
+	}
+
+	// Create a custom boundary for the form - use the same one that worked in testing
+	boundary := "----WebKitFormBoundary7MA4YWxkTrZu0gW"
+
+	// Create a buffer to store the request body
+	var requestBody bytes.Buffer
+
+	// Get the filename with proper extension
+	filename := filepath.Base(filePath)
+	if !strings.Contains(filename, ".") {
+		// Add extension based on detected type if missing
+		filename = filename + "." + imgType
+	}
+
+	// Manually construct the multipart form
+	// 1. Add the file part with explicit content type
+	requestBody.WriteString("--" + boundary + "\r\n")
+	requestBody.WriteString(fmt.Sprintf(`Content-Disposition: form-data; name="file"; filename="%s"`, filename) + "\r\n")
+	requestBody.WriteString(fmt.Sprintf("Content-Type: image/%s\r\n\r\n", imgType))
+	requestBody.Write(fileData)
+	requestBody.WriteString("\r\n")
+
+	// 2. Add moderation mode
+	requestBody.WriteString("--" + boundary + "\r\n")
+	requestBody.WriteString(`Content-Disposition: form-data; name="moderation_mode"` + "\r\n\r\n")
+	requestBody.WriteString(p.Mode + "\r\n")
+
+	// 3. Add threshold
+	requestBody.WriteString("--" + boundary + "\r\n")
+	requestBody.WriteString(`Content-Disposition: form-data; name="threshold"` + "\r\n\r\n")
+	requestBody.WriteString(fmt.Sprintf("%f", p.Threshold) + "\r\n")
+
+	// 4. End of form
+	requestBody.WriteString("--" + boundary + "--\r\n")
+
+	// Debug the request body size
+	logging.Infof("Request body size: %d bytes", requestBody.Len())
+
+	// Create and send the request
+	requestURL := fmt.Sprintf("%s?moderation_mode=%s&threshold=%f",
+		p.Endpoint, p.Mode, p.Threshold)
+
+	req, err := http.NewRequest("POST", requestURL, &requestBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create HTTP request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", fmt.Sprintf("multipart/form-data; boundary=%s", boundary))
+	req.Header.Set("Accept", "application/json")
+
+	// Send the request
+	resp, err := p.Client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("API request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// Check response status
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("API returned non-OK status %d: %s", resp.StatusCode, string(bodyBytes))
+	}
+
+	// Parse response
+	var result ModerationResponse
+	err = json.NewDecoder(resp.Body).Decode(&result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse API response: %w", err)
+	}
+
+	return &result, nil
+}
+
+// ModerateDisputeFile sends a local image file to the moderation API with dispute-specific parameters
+func (p *APIProvider) ModerateDisputeFile(filePath string, disputeReason string) (*ModerationResponse, error) {
+	fileInfo, err := os.Stat(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat image file: %w", err)
+	}
+
+	imgType, err := validateImageFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("file validation failed: %w", err)
+	}
+
+	// Get the absolute path to the file for debugging
+	absPath, err := filepath.Abs(filePath)
+	if err != nil {
+		absPath = filePath // Fallback to relative path if abs fails
+	}
+
+	// Log file information for debugging
+	logging.Infof("Uploading image for dispute moderation: %s (type: %s, size: %d bytes, path: %s)",
+		filepath.Base(filePath), imgType, fileInfo.Size(), absPath)
+
+	// Read the entire file into memory
+	fileData, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read image file: %w", err)
+	}
+
+	// Create a custom boundary for the form
+	boundary := "----WebKitFormBoundary7MA4YWxkTrZu0gW"
+
+	// Create a buffer to store the request body
+	var requestBody bytes.Buffer
+
+	// Get the filename with proper extension
+	filename := filepath.Base(filePath)
+	if !strings.Contains(filename, ".") {
+		// Add extension based on detected type if missing
+		filename = filename + "." + imgType
+	}
+
+	// Manually construct the multipart form
+	// 1. Add the file part with explicit content type
+	requestBody.WriteString("--" + boundary + "\r\n")
+	requestBody.WriteString(fmt.Sprintf(`Content-Disposition: form-data; name="file"; filename="%s"`, filename) + "\r\n")
+	requestBody.WriteString(fmt.Sprintf("Content-Type: image/%s\r\n\r\n", imgType))
+	requestBody.Write(fileData)
+	requestBody.WriteString("\r\n")
+
+	// 2. Add moderation mode - always use "full" for disputes
+	requestBody.WriteString("--" + boundary + "\r\n")
+	requestBody.WriteString(`Content-Disposition: form-data; name="moderation_mode"` + "\r\n\r\n")
+	requestBody.WriteString("full\r\n")
+
+	// 3. Add threshold - use a lower threshold for disputes (0.35 instead of 0.4)
+	requestBody.WriteString("--" + boundary + "\r\n")
+	requestBody.WriteString(`Content-Disposition: form-data; name="threshold"` + "\r\n\r\n")
+	requestBody.WriteString(fmt.Sprintf("%f", 0.35) + "\r\n")
+
+	// 4. Add dispute reason if provided
+	if disputeReason != "" {
+		requestBody.WriteString("--" + boundary + "\r\n")
+		requestBody.WriteString(`Content-Disposition: form-data; name="dispute_reason"` + "\r\n\r\n")
+		requestBody.WriteString(disputeReason + "\r\n")
+	}
+
+	// 5. End of form
+	requestBody.WriteString("--" + boundary + "--\r\n")
+
+	// Debug the request body size
+	logging.Infof("Dispute moderation request body size: %d bytes", requestBody.Len())
+
+	// Create and send the request
+	requestURL := fmt.Sprintf("%s_dispute?moderation_mode=%s&threshold=%f", p.Endpoint, p.Mode, p.Threshold)
+
+	req, err := http.NewRequest("POST", requestURL, &requestBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create HTTP request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", fmt.Sprintf("multipart/form-data; boundary=%s", boundary))
+	req.Header.Set("Accept", "application/json")
+
+	// Send the request
+	resp, err := p.Client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("API request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// Check response status
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("API returned non-OK status %d: %s", resp.StatusCode, string(bodyBytes))
+	}
+
+	// Parse response
+	var result ModerationResponse
+	err = json.NewDecoder(resp.Body).Decode(&result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse API response: %w", err)
+	}
+
+	return &result, nil
+}
@@ -0,0 +1,117 @@
+package image
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/HORNET-Storage/hornet-storage/lib/logging"
+	"github.com/HORNET-Storage/hornet-storage/lib/types"
+)
+
+// DefaultHashThreshold is the Hamming distance, out of 64 bits, under which both hashes
+// must fall for a file to match a blocklist entry
+const DefaultHashThreshold = 10
+
+// HashBlocklist is the admin-managed list of banned image hashes
+type HashBlocklist interface {
+	GetMediaHashBlocks() ([]types.MediaHashBlock, error)
+}
+
+// PerceptualHashProvider blocks images that look like an entry in the hash blocklist.
+// It runs entirely in-process, so it keeps working without the moderation API.
+type PerceptualHashProvider struct {
+	Blocklist HashBlocklist
+	Threshold int
+}
+
+// NewPerceptualHashProvider creates a provider matching against blocklist within threshold bits
+func NewPerceptualHashProvider(blocklist HashBlocklist, threshold int) *PerceptualHashProvider {
+	if threshold <= 0 {
+		threshold = DefaultHashThreshold
+	}
+	return &PerceptualHashProvider{Blocklist: blocklist, Threshold: threshold}
+}
+
+// Name identifies the provider in logs and configuration
+func (p *PerceptualHashProvider) Name() string {
+	return "phash"
+}
+
+// ModerateFile hashes an image and blocks it if it is close to a blocklist entry
+func (p *PerceptualHashProvider) ModerateFile(filePath string) (*ModerationResponse, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open image file: %w", err)
+	}
+	defer file.Close()
+
+	hashes, err := ComputeImageHashes(file)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnsupportedMedia, err)
+	}
+
+	block, distance, err := p.Match(hashes)
+	if err != nil {
+		return nil, err
+	}
+
+	if block == nil {
+		return &ModerationResponse{
+			Decision:       string(DecisionAllow),
+			ContentLevel:   int(Level0_Appropriate),
+			Category:       "hash_blocklist",
+			Explanation:    "No match in the image hash blocklist",
+			ModerationMode: p.Name(),
+		}, nil
+	}
+
+	logging.Infof("Image %s matches blocklist entry %d at distance %d", filePath, block.ID, distance)
+
+	explanation := fmt.Sprintf("Matches blocked image #%d", block.ID)
+	if block.Reason != "" {
+		explanation = fmt.Sprintf("%s: %s", explanation, block.Reason)
+	}
+
+	return &ModerationResponse{
+		Decision:        string(DecisionBlock),
+		ContentLevel:    block.ContentLevel,
+		IsExplicit:      block.ContentLevel >= int(Level5_Explicit),
+		Confidence:      1 - float64(distance)/64,
+		Category:        "hash_blocklist",
+		Explanation:     explanation,
+		DetectedClasses: []string{"hash_blocklist"},
+		ModerationMode:  p.Name(),
+	}, nil
+}
+
+// Match returns the closest blocklist entry within the threshold and its pHash distance,
+// or nil when nothing matches
+func (p *PerceptualHashProvider) Match(hashes ImageHashes) (*types.MediaHashBlock, int, error) {
+	blocks, err := p.Blocklist.GetMediaHashBlocks()
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to load image hash blocklist: %w", err)
+	}
+
+	var closest *types.MediaHashBlock
+	closestDistance := 0
+	for i := range blocks {
+		phash, err := ParseHash(blocks[i].PHash)
+		if err != nil {
+			continue
+		}
+		dhash, err := ParseHash(blocks[i].DHash)
+		if err != nil {
+			continue
+		}
+
+		phashDistance := HammingDistance(hashes.PHash, phash)
+		if phashDistance > p.Threshold || HammingDistance(hashes.DHash, dhash) > p.Threshold {
+			continue
+		}
+		if closest == nil || phashDistance < closestDistance {
+			closest = &blocks[i]
+			closestDistance = phashDistance
+		}
+	}
+	return closest, closestDistance, nil
+}
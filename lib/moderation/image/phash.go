@@ -0,0 +1,174 @@
+package image
+
+import (
+	"fmt"
+	"image"
+	"io"
+	"math"
+	"math/bits"
+	"sort"
+	"strconv"
+
+	// Register the decoders the hash provider understands
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+)
+
+const (
+	// Decompression bomb guard for hashing
+	maxHashPixels = 50 * 1000 * 1000
+
+	phashSize  = 32 // DCT input is phashSize x phashSize
+	phashLowHz = 8  // Low frequency block kept from the DCT
+)
+
+// ImageHashes are the perceptual hashes of an image. Re-encoding, resizing or light
+// edits change only a few bits, so copies are found by Hamming distance.
+type ImageHashes struct {
+	PHash uint64 // DCT hash, robust to scaling and compression
+	DHash uint64 // Gradient hash, robust to brightness and contrast changes
+}
+
+// ComputeImageHashes decodes a JPEG, PNG or GIF image and computes its hashes
+func ComputeImageHashes(r io.ReadSeeker) (ImageHashes, error) {
+	config, _, err := image.DecodeConfig(r)
+	if err != nil {
+		return ImageHashes{}, fmt.Errorf("failed to decode image: %w", err)
+	}
+	if config.Width*config.Height > maxHashPixels {
+		return ImageHashes{}, fmt.Errorf("image is too large to hash: %dx%d", config.Width, config.Height)
+	}
+
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return ImageHashes{}, err
+	}
+	img, _, err := image.Decode(r)
+	if err != nil {
+		return ImageHashes{}, fmt.Errorf("failed to decode image: %w", err)
+	}
+
+	return HashImage(img), nil
+}
+
+// HashImage computes the perceptual hashes of a decoded image
+func HashImage(img image.Image) ImageHashes {
+	return ImageHashes{
+		PHash: phash(luminance(img, phashSize, phashSize)),
+		DHash: dhash(luminance(img, 9, 8)),
+	}
+}
+
+// HammingDistance counts the bits that differ between two hashes
+func HammingDistance(a, b uint64) int {
+	return bits.OnesCount64(a ^ b)
+}
+
+// FormatHash encodes a hash as 16 hex digits
+func FormatHash(hash uint64) string {
+	return fmt.Sprintf("%016x", hash)
+}
+
+// ParseHash decodes a hash written by FormatHash
+func ParseHash(value string) (uint64, error) {
+	if len(value) != 16 {
+		return 0, fmt.Errorf("hash must be 16 hex digits")
+	}
+	return strconv.ParseUint(value, 16, 64)
+}
+
+// luminance shrinks the image to width x height by averaging the grey level of every
+// source pixel that falls in each cell
+func luminance(img image.Image, width, height int) [][]float64 {
+	bounds := img.Bounds()
+	sums := make([][]float64, height)
+	counts := make([][]float64, height)
+	for y := range sums {
+		sums[y] = make([]float64, width)
+		counts[y] = make([]float64, width)
+	}
+
+	sourceWidth, sourceHeight := bounds.Dx(), bounds.Dy()
+	for y := 0; y < sourceHeight; y++ {
+		cellY := y * height / sourceHeight
+		for x := 0; x < sourceWidth; x++ {
+			cellX := x * width / sourceWidth
+			r, g, b, _ := img.At(bounds.Min.X+x, bounds.Min.Y+y).RGBA()
+			sums[cellY][cellX] += 0.299*float64(r) + 0.587*float64(g) + 0.114*float64(b)
+			counts[cellY][cellX]++
+		}
+	}
+
+	for y := range sums {
+		for x := range sums[y] {
+			if counts[y][x] > 0 {
+				sums[y][x] /= counts[y][x]
+			}
+		}
+	}
+	return sums
+}
+
+// phash sets a bit for each low frequency DCT coefficient above their median
+func phash(pixels [][]float64) uint64 {
+	n := len(pixels)
+
+	// cosines[u][x] = cos((2x+1)uπ / 2n)
+	cosines := make([][]float64, phashLowHz)
+	for u := range cosines {
+		cosines[u] = make([]float64, n)
+		for x := 0; x < n; x++ {
+			cosines[u][x] = math.Cos(float64(2*x+1) * float64(u) * math.Pi / float64(2*n))
+		}
+	}
+
+	// Separable 2D DCT-II, only for the low frequencies that make up the hash
+	rows := make([][]float64, n)
+	for y := 0; y < n; y++ {
+		rows[y] = make([]float64, phashLowHz)
+		for u := 0; u < phashLowHz; u++ {
+			for x := 0; x < n; x++ {
+				rows[y][u] += pixels[y][x] * cosines[u][x]
+			}
+		}
+	}
+
+	coefficients := make([]float64, 0, phashLowHz*phashLowHz)
+	for v := 0; v < phashLowHz; v++ {
+		for u := 0; u < phashLowHz; u++ {
+			var sum float64
+			for y := 0; y < n; y++ {
+				sum += rows[y][u] * cosines[v][y]
+			}
+			coefficients = append(coefficients, sum)
+		}
+	}
+
+	// The DC term is the average brightness and would dominate the median
+	sorted := append([]float64{}, coefficients[1:]...)
+	sort.Float64s(sorted)
+	median := sorted[len(sorted)/2]
+
+	var hash uint64
+	for i, coefficient := range coefficients {
+		if coefficient > median {
+			hash |= 1 << uint(i)
+		}
+	}
+	return hash
+}
+
+// dhash sets a bit wherever a pixel is brighter than its right neighbour
+func dhash(pixels [][]float64) uint64 {
+	var hash uint64
+	bit := 0
+	for y := range pixels {
+		for x := 0; x < len(pixels[y])-1; x++ {
+			if pixels[y][x] > pixels[y][x+1] {
+				hash |= 1 << uint(bit)
+			}
+			bit++
+		}
+	}
+	return hash
+}
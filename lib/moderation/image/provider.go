@@ -0,0 +1,126 @@
+package image
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/HORNET-Storage/hornet-storage/lib/logging"
+)
+
+// ErrUnsupportedMedia means a provider can't judge a file, e.g. a video given to the hash provider
+var ErrUnsupportedMedia = errors.New("media type not supported by moderation provider")
+
+// ModerationProvider classifies a local media file with the ContentLevel and decision
+// semantics of the moderation API
+type ModerationProvider interface {
+	Name() string
+	ModerateFile(filePath string) (*ModerationResponse, error)
+}
+
+// DisputeProvider is implemented by providers with a separate, more careful evaluation
+// for disputed content
+type DisputeProvider interface {
+	ModerateDisputeFile(filePath string, disputeReason string) (*ModerationResponse, error)
+}
+
+// CombineStrategy decides how a chain merges the responses of its providers
+type CombineStrategy string
+
+const (
+	// CombineFirstBlock stops at the first provider that blocks, so cheap local
+	// providers placed first can spare a call to the API
+	CombineFirstBlock CombineStrategy = "first_block"
+	// CombineMaxLevel asks every provider and keeps the most severe response
+	CombineMaxLevel CombineStrategy = "max_level"
+)
+
+// ChainProvider runs several providers in order. A provider that fails is skipped, so
+// moderation keeps working with the local providers when the API is unreachable.
+type ChainProvider struct {
+	Providers []ModerationProvider
+	Strategy  CombineStrategy
+}
+
+// NewChainProvider chains providers with the given strategy
+func NewChainProvider(strategy CombineStrategy, providers ...ModerationProvider) (*ChainProvider, error) {
+	if strategy != CombineFirstBlock && strategy != CombineMaxLevel {
+		return nil, fmt.Errorf("unknown moderation combine strategy %q", strategy)
+	}
+	if len(providers) == 0 {
+		return nil, fmt.Errorf("a moderation chain needs at least one provider")
+	}
+
+	return &ChainProvider{Providers: providers, Strategy: strategy}, nil
+}
+
+// Name lists the chained providers
+func (c *ChainProvider) Name() string {
+	names := make([]string, len(c.Providers))
+	for i, provider := range c.Providers {
+		names[i] = provider.Name()
+	}
+	return "chain(" + strings.Join(names, ",") + ")"
+}
+
+// ModerateFile asks the chained providers about a file
+func (c *ChainProvider) ModerateFile(filePath string) (*ModerationResponse, error) {
+	return c.run(func(provider ModerationProvider) (*ModerationResponse, error) {
+		return provider.ModerateFile(filePath)
+	})
+}
+
+// ModerateDisputeFile re-evaluates a file, using each provider's dispute mode where it has one
+func (c *ChainProvider) ModerateDisputeFile(filePath string, disputeReason string) (*ModerationResponse, error) {
+	return c.run(func(provider ModerationProvider) (*ModerationResponse, error) {
+		if disputer, ok := provider.(DisputeProvider); ok {
+			return disputer.ModerateDisputeFile(filePath, disputeReason)
+		}
+		return provider.ModerateFile(filePath)
+	})
+}
+
+func (c *ChainProvider) run(moderate func(ModerationProvider) (*ModerationResponse, error)) (*ModerationResponse, error) {
+	var result *ModerationResponse
+	var errs []error
+
+	for _, provider := range c.Providers {
+		response, err := moderate(provider)
+		if err != nil {
+			logging.Infof("Moderation provider %s skipped: %v", provider.Name(), err)
+			errs = append(errs, fmt.Errorf("%s: %w", provider.Name(), err))
+			continue
+		}
+
+		if c.Strategy == CombineFirstBlock && response.ShouldBlock() {
+			return response, nil
+		}
+		if result == nil || moreSevere(response, result) {
+			result = response
+		}
+	}
+
+	if result == nil {
+		return nil, errors.Join(errs...)
+	}
+	return result, nil
+}
+
+// moreSevere orders responses by decision, then by content level
+func moreSevere(a, b *ModerationResponse) bool {
+	if rankA, rankB := decisionRank(a.GetDecision()), decisionRank(b.GetDecision()); rankA != rankB {
+		return rankA > rankB
+	}
+	return a.ContentLevel > b.ContentLevel
+}
+
+func decisionRank(decision ModerationType) int {
+	switch decision {
+	case DecisionBlock:
+		return 2
+	case DecisionFlag:
+		return 1
+	default:
+		return 0
+	}
+}
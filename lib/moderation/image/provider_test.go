@@ -0,0 +1,191 @@
+package image
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/HORNET-Storage/hornet-storage/lib/types"
+)
+
+// testImage draws a scene with enough structure for the hashes to be meaningful
+func testImage(width, height int, seed int) image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			fx, fy := x*64/width, y*64/height
+			var c color.RGBA
+			switch seed {
+			case 0:
+				// Diagonal bands with a bright square
+				v := uint8((fx + fy) * 2)
+				if fx > 20 && fx < 40 && fy > 10 && fy < 30 {
+					v = 250
+				}
+				c = color.RGBA{v, v / 2, 255 - v, 255}
+			default:
+				// Checkerboard with a dark circle
+				v := uint8(40)
+				if (fx/8+fy/8)%2 == 0 {
+					v = 220
+				}
+				if (fx-32)*(fx-32)+(fy-40)*(fy-40) < 100 {
+					v = 0
+				}
+				c = color.RGBA{v, v, v, 255}
+			}
+			img.Set(x, y, c)
+		}
+	}
+	return img
+}
+
+func writeImage(t *testing.T, img image.Image, name string) string {
+	t.Helper()
+	var buf bytes.Buffer
+	var err error
+	if filepath.Ext(name) == ".png" {
+		err = png.Encode(&buf, img)
+	} else {
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: 40})
+	}
+	if err != nil {
+		t.Fatalf("failed to encode %s: %v", name, err)
+	}
+
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatalf("failed to write %s: %v", name, err)
+	}
+	return path
+}
+
+type staticBlocklist []types.MediaHashBlock
+
+func (b staticBlocklist) GetMediaHashBlocks() ([]types.MediaHashBlock, error) {
+	return b, nil
+}
+
+func blocklistFor(img image.Image) staticBlocklist {
+	hashes := HashImage(img)
+	return staticBlocklist{{
+		ID:           1,
+		PHash:        FormatHash(hashes.PHash),
+		DHash:        FormatHash(hashes.DHash),
+		Reason:       "banned",
+		ContentLevel: int(Level5_Explicit),
+	}}
+}
+
+func TestPerceptualHashProviderMatchesReencodes(t *testing.T) {
+	banned := testImage(512, 384, 0)
+	provider := NewPerceptualHashProvider(blocklistFor(banned), 0)
+
+	// A low quality, downscaled JPEG copy of the banned PNG
+	copyPath := writeImage(t, testImage(200, 150, 0), "copy.jpg")
+	response, err := provider.ModerateFile(copyPath)
+	if err != nil {
+		t.Fatalf("moderation failed: %v", err)
+	}
+	if !response.ShouldBlock() || response.ContentLevel != int(Level5_Explicit) {
+		t.Errorf("expected the re-encoded copy to be blocked, got %+v", response)
+	}
+
+	otherPath := writeImage(t, testImage(512, 384, 1), "other.png")
+	response, err = provider.ModerateFile(otherPath)
+	if err != nil {
+		t.Fatalf("moderation failed: %v", err)
+	}
+	if response.ShouldBlock() {
+		t.Errorf("expected an unrelated image to be allowed, got %+v", response)
+	}
+}
+
+func TestPerceptualHashProviderRejectsUnsupportedMedia(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "clip.mp4")
+	if err := os.WriteFile(path, []byte("\x00\x00\x00\x18ftypmp42"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := NewPerceptualHashProvider(staticBlocklist{}, 0).ModerateFile(path)
+	if !errors.Is(err, ErrUnsupportedMedia) {
+		t.Errorf("expected ErrUnsupportedMedia, got %v", err)
+	}
+}
+
+type fixedProvider struct {
+	name     string
+	response *ModerationResponse
+	err      error
+	calls    int
+}
+
+func (p *fixedProvider) Name() string { return p.name }
+
+func (p *fixedProvider) ModerateFile(string) (*ModerationResponse, error) {
+	p.calls++
+	return p.response, p.err
+}
+
+func TestChainProviderStrategies(t *testing.T) {
+	flag := &fixedProvider{name: "flag", response: &ModerationResponse{Decision: string(DecisionFlag), ContentLevel: 3}}
+	block := &fixedProvider{name: "block", response: &ModerationResponse{Decision: string(DecisionBlock), ContentLevel: 4}}
+	allow := &fixedProvider{name: "allow", response: &ModerationResponse{Decision: string(DecisionAllow)}}
+
+	chain, err := NewChainProvider(CombineFirstBlock, block, allow)
+	if err != nil {
+		t.Fatal(err)
+	}
+	response, err := chain.ModerateFile("unused")
+	if err != nil || response != block.response || allow.calls != 0 {
+		t.Errorf("expected first_block to stop at the blocking provider")
+	}
+
+	chain, _ = NewChainProvider(CombineMaxLevel, allow, flag, block)
+	response, err = chain.ModerateFile("unused")
+	if err != nil || response != block.response || allow.calls != 1 || flag.calls != 1 {
+		t.Errorf("expected max_level to ask every provider and keep the block")
+	}
+
+	chain, _ = NewChainProvider(CombineFirstBlock, allow, flag)
+	if response, _ := chain.ModerateFile("unused"); response != flag.response {
+		t.Errorf("expected the most severe response when nothing blocks, got %+v", response)
+	}
+
+	if _, err := NewChainProvider("loudest", allow); err == nil {
+		t.Errorf("expected an unknown strategy to be rejected")
+	}
+}
+
+func TestChainProviderWorksOffline(t *testing.T) {
+	banned := testImage(320, 240, 0)
+	path := writeImage(t, banned, "banned.png")
+
+	// The moderation API is unreachable
+	api := NewAPIProvider("http://127.0.0.1:1/moderate", 0.4, "full", time.Second)
+	chain, err := NewChainProvider(CombineMaxLevel, api, NewPerceptualHashProvider(blocklistFor(banned), 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	response, err := chain.ModerateFile(path)
+	if err != nil {
+		t.Fatalf("expected the hash provider to answer without the API: %v", err)
+	}
+	if !response.ShouldBlock() {
+		t.Errorf("expected the banned image to be blocked offline, got %+v", response)
+	}
+
+	failing := &fixedProvider{name: "down", err: errors.New("connection refused")}
+	chain, _ = NewChainProvider(CombineFirstBlock, failing, failing)
+	if _, err := chain.ModerateFile(path); err == nil {
+		t.Errorf("expected an error when every provider fails")
+	}
+}
@@ -0,0 +1,181 @@
+package image
+
+import (
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/HORNET-Storage/hdk-nostr-go/lib/signing"
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/spf13/viper"
+
+	"github.com/HORNET-Storage/hornet-storage/lib"
+	"github.com/HORNET-Storage/hornet-storage/lib/logging"
+	stores "github.com/HORNET-Storage/hornet-storage/lib/stores"
+	"github.com/HORNET-Storage/hornet-storage/lib/types"
+)
+
+// ContentWarningNamespace is the NIP-32 label namespace for NIP-36 content warnings
+const ContentWarningNamespace = "content-warning"
+
+// Label used when the moderation result doesn't name a category
+const defaultContentWarning = "sensitive"
+
+// QueueForReview records a FLAG result for an event and labels the event with a content
+// warning until a reviewer decides on it
+func QueueForReview(store stores.Store, eventID, pubkey, mediaURL, contentType string, response *ModerationResponse) error {
+	statsStore := store.GetStatsStore()
+	if statsStore == nil {
+		return fmt.Errorf("stats store not available")
+	}
+
+	existing, err := statsStore.GetModerationReviewByEvent(eventID)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		return nil
+	}
+
+	review := &types.ModerationReview{
+		EventID:      eventID,
+		PubKey:       pubkey,
+		MediaURL:     mediaURL,
+		ContentType:  contentType,
+		ContentLevel: response.ContentLevel,
+		Confidence:   response.Confidence,
+		Category:     response.Category,
+		Explanation:  response.Explanation,
+		Status:       types.ReviewStatusPending,
+		Label:        contentWarningFor(response),
+	}
+
+	if label, err := PublishContentWarning(store, eventID, pubkey, review.Label); err != nil {
+		logging.Infof("Error publishing content warning for event %s: %v", eventID, err)
+	} else {
+		review.LabelEventID = label.ID
+	}
+
+	return statsStore.CreateModerationReview(review)
+}
+
+// ApproveReview clears the content warning and serves the event as is
+func ApproveReview(store stores.Store, review *types.ModerationReview) error {
+	retractContentWarning(store, review)
+	return finishReview(store, review, types.ReviewStatusApproved)
+}
+
+// LabelReview keeps serving the event with a content warning chosen by the reviewer
+func LabelReview(store stores.Store, review *types.ModerationReview, label string) error {
+	if label == "" {
+		label = review.Label
+	}
+	if label == "" {
+		label = defaultContentWarning
+	}
+
+	event, err := PublishContentWarning(store, review.EventID, review.PubKey, label)
+	if err != nil {
+		return err
+	}
+
+	retractContentWarning(store, review)
+	review.Label = label
+	review.LabelEventID = event.ID
+	return finishReview(store, review, types.ReviewStatusLabeled)
+}
+
+// EscalateReview blocks the event like a BLOCK decision would, starting the usual
+// retention period and notifying the author
+func EscalateReview(store stores.Store, review *types.ModerationReview, reason string) error {
+	if reason == "" {
+		reason = review.Explanation
+	}
+
+	if err := store.MarkEventBlockedWithDetails(review.EventID, time.Now().Unix(), reason, review.ContentLevel, review.MediaURL); err != nil {
+		return fmt.Errorf("failed to block event: %w", err)
+	}
+
+	notification := &lib.ModerationNotification{
+		PubKey:      review.PubKey,
+		EventID:     review.EventID,
+		Reason:      reason,
+		CreatedAt:   time.Now(),
+		ContentType: review.ContentType,
+		MediaURL:    review.MediaURL,
+	}
+	if err := store.GetStatsStore().CreateModerationNotification(notification); err != nil {
+		logging.Infof("Error creating moderation notification for event %s: %v", review.EventID, err)
+	}
+
+	retractContentWarning(store, review)
+	return finishReview(store, review, types.ReviewStatusBlocked)
+}
+
+// PublishContentWarning stores a relay-signed kind 1985 label marking an event as
+// sensitive, so clients that honour NIP-36 can blur it
+func PublishContentWarning(store stores.Store, eventID, pubkey, reason string) (*nostr.Event, error) {
+	serializedKey := viper.GetString("relay.private_key")
+	if serializedKey == "" {
+		return nil, fmt.Errorf("relay private key not configured")
+	}
+	privateKey, _, err := signing.DeserializePrivateKey(serializedKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load relay key: %w", err)
+	}
+
+	label := newContentWarningLabel(eventID, pubkey, reason)
+	if err := label.Sign(hex.EncodeToString(privateKey.Serialize())); err != nil {
+		return nil, fmt.Errorf("failed to sign content warning: %w", err)
+	}
+
+	if err := store.StoreEvent(label); err != nil {
+		return nil, fmt.Errorf("failed to store content warning: %w", err)
+	}
+	return label, nil
+}
+
+func newContentWarningLabel(eventID, pubkey, reason string) *nostr.Event {
+	if reason == "" {
+		reason = defaultContentWarning
+	}
+
+	return &nostr.Event{
+		CreatedAt: nostr.Timestamp(time.Now().Unix()),
+		Kind:      1985,
+		Tags: nostr.Tags{
+			{"L", ContentWarningNamespace},
+			{"l", reason, ContentWarningNamespace},
+			{"e", eventID},
+			{"p", pubkey},
+		},
+		Content: reason,
+	}
+}
+
+// contentWarningFor picks the content-warning reason for a flagged result
+func contentWarningFor(response *ModerationResponse) string {
+	if response.Category != "" {
+		return response.Category
+	}
+	return defaultContentWarning
+}
+
+func retractContentWarning(store stores.Store, review *types.ModerationReview) {
+	if review.LabelEventID == "" {
+		return
+	}
+
+	if err := store.DeleteEvent(review.LabelEventID); err != nil {
+		logging.Infof("Error deleting content warning %s: %v", review.LabelEventID, err)
+		return
+	}
+	review.LabelEventID = ""
+}
+
+func finishReview(store stores.Store, review *types.ModerationReview, status string) error {
+	now := time.Now()
+	review.Status = status
+	review.ReviewedAt = &now
+	return store.GetStatsStore().UpdateModerationReview(review)
+}
@@ -0,0 +1,190 @@
+package image
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/spf13/viper"
+
+	"github.com/HORNET-Storage/hornet-storage/lib/stores"
+	"github.com/HORNET-Storage/hornet-storage/lib/stores/statistics"
+	"github.com/HORNET-Storage/hornet-storage/lib/stores/statistics/gorm/sqlite"
+	"github.com/HORNET-Storage/hornet-storage/lib/types"
+)
+
+func TestContentWarningLabel(t *testing.T) {
+	label := newContentWarningLabel("event-id", "author", contentWarningFor(&ModerationResponse{Decision: "FLAG", Category: "suggestive"}))
+
+	if label.Kind != 1985 {
+		t.Fatalf("expected a kind 1985 label, got %d", label.Kind)
+	}
+
+	expected := map[string][]string{
+		"L": {"L", ContentWarningNamespace},
+		"l": {"l", "suggestive", ContentWarningNamespace},
+		"e": {"e", "event-id"},
+		"p": {"p", "author"},
+	}
+	for key, want := range expected {
+		tag := label.Tags.GetFirst([]string{key})
+		if tag == nil || len(*tag) != len(want) {
+			t.Errorf("expected tag %v, got %v", want, tag)
+			continue
+		}
+		for i := range want {
+			if (*tag)[i] != want[i] {
+				t.Errorf("expected tag %v, got %v", want, *tag)
+				break
+			}
+		}
+	}
+
+	if reason := contentWarningFor(&ModerationResponse{Decision: "FLAG"}); reason != defaultContentWarning {
+		t.Errorf("expected the default warning without a category, got %q", reason)
+	}
+}
+
+// reviewStore keeps events and blocks in memory on top of a real statistics store
+type reviewStore struct {
+	stores.Store
+	stats   statistics.StatisticsStore
+	events  map[string]*nostr.Event
+	blocked map[string]string
+}
+
+func newReviewStore(t *testing.T) *reviewStore {
+	t.Helper()
+
+	viper.Reset()
+	viper.Set("relay.private_key", relaySecret)
+	t.Cleanup(viper.Reset)
+
+	stats, err := sqlite.InitStore(filepath.Join(t.TempDir(), "stats.db"))
+	if err != nil {
+		t.Fatalf("InitStore: %v", err)
+	}
+	return &reviewStore{stats: stats, events: map[string]*nostr.Event{}, blocked: map[string]string{}}
+}
+
+func (s *reviewStore) GetStatsStore() statistics.StatisticsStore { return s.stats }
+
+func (s *reviewStore) StoreEvent(event *nostr.Event) error {
+	s.events[event.ID] = event
+	return nil
+}
+
+func (s *reviewStore) DeleteEvent(eventID string) error {
+	delete(s.events, eventID)
+	return nil
+}
+
+func (s *reviewStore) MarkEventBlockedWithDetails(eventID string, timestamp int64, reason string, contentLevel int, mediaURL string) error {
+	s.blocked[eventID] = reason
+	return nil
+}
+
+// labels returns the stored content warnings
+func (s *reviewStore) labels() []*nostr.Event {
+	var labels []*nostr.Event
+	for _, event := range s.events {
+		if event.Kind == 1985 {
+			labels = append(labels, event)
+		}
+	}
+	return labels
+}
+
+// relaySecret signs content warnings in tests
+var relaySecret = nostr.GeneratePrivateKey()
+
+func queueTestReview(t *testing.T, store *reviewStore, eventID string) *types.ModerationReview {
+	t.Helper()
+
+	response := &ModerationResponse{Decision: "FLAG", ContentLevel: 3, Category: "suggestive", Explanation: "flagged by the model"}
+	if err := QueueForReview(store, eventID, "author", "https://example.com/a.jpg", "image", response); err != nil {
+		t.Fatalf("QueueForReview: %v", err)
+	}
+
+	review, err := store.stats.GetModerationReviewByEvent(eventID)
+	if err != nil || review == nil {
+		t.Fatalf("expected a review for %s, got %v (%v)", eventID, review, err)
+	}
+	return review
+}
+
+func TestQueueForReviewPublishesSignedContentWarning(t *testing.T) {
+	store := newReviewStore(t)
+
+	review := queueTestReview(t, store, "event-1")
+	if review.Status != types.ReviewStatusPending || review.Label != "suggestive" {
+		t.Fatalf("expected a pending suggestive review, got %+v", review)
+	}
+
+	labels := store.labels()
+	if len(labels) != 1 || labels[0].ID != review.LabelEventID {
+		t.Fatalf("expected the review to reference its content warning, got %d labels", len(labels))
+	}
+	relayPubkey, _ := nostr.GetPublicKey(relaySecret)
+	if ok, err := labels[0].CheckSignature(); !ok || err != nil || labels[0].PubKey != relayPubkey {
+		t.Errorf("expected the content warning to be signed by the relay key")
+	}
+
+	// Flagging the same event again keeps the existing review
+	queueTestReview(t, store, "event-1")
+	if len(store.labels()) != 1 {
+		t.Errorf("expected a single content warning per event, got %d", len(store.labels()))
+	}
+}
+
+func TestApproveReviewRetractsContentWarning(t *testing.T) {
+	store := newReviewStore(t)
+	review := queueTestReview(t, store, "event-1")
+
+	if err := ApproveReview(store, review); err != nil {
+		t.Fatalf("ApproveReview: %v", err)
+	}
+
+	if len(store.labels()) != 0 {
+		t.Errorf("expected the content warning to be deleted")
+	}
+	saved, _ := store.stats.GetModerationReview(review.ID)
+	if saved.Status != types.ReviewStatusApproved || saved.LabelEventID != "" || saved.ReviewedAt == nil {
+		t.Errorf("expected an approved review without a label, got %+v", saved)
+	}
+}
+
+func TestEscalateReviewBlocksAndNotifies(t *testing.T) {
+	store := newReviewStore(t)
+	review := queueTestReview(t, store, "event-1")
+
+	if err := EscalateReview(store, review, ""); err != nil {
+		t.Fatalf("EscalateReview: %v", err)
+	}
+
+	if reason := store.blocked["event-1"]; reason != "flagged by the model" {
+		t.Errorf("expected the event to be blocked with the model's explanation, got %q", reason)
+	}
+	if len(store.labels()) != 0 {
+		t.Errorf("expected the content warning to be deleted")
+	}
+	notifications, _, err := store.stats.GetUserModerationNotifications("author", 1, 10)
+	if err != nil || len(notifications) != 1 || notifications[0].EventID != "event-1" {
+		t.Errorf("expected the author to be notified, got %+v (%v)", notifications, err)
+	}
+	saved, _ := store.stats.GetModerationReview(review.ID)
+	if saved.Status != types.ReviewStatusBlocked {
+		t.Errorf("expected a blocked review, got %s", saved.Status)
+	}
+}
+
+func TestQueueForReviewWithoutRelayKey(t *testing.T) {
+	store := newReviewStore(t)
+	viper.Set("relay.private_key", "")
+
+	// The review is still queued so a moderator sees it
+	review := queueTestReview(t, store, "event-1")
+	if review.LabelEventID != "" || len(store.labels()) != 0 {
+		t.Errorf("expected no content warning without a relay key")
+	}
+}
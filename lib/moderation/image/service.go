@@ -2,7 +2,6 @@ package image
 
 import (
 	"bytes"
-	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
@@ -14,53 +13,37 @@ import (
 	"github.com/HORNET-Storage/hornet-storage/lib/logging"
 )
 
-// ModerationService handles communication with the moderation API
+// ModerationService downloads media and classifies it with its moderation provider
 type ModerationService struct {
-	APIEndpoint string       // URL of the moderation API
-	Threshold   float64      // Confidence threshold for moderation
-	Mode        string       // Moderation mode (full, fast, etc.)
-	Client      *http.Client // HTTP client for API requests
-	DownloadDir string       // Directory for temporarily downloading media files
-	Enabled     bool         // Whether moderation is enabled
+	Provider    ModerationProvider // Classifies downloaded files, possibly a chain of providers
+	DownloadDir string             // Directory for temporarily downloading media files
+	Enabled     bool               // Whether moderation is enabled
+
+	Verdicts   VerdictStore  // Cache of verdicts by media sha256, nil to judge every download
+	VerdictTTL time.Duration // How long ALLOW and FLAG verdicts are reused, 0 for no limit
 }
 
-// NewModerationService creates a new moderation service instance
+// NewModerationService creates a new moderation service instance that uses the moderation API
 func NewModerationService(endpoint string, threshold float64, mode string, timeout time.Duration, downloadDir string) *ModerationService {
+	return NewModerationServiceWithProvider(NewAPIProvider(endpoint, threshold, mode, timeout), downloadDir)
+}
+
+// NewModerationServiceWithProvider creates a moderation service that classifies media with provider
+func NewModerationServiceWithProvider(provider ModerationProvider, downloadDir string) *ModerationService {
 	// Create download directory if it doesn't exist
 	if downloadDir != "" {
 		os.MkdirAll(downloadDir, 0755)
 	}
 
-	// Make sure the endpoint is properly formatted
-	if !strings.HasPrefix(endpoint, "http") {
-		endpoint = "http://" + endpoint
-	}
-
-	// Default to port 8000 if not specified
-	if !strings.Contains(endpoint, ":") {
-		if strings.HasPrefix(endpoint, "https") {
-			endpoint = endpoint + ":443"
-		} else {
-			endpoint = endpoint + ":8000"
-		}
-	}
-
-	// Ensure endpoint points to /moderate
-	if !strings.HasSuffix(endpoint, "/moderate") {
-		endpoint = strings.TrimSuffix(endpoint, "/") + "/moderate"
-	}
-
 	return &ModerationService{
-		APIEndpoint: endpoint,
-		Threshold:   threshold,
-		Mode:        mode,
-		Client:      &http.Client{Timeout: timeout},
+		Provider:    provider,
 		DownloadDir: downloadDir,
 		Enabled:     true,
 	}
 }
 
-// ModerateURL sends a media URL to the moderation API
+// ModerateURL downloads a media URL and passes it to the moderation provider. With a verdict
+// cache, URLs and files that were already judged are answered without asking the provider.
 func (s *ModerationService) ModerateURL(mediaURL string) (*ModerationResponse, error) {
 	if !s.Enabled {
 		// Return default "allow" response if moderation is disabled
@@ -71,6 +54,11 @@ func (s *ModerationService) ModerateURL(mediaURL string) (*ModerationResponse, e
 		}, nil
 	}
 
+	if response := s.cachedURLVerdict(mediaURL); response != nil {
+		logging.Infof("Using cached moderation verdict for %s", mediaURL)
+		return response, nil
+	}
+
 	// For URL-based moderation, we have two options:
 	// 1. Download the image first and then moderate it (more reliable)
 	// 2. Send the URL directly to the API (depends on API capability)
@@ -86,10 +74,10 @@ func (s *ModerationService) ModerateURL(mediaURL string) (*ModerationResponse, e
 	defer os.Remove(imagePath) // Clean up the temporary file
 
 	// Moderate the downloaded image
-	return s.ModerateFile(imagePath)
+	return s.moderateDownloaded(mediaURL, imagePath)
 }
 
-// ModerateFile sends a local image file to the moderation API
+// ModerateFile checks a local media file and passes it to the moderation provider
 func (s *ModerationService) ModerateFile(filePath string) (*ModerationResponse, error) {
 	if !s.Enabled {
 		// Return default "allow" response if moderation is disabled
@@ -112,97 +100,11 @@ func (s *ModerationService) ModerateFile(filePath string) (*ModerationResponse,
 	}
 
 	// Validate file is an image by checking magic bytes
-	imgType, err := validateImageFile(filePath)
-	if err != nil {
+	if _, err := validateImageFile(filePath); err != nil {
 		return nil, fmt.Errorf("file validation failed: %w", err)
 	}
 
-	// Get the absolute path to the file for debugging
-	absPath, err := filepath.Abs(filePath)
-	if err != nil {
-		absPath = filePath // Fallback to relative path if abs fails
-	}
-
-	// Log file information for debugging
-	logging.Infof("Uploading image: %s (type: %s, size: %d bytes, path: %s)",
-		filepath.Base(filePath), imgType, fileInfo.Size(), absPath)
-
-	// Read the entire file into memory
-	fileData, err := os.ReadFile(filePath)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read image file: %w", err)
-	}
-
-	// Create a custom boundary for the form - use the same one that worked in testing
-	boundary := "----WebKitFormBoundary7MA4YWxkTrZu0gW"
-
-	// Create a buffer to store the request body
-	var requestBody bytes.Buffer
-
-	// Get the filename with proper extension
-	filename := filepath.Base(filePath)
-	if !strings.Contains(filename, ".") {
-		// Add extension based on detected type if missing
-		filename = filename + "." + imgType
-	}
-
-	// Manually construct the multipart form
-	// 1. Add the file part with explicit content type
-	requestBody.WriteString("--" + boundary + "\r\n")
-	requestBody.WriteString(fmt.Sprintf(`Content-Disposition: form-data; name="file"; filename="%s"`, filename) + "\r\n")
-	requestBody.WriteString(fmt.Sprintf("Content-Type: image/%s\r\n\r\n", imgType))
-	requestBody.Write(fileData)
-	requestBody.WriteString("\r\n")
-
-	// 2. Add moderation mode
-	requestBody.WriteString("--" + boundary + "\r\n")
-	requestBody.WriteString(`Content-Disposition: form-data; name="moderation_mode"` + "\r\n\r\n")
-	requestBody.WriteString(s.Mode + "\r\n")
-
-	// 3. Add threshold
-	requestBody.WriteString("--" + boundary + "\r\n")
-	requestBody.WriteString(`Content-Disposition: form-data; name="threshold"` + "\r\n\r\n")
-	requestBody.WriteString(fmt.Sprintf("%f", s.Threshold) + "\r\n")
-
-	// 4. End of form
-	requestBody.WriteString("--" + boundary + "--\r\n")
-
-	// Debug the request body size
-	logging.Infof("Request body size: %d bytes", requestBody.Len())
-
-	// Create and send the request
-	requestURL := fmt.Sprintf("%s?moderation_mode=%s&threshold=%f",
-		s.APIEndpoint, s.Mode, s.Threshold)
-
-	req, err := http.NewRequest("POST", requestURL, &requestBody)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create HTTP request: %w", err)
-	}
-
-	req.Header.Set("Content-Type", fmt.Sprintf("multipart/form-data; boundary=%s", boundary))
-	req.Header.Set("Accept", "application/json")
-
-	// Send the request
-	resp, err := s.Client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("API request failed: %w", err)
-	}
-	defer resp.Body.Close()
-
-	// Check response status
-	if resp.StatusCode != http.StatusOK {
-		bodyBytes, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("API returned non-OK status %d: %s", resp.StatusCode, string(bodyBytes))
-	}
-
-	// Parse response
-	var result ModerationResponse
-	err = json.NewDecoder(resp.Body).Decode(&result)
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse API response: %w", err)
-	}
-
-	return &result, nil
+	return s.Provider.ModerateFile(filePath)
 }
 
 // downloadImageWithRetry downloads media with retry logic and exponential backoff
@@ -408,7 +310,7 @@ func (s *ModerationService) Disable() {
 	s.Enabled = false
 }
 
-// ModerateDisputeURL sends a media URL to the moderation API with dispute-specific parameters
+// ModerateDisputeURL downloads a media URL and re-evaluates it for a dispute
 func (s *ModerationService) ModerateDisputeURL(mediaURL string, disputeReason string) (*ModerationResponse, error) {
 	if !s.Enabled {
 		// Return default "allow" response if moderation is disabled
@@ -429,10 +331,11 @@ func (s *ModerationService) ModerateDisputeURL(mediaURL string, disputeReason st
 	defer os.Remove(imagePath) // Clean up the temporary file
 
 	// Moderate the downloaded image with dispute-specific parameters
-	return s.ModerateDisputeFile(imagePath, disputeReason)
+	return s.moderateDownloadedDispute(mediaURL, imagePath, disputeReason)
 }
 
-// ModerateDisputeFile sends a local image file to the moderation API with dispute-specific parameters
+// ModerateDisputeFile re-evaluates a local media file for a dispute. Providers without a
+// dispute mode evaluate it as usual.
 func (s *ModerationService) ModerateDisputeFile(filePath string, disputeReason string) (*ModerationResponse, error) {
 	if !s.Enabled {
 		// Return default "allow" response if moderation is disabled
@@ -455,101 +358,12 @@ func (s *ModerationService) ModerateDisputeFile(filePath string, disputeReason s
 	}
 
 	// Validate file is an image by checking magic bytes
-	imgType, err := validateImageFile(filePath)
-	if err != nil {
+	if _, err := validateImageFile(filePath); err != nil {
 		return nil, fmt.Errorf("file validation failed: %w", err)
 	}
 
-	// Get the absolute path to the file for debugging
-	absPath, err := filepath.Abs(filePath)
-	if err != nil {
-		absPath = filePath // Fallback to relative path if abs fails
-	}
-
-	// Log file information for debugging
-	logging.Infof("Uploading image for dispute moderation: %s (type: %s, size: %d bytes, path: %s)",
-		filepath.Base(filePath), imgType, fileInfo.Size(), absPath)
-
-	// Read the entire file into memory
-	fileData, err := os.ReadFile(filePath)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read image file: %w", err)
-	}
-
-	// Create a custom boundary for the form
-	boundary := "----WebKitFormBoundary7MA4YWxkTrZu0gW"
-
-	// Create a buffer to store the request body
-	var requestBody bytes.Buffer
-
-	// Get the filename with proper extension
-	filename := filepath.Base(filePath)
-	if !strings.Contains(filename, ".") {
-		// Add extension based on detected type if missing
-		filename = filename + "." + imgType
-	}
-
-	// Manually construct the multipart form
-	// 1. Add the file part with explicit content type
-	requestBody.WriteString("--" + boundary + "\r\n")
-	requestBody.WriteString(fmt.Sprintf(`Content-Disposition: form-data; name="file"; filename="%s"`, filename) + "\r\n")
-	requestBody.WriteString(fmt.Sprintf("Content-Type: image/%s\r\n\r\n", imgType))
-	requestBody.Write(fileData)
-	requestBody.WriteString("\r\n")
-
-	// 2. Add moderation mode - always use "full" for disputes
-	requestBody.WriteString("--" + boundary + "\r\n")
-	requestBody.WriteString(`Content-Disposition: form-data; name="moderation_mode"` + "\r\n\r\n")
-	requestBody.WriteString("full\r\n")
-
-	// 3. Add threshold - use a lower threshold for disputes (0.35 instead of 0.4)
-	requestBody.WriteString("--" + boundary + "\r\n")
-	requestBody.WriteString(`Content-Disposition: form-data; name="threshold"` + "\r\n\r\n")
-	requestBody.WriteString(fmt.Sprintf("%f", 0.35) + "\r\n")
-
-	// 4. Add dispute reason if provided
-	if disputeReason != "" {
-		requestBody.WriteString("--" + boundary + "\r\n")
-		requestBody.WriteString(`Content-Disposition: form-data; name="dispute_reason"` + "\r\n\r\n")
-		requestBody.WriteString(disputeReason + "\r\n")
+	if disputer, ok := s.Provider.(DisputeProvider); ok {
+		return disputer.ModerateDisputeFile(filePath, disputeReason)
 	}
-
-	// 5. End of form
-	requestBody.WriteString("--" + boundary + "--\r\n")
-
-	// Debug the request body size
-	logging.Infof("Dispute moderation request body size: %d bytes", requestBody.Len())
-
-	// Create and send the request
-	requestURL := fmt.Sprintf("%s_dispute?moderation_mode=%s&threshold=%f", s.APIEndpoint, s.Mode, s.Threshold)
-
-	req, err := http.NewRequest("POST", requestURL, &requestBody)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create HTTP request: %w", err)
-	}
-
-	req.Header.Set("Content-Type", fmt.Sprintf("multipart/form-data; boundary=%s", boundary))
-	req.Header.Set("Accept", "application/json")
-
-	// Send the request
-	resp, err := s.Client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("API request failed: %w", err)
-	}
-	defer resp.Body.Close()
-
-	// Check response status
-	if resp.StatusCode != http.StatusOK {
-		bodyBytes, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("API returned non-OK status %d: %s", resp.StatusCode, string(bodyBytes))
-	}
-
-	// Parse response
-	var result ModerationResponse
-	err = json.NewDecoder(resp.Body).Decode(&result)
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse API response: %w", err)
-	}
-
-	return &result, nil
+	return s.Provider.ModerateFile(filePath)
 }
@@ -0,0 +1,223 @@
+package image
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/HORNET-Storage/hornet-storage/lib/logging"
+	"github.com/HORNET-Storage/hornet-storage/lib/types"
+)
+
+// VerdictStore persists moderation verdicts keyed by the sha256 of the media bytes
+type VerdictStore interface {
+	GetMediaVerdict(sha256 string) (*types.MediaVerdict, error)
+	GetMediaVerdictByURL(url string) (*types.MediaVerdict, error)
+	SaveMediaVerdict(verdict *types.MediaVerdict) error
+	SaveMediaVerdictURL(url string, sha256 string) error
+}
+
+// SetVerdictCache makes the service reuse verdicts for media it has already judged.
+// ALLOW and FLAG verdicts older than ttl are judged again; BLOCK verdicts don't expire.
+func (s *ModerationService) SetVerdictCache(store VerdictStore, ttl time.Duration) {
+	s.Verdicts = store
+	s.VerdictTTL = ttl
+}
+
+// GetVerdict returns the cached verdict for a file's sha256, or nil if there is none
+func (s *ModerationService) GetVerdict(sha256 string) *ModerationResponse {
+	if s.Verdicts == nil {
+		return nil
+	}
+
+	verdict, err := s.Verdicts.GetMediaVerdict(sha256)
+	if err != nil {
+		logging.Infof("Error reading moderation verdict for %s: %v", sha256, err)
+		return nil
+	}
+	return s.usableVerdict(verdict)
+}
+
+// ModerateData moderates media held in memory, such as a Blossom upload. ext is used as
+// the temporary file's extension so providers can tell the media type.
+func (s *ModerationService) ModerateData(data []byte, ext string) (*ModerationResponse, error) {
+	if !s.Enabled {
+		return &ModerationResponse{
+			Decision:     string(DecisionAllow),
+			Explanation:  "Moderation is disabled",
+			ContentLevel: 0,
+		}, nil
+	}
+
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+	if response := s.GetVerdict(hash); response != nil {
+		return response, nil
+	}
+
+	file, err := os.CreateTemp(s.DownloadDir, "upload-*"+ext)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer os.Remove(file.Name())
+
+	_, err = file.Write(data)
+	file.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to write temporary file: %w", err)
+	}
+
+	response, err := s.ModerateFile(file.Name())
+	if err != nil {
+		return nil, err
+	}
+	s.saveVerdict(hash, "", response)
+	return response, nil
+}
+
+// cachedURLVerdict returns the verdict for the file a URL served last time, if it still
+// holds for whatever the URL serves now. BLOCK verdicts always do, others only when the
+// URL names the sha256 of that file. Any other URL may serve different bytes by now, so
+// it is downloaded and looked up by the hash of its content instead.
+func (s *ModerationService) cachedURLVerdict(mediaURL string) *ModerationResponse {
+	if s.Verdicts == nil {
+		return nil
+	}
+
+	verdict, err := s.Verdicts.GetMediaVerdictByURL(mediaURL)
+	if err != nil {
+		logging.Infof("Error reading moderation verdict for %s: %v", mediaURL, err)
+		return nil
+	}
+	if verdict == nil || (verdict.Decision != string(DecisionBlock) && !contentAddressed(mediaURL, verdict.SHA256)) {
+		return nil
+	}
+	return s.usableVerdict(verdict)
+}
+
+// contentAddressed reports whether a path segment of the URL is the sha256 of its
+// content, optionally followed by an extension, as Blossom servers name blobs
+func contentAddressed(mediaURL string, sha256 string) bool {
+	parsed, err := url.Parse(mediaURL)
+	if err != nil || sha256 == "" {
+		return false
+	}
+
+	for _, segment := range strings.Split(parsed.Path, "/") {
+		if strings.EqualFold(strings.TrimSuffix(segment, path.Ext(segment)), sha256) {
+			return true
+		}
+	}
+	return false
+}
+
+// moderateDownloaded moderates a downloaded file, reusing the verdict for identical bytes
+// fetched from any other URL
+func (s *ModerationService) moderateDownloaded(mediaURL string, filePath string) (*ModerationResponse, error) {
+	if s.Verdicts == nil {
+		return s.ModerateFile(filePath)
+	}
+
+	hash, err := fileSHA256(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	if response := s.GetVerdict(hash); response != nil {
+		if err := s.Verdicts.SaveMediaVerdictURL(mediaURL, hash); err != nil {
+			logging.Infof("Error saving moderation verdict URL %s: %v", mediaURL, err)
+		}
+		return response, nil
+	}
+
+	response, err := s.ModerateFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	s.saveVerdict(hash, mediaURL, response)
+	return response, nil
+}
+
+// moderateDownloadedDispute re-evaluates a downloaded file and replaces its cached verdict,
+// so an overturned BLOCK no longer applies to other copies of the file
+func (s *ModerationService) moderateDownloadedDispute(mediaURL string, filePath string, disputeReason string) (*ModerationResponse, error) {
+	response, err := s.ModerateDisputeFile(filePath, disputeReason)
+	if err != nil || s.Verdicts == nil {
+		return response, err
+	}
+
+	hash, err := fileSHA256(filePath)
+	if err != nil {
+		logging.Infof("Error hashing %s for the verdict cache: %v", mediaURL, err)
+		return response, nil
+	}
+	s.saveVerdict(hash, mediaURL, response)
+	return response, nil
+}
+
+func (s *ModerationService) saveVerdict(hash string, mediaURL string, response *ModerationResponse) {
+	if s.Verdicts == nil {
+		return
+	}
+
+	verdict := &types.MediaVerdict{
+		SHA256:       hash,
+		Decision:     string(response.GetDecision()),
+		ContentLevel: response.ContentLevel,
+		Confidence:   response.Confidence,
+		Category:     response.Category,
+		Explanation:  response.Explanation,
+		Provider:     response.ModerationMode,
+		UpdatedAt:    time.Now(),
+	}
+	if err := s.Verdicts.SaveMediaVerdict(verdict); err != nil {
+		logging.Infof("Error saving moderation verdict for %s: %v", hash, err)
+		return
+	}
+
+	if mediaURL != "" {
+		if err := s.Verdicts.SaveMediaVerdictURL(mediaURL, hash); err != nil {
+			logging.Infof("Error saving moderation verdict URL %s: %v", mediaURL, err)
+		}
+	}
+}
+
+// usableVerdict converts a stored verdict back into a response, or returns nil if it has expired
+func (s *ModerationService) usableVerdict(verdict *types.MediaVerdict) *ModerationResponse {
+	if verdict == nil {
+		return nil
+	}
+	if verdict.Decision != string(DecisionBlock) && s.VerdictTTL > 0 && time.Since(verdict.UpdatedAt) > s.VerdictTTL {
+		return nil
+	}
+
+	return &ModerationResponse{
+		ContentLevel:   verdict.ContentLevel,
+		IsExplicit:     verdict.ContentLevel >= int(Level5_Explicit),
+		Confidence:     verdict.Confidence,
+		Category:       verdict.Category,
+		Explanation:    verdict.Explanation,
+		Decision:       verdict.Decision,
+		ModerationMode: verdict.Provider,
+	}
+}
+
+func fileSHA256(filePath string) (string, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to open file for hashing: %w", err)
+	}
+	defer file.Close()
+
+	hasher := sha256.New()
+	if _, err := io.Copy(hasher, file); err != nil {
+		return "", fmt.Errorf("failed to hash file: %w", err)
+	}
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
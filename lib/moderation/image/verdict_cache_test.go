@@ -0,0 +1,171 @@
+package image
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/HORNET-Storage/hornet-storage/lib/types"
+)
+
+type memoryVerdicts struct {
+	verdicts map[string]types.MediaVerdict
+	urls     map[string]string
+}
+
+func newMemoryVerdicts() *memoryVerdicts {
+	return &memoryVerdicts{verdicts: map[string]types.MediaVerdict{}, urls: map[string]string{}}
+}
+
+func (m *memoryVerdicts) GetMediaVerdict(sha256 string) (*types.MediaVerdict, error) {
+	if verdict, ok := m.verdicts[sha256]; ok {
+		return &verdict, nil
+	}
+	return nil, nil
+}
+
+func (m *memoryVerdicts) GetMediaVerdictByURL(url string) (*types.MediaVerdict, error) {
+	return m.GetMediaVerdict(m.urls[url])
+}
+
+func (m *memoryVerdicts) SaveMediaVerdict(verdict *types.MediaVerdict) error {
+	m.verdicts[verdict.SHA256] = *verdict
+	return nil
+}
+
+func (m *memoryVerdicts) SaveMediaVerdictURL(url string, sha256 string) error {
+	m.urls[url] = sha256
+	return nil
+}
+
+func TestVerdictCacheReusesVerdicts(t *testing.T) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, testImage(64, 64, 0)); err != nil {
+		t.Fatal(err)
+	}
+
+	downloads := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		downloads++
+		w.Write(buf.Bytes())
+	}))
+	defer server.Close()
+
+	provider := &fixedProvider{name: "counting", response: &ModerationResponse{Decision: string(DecisionBlock), ContentLevel: 5, Explanation: "explicit"}}
+	service := NewModerationServiceWithProvider(provider, t.TempDir())
+	verdicts := newMemoryVerdicts()
+	service.SetVerdictCache(verdicts, time.Hour)
+
+	if response, err := service.ModerateURL(server.URL + "/meme.png"); err != nil || !response.ShouldBlock() {
+		t.Fatalf("expected the first download to be blocked, got %+v, %v", response, err)
+	}
+
+	// The same bytes from another URL are downloaded but not judged again
+	response, err := service.ModerateURL(server.URL + "/copy.png")
+	if err != nil || !response.ShouldBlock() || response.Explanation != "explicit" {
+		t.Fatalf("expected the cached verdict for a copy, got %+v, %v", response, err)
+	}
+	if provider.calls != 1 || downloads != 2 {
+		t.Errorf("expected 1 provider call and 2 downloads, got %d and %d", provider.calls, downloads)
+	}
+
+	// A known URL isn't downloaded at all
+	if _, err := service.ModerateURL(server.URL + "/meme.png"); err != nil {
+		t.Fatal(err)
+	}
+	if provider.calls != 1 || downloads != 2 {
+		t.Errorf("expected a known URL to skip the download, got %d calls and %d downloads", provider.calls, downloads)
+	}
+
+	// Uploads with the same bytes hit the cache too
+	if response, err := service.ModerateData(buf.Bytes(), ".png"); err != nil || !response.ShouldBlock() || provider.calls != 1 {
+		t.Errorf("expected an upload of the same bytes to use the cached verdict")
+	}
+}
+
+func TestVerdictCacheRechecksMutableURLs(t *testing.T) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, testImage(64, 64, 0)); err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256(buf.Bytes())
+	hash := hex.EncodeToString(sum[:])
+
+	downloads := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		downloads++
+		w.Write(buf.Bytes())
+	}))
+	defer server.Close()
+
+	provider := &fixedProvider{name: "counting", response: &ModerationResponse{Decision: string(DecisionAllow)}}
+	service := NewModerationServiceWithProvider(provider, t.TempDir())
+	service.SetVerdictCache(newMemoryVerdicts(), time.Hour)
+
+	// An allowed URL could serve other bytes next time, so it is downloaded again
+	// and only the provider call is saved by the bytes hash
+	for i := 0; i < 2; i++ {
+		if _, err := service.ModerateURL(server.URL + "/avatar.png"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if provider.calls != 1 || downloads != 2 {
+		t.Errorf("expected 1 provider call and 2 downloads, got %d and %d", provider.calls, downloads)
+	}
+
+	// A URL named after the hash of its content can't change, so it is answered from the cache
+	blob := server.URL + "/" + hash + ".png"
+	for i := 0; i < 2; i++ {
+		if _, err := service.ModerateURL(blob); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if provider.calls != 1 || downloads != 3 {
+		t.Errorf("expected a content-addressed URL to skip the second download, got %d calls and %d downloads", provider.calls, downloads)
+	}
+}
+
+func TestContentAddressed(t *testing.T) {
+	hash := strings.Repeat("ab", 32)
+
+	tests := []struct {
+		url      string
+		expected bool
+	}{
+		{"https://blossom.example.com/" + hash, true},
+		{"https://blossom.example.com/" + hash + ".jpg", true},
+		{"https://cdn.example.com/media/" + strings.ToUpper(hash) + ".png?w=200", true},
+		{"https://cdn.example.com/avatar.png", false},
+		{"https://cdn.example.com/" + hash[:63] + ".png", false},
+		{"https://cdn.example.com/avatar.png?hash=" + hash, false},
+	}
+
+	for _, test := range tests {
+		if got := contentAddressed(test.url, hash); got != test.expected {
+			t.Errorf("contentAddressed(%s) = %v, expected %v", test.url, got, test.expected)
+		}
+	}
+}
+
+func TestVerdictCacheExpiresAllowedVerdicts(t *testing.T) {
+	service := NewModerationServiceWithProvider(&fixedProvider{name: "unused"}, t.TempDir())
+	verdicts := newMemoryVerdicts()
+	service.SetVerdictCache(verdicts, time.Hour)
+
+	old := time.Now().Add(-2 * time.Hour)
+	verdicts.SaveMediaVerdict(&types.MediaVerdict{SHA256: "allowed", Decision: "ALLOW", UpdatedAt: old})
+	verdicts.SaveMediaVerdict(&types.MediaVerdict{SHA256: "blocked", Decision: "BLOCK", ContentLevel: 5, UpdatedAt: old})
+
+	if service.GetVerdict("allowed") != nil {
+		t.Errorf("expected an old ALLOW verdict to be judged again")
+	}
+	if response := service.GetVerdict("blocked"); response == nil || !response.ShouldBlock() {
+		t.Errorf("expected BLOCK verdicts not to expire")
+	}
+}
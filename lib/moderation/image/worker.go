@@ -79,6 +79,9 @@ func (w *Worker) Start() {
 	// Ticker for resolution events cleanup (daily)
 	resolutionEventsCleanupTicker := time.NewTicker(24 * time.Hour)
 
+	// Ticker for expired verdict cleanup (daily)
+	verdictCleanupTicker := time.NewTicker(24 * time.Hour)
+
 	// Create a worker pool using semaphore pattern
 	semaphore := make(chan struct{}, w.Concurrency)
 
@@ -89,6 +92,7 @@ func (w *Worker) Start() {
 		defer tempCleanupTicker.Stop()
 		defer blockedEventsCleanupTicker.Stop()
 		defer resolutionEventsCleanupTicker.Stop()
+		defer verdictCleanupTicker.Stop()
 
 		for {
 			select {
@@ -165,6 +169,10 @@ func (w *Worker) Start() {
 				// Delete resolution events older than 7 days
 				go w.cleanupResolutionEvents()
 
+			case <-verdictCleanupTicker.C:
+				// Forget ALLOW and FLAG verdicts past their TTL
+				go w.cleanupVerdicts()
+
 			case <-w.StopChan:
 				logging.Info("Stopping image moderation worker")
 				return
@@ -211,6 +219,24 @@ func (w *Worker) cleanupResolutionEvents() {
 	}
 }
 
+// cleanupVerdicts deletes cached ALLOW and FLAG verdicts that are past the verdict TTL
+func (w *Worker) cleanupVerdicts() {
+	statsStore := w.Store.GetStatsStore()
+	if w.ModerationService.Verdicts == nil || w.ModerationService.VerdictTTL <= 0 || statsStore == nil {
+		return
+	}
+
+	count, err := statsStore.PurgeMediaVerdicts(time.Now().Add(-w.ModerationService.VerdictTTL))
+	if err != nil {
+		logging.Infof("Error cleaning up moderation verdicts: %v", err)
+		return
+	}
+
+	if count > 0 {
+		logging.Infof("Deleted %d expired moderation verdicts", count)
+	}
+}
+
 // cleanupStaleFiles removes old temporary files that may have been leaked
 func (w *Worker) cleanupStaleFiles() {
 	if w.TempDir == "" {
@@ -287,6 +313,9 @@ func (w *Worker) processEvent(eventID string, mediaURLs []string) {
 	var contentType string
 	var pubKey string
 	var lastResponse *ModerationResponse
+	var flagResponse *ModerationResponse
+	var flaggedMediaURL string
+	var flaggedContentType string
 
 	// Get the event to extract the pubkey using QueryEvents with the event ID
 	events, err := w.Store.QueryEvents(nostr.Filter{
@@ -337,6 +366,13 @@ func (w *Worker) processEvent(eventID string, mediaURLs []string) {
 				eventID, mediaType, mediaURL, response.Explanation)
 			break // No need to check other media
 		}
+
+		// Borderline media is served with a content warning until someone reviews it
+		if response.GetDecision() == DecisionFlag && flagResponse == nil {
+			flagResponse = response
+			flaggedMediaURL = mediaURL
+			flaggedContentType = mediaType
+		}
 	}
 
 	// Take action based on moderation results
@@ -380,6 +416,12 @@ func (w *Worker) processEvent(eventID string, mediaURLs []string) {
 		} else {
 			logging.Infof("Stats store not available, can't create notification for event %s", eventID)
 		}
+	} else if flagResponse != nil {
+		if err := QueueForReview(w.Store, eventID, pubKey, flaggedMediaURL, flaggedContentType, flagResponse); err != nil {
+			logging.Infof("Error queueing event %s for review: %v", eventID, err)
+		} else {
+			logging.Infof("Event %s flagged for review, served with a content warning", eventID)
+		}
 	} else {
 		logging.Infof("Event %s passed moderation, available for queries", eventID)
 	}
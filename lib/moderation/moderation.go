@@ -1,6 +1,7 @@
 package moderation
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
 	"time"
@@ -23,8 +24,13 @@ const (
 	DefaultTempDir       = "./data/moderation/temp"
 	DefaultThreshold     = 0.4
 	DefaultMode          = "full"
+	DefaultCombine       = string(image.CombineFirstBlock)
+	DefaultVerdictTTL    = 30 * 24 * time.Hour
 )
 
+// DefaultProviders checks the local hash blocklist before asking the moderation API
+var DefaultProviders = []string{"phash", "api"}
+
 // InitModeration initializes the image moderation system
 func InitModeration(store stores.Store, apiEndpoint string, options ...Option) error {
 	// Apply default configuration
@@ -37,6 +43,11 @@ func InitModeration(store stores.Store, apiEndpoint string, options ...Option) e
 		TempDir:       DefaultTempDir,
 		Concurrency:   DefaultConcurrency,
 		Enabled:       true,
+		Providers:     DefaultProviders,
+		Combine:       DefaultCombine,
+		HashThreshold: image.DefaultHashThreshold,
+		VerdictCache:  true,
+		VerdictTTL:    DefaultVerdictTTL,
 	}
 
 	// Apply custom options
@@ -44,14 +55,18 @@ func InitModeration(store stores.Store, apiEndpoint string, options ...Option) e
 		option(config)
 	}
 
+	provider, err := buildProvider(store, config)
+	if err != nil {
+		return err
+	}
+
 	// Initialize moderation service
-	moderationService = image.NewModerationService(
-		config.APIEndpoint,
-		config.Threshold,
-		config.Mode,
-		config.Timeout,
-		config.TempDir,
-	)
+	moderationService = image.NewModerationServiceWithProvider(provider, config.TempDir)
+	if config.VerdictCache {
+		if statsStore := store.GetStatsStore(); statsStore != nil {
+			moderationService.SetVerdictCache(statsStore, config.VerdictTTL)
+		}
+	}
 
 	// Initialize worker if enabled
 	if config.Enabled {
@@ -65,7 +80,7 @@ func InitModeration(store stores.Store, apiEndpoint string, options ...Option) e
 
 		// Start the worker
 		imageWorker.Start()
-		logging.Infof("Image moderation system initialized with API endpoint: %s", config.APIEndpoint)
+		logging.Infof("Image moderation system initialized with providers %s (API endpoint: %s)", provider.Name(), config.APIEndpoint)
 	} else {
 		logging.Infof("Image moderation system initialized but disabled")
 	}
@@ -83,6 +98,35 @@ type Configuration struct {
 	TempDir       string
 	Concurrency   int
 	Enabled       bool
+	Providers     []string // "api" and/or "phash", in the order they are asked
+	Combine       string   // How a chain merges responses: "first_block" or "max_level"
+	HashThreshold int      // Hamming distance for a hash blocklist match
+	VerdictCache  bool     // Reuse verdicts for media that was already judged
+	VerdictTTL    time.Duration
+}
+
+// buildProvider creates the configured providers, chaining them when there is more than one
+func buildProvider(store stores.Store, config *Configuration) (image.ModerationProvider, error) {
+	var providers []image.ModerationProvider
+	for _, name := range config.Providers {
+		switch name {
+		case "api":
+			providers = append(providers, image.NewAPIProvider(config.APIEndpoint, config.Threshold, config.Mode, config.Timeout))
+		case "phash":
+			statsStore := store.GetStatsStore()
+			if statsStore == nil {
+				return nil, fmt.Errorf("the phash moderation provider needs the statistics store")
+			}
+			providers = append(providers, image.NewPerceptualHashProvider(statsStore, config.HashThreshold))
+		default:
+			return nil, fmt.Errorf("unknown moderation provider %q", name)
+		}
+	}
+
+	if len(providers) == 1 {
+		return providers[0], nil
+	}
+	return image.NewChainProvider(image.CombineStrategy(config.Combine), providers...)
 }
 
 // Option represents a configuration option for the moderation system
@@ -130,6 +174,44 @@ func WithConcurrency(concurrency int) Option {
 	}
 }
 
+// WithProviders sets which moderation providers are used and in what order
+func WithProviders(providers []string) Option {
+	return func(c *Configuration) {
+		if len(providers) > 0 {
+			c.Providers = providers
+		}
+	}
+}
+
+// WithCombine sets how chained providers' responses are merged
+func WithCombine(combine string) Option {
+	return func(c *Configuration) {
+		if combine != "" {
+			c.Combine = combine
+		}
+	}
+}
+
+// WithHashThreshold sets the Hamming distance for hash blocklist matches
+func WithHashThreshold(threshold int) Option {
+	return func(c *Configuration) {
+		if threshold > 0 {
+			c.HashThreshold = threshold
+		}
+	}
+}
+
+// WithVerdictCache enables reusing verdicts by media sha256. ALLOW and FLAG verdicts
+// are judged again after ttl; 0 keeps them forever.
+func WithVerdictCache(enabled bool, ttl time.Duration) Option {
+	return func(c *Configuration) {
+		c.VerdictCache = enabled
+		if ttl >= 0 {
+			c.VerdictTTL = ttl
+		}
+	}
+}
+
 // WithEnabled enables or disables the moderation system
 func WithEnabled(enabled bool) Option {
 	return func(c *Configuration) {
@@ -0,0 +1,268 @@
+// Package reports aggregates NIP-56 (kind 1984) reports into weighted scores
+// and moves reported content into moderation once configured thresholds are crossed.
+package reports
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/nbd-wtf/go-nostr"
+
+	"github.com/HORNET-Storage/hornet-storage/lib/config"
+	"github.com/HORNET-Storage/hornet-storage/lib/logging"
+	"github.com/HORNET-Storage/hornet-storage/lib/moderation/image"
+	"github.com/HORNET-Storage/hornet-storage/lib/stores"
+	"github.com/HORNET-Storage/hornet-storage/lib/types"
+	"github.com/HORNET-Storage/hornet-storage/lib/wot"
+)
+
+const (
+	TargetTypeEvent  = "event"
+	TargetTypePubkey = "pubkey"
+
+	ActionNone    = ""
+	ActionPending = "pending"
+	ActionBlock   = "block"
+	ActionNotify  = "notify"
+
+	// DefaultThresholdKey is the thresholds entry used for report types without their own entry
+	DefaultThresholdKey = "default"
+)
+
+// Result describes what happened to a single submitted report
+type Result struct {
+	Counted      bool    // False when the reporter had already reported this target as this type
+	NewReporter  bool    // True when this is the reporter's first report against the target
+	Weight       float64 // Weight the reporter contributed
+	Score        float64 // Weighted score of the target for this report type
+	PubkeyScore  float64 // Weighted score of the reported pubkey across all targets
+	EventAction  string  // Action taken against the reported event, if any
+	PubkeyAction string  // Action taken against the reported pubkey, if any
+}
+
+// Aggregator records deduplicated, weighted reports and applies threshold actions
+type Aggregator struct {
+	store    stores.Store
+	wotCache *wot.Cache
+}
+
+// NewAggregator creates a report aggregator. wotCache may be nil, in which case
+// WoT distance is not considered when weighting reporters.
+func NewAggregator(store stores.Store, wotCache *wot.Cache) *Aggregator {
+	return &Aggregator{
+		store:    store,
+		wotCache: wotCache,
+	}
+}
+
+// Submit records a report and takes any automatic action the new score triggers.
+// reported is the reported event and may be nil for pubkey-only reports.
+func (a *Aggregator) Submit(report *nostr.Event, reported *nostr.Event, reportedPubkey string, reportType string) (*Result, error) {
+	statsStore := a.store.GetStatsStore()
+	if statsStore == nil {
+		return nil, fmt.Errorf("statistics store not available")
+	}
+
+	cfg := a.settings()
+	reportType = strings.ToLower(reportType)
+
+	target := reportedPubkey
+	targetType := TargetTypePubkey
+	if reported != nil {
+		target = reported.ID
+		targetType = TargetTypeEvent
+		reportedPubkey = reported.PubKey
+	}
+
+	result := &Result{
+		Weight: a.reporterWeight(cfg, report.PubKey),
+	}
+
+	scores, err := statsStore.SaveReport(&types.Report{
+		Target:         target,
+		TargetType:     targetType,
+		TargetPubKey:   reportedPubkey,
+		ReporterPubKey: report.PubKey,
+		ReportType:     reportType,
+		ReportEventID:  report.ID,
+		Weight:         result.Weight,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to save report: %w", err)
+	}
+	result.Counted = scores.Counted
+	result.NewReporter = scores.NewReporter
+	result.Score = scores.Score
+	result.PubkeyScore = scores.PubkeyScore
+
+	// Duplicate reports never change a score, and a disabled aggregator only counts
+	if !result.Counted || !cfg.Enabled {
+		return result, nil
+	}
+
+	if reported != nil {
+		// Checked on every counted report rather than only when the score crosses the
+		// threshold, so an action that failed is retried by the next report
+		threshold, ok := thresholdFor(cfg, reportType)
+		if ok && result.Score >= threshold.Score && scores.Action == ActionNone {
+			result.EventAction, err = a.applyEventAction(reported, reportType, threshold, result.Score)
+			if err != nil {
+				return nil, err
+			}
+			if result.EventAction != ActionNone {
+				if err := statsStore.RecordReportAction(target, reportType, result.EventAction); err != nil {
+					logging.Infof("Error recording action for reported event %s: %v", target, err)
+				}
+			}
+		}
+	}
+
+	if crossed(scores.PreviousPubkeyScore, result.PubkeyScore, cfg.PubkeyThreshold.Score) {
+		result.PubkeyAction = a.applyPubkeyAction(reportedPubkey, report, cfg.PubkeyThreshold, result.PubkeyScore)
+	}
+
+	return result, nil
+}
+
+// reporterWeight returns the highest weight the reporter qualifies for
+func (a *Aggregator) reporterWeight(cfg types.ReportsConfig, reporter string) float64 {
+	weight := cfg.Weights.Base
+	if weight <= 0 {
+		weight = 1
+	}
+
+	statsStore := a.store.GetStatsStore()
+
+	if user, err := statsStore.GetAllowedUser(reporter); err == nil && user != nil {
+		weight = max(weight, cfg.Weights.AllowedUser)
+	}
+
+	if subscriber, err := statsStore.GetPaidSubscriberByNpub(reporter); err == nil && subscriber != nil {
+		if time.Now().Before(subscriber.ExpirationDate) {
+			weight = max(weight, cfg.Weights.PaidSubscriber)
+		}
+	}
+
+	if a.wotCache != nil && cfg.WotRoot != "" && len(cfg.Weights.WotHops) > 0 {
+		if distance, ok := a.wotCache.Distance(cfg.WotRoot, reporter); ok {
+			// The graph owner is weighted the same as a direct follow
+			hop := max(distance, 1)
+			if hop <= len(cfg.Weights.WotHops) {
+				weight = max(weight, cfg.Weights.WotHops[hop-1])
+			}
+		}
+	}
+
+	return weight
+}
+
+// applyEventAction moves a reported event into pending moderation or blocks it outright
+func (a *Aggregator) applyEventAction(reported *nostr.Event, reportType string, threshold types.ReportThreshold, score float64) (string, error) {
+	if blocked, _ := a.store.IsEventBlocked(reported.ID); blocked {
+		return ActionNone, nil
+	}
+
+	reason := fmt.Sprintf("Reported as %s (score %.1f, threshold %.1f)", reportType, score, threshold.Score)
+
+	switch threshold.Action {
+	case ActionBlock:
+		if err := a.store.MarkEventBlockedWithDetails(reported.ID, time.Now().Unix(), reason, 0, ""); err != nil {
+			// The block is recorded before the moderation ticket is created, so an
+			// error with the event blocked is only a ticket failure
+			if blocked, _ := a.store.IsEventBlocked(reported.ID); !blocked {
+				return ActionNone, fmt.Errorf("failed to block reported event %s: %w", reported.ID, err)
+			}
+			logging.Infof("Error creating moderation ticket for reported event %s: %v", reported.ID, err)
+		}
+		if pending, _ := a.store.IsPendingModeration(reported.ID); pending {
+			if err := a.store.RemoveFromPendingModeration(reported.ID); err != nil {
+				logging.Infof("Error removing reported event %s from pending moderation: %v", reported.ID, err)
+			}
+		}
+	case ActionPending:
+		if pending, _ := a.store.IsPendingModeration(reported.ID); pending {
+			return ActionNone, nil
+		}
+		if err := a.store.AddToPendingModeration(reported.ID, image.ExtractMediaURLs(reported)); err != nil {
+			return ActionNone, fmt.Errorf("failed to add reported event %s to pending moderation: %w", reported.ID, err)
+		}
+	default:
+		logging.Infof("Unknown report threshold action %q for report type %s", threshold.Action, reportType)
+		return ActionNone, nil
+	}
+
+	logging.Infof("Reported event %s moved to %s: %s", reported.ID, threshold.Action, reason)
+
+	a.notify(&types.ModerationNotification{
+		PubKey:      reported.PubKey,
+		EventID:     reported.ID,
+		Reason:      reason,
+		ContentType: "report",
+	})
+
+	return threshold.Action, nil
+}
+
+// applyPubkeyAction blocks a reported pubkey or only notifies moderators about it
+func (a *Aggregator) applyPubkeyAction(pubkey string, report *nostr.Event, threshold types.ReportThreshold, score float64) string {
+	reason := fmt.Sprintf("Pubkey reported by multiple users (score %.1f, threshold %.1f)", score, threshold.Score)
+
+	switch threshold.Action {
+	case ActionBlock:
+		if blocked, _ := a.store.IsBlockedPubkey(pubkey); blocked {
+			return ActionNone
+		}
+		if err := a.store.BlockPubkey(pubkey, reason); err != nil {
+			logging.Infof("Error blocking reported pubkey %s: %v", pubkey, err)
+			return ActionNone
+		}
+		logging.Infof("Reported pubkey %s blocked: %s", pubkey, reason)
+	case ActionNotify:
+	default:
+		logging.Infof("Unknown pubkey report threshold action %q", threshold.Action)
+		return ActionNone
+	}
+
+	// Notifications are unique per event ID, so pubkey-level notifications
+	// reference the report that pushed the pubkey over the threshold
+	a.notify(&types.ModerationNotification{
+		PubKey:      pubkey,
+		EventID:     report.ID,
+		Reason:      reason,
+		ContentType: TargetTypePubkey,
+	})
+
+	return threshold.Action
+}
+
+func (a *Aggregator) notify(notification *types.ModerationNotification) {
+	notification.CreatedAt = time.Now()
+	if err := a.store.GetStatsStore().CreateModerationNotification(notification); err != nil {
+		logging.Infof("Error creating moderation notification for %s: %v", notification.EventID, err)
+	}
+}
+
+// settings returns the current report configuration
+func (a *Aggregator) settings() types.ReportsConfig {
+	cfg, err := config.GetConfig()
+	if err != nil {
+		logging.Infof("Failed to load report settings: %v", err)
+		return types.ReportsConfig{}
+	}
+	return cfg.ContentFiltering.Reports
+}
+
+// thresholdFor returns the threshold for a report type, falling back to the default entry
+func thresholdFor(cfg types.ReportsConfig, reportType string) (types.ReportThreshold, bool) {
+	if threshold, ok := cfg.Thresholds[reportType]; ok {
+		return threshold, threshold.Score > 0
+	}
+	threshold, ok := cfg.Thresholds[DefaultThresholdKey]
+	return threshold, ok && threshold.Score > 0
+}
+
+// crossed reports whether a score moved from below the threshold to at or above it
+func crossed(previous, current, threshold float64) bool {
+	return threshold > 0 && previous < threshold && current >= threshold
+}
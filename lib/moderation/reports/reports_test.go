@@ -0,0 +1,281 @@
+package reports
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/spf13/viper"
+
+	"github.com/HORNET-Storage/hornet-storage/lib/config"
+	"github.com/HORNET-Storage/hornet-storage/lib/stores"
+	"github.com/HORNET-Storage/hornet-storage/lib/stores/badgerhold"
+	"github.com/HORNET-Storage/hornet-storage/lib/types"
+)
+
+func setupAggregator(t *testing.T) (*Aggregator, *badgerhold.BadgerholdStore) {
+	t.Helper()
+
+	viper.Reset()
+	viper.Set("content_filtering.reports.enabled", true)
+	viper.Set("content_filtering.reports.weights.base", 1.0)
+	viper.Set("content_filtering.reports.weights.paid_subscriber", 3.0)
+	viper.Set("content_filtering.reports.thresholds", map[string]interface{}{
+		"default": map[string]interface{}{"score": 3.0, "action": ActionPending},
+		"illegal": map[string]interface{}{"score": 4.0, "action": ActionBlock},
+	})
+	viper.Set("content_filtering.reports.pubkey_threshold.score", 4.0)
+	viper.Set("content_filtering.reports.pubkey_threshold.action", ActionNotify)
+	config.InitConfigForTesting()
+	t.Cleanup(viper.Reset)
+
+	tempDir := t.TempDir()
+	store, err := badgerhold.InitStore(filepath.Join(tempDir, "store"), filepath.Join(tempDir, "stats.db"))
+	if err != nil {
+		t.Fatalf("InitStore: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := store.Cleanup(); err != nil {
+			t.Fatalf("Cleanup: %v", err)
+		}
+	})
+
+	return NewAggregator(store, nil), store
+}
+
+func signedEvent(t *testing.T, privateKey string, kind int, tags nostr.Tags) *nostr.Event {
+	t.Helper()
+
+	pubkey, err := nostr.GetPublicKey(privateKey)
+	if err != nil {
+		t.Fatalf("GetPublicKey: %v", err)
+	}
+	event := &nostr.Event{
+		PubKey:    pubkey,
+		CreatedAt: nostr.Timestamp(time.Now().Unix()),
+		Kind:      kind,
+		Tags:      tags,
+	}
+	if err := event.Sign(privateKey); err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+	return event
+}
+
+func TestSubmitDeduplicatesReportersAndQuarantines(t *testing.T) {
+	aggregator, store := setupAggregator(t)
+
+	note := signedEvent(t, nostr.GeneratePrivateKey(), 1, nil)
+	if err := store.StoreEvent(note); err != nil {
+		t.Fatalf("StoreEvent: %v", err)
+	}
+	reportTags := nostr.Tags{{"e", note.ID, "spam"}, {"p", note.PubKey}}
+
+	// One reporter repeating themselves never moves the score
+	spammer := nostr.GeneratePrivateKey()
+	for i := 0; i < 5; i++ {
+		result, err := aggregator.Submit(signedEvent(t, spammer, 1984, reportTags), note, note.PubKey, "spam")
+		if err != nil {
+			t.Fatalf("Submit: %v", err)
+		}
+		if result.Counted != (i == 0) {
+			t.Fatalf("report %d: expected counted=%v, got %v", i, i == 0, result.Counted)
+		}
+		if result.Score != 1 {
+			t.Fatalf("report %d: expected score 1, got %v", i, result.Score)
+		}
+	}
+
+	result, err := aggregator.Submit(signedEvent(t, nostr.GeneratePrivateKey(), 1984, reportTags), note, note.PubKey, "spam")
+	if err != nil {
+		t.Fatalf("Submit: %v", err)
+	}
+	if result.EventAction != ActionNone {
+		t.Fatalf("expected no action below threshold, got %q", result.EventAction)
+	}
+
+	result, err = aggregator.Submit(signedEvent(t, nostr.GeneratePrivateKey(), 1984, reportTags), note, note.PubKey, "spam")
+	if err != nil {
+		t.Fatalf("Submit: %v", err)
+	}
+	if result.EventAction != ActionPending {
+		t.Fatalf("expected %q at threshold, got %q", ActionPending, result.EventAction)
+	}
+	if pending, _ := store.IsPendingModeration(note.ID); !pending {
+		t.Fatalf("expected reported event to be pending moderation")
+	}
+	notifications, _, err := store.GetStatsStore().GetUserModerationNotifications(note.PubKey, 1, 10)
+	if err != nil {
+		t.Fatalf("GetUserModerationNotifications: %v", err)
+	}
+	if len(notifications) != 1 || notifications[0].EventID != note.ID {
+		t.Fatalf("expected one moderation notification for the reported event, got %+v", notifications)
+	}
+}
+
+func TestSubmitWeightsPaidSubscribersAndPubkeyReports(t *testing.T) {
+	aggregator, store := setupAggregator(t)
+
+	authorKey := nostr.GeneratePrivateKey()
+	note := signedEvent(t, authorKey, 1, nil)
+	if err := store.StoreEvent(note); err != nil {
+		t.Fatalf("StoreEvent: %v", err)
+	}
+
+	subscriberKey := nostr.GeneratePrivateKey()
+	subscriber, _ := nostr.GetPublicKey(subscriberKey)
+	if err := store.GetStatsStore().SavePaidSubscriber(&types.PaidSubscriber{
+		Npub:           subscriber,
+		Tier:           "basic",
+		ExpirationDate: time.Now().Add(24 * time.Hour),
+	}); err != nil {
+		t.Fatalf("SavePaidSubscriber: %v", err)
+	}
+
+	result, err := aggregator.Submit(signedEvent(t, subscriberKey, 1984, nostr.Tags{{"e", note.ID, "illegal"}}), note, note.PubKey, "illegal")
+	if err != nil {
+		t.Fatalf("Submit: %v", err)
+	}
+	if result.Weight != 3 {
+		t.Fatalf("expected paid subscriber weight 3, got %v", result.Weight)
+	}
+
+	// A pubkey-only report counts toward the pubkey threshold but not the event score
+	result, err = aggregator.Submit(signedEvent(t, nostr.GeneratePrivateKey(), 1984, nostr.Tags{{"p", note.PubKey, "illegal"}}), nil, note.PubKey, "illegal")
+	if err != nil {
+		t.Fatalf("Submit: %v", err)
+	}
+	if result.EventAction != ActionNone {
+		t.Fatalf("pubkey reports must not act on events, got %q", result.EventAction)
+	}
+	if result.PubkeyScore != 4 || result.PubkeyAction != ActionNotify {
+		t.Fatalf("expected pubkey score 4 with %q, got %v with %q", ActionNotify, result.PubkeyScore, result.PubkeyAction)
+	}
+
+	result, err = aggregator.Submit(signedEvent(t, nostr.GeneratePrivateKey(), 1984, nostr.Tags{{"e", note.ID, "illegal"}}), note, note.PubKey, "illegal")
+	if err != nil {
+		t.Fatalf("Submit: %v", err)
+	}
+	if result.EventAction != ActionBlock {
+		t.Fatalf("expected %q at threshold, got %q", ActionBlock, result.EventAction)
+	}
+	if blocked, _ := store.IsEventBlocked(note.ID); !blocked {
+		t.Fatalf("expected reported event to be blocked")
+	}
+}
+
+func TestSubmitCountsEachReportTypeOnce(t *testing.T) {
+	aggregator, store := setupAggregator(t)
+
+	note := signedEvent(t, nostr.GeneratePrivateKey(), 1, nil)
+	if err := store.StoreEvent(note); err != nil {
+		t.Fatalf("StoreEvent: %v", err)
+	}
+
+	// The same reporter may report an event as several types, each scored on its own
+	reporter := nostr.GeneratePrivateKey()
+	for i, reportType := range []string{"spam", "illegal", "spam"} {
+		result, err := aggregator.Submit(signedEvent(t, reporter, 1984, nostr.Tags{{"e", note.ID, reportType}}), note, note.PubKey, reportType)
+		if err != nil {
+			t.Fatalf("Submit: %v", err)
+		}
+		if result.Score != 1 {
+			t.Fatalf("expected %s score 1, got %v", reportType, result.Score)
+		}
+		// Only the first report is a new reporter for the event's report count
+		if result.NewReporter != (i == 0) {
+			t.Fatalf("report %d: expected NewReporter %v, got %v", i, i == 0, result.NewReporter)
+		}
+		// The reporter still counts once towards the pubkey
+		if result.PubkeyScore != 1 {
+			t.Fatalf("expected pubkey score 1, got %v", result.PubkeyScore)
+		}
+	}
+}
+
+// failingBlockStore fails to block events without recording anything, for the
+// first failures calls or forever when failures is negative
+type failingBlockStore struct {
+	stores.Store
+	failures int
+}
+
+func (s *failingBlockStore) MarkEventBlockedWithDetails(eventID string, timestamp int64, reason string, contentLevel int, mediaURL string) error {
+	if s.failures == 0 {
+		return s.Store.MarkEventBlockedWithDetails(eventID, timestamp, reason, contentLevel, mediaURL)
+	}
+	s.failures--
+	return fmt.Errorf("database unavailable")
+}
+
+func TestSubmitReportsFailedBlocks(t *testing.T) {
+	_, store := setupAggregator(t)
+	aggregator := NewAggregator(&failingBlockStore{Store: store, failures: -1}, nil)
+
+	note := signedEvent(t, nostr.GeneratePrivateKey(), 1, nil)
+	if err := store.StoreEvent(note); err != nil {
+		t.Fatalf("StoreEvent: %v", err)
+	}
+
+	var result *Result
+	var err error
+	for i := 0; i < 4 && err == nil; i++ {
+		result, err = aggregator.Submit(signedEvent(t, nostr.GeneratePrivateKey(), 1984, nostr.Tags{{"e", note.ID, "illegal"}}), note, note.PubKey, "illegal")
+	}
+	if err == nil {
+		t.Fatalf("expected the failed block to be reported, got %+v", result)
+	}
+	if blocked, _ := store.IsEventBlocked(note.ID); blocked {
+		t.Fatalf("expected the event not to be blocked")
+	}
+	notifications, _, _ := store.GetStatsStore().GetUserModerationNotifications(note.PubKey, 1, 10)
+	if len(notifications) != 0 {
+		t.Fatalf("expected no moderation notification for a failed block, got %+v", notifications)
+	}
+}
+
+func TestSubmitRetriesFailedBlocks(t *testing.T) {
+	_, store := setupAggregator(t)
+	aggregator := NewAggregator(&failingBlockStore{Store: store, failures: 1}, nil)
+
+	note := signedEvent(t, nostr.GeneratePrivateKey(), 1, nil)
+	if err := store.StoreEvent(note); err != nil {
+		t.Fatalf("StoreEvent: %v", err)
+	}
+	submit := func() (*Result, error) {
+		return aggregator.Submit(signedEvent(t, nostr.GeneratePrivateKey(), 1984, nostr.Tags{{"e", note.ID, "illegal"}}), note, note.PubKey, "illegal")
+	}
+
+	for i := 0; i < 3; i++ {
+		if _, err := submit(); err != nil {
+			t.Fatalf("Submit: %v", err)
+		}
+	}
+	// The report that crosses the threshold fails to block the event
+	if _, err := submit(); err == nil {
+		t.Fatalf("expected the failed block to be reported")
+	}
+
+	// The next report above the threshold tries again
+	result, err := submit()
+	if err != nil {
+		t.Fatalf("Submit: %v", err)
+	}
+	if result.EventAction != ActionBlock {
+		t.Fatalf("expected the retried block, got %+v", result)
+	}
+	if blocked, _ := store.IsEventBlocked(note.ID); !blocked {
+		t.Fatalf("expected the event to be blocked")
+	}
+
+	// Once recorded, the action isn't taken again
+	result, err = submit()
+	if err != nil {
+		t.Fatalf("Submit: %v", err)
+	}
+	if result.EventAction != ActionNone {
+		t.Fatalf("expected no further action, got %s", result.EventAction)
+	}
+}
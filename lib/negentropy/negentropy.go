@@ -0,0 +1,371 @@
+// Package negentropy implements version 1 of the negentropy range-based set
+// reconciliation protocol used by NIP-77 (NEG-OPEN / NEG-MSG). The same type runs
+// either side: the initiator learns which ids it has or needs, the responder
+// answers with fingerprints and id lists.
+package negentropy
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+)
+
+const (
+	// ProtocolVersion is the first byte of every message
+	ProtocolVersion = 0x61
+
+	IDSize          = 32
+	FingerprintSize = 16
+
+	modeSkip        = 0
+	modeFingerprint = 1
+	modeIDList      = 2
+
+	// Ranges are split into this many buckets until they are small enough to list
+	buckets = 16
+
+	maxTimestamp = math.MaxUint64
+)
+
+// ID is a raw 32-byte event id
+type ID [IDSize]byte
+
+// Item is one element of the set, ordered by timestamp then id
+type Item struct {
+	Timestamp uint64
+	ID        ID
+}
+
+func compareItems(a, b Item) int {
+	if a.Timestamp != b.Timestamp {
+		if a.Timestamp < b.Timestamp {
+			return -1
+		}
+		return 1
+	}
+	return bytes.Compare(a.ID[:], b.ID[:])
+}
+
+// bound is an exclusive range limit: a timestamp and the shortest id prefix that separates two items
+type bound struct {
+	timestamp uint64
+	idPrefix  []byte
+}
+
+func (b bound) item() Item {
+	item := Item{Timestamp: b.timestamp}
+	copy(item.ID[:], b.idPrefix)
+	return item
+}
+
+// Negentropy reconciles a local set of items against a remote one
+type Negentropy struct {
+	items     []Item
+	initiator bool
+
+	lastTimestampIn  uint64
+	lastTimestampOut uint64
+}
+
+// New creates a reconciler over a copy of items
+func New(items []Item) *Negentropy {
+	sorted := append([]Item(nil), items...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return compareItems(sorted[i], sorted[j]) < 0
+	})
+	return &Negentropy{items: sorted}
+}
+
+// Initiate returns the first message of a reconciliation and makes this side the initiator
+func (n *Negentropy) Initiate() []byte {
+	n.initiator = true
+	n.lastTimestampOut = 0
+	return n.splitRange([]byte{ProtocolVersion}, 0, len(n.items), bound{timestamp: maxTimestamp})
+}
+
+// Reconcile processes a message from the other side. For the initiator it returns
+// the ids only it has and the ids only the remote has, and a nil response once
+// reconciliation is complete. For the responder have and need are always empty.
+func (n *Negentropy) Reconcile(query []byte) (response []byte, have []ID, need []ID, err error) {
+	if len(query) == 0 {
+		return nil, nil, nil, errors.New("negentropy: empty message")
+	}
+	if query[0] != ProtocolVersion {
+		return nil, nil, nil, fmt.Errorf("negentropy: unsupported protocol version 0x%x", query[0])
+	}
+
+	n.lastTimestampIn, n.lastTimestampOut = 0, 0
+	r := &reader{data: query[1:]}
+	out := []byte{ProtocolVersion}
+
+	prevBound := bound{}
+	prevIndex := 0
+	skip := false
+
+	// Consecutive matching ranges are sent as a single skip before the next real range
+	doSkip := func() {
+		if skip {
+			skip = false
+			out = n.encodeBound(out, prevBound)
+			out = appendVarint(out, modeSkip)
+		}
+	}
+
+	for len(r.data) > 0 {
+		currBound, err := n.decodeBound(r)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		mode, err := r.varint()
+		if err != nil {
+			return nil, nil, nil, err
+		}
+
+		lower := prevIndex
+		upper := n.findLowerBound(prevIndex, currBound)
+
+		switch mode {
+		case modeSkip:
+			skip = true
+
+		case modeFingerprint:
+			theirs, err := r.bytes(FingerprintSize)
+			if err != nil {
+				return nil, nil, nil, err
+			}
+			if !bytes.Equal(theirs, n.fingerprint(lower, upper)) {
+				doSkip()
+				out = n.splitRange(out, lower, upper, currBound)
+			} else {
+				skip = true
+			}
+
+		case modeIDList:
+			count, err := r.varint()
+			if err != nil {
+				return nil, nil, nil, err
+			}
+			theirs := make(map[ID]struct{}, count)
+			for i := uint64(0); i < count; i++ {
+				raw, err := r.bytes(IDSize)
+				if err != nil {
+					return nil, nil, nil, err
+				}
+				var id ID
+				copy(id[:], raw)
+				theirs[id] = struct{}{}
+			}
+
+			for _, item := range n.items[lower:upper] {
+				if _, ok := theirs[item.ID]; ok {
+					delete(theirs, item.ID)
+				} else if n.initiator {
+					have = append(have, item.ID)
+				}
+			}
+
+			if n.initiator {
+				skip = true
+				for id := range theirs {
+					need = append(need, id)
+				}
+			} else {
+				doSkip()
+				out = n.encodeBound(out, currBound)
+				out = appendVarint(out, modeIDList)
+				out = appendVarint(out, uint64(upper-lower))
+				for _, item := range n.items[lower:upper] {
+					out = append(out, item.ID[:]...)
+				}
+			}
+
+		default:
+			return nil, nil, nil, fmt.Errorf("negentropy: unexpected mode %d", mode)
+		}
+
+		prevIndex = upper
+		prevBound = currBound
+	}
+
+	if n.initiator && len(out) == 1 {
+		return nil, have, need, nil
+	}
+	return out, have, need, nil
+}
+
+// splitRange lists the ids in a small range, or fingerprints it in buckets otherwise
+func (n *Negentropy) splitRange(out []byte, lower int, upper int, upperBound bound) []byte {
+	numElems := upper - lower
+
+	if numElems < buckets*2 {
+		out = n.encodeBound(out, upperBound)
+		out = appendVarint(out, modeIDList)
+		out = appendVarint(out, uint64(numElems))
+		for _, item := range n.items[lower:upper] {
+			out = append(out, item.ID[:]...)
+		}
+		return out
+	}
+
+	itemsPerBucket := numElems / buckets
+	bucketsWithExtra := numElems % buckets
+	curr := lower
+
+	for i := 0; i < buckets; i++ {
+		bucketSize := itemsPerBucket
+		if i < bucketsWithExtra {
+			bucketSize++
+		}
+		fingerprint := n.fingerprint(curr, curr+bucketSize)
+		curr += bucketSize
+
+		nextBound := upperBound
+		if curr != upper {
+			nextBound = minimalBound(n.items[curr-1], n.items[curr])
+		}
+
+		out = n.encodeBound(out, nextBound)
+		out = appendVarint(out, modeFingerprint)
+		out = append(out, fingerprint...)
+	}
+
+	return out
+}
+
+// findLowerBound returns the index of the first item at or after start that is not below b
+func (n *Negentropy) findLowerBound(start int, b bound) int {
+	limit := b.item()
+	return start + sort.Search(len(n.items)-start, func(i int) bool {
+		return compareItems(n.items[start+i], limit) >= 0
+	})
+}
+
+// fingerprint hashes the 256-bit little-endian sum of the ids in the range with their count
+func (n *Negentropy) fingerprint(lower int, upper int) []byte {
+	var sum [IDSize]byte
+	for _, item := range n.items[lower:upper] {
+		carry := uint16(0)
+		for i := 0; i < IDSize; i++ {
+			total := uint16(sum[i]) + uint16(item.ID[i]) + carry
+			sum[i] = byte(total)
+			carry = total >> 8
+		}
+	}
+
+	hash := sha256.Sum256(appendVarint(sum[:], uint64(upper-lower)))
+	return hash[:FingerprintSize]
+}
+
+func minimalBound(prev Item, curr Item) bound {
+	if curr.Timestamp != prev.Timestamp {
+		return bound{timestamp: curr.Timestamp}
+	}
+
+	shared := 0
+	for shared < IDSize && curr.ID[shared] == prev.ID[shared] {
+		shared++
+	}
+	prefix := make([]byte, shared+1)
+	copy(prefix, curr.ID[:shared+1])
+	return bound{timestamp: curr.Timestamp, idPrefix: prefix}
+}
+
+// encodeBound writes the timestamp as a delta from the previous bound in this message
+func (n *Negentropy) encodeBound(out []byte, b bound) []byte {
+	if b.timestamp == maxTimestamp {
+		n.lastTimestampOut = maxTimestamp
+		out = appendVarint(out, 0)
+	} else {
+		delta := b.timestamp - n.lastTimestampOut
+		n.lastTimestampOut = b.timestamp
+		out = appendVarint(out, delta+1)
+	}
+
+	out = appendVarint(out, uint64(len(b.idPrefix)))
+	return append(out, b.idPrefix...)
+}
+
+func (n *Negentropy) decodeBound(r *reader) (bound, error) {
+	encoded, err := r.varint()
+	if err != nil {
+		return bound{}, err
+	}
+
+	timestamp := uint64(maxTimestamp)
+	if encoded != 0 {
+		timestamp = encoded - 1
+	}
+	if n.lastTimestampIn == maxTimestamp || timestamp == maxTimestamp {
+		timestamp = maxTimestamp
+	} else {
+		timestamp += n.lastTimestampIn
+	}
+	n.lastTimestampIn = timestamp
+
+	length, err := r.varint()
+	if err != nil {
+		return bound{}, err
+	}
+	if length > IDSize {
+		return bound{}, errors.New("negentropy: bound id prefix too long")
+	}
+	prefix, err := r.bytes(int(length))
+	if err != nil {
+		return bound{}, err
+	}
+
+	return bound{timestamp: timestamp, idPrefix: append([]byte(nil), prefix...)}, nil
+}
+
+// appendVarint writes n in base 128, most significant group first, with the
+// high bit set on every byte but the last
+func appendVarint(out []byte, n uint64) []byte {
+	if n == 0 {
+		return append(out, 0)
+	}
+
+	var groups []byte
+	for n > 0 {
+		groups = append(groups, byte(n&0x7f))
+		n >>= 7
+	}
+	for i := len(groups) - 1; i >= 0; i-- {
+		b := groups[i]
+		if i > 0 {
+			b |= 0x80
+		}
+		out = append(out, b)
+	}
+	return out
+}
+
+type reader struct {
+	data []byte
+}
+
+func (r *reader) varint() (uint64, error) {
+	var n uint64
+	for {
+		if len(r.data) == 0 {
+			return 0, errors.New("negentropy: truncated varint")
+		}
+		b := r.data[0]
+		r.data = r.data[1:]
+		n = (n << 7) | uint64(b&0x7f)
+		if b&0x80 == 0 {
+			return n, nil
+		}
+	}
+}
+
+func (r *reader) bytes(count int) ([]byte, error) {
+	if len(r.data) < count {
+		return nil, errors.New("negentropy: truncated message")
+	}
+	out := r.data[:count]
+	r.data = r.data[count:]
+	return out, nil
+}
@@ -0,0 +1,118 @@
+package negentropy
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"testing"
+)
+
+func testItem(seed int, timestamp uint64) Item {
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], uint64(seed))
+	return Item{Timestamp: timestamp, ID: sha256.Sum256(buf[:])}
+}
+
+func TestVarint(t *testing.T) {
+	cases := map[uint64][]byte{
+		0:   {0x00},
+		127: {0x7f},
+		128: {0x81, 0x00},
+		300: {0x82, 0x2c},
+	}
+	for n, expected := range cases {
+		encoded := appendVarint(nil, n)
+		if !bytes.Equal(encoded, expected) {
+			t.Errorf("varint %d: expected %x, got %x", n, expected, encoded)
+		}
+		decoded, err := (&reader{data: encoded}).varint()
+		if err != nil || decoded != n {
+			t.Errorf("varint %x: expected %d, got %d (%v)", encoded, n, decoded, err)
+		}
+	}
+}
+
+func TestReconcile(t *testing.T) {
+	var shared, clientOnly, serverOnly []Item
+	for i := 0; i < 2000; i++ {
+		// Many items share a timestamp so bounds need id prefixes
+		item := testItem(i, uint64(1700000000+i/7))
+		switch {
+		case i%41 == 0:
+			clientOnly = append(clientOnly, item)
+		case i%29 == 0:
+			serverOnly = append(serverOnly, item)
+		default:
+			shared = append(shared, item)
+		}
+	}
+
+	client := New(append(append([]Item{}, shared...), clientOnly...))
+	server := New(append(append([]Item{}, shared...), serverOnly...))
+
+	have := map[ID]bool{}
+	need := map[ID]bool{}
+
+	message := client.Initiate()
+	for rounds := 0; message != nil; rounds++ {
+		if rounds > 20 {
+			t.Fatal("reconciliation did not converge")
+		}
+
+		response, _, _, err := server.Reconcile(message)
+		if err != nil {
+			t.Fatalf("server Reconcile: %v", err)
+		}
+
+		next, haveIDs, needIDs, err := client.Reconcile(response)
+		if err != nil {
+			t.Fatalf("client Reconcile: %v", err)
+		}
+		for _, id := range haveIDs {
+			have[id] = true
+		}
+		for _, id := range needIDs {
+			need[id] = true
+		}
+		message = next
+	}
+
+	if len(have) != len(clientOnly) || len(need) != len(serverOnly) {
+		t.Fatalf("expected %d have and %d need, got %d and %d", len(clientOnly), len(serverOnly), len(have), len(need))
+	}
+	for _, item := range clientOnly {
+		if !have[item.ID] {
+			t.Errorf("missing have id %x", item.ID)
+		}
+	}
+	for _, item := range serverOnly {
+		if !need[item.ID] {
+			t.Errorf("missing need id %x", item.ID)
+		}
+	}
+}
+
+func TestReconcileIdenticalSets(t *testing.T) {
+	var items []Item
+	for i := 0; i < 500; i++ {
+		items = append(items, testItem(i, uint64(1700000000+i)))
+	}
+
+	client := New(items)
+	server := New(items)
+
+	response, _, _, err := server.Reconcile(client.Initiate())
+	if err != nil {
+		t.Fatalf("server Reconcile: %v", err)
+	}
+	next, have, need, err := client.Reconcile(response)
+	if err != nil || next != nil || len(have) != 0 || len(need) != 0 {
+		t.Fatalf("expected identical sets to finish in one round, got %d bytes, %d have, %d need (%v)", len(next), len(have), len(need), err)
+	}
+}
+
+func TestRejectsUnknownVersion(t *testing.T) {
+	if _, _, _, err := New(nil).Reconcile([]byte{0x62}); err == nil {
+		t.Error("expected an unsupported protocol version to be rejected")
+	}
+}
@@ -183,6 +183,41 @@ func (store *BadgerholdStore) IsClosed() bool {
 	return store.closed
 }
 
+// healthProbeKey is written and read back by Probe; it holds nothing else
+const healthProbeKey = "_health:probe"
+
+// Probe writes a value to the database and reads it back, so health checks notice a
+// store that is closed, read-only or out of disk before clients do.
+func (store *BadgerholdStore) Probe() error {
+	if store.IsClosed() {
+		return fmt.Errorf("store is closed")
+	}
+
+	db := store.Database.Badger()
+	value := []byte(time.Now().UTC().Format(time.RFC3339Nano))
+	if err := db.Update(func(txn *badger.Txn) error {
+		return txn.Set([]byte(healthProbeKey), value)
+	}); err != nil {
+		return fmt.Errorf("write probe failed: %w", err)
+	}
+
+	var read []byte
+	if err := db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get([]byte(healthProbeKey))
+		if err != nil {
+			return err
+		}
+		read, err = item.ValueCopy(nil)
+		return err
+	}); err != nil {
+		return fmt.Errorf("read probe failed: %w", err)
+	}
+	if string(read) != string(value) {
+		return fmt.Errorf("read probe returned a different value than was written")
+	}
+	return nil
+}
+
 // RunGC runs garbage collection on demand. This should be called during bulk
 // write operations to prevent disk space from growing unbounded.
 // It runs with aggressive settings (lower discard ratio) to reclaim space quickly.
@@ -0,0 +1,77 @@
+package badgerhold
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/HORNET-Storage/hornet-storage/lib/types"
+	"github.com/timshannon/badgerhold/v4"
+)
+
+// SaveMirrorDelivery inserts or updates a queued outbound delivery
+func (store *BadgerholdStore) SaveMirrorDelivery(delivery *types.MirrorDelivery) error {
+	// Key format: "mirror:{eventID}:{relay}" so an event is queued once per relay
+	key := fmt.Sprintf("mirror:%s", delivery.ID)
+	return store.Database.Upsert(key, *delivery)
+}
+
+// GetDueMirrorDeliveries returns pending deliveries whose next attempt is at or before now, oldest first
+func (store *BadgerholdStore) GetDueMirrorDeliveries(now time.Time, limit int) ([]types.MirrorDelivery, error) {
+	var results []types.MirrorDelivery
+
+	query := badgerhold.Where("Status").Eq(types.MirrorStatusPending).
+		And("NextAttempt").Le(now).
+		SortBy("NextAttempt")
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+
+	err := store.Database.Find(&results, query)
+	if err != nil && err != badgerhold.ErrNotFound {
+		return nil, fmt.Errorf("failed to query due mirror deliveries: %w", err)
+	}
+
+	return results, nil
+}
+
+// ListMirrorDeliveries returns the most recently updated deliveries, optionally filtered by status
+func (store *BadgerholdStore) ListMirrorDeliveries(status string, limit int) ([]types.MirrorDelivery, error) {
+	var results []types.MirrorDelivery
+
+	query := badgerhold.Where("ID").Ne("")
+	if status != "" {
+		query = query.And("Status").Eq(status)
+	}
+	query = query.SortBy("UpdatedAt").Reverse()
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+
+	err := store.Database.Find(&results, query)
+	if err != nil && err != badgerhold.ErrNotFound {
+		return nil, fmt.Errorf("failed to query mirror deliveries: %w", err)
+	}
+
+	return results, nil
+}
+
+// DeleteFinishedMirrorDeliveries removes delivered and failed deliveries last updated before the cutoff
+func (store *BadgerholdStore) DeleteFinishedMirrorDeliveries(before time.Time) (int, error) {
+	var finished []types.MirrorDelivery
+
+	err := store.Database.Find(&finished, badgerhold.Where("Status").Ne(types.MirrorStatusPending).And("UpdatedAt").Lt(before))
+	if err != nil && err != badgerhold.ErrNotFound {
+		return 0, fmt.Errorf("failed to query finished mirror deliveries: %w", err)
+	}
+
+	deleted := 0
+	for _, delivery := range finished {
+		key := fmt.Sprintf("mirror:%s", delivery.ID)
+		if err := store.Database.Delete(key, types.MirrorDelivery{}); err != nil {
+			return deleted, fmt.Errorf("failed to delete mirror delivery %s: %w", delivery.ID, err)
+		}
+		deleted++
+	}
+
+	return deleted, nil
+}
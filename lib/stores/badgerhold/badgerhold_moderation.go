@@ -64,6 +64,20 @@ func (store *BadgerholdStore) GetPendingModerationEvents() ([]lib.PendingModerat
 	return results, nil
 }
 
+// CountPendingModeration returns how many events and disputes are waiting for the moderation worker
+func (store *BadgerholdStore) CountPendingModeration() (events uint64, disputes uint64, err error) {
+	events, err = store.Database.Count(&lib.PendingModeration{}, badgerhold.Where("EventID").Ne(""))
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to count pending moderation events: %w", err)
+	}
+
+	disputes, err = store.Database.Count(&lib.PendingDisputeModeration{}, badgerhold.Where("DisputeID").Ne(""))
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to count pending dispute moderation events: %w", err)
+	}
+	return events, disputes, nil
+}
+
 // GetAndRemovePendingModeration atomically gets and removes pending moderation events up to the batch size.
 // This method provides race-condition-free event processing by ensuring each event is only processed once.
 // It's designed to solve the problem of duplicate event processing in concurrent environments.
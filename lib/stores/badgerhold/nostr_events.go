@@ -12,6 +12,7 @@ import (
 
 	"github.com/HORNET-Storage/hornet-storage/lib/config"
 	"github.com/HORNET-Storage/hornet-storage/lib/logging"
+	"github.com/HORNET-Storage/hornet-storage/lib/stores"
 	"github.com/HORNET-Storage/hornet-storage/lib/transports/websocket"
 )
 
@@ -39,7 +40,7 @@ const (
 	schemaVersionKey     = "_schema:version"
 	currentSchemaVersion = 2
 
-	defaultMaxLimit = 500
+	defaultMaxLimit = stores.DefaultMaxLimit
 )
 
 // storedEvent is the CBOR value stored at evt:{id}.
@@ -0,0 +1,44 @@
+package stores
+
+import (
+	"github.com/nbd-wtf/go-nostr"
+)
+
+// QueryAllEvents returns every event matching the filter, paging back through
+// created_at with Until instead of stopping at the store's DefaultMaxLimit cap.
+// The filter's own Limit and Until are replaced.
+func QueryAllEvents(store Store, filter nostr.Filter) ([]*nostr.Event, error) {
+	seen := make(map[string]bool)
+	var all []*nostr.Event
+	var until *nostr.Timestamp
+
+	for {
+		filter.Until = until
+		filter.Limit = DefaultMaxLimit
+
+		events, err := store.QueryEvents(filter)
+		if err != nil {
+			return all, err
+		}
+
+		// Until is inclusive, so events sharing the oldest timestamp come back again
+		newEvents := 0
+		for _, event := range events {
+			if seen[event.ID] {
+				continue
+			}
+			seen[event.ID] = true
+			newEvents++
+			all = append(all, event)
+
+			if until == nil || event.CreatedAt < *until {
+				createdAt := event.CreatedAt
+				until = &createdAt
+			}
+		}
+
+		if newEvents == 0 || len(events) < DefaultMaxLimit {
+			return all, nil
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package gorm
+
+import (
+	"time"
+
+	"github.com/HORNET-Storage/hornet-storage/lib/config"
+	"github.com/HORNET-Storage/hornet-storage/lib/types"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// CreateInviteCode stores a new invite code
+func (store *GormStatisticsStore) CreateInviteCode(invite *types.InviteCode) error {
+	return store.DB.Create(invite).Error
+}
+
+// GetInviteCode finds an invite code, returning nil if it does not exist
+func (store *GormStatisticsStore) GetInviteCode(code string) (*types.InviteCode, error) {
+	var invite types.InviteCode
+	if err := store.DB.Where("code = ?", code).First(&invite).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &invite, nil
+}
+
+// ListInviteCodes returns every invite code, newest first
+func (store *GormStatisticsStore) ListInviteCodes() ([]types.InviteCode, error) {
+	var invites []types.InviteCode
+	err := store.DB.Order("created_at DESC").Find(&invites).Error
+	return invites, err
+}
+
+// RevokeInviteCode stops a code from being redeemed. Existing grants are left in place.
+func (store *GormStatisticsStore) RevokeInviteCode(code string) error {
+	result := store.DB.Model(&types.InviteCode{}).Where("code = ?", code).Update("revoked", true)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return types.ErrInviteNotFound
+	}
+	return nil
+}
+
+// RedeemInviteCode consumes one use of code for npub and grants the code's tier.
+// The use count is claimed with a conditional update, so concurrent redemptions
+// can never exceed MaxUses. An invite only ever widens an active grant: a higher
+// tier, write access or a later expiry are taken from the invite, anything the
+// redeemer already holds beyond that, including a permanent grant, is kept.
+func (store *GormStatisticsStore) RedeemInviteCode(code string, npub string, now time.Time) (*types.AllowedUser, error) {
+	var granted types.AllowedUser
+
+	err := store.DB.Transaction(func(tx *gorm.DB) error {
+		var invite types.InviteCode
+		if err := tx.Where("code = ?", code).First(&invite).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return types.ErrInviteNotFound
+			}
+			return err
+		}
+
+		switch {
+		case invite.Revoked:
+			return types.ErrInviteRevoked
+		case invite.ExpiresAt != nil && !now.Before(*invite.ExpiresAt):
+			return types.ErrInviteExpired
+		}
+
+		var redeemed int64
+		if err := tx.Model(&types.InviteRedemption{}).Where("code = ? AND npub = ?", code, npub).Count(&redeemed).Error; err != nil {
+			return err
+		}
+		if redeemed > 0 {
+			return types.ErrInviteAlreadyRedeemed
+		}
+
+		claim := tx.Model(&types.InviteCode{}).
+			Where("id = ? AND (max_uses = 0 OR uses < max_uses)", invite.ID).
+			Update("uses", gorm.Expr("uses + 1"))
+		if claim.Error != nil {
+			return claim.Error
+		}
+		if claim.RowsAffected == 0 {
+			return types.ErrInviteExhausted
+		}
+
+		if err := tx.Create(&types.InviteRedemption{Code: code, Npub: npub, RedeemedAt: now}).Error; err != nil {
+			return err
+		}
+
+		var expiresAt *time.Time
+		if invite.GrantDuration > 0 {
+			expiry := now.Add(time.Duration(invite.GrantDuration) * time.Second)
+			expiresAt = &expiry
+		}
+
+		granted = types.AllowedUser{
+			Npub:      npub,
+			Tier:      invite.Tier,
+			ReadOnly:  invite.ReadOnly,
+			CreatedBy: "invite:" + code,
+			ExpiresAt: expiresAt,
+		}
+
+		var existing types.AllowedUser
+		err := tx.Where("npub = ?", npub).First(&existing).Error
+		if err != nil && err != gorm.ErrRecordNotFound {
+			return err
+		}
+		if err == nil && !existing.Expired(now) {
+			if existing.Tier != "" && inviteTierRank(existing.Tier) >= inviteTierRank(invite.Tier) {
+				granted.Tier = existing.Tier
+			}
+			granted.ReadOnly = existing.ReadOnly && invite.ReadOnly
+			granted.CreatedBy = existing.CreatedBy
+
+			if existing.ExpiresAt == nil {
+				granted.ExpiresAt = nil
+			} else if expiresAt != nil && existing.ExpiresAt.After(*expiresAt) {
+				granted.ExpiresAt = existing.ExpiresAt
+			}
+		}
+
+		return tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "npub"}},
+			DoUpdates: clause.AssignmentColumns([]string{"tier", "read_only", "created_by", "expires_at"}),
+		}).Create(&granted).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &granted, nil
+}
+
+// inviteTierRank ranks a tier among the configured tiers, -1 for a tier that is not configured
+func inviteTierRank(name string) int64 {
+	cfg, err := config.GetConfig()
+	if err != nil {
+		return -1
+	}
+	return types.TierRank(cfg.AllowedUsersSettings.Tiers, name)
+}
@@ -0,0 +1,123 @@
+package gorm
+
+import (
+	"time"
+
+	"github.com/HORNET-Storage/hornet-storage/lib/types"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// CreateLightningInvoice records a newly issued Lightning invoice
+func (store *GormStatisticsStore) CreateLightningInvoice(invoice *types.LightningInvoice) error {
+	if invoice.Status == "" {
+		invoice.Status = types.LightningInvoicePending
+	}
+	return store.DB.Create(invoice).Error
+}
+
+// GetLightningInvoice finds an invoice by payment hash
+func (store *GormStatisticsStore) GetLightningInvoice(paymentHash string) (*types.LightningInvoice, error) {
+	var invoice types.LightningInvoice
+	if err := store.DB.Where("payment_hash = ?", paymentHash).First(&invoice).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil // No invoice found, not an error
+		}
+		return nil, err
+	}
+	return &invoice, nil
+}
+
+// GetPendingLightningInvoices retrieves all invoices that are still awaiting payment
+func (store *GormStatisticsStore) GetPendingLightningInvoices() ([]types.LightningInvoice, error) {
+	var invoices []types.LightningInvoice
+	err := store.DB.Where("status = ?", types.LightningInvoicePending).
+		Order("created_at ASC").
+		Find(&invoices).Error
+	return invoices, err
+}
+
+// GetLatestPendingLightningInvoice retrieves the most recent unexpired pending invoice for a subscriber
+func (store *GormStatisticsStore) GetLatestPendingLightningInvoice(npub string) (*types.LightningInvoice, error) {
+	var invoice types.LightningInvoice
+	err := store.DB.Where("npub = ? AND status = ? AND expires_at > ?", npub, types.LightningInvoicePending, time.Now()).
+		Order("created_at DESC").
+		First(&invoice).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &invoice, nil
+}
+
+// ClaimLightningInvoice moves a pending invoice to crediting, recording the amount paid.
+// Returns false if the invoice was not pending, so callers credit each payment exactly once.
+func (store *GormStatisticsStore) ClaimLightningInvoice(paymentHash string, amountSats int64, settledAt time.Time) (bool, error) {
+	result := store.DB.Model(&types.LightningInvoice{}).
+		Where("payment_hash = ? AND status = ?", paymentHash, types.LightningInvoicePending).
+		Updates(map[string]interface{}{
+			"status":      types.LightningInvoiceCrediting,
+			"amount_sats": amountSats,
+			"settled_at":  settledAt,
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// MarkLightningInvoiceSettled moves a claimed invoice to settled once it has been credited
+func (store *GormStatisticsStore) MarkLightningInvoiceSettled(paymentHash string) error {
+	return store.DB.Model(&types.LightningInvoice{}).
+		Where("payment_hash = ? AND status = ?", paymentHash, types.LightningInvoiceCrediting).
+		Update("status", types.LightningInvoiceSettled).Error
+}
+
+// ReleaseLightningInvoice moves a claimed invoice back to pending, undoing
+// ClaimLightningInvoice when crediting the payment failed
+func (store *GormStatisticsStore) ReleaseLightningInvoice(paymentHash string) error {
+	return store.DB.Model(&types.LightningInvoice{}).
+		Where("payment_hash = ? AND status = ?", paymentHash, types.LightningInvoiceCrediting).
+		Updates(map[string]interface{}{
+			"status":     types.LightningInvoicePending,
+			"settled_at": nil,
+		}).Error
+}
+
+// GetCreditingLightningInvoices retrieves invoices that were claimed but never marked settled,
+// such as those interrupted by a restart while being credited
+func (store *GormStatisticsStore) GetCreditingLightningInvoices() ([]types.LightningInvoice, error) {
+	var invoices []types.LightningInvoice
+	err := store.DB.Where("status = ?", types.LightningInvoiceCrediting).
+		Order("created_at ASC").
+		Find(&invoices).Error
+	return invoices, err
+}
+
+// ExpireLightningInvoices marks pending invoices that expired before the given time
+func (store *GormStatisticsStore) ExpireLightningInvoices(before time.Time) (int64, error) {
+	result := store.DB.Model(&types.LightningInvoice{}).
+		Where("status = ? AND expires_at < ?", types.LightningInvoicePending, before).
+		Update("status", types.LightningInvoiceExpired)
+	return result.RowsAffected, result.Error
+}
+
+// RecordLightningPayment records a payment that was not issued by the relay, such as a zap.
+// Returns false if a record with the same payment hash already exists.
+func (store *GormStatisticsStore) RecordLightningPayment(invoice *types.LightningInvoice) (bool, error) {
+	result := store.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "payment_hash"}},
+		DoNothing: true,
+	}).Create(invoice)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// DeleteLightningInvoice removes an invoice record by payment hash
+func (store *GormStatisticsStore) DeleteLightningInvoice(paymentHash string) error {
+	return store.DB.Where("payment_hash = ?", paymentHash).Delete(&types.LightningInvoice{}).Error
+}
@@ -0,0 +1,22 @@
+package gorm
+
+import (
+	"github.com/HORNET-Storage/hornet-storage/lib/types"
+)
+
+// AddMediaHashBlock adds an image's hashes to the moderation blocklist
+func (store *GormStatisticsStore) AddMediaHashBlock(block *types.MediaHashBlock) error {
+	return store.DB.Create(block).Error
+}
+
+// GetMediaHashBlocks returns the whole blocklist, newest first
+func (store *GormStatisticsStore) GetMediaHashBlocks() ([]types.MediaHashBlock, error) {
+	var blocks []types.MediaHashBlock
+	err := store.DB.Order("created_at DESC").Find(&blocks).Error
+	return blocks, err
+}
+
+// DeleteMediaHashBlock removes an entry from the blocklist
+func (store *GormStatisticsStore) DeleteMediaHashBlock(id uint) error {
+	return store.DB.Delete(&types.MediaHashBlock{}, id).Error
+}
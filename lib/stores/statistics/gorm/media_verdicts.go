@@ -0,0 +1,67 @@
+package gorm
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/HORNET-Storage/hornet-storage/lib/types"
+)
+
+// GetMediaVerdict returns the cached verdict for a file's sha256, or nil if it hasn't been judged
+func (store *GormStatisticsStore) GetMediaVerdict(sha256 string) (*types.MediaVerdict, error) {
+	var verdict types.MediaVerdict
+	if err := store.DB.Where("sha256 = ?", sha256).First(&verdict).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &verdict, nil
+}
+
+// GetMediaVerdictByURL returns the cached verdict for the file a URL served, or nil if unknown
+func (store *GormStatisticsStore) GetMediaVerdictByURL(url string) (*types.MediaVerdict, error) {
+	var alias types.MediaVerdictURL
+	if err := store.DB.Where("url = ?", url).First(&alias).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return store.GetMediaVerdict(alias.SHA256)
+}
+
+// SaveMediaVerdict stores or replaces the verdict for a file
+func (store *GormStatisticsStore) SaveMediaVerdict(verdict *types.MediaVerdict) error {
+	return store.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "sha256"}},
+		DoUpdates: clause.AssignmentColumns([]string{"decision", "content_level", "confidence", "category", "explanation", "provider", "updated_at"}),
+	}).Create(verdict).Error
+}
+
+// SaveMediaVerdictURL records that a URL served the file with the given sha256
+func (store *GormStatisticsStore) SaveMediaVerdictURL(url string, sha256 string) error {
+	return store.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "url"}},
+		DoUpdates: clause.AssignmentColumns([]string{"sha256"}),
+	}).Create(&types.MediaVerdictURL{URL: url, SHA256: sha256}).Error
+}
+
+// PurgeMediaVerdicts removes ALLOW and FLAG verdicts last updated before the cutoff, along
+// with URLs pointing at them. BLOCK verdicts are kept until a dispute overturns them.
+func (store *GormStatisticsStore) PurgeMediaVerdicts(before time.Time) (int64, error) {
+	var deleted int64
+	err := store.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("decision <> ? AND updated_at < ?", "BLOCK", before).Delete(&types.MediaVerdict{})
+		if result.Error != nil {
+			return result.Error
+		}
+		deleted = result.RowsAffected
+
+		return tx.Where("sha256 NOT IN (?)", tx.Model(&types.MediaVerdict{}).Select("sha256")).
+			Delete(&types.MediaVerdictURL{}).Error
+	})
+	return deleted, err
+}
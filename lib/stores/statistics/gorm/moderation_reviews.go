@@ -0,0 +1,93 @@
+package gorm
+
+import (
+	"math"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/HORNET-Storage/hornet-storage/lib/types"
+)
+
+// CreateModerationReview queues an event for review. An event already in the queue keeps
+// its existing review.
+func (store *GormStatisticsStore) CreateModerationReview(review *types.ModerationReview) error {
+	if review.Status == "" {
+		review.Status = types.ReviewStatusPending
+	}
+	return store.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "event_id"}},
+		DoNothing: true,
+	}).Create(review).Error
+}
+
+// GetModerationReview returns a review by ID, or nil if it doesn't exist
+func (store *GormStatisticsStore) GetModerationReview(id uint) (*types.ModerationReview, error) {
+	var review types.ModerationReview
+	if err := store.DB.First(&review, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &review, nil
+}
+
+// GetModerationReviewByEvent returns the review for an event, or nil if it was never flagged
+func (store *GormStatisticsStore) GetModerationReviewByEvent(eventID string) (*types.ModerationReview, error) {
+	var review types.ModerationReview
+	if err := store.DB.Where("event_id = ?", eventID).First(&review).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &review, nil
+}
+
+// GetModerationReviews lists reviews with the given status, or all reviews if status is
+// empty, oldest first so the queue is worked in order
+func (store *GormStatisticsStore) GetModerationReviews(status string, page, limit int) ([]types.ModerationReview, *types.PaginationMetadata, error) {
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 {
+		limit = 10
+	}
+
+	query := store.DB.Model(&types.ModerationReview{})
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, nil, err
+	}
+
+	totalPages := int(math.Ceil(float64(total) / float64(limit)))
+	metadata := &types.PaginationMetadata{
+		CurrentPage: page,
+		PageSize:    limit,
+		TotalItems:  total,
+		TotalPages:  totalPages,
+		HasNext:     page < totalPages,
+		HasPrevious: page > 1,
+	}
+
+	var reviews []types.ModerationReview
+	err := query.Order("created_at ASC").
+		Limit(limit).
+		Offset((page - 1) * limit).
+		Find(&reviews).Error
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return reviews, metadata, nil
+}
+
+// UpdateModerationReview saves a reviewer's decision
+func (store *GormStatisticsStore) UpdateModerationReview(review *types.ModerationReview) error {
+	return store.DB.Save(review).Error
+}
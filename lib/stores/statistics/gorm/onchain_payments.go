@@ -0,0 +1,40 @@
+package gorm
+
+import (
+	"github.com/HORNET-Storage/hornet-storage/lib/types"
+	"gorm.io/gorm"
+)
+
+// GetOnchainPayment finds a tracked on-chain payment by outpoint
+func (store *GormStatisticsStore) GetOnchainPayment(outpoint string) (*types.OnchainPayment, error) {
+	var payment types.OnchainPayment
+	if err := store.DB.Where("outpoint = ?", outpoint).First(&payment).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil // No payment found, not an error
+		}
+		return nil, err
+	}
+	return &payment, nil
+}
+
+// GetOnchainPaymentsByTxID retrieves every tracked output of a transaction
+func (store *GormStatisticsStore) GetOnchainPaymentsByTxID(txID string) ([]types.OnchainPayment, error) {
+	var payments []types.OnchainPayment
+	err := store.DB.Where("tx_id = ?", txID).Order("id ASC").Find(&payments).Error
+	return payments, err
+}
+
+// GetPendingOnchainPayments retrieves payments for a subscriber that are waiting for confirmations.
+// Subscribers may be stored by npub or hex key so both are accepted.
+func (store *GormStatisticsStore) GetPendingOnchainPayments(pubkeys ...string) ([]types.OnchainPayment, error) {
+	var payments []types.OnchainPayment
+	err := store.DB.Where("npub IN ? AND status = ?", pubkeys, types.OnchainPaymentPending).
+		Order("created_at ASC").
+		Find(&payments).Error
+	return payments, err
+}
+
+// SaveOnchainPayment creates or updates a tracked on-chain payment
+func (store *GormStatisticsStore) SaveOnchainPayment(payment *types.OnchainPayment) error {
+	return store.DB.Save(payment).Error
+}
@@ -7,8 +7,13 @@ import (
 
 	"github.com/HORNET-Storage/hornet-storage/lib"
 	"github.com/HORNET-Storage/hornet-storage/lib/logging"
+	"github.com/HORNET-Storage/hornet-storage/lib/types"
 )
 
+// unsettledPaymentStatuses are on-chain notifications that never moved funds into a subscription.
+// Reverted payments are stored with a negative amount so revenue sums stay net of rollbacks.
+var unsettledPaymentStatuses = []string{types.PaymentStatusPending, types.PaymentStatusCancelled}
+
 // CreatePaymentNotification creates a new payment notification
 func (store *GormStatisticsStore) CreatePaymentNotification(notification *lib.PaymentNotification) error {
 	if notification.CreatedAt.IsZero() {
@@ -247,6 +252,7 @@ func (store *GormStatisticsStore) GetTotalRevenue() (int64, error) {
 	var total int64
 	err := store.DB.Model(&lib.PaymentNotification{}).
 		Select("COALESCE(SUM(amount), 0)").
+		Where("COALESCE(status, '') NOT IN ?", unsettledPaymentStatuses).
 		Row().
 		Scan(&total)
 
@@ -263,7 +269,7 @@ func (store *GormStatisticsStore) GetTodayRevenue() (int64, error) {
 
 	err := store.DB.Model(&lib.PaymentNotification{}).
 		Select("COALESCE(SUM(amount), 0)").
-		Where("created_at >= ?", startOfDay).
+		Where("created_at >= ? AND COALESCE(status, '') NOT IN ?", startOfDay, unsettledPaymentStatuses).
 		Row().
 		Scan(&total)
 
@@ -305,6 +311,7 @@ func (store *GormStatisticsStore) GetRevenueByTier() ([]lib.TierStat, error) {
 
 	err := store.DB.Model(&lib.PaymentNotification{}).
 		Select("subscription_tier as tier, COUNT(*) as count, SUM(amount) as revenue").
+		Where("COALESCE(status, '') NOT IN ?", unsettledPaymentStatuses).
 		Group("subscription_tier").
 		Find(&results).Error
 
@@ -330,6 +337,7 @@ func (store *GormStatisticsStore) GetRecentTransactions(limit int) ([]lib.TxSumm
 	var notifications []lib.PaymentNotification
 
 	err := store.DB.
+		Where("COALESCE(status, '') NOT IN ?", unsettledPaymentStatuses).
 		Order("created_at DESC").
 		Limit(limit).
 		Find(&notifications).Error
@@ -0,0 +1,110 @@
+package gorm
+
+import (
+	"time"
+
+	"github.com/HORNET-Storage/hornet-storage/lib/types"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// SaveReport records a report unless the reporter has already reported the same target
+// as the same type, and returns the target's scores as of that report. The scores are
+// read in the same transaction as the insert so concurrent reports each see their own.
+func (store *GormStatisticsStore) SaveReport(report *types.Report) (*types.ReportScores, error) {
+	if report.CreatedAt.IsZero() {
+		report.CreatedAt = time.Now()
+	}
+
+	scores := &types.ReportScores{}
+	err := store.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "target"}, {Name: "reporter_pub_key"}, {Name: "report_type"}},
+			DoNothing: true,
+		}).Create(report)
+		if result.Error != nil {
+			return result.Error
+		}
+		scores.Counted = result.RowsAffected > 0
+
+		if scores.Counted {
+			var earlier int64
+			if err := tx.Model(&types.Report{}).
+				Where("target = ? AND reporter_pub_key = ? AND id <> ?", report.Target, report.ReporterPubKey, report.ID).
+				Count(&earlier).Error; err != nil {
+				return err
+			}
+			scores.NewReporter = earlier == 0
+		}
+
+		if err := tx.Model(&types.ReportAction{}).
+			Select("action").
+			Where("target = ? AND report_type = ?", report.Target, report.ReportType).
+			Scan(&scores.Action).Error; err != nil {
+			return err
+		}
+
+		var err error
+		if scores.Score, err = reportScore(tx, report.Target, report.ReportType); err != nil {
+			return err
+		}
+		if scores.PubkeyScore, err = pubkeyReportScore(tx, report.TargetPubKey, 0); err != nil {
+			return err
+		}
+
+		scores.PreviousPubkeyScore = scores.PubkeyScore
+		if scores.Counted {
+			scores.PreviousPubkeyScore, err = pubkeyReportScore(tx, report.TargetPubKey, report.ID)
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return scores, nil
+}
+
+// RecordReportAction records the automatic action taken against a target for a report type,
+// keeping the first action if one was already recorded
+func (store *GormStatisticsStore) RecordReportAction(target string, reportType string, action string) error {
+	return store.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&types.ReportAction{
+		Target:     target,
+		ReportType: reportType,
+		Action:     action,
+	}).Error
+}
+
+// GetReportScore returns the summed reporter weight of all reports of a given type against a target
+func (store *GormStatisticsStore) GetReportScore(target string, reportType string) (float64, error) {
+	return reportScore(store.DB, target, reportType)
+}
+
+// GetPubkeyReportScore returns the weighted report score against a pubkey across all of its content.
+// Each reporter counts once (with their highest weight) no matter how many events they reported.
+func (store *GormStatisticsStore) GetPubkeyReportScore(pubkey string) (float64, error) {
+	return pubkeyReportScore(store.DB, pubkey, 0)
+}
+
+func reportScore(db *gorm.DB, target string, reportType string) (float64, error) {
+	var score float64
+	err := db.Model(&types.Report{}).
+		Select("COALESCE(SUM(weight), 0)").
+		Where("target = ? AND report_type = ?", target, reportType).
+		Scan(&score).Error
+	return score, err
+}
+
+// pubkeyReportScore sums each reporter's highest weight against pubkey, leaving out the
+// report with excludeID when it isn't zero
+func pubkeyReportScore(db *gorm.DB, pubkey string, excludeID uint) (float64, error) {
+	var score float64
+	err := db.Raw(`
+		SELECT COALESCE(SUM(max_weight), 0) FROM (
+			SELECT MAX(weight) AS max_weight
+			FROM reports
+			WHERE target_pub_key = ? AND id <> ?
+			GROUP BY reporter_pub_key
+		) AS per_reporter`, pubkey, excludeID).Scan(&score).Error
+	return score, err
+}
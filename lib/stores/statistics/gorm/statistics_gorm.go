@@ -63,11 +63,23 @@ func (store *GormStatisticsStore) Init() error {
 		&types.PaidSubscriber{},
 		&types.ModerationNotification{},
 		&types.PaymentNotification{},
+		&types.LightningInvoice{},   // Add LightningInvoice to be migrated
+		&types.OnchainPayment{},     // Add OnchainPayment to be migrated
 		&types.ReportNotification{}, // Add ReportNotification to be migrated
+		&types.Report{},             // Add Report to be migrated
+		&types.ReportAction{},       // Add ReportAction to be migrated
+		&types.MediaHashBlock{},
+		&types.MediaVerdict{},
+		&types.MediaVerdictURL{},
+		&types.ModerationReview{},
+		&types.SubscriptionLifecycleNotification{},
 		&types.AllowedUser{},
+		&types.InviteCode{},
+		&types.InviteRedemption{},
 		&types.RelayOwner{},
 		&types.PushDevice{},          // Add PushDevice to be migrated
 		&types.PushNotificationLog{}, // Add PushNotificationLog to be migrated
+		&types.PushPreferences{},
 	)
 	if err != nil {
 		return fmt.Errorf("failed to migrate database schema: %v", err)
@@ -85,6 +97,20 @@ func (store *GormStatisticsStore) Close() error {
 	return db.Close()
 }
 
+// Ping checks the connection and that the database answers a query
+func (store *GormStatisticsStore) Ping(ctx context.Context) error {
+	db, err := store.DB.DB()
+	if err != nil {
+		return err
+	}
+	if err := db.PingContext(ctx); err != nil {
+		return err
+	}
+
+	var result int
+	return store.DB.WithContext(ctx).Raw("SELECT 1").Scan(&result).Error
+}
+
 func (store *GormStatisticsStore) AllocateBitcoinAddress(npub string) (*types.Address, error) {
 	// Use a dedicated mutex for address allocation
 	store.addressMutex.Lock()
@@ -1819,16 +1845,23 @@ func (store *GormStatisticsStore) GetAllowedUser(npub string) (*types.AllowedUse
 }
 
 func (store *GormStatisticsStore) AddAllowedUser(npub string, canWrite bool, tier string, createdBy string) error {
+	return store.AddAllowedUserUntil(npub, canWrite, tier, createdBy, nil)
+}
+
+// AddAllowedUserUntil adds or updates an allowed user whose access lapses at expiresAt.
+// A nil expiresAt makes the grant permanent.
+func (store *GormStatisticsStore) AddAllowedUserUntil(npub string, canWrite bool, tier string, createdBy string, expiresAt *time.Time) error {
 	allowedNpub := types.AllowedUser{
 		Npub:      npub,
 		Tier:      tier,
 		ReadOnly:  !canWrite,
 		CreatedBy: createdBy,
+		ExpiresAt: expiresAt,
 	}
 
 	return store.DB.Clauses(clause.OnConflict{
 		Columns:   []clause.Column{{Name: "npub"}},
-		DoUpdates: clause.AssignmentColumns([]string{"tier", "read_only", "created_by"}),
+		DoUpdates: clause.AssignmentColumns([]string{"tier", "read_only", "created_by", "expires_at"}),
 	}).Create(&allowedNpub).Error
 }
 
@@ -1844,11 +1877,12 @@ func (store *GormStatisticsStore) BulkAddAllowedUser(users []types.AllowedUser)
 				Tier:      user.Tier,
 				ReadOnly:  user.ReadOnly,
 				CreatedBy: user.CreatedBy,
+				ExpiresAt: user.ExpiresAt,
 			}
 
 			if err := tx.Clauses(clause.OnConflict{
 				Columns:   []clause.Column{{Name: "npub"}},
-				DoUpdates: clause.AssignmentColumns([]string{"tier", "read_only", "created_by"}),
+				DoUpdates: clause.AssignmentColumns([]string{"tier", "read_only", "created_by", "expires_at"}),
 			}).Create(&allowedUser).Error; err != nil {
 				return err
 			}
@@ -1857,6 +1891,23 @@ func (store *GormStatisticsStore) BulkAddAllowedUser(users []types.AllowedUser)
 	})
 }
 
+// RemoveExpiredAllowedUsers deletes allowed users whose grant lapsed before now and returns their npubs
+func (store *GormStatisticsStore) RemoveExpiredAllowedUsers(now time.Time) ([]string, error) {
+	var npubs []string
+	err := store.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&types.AllowedUser{}).
+			Where("expires_at IS NOT NULL AND expires_at <= ?", now).
+			Pluck("npub", &npubs).Error; err != nil {
+			return err
+		}
+		if len(npubs) == 0 {
+			return nil
+		}
+		return tx.Where("npub IN ?", npubs).Delete(&types.AllowedUser{}).Error
+	})
+	return npubs, err
+}
+
 func (store *GormStatisticsStore) ClearAllowedUsers() error {
 	return store.DB.Where("1 = 1").Delete(&types.AllowedUser{}).Error
 }
@@ -1988,6 +2039,33 @@ func (store *GormStatisticsStore) RegisterPushDevice(pubkey string, deviceToken
 	return store.DB.Create(device).Error
 }
 
+// RegisterWebPushDevice registers a browser push subscription for a user. The endpoint
+// is stored as the device token.
+func (store *GormStatisticsStore) RegisterWebPushDevice(pubkey string, endpoint string, p256dh string, auth string) error {
+	var existingDevice types.PushDevice
+	result := store.DB.Where("pubkey = ? AND device_token = ?", pubkey, endpoint).First(&existingDevice)
+
+	if result.Error == nil {
+		// Browsers may rotate keys for the same endpoint
+		existingDevice.Platform = "web"
+		existingDevice.P256dh = p256dh
+		existingDevice.Auth = auth
+		existingDevice.IsActive = true
+		return store.DB.Save(&existingDevice).Error
+	}
+
+	device := &types.PushDevice{
+		Pubkey:      pubkey,
+		DeviceToken: endpoint,
+		Platform:    "web",
+		IsActive:    true,
+		P256dh:      p256dh,
+		Auth:        auth,
+	}
+
+	return store.DB.Create(device).Error
+}
+
 // UnregisterPushDevice removes a push device for a user
 func (store *GormStatisticsStore) UnregisterPushDevice(pubkey string, deviceToken string) error {
 	return store.DB.Where("pubkey = ? AND device_token = ?", pubkey, deviceToken).Delete(&types.PushDevice{}).Error
@@ -2017,6 +2095,34 @@ func (store *GormStatisticsStore) CleanupInactivePushDevices(olderThan time.Time
 	return store.DB.Where("updated_at < ?", olderThan).Delete(&types.PushDevice{}).Error
 }
 
+// GetPushPreferences returns a user's notification preferences, or nil if they never set any
+func (store *GormStatisticsStore) GetPushPreferences(pubkey string) (*types.PushPreferences, error) {
+	var preferences types.PushPreferences
+	err := store.DB.Where("pubkey = ?", pubkey).First(&preferences).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &preferences, nil
+}
+
+// GetPushPreferencesByPubkeys returns the stored preferences of any of the given users
+func (store *GormStatisticsStore) GetPushPreferencesByPubkeys(pubkeys []string) ([]types.PushPreferences, error) {
+	var preferences []types.PushPreferences
+	if len(pubkeys) == 0 {
+		return preferences, nil
+	}
+	err := store.DB.Where("pubkey IN ?", pubkeys).Find(&preferences).Error
+	return preferences, err
+}
+
+// SavePushPreferences creates or replaces a user's notification preferences
+func (store *GormStatisticsStore) SavePushPreferences(preferences *types.PushPreferences) error {
+	return store.DB.Save(preferences).Error
+}
+
 // Push notification logging implementation
 
 // LogPushNotification logs a push notification attempt
@@ -2037,6 +2143,21 @@ func (store *GormStatisticsStore) GetPushNotificationHistory(pubkey string, limi
 	return logs, err
 }
 
+// GetPushNotificationStats counts logged notifications and how many events coalescing merged
+func (store *GormStatisticsStore) GetPushNotificationStats() (*types.PushNotificationStats, error) {
+	var stats types.PushNotificationStats
+	err := store.DB.Model(&types.PushNotificationLog{}).Select(
+		"COUNT(*) AS total_notifications, " +
+			"COALESCE(SUM(CASE WHEN delivered THEN 1 ELSE 0 END), 0) AS delivered, " +
+			"COALESCE(SUM(CASE WHEN collapsed_count > 1 THEN 1 ELSE 0 END), 0) AS summaries, " +
+			"COALESCE(SUM(CASE WHEN collapsed_count > 1 THEN collapsed_count - 1 ELSE 0 END), 0) AS events_suppressed",
+	).Scan(&stats).Error
+	if err != nil {
+		return nil, err
+	}
+	return &stats, nil
+}
+
 // UpdatePushNotificationDelivery updates the delivery status of a push notification
 func (store *GormStatisticsStore) UpdatePushNotificationDelivery(id uint, delivered bool, errorMessage string) error {
 	updates := map[string]interface{}{
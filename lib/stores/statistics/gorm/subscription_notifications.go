@@ -0,0 +1,31 @@
+package gorm
+
+import (
+	"github.com/HORNET-Storage/hornet-storage/lib/types"
+	"gorm.io/gorm/clause"
+)
+
+// ClaimSubscriptionNotification records a lifecycle notification before it is sent.
+// Returns false if the same notification was already recorded, so it is never sent twice.
+func (store *GormStatisticsStore) ClaimSubscriptionNotification(notification *types.SubscriptionLifecycleNotification) (bool, error) {
+	result := store.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "pub_key"}, {Name: "type"}, {Name: "key"}},
+		DoNothing: true,
+	}).Create(notification)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// UpdateSubscriptionNotificationGiftWrap stores the ID of the gift wrap that delivered a notification
+func (store *GormStatisticsStore) UpdateSubscriptionNotificationGiftWrap(id uint, giftWrapID string) error {
+	return store.DB.Model(&types.SubscriptionLifecycleNotification{}).
+		Where("id = ?", id).
+		Update("gift_wrap_id", giftWrapID).Error
+}
+
+// ReleaseSubscriptionNotification removes a claimed notification that could not be delivered
+func (store *GormStatisticsStore) ReleaseSubscriptionNotification(id uint) error {
+	return store.DB.Delete(&types.SubscriptionLifecycleNotification{}, id).Error
+}
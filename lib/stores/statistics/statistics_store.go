@@ -1,6 +1,7 @@
 package statistics
 
 import (
+	"context"
 	"time"
 
 	libtypes "github.com/HORNET-Storage/hornet-storage/lib"
@@ -14,6 +15,9 @@ import (
 type StatisticsStore interface {
 	Close() error
 
+	// Ping checks that the database connection is usable
+	Ping(ctx context.Context) error
+
 	// Bitcoin-related statistics
 	SaveBitcoinRate(rate float64) error
 	GetBitcoinRates(days int) ([]types.BitcoinRate, error)
@@ -128,6 +132,30 @@ type StatisticsStore interface {
 	GetRevenueByTier() ([]libtypes.TierStat, error)
 	GetRecentTransactions(limit int) ([]libtypes.TxSummary, error)
 
+	// Lightning invoice management
+	CreateLightningInvoice(invoice *types.LightningInvoice) error
+	GetLightningInvoice(paymentHash string) (*types.LightningInvoice, error)
+	GetPendingLightningInvoices() ([]types.LightningInvoice, error)
+	GetLatestPendingLightningInvoice(npub string) (*types.LightningInvoice, error)
+	ClaimLightningInvoice(paymentHash string, amountSats int64, settledAt time.Time) (bool, error)
+	MarkLightningInvoiceSettled(paymentHash string) error
+	ReleaseLightningInvoice(paymentHash string) error
+	GetCreditingLightningInvoices() ([]types.LightningInvoice, error)
+	ExpireLightningInvoices(before time.Time) (int64, error)
+	RecordLightningPayment(invoice *types.LightningInvoice) (bool, error)
+	DeleteLightningInvoice(paymentHash string) error
+
+	// On-chain payment confirmation tracking
+	GetOnchainPayment(outpoint string) (*types.OnchainPayment, error)
+	GetOnchainPaymentsByTxID(txID string) ([]types.OnchainPayment, error)
+	GetPendingOnchainPayments(pubkeys ...string) ([]types.OnchainPayment, error)
+	SaveOnchainPayment(payment *types.OnchainPayment) error
+
+	// Subscription lifecycle notifications
+	ClaimSubscriptionNotification(notification *types.SubscriptionLifecycleNotification) (bool, error)
+	UpdateSubscriptionNotificationGiftWrap(id uint, giftWrapID string) error
+	ReleaseSubscriptionNotification(id uint) error
+
 	// Report notification management
 	CreateReportNotification(notification *types.ReportNotification) error
 	GetReportNotificationByEventID(eventID string) (*types.ReportNotification, error)
@@ -145,14 +173,29 @@ type StatisticsStore interface {
 	GetReportsByType() ([]libtypes.TypeStat, error)
 	GetMostReportedContent(limit int) ([]libtypes.ReportSummary, error)
 
+	// Report aggregation (deduplicated, weighted NIP-56 reports)
+	SaveReport(report *types.Report) (*types.ReportScores, error)
+	GetReportScore(target string, reportType string) (float64, error)
+	GetPubkeyReportScore(pubkey string) (float64, error)
+	RecordReportAction(target string, reportType string, action string) error
+
 	// NPUB access control management
 	GetAllowedUser(npub string) (*types.AllowedUser, error)
 	AddAllowedUser(npub string, canWrite bool, tier string, createdBy string) error
+	AddAllowedUserUntil(npub string, canWrite bool, tier string, createdBy string, expiresAt *time.Time) error
 	RemoveAllowedUser(npub string) error
+	RemoveExpiredAllowedUsers(now time.Time) ([]string, error)
 	BulkAddAllowedUser(users []types.AllowedUser) error
 	ClearAllowedUsers() error
 	GetUsersPaginated(page int, pageSize int) ([]*types.AllowedUser, *types.PaginationMetadata, error)
 
+	// Invite codes
+	CreateInviteCode(invite *types.InviteCode) error
+	GetInviteCode(code string) (*types.InviteCode, error)
+	ListInviteCodes() ([]types.InviteCode, error)
+	RevokeInviteCode(code string) error
+	RedeemInviteCode(code string, npub string, now time.Time) (*types.AllowedUser, error)
+
 	// Relay owner management
 	GetRelayOwner() (*types.RelayOwner, error)
 	SetRelayOwner(npub string, createdBy string) error
@@ -162,16 +205,42 @@ type StatisticsStore interface {
 	GetAvailableBitcoinAddressCount() (int, error)
 	CountUsersWithoutBitcoinAddresses() (int, error)
 
+	// Admin-managed perceptual hash blocklist for media moderation
+	AddMediaHashBlock(block *types.MediaHashBlock) error
+	GetMediaHashBlocks() ([]types.MediaHashBlock, error)
+	DeleteMediaHashBlock(id uint) error
+
+	// Media moderation verdict cache
+	GetMediaVerdict(sha256 string) (*types.MediaVerdict, error)
+	GetMediaVerdictByURL(url string) (*types.MediaVerdict, error)
+	SaveMediaVerdict(verdict *types.MediaVerdict) error
+	SaveMediaVerdictURL(url string, sha256 string) error
+	PurgeMediaVerdicts(before time.Time) (int64, error)
+
+	// Review queue for media flagged by moderation
+	CreateModerationReview(review *types.ModerationReview) error
+	GetModerationReview(id uint) (*types.ModerationReview, error)
+	GetModerationReviewByEvent(eventID string) (*types.ModerationReview, error)
+	GetModerationReviews(status string, page, limit int) ([]types.ModerationReview, *types.PaginationMetadata, error)
+	UpdateModerationReview(review *types.ModerationReview) error
+
 	// Push notification device management
 	RegisterPushDevice(pubkey string, deviceToken string, platform string) error
+	RegisterWebPushDevice(pubkey string, endpoint string, p256dh string, auth string) error
 	UnregisterPushDevice(pubkey string, deviceToken string) error
 	GetPushDevicesByPubkey(pubkey string) ([]types.PushDevice, error)
 	GetAllActivePushDevices() ([]types.PushDevice, error)
 	UpdatePushDeviceStatus(deviceToken string, isActive bool) error
 	CleanupInactivePushDevices(olderThan time.Time) error
 
+	// Push notification preferences
+	GetPushPreferences(pubkey string) (*types.PushPreferences, error)
+	GetPushPreferencesByPubkeys(pubkeys []string) ([]types.PushPreferences, error)
+	SavePushPreferences(preferences *types.PushPreferences) error
+
 	// Push notification logging
 	LogPushNotification(log *types.PushNotificationLog) error
 	GetPushNotificationHistory(pubkey string, limit int) ([]types.PushNotificationLog, error)
+	GetPushNotificationStats() (*types.PushNotificationStats, error)
 	UpdatePushNotificationDelivery(id uint, delivered bool, errorMessage string) error
 }
@@ -2,6 +2,7 @@ package stores
 
 import (
 	"fmt"
+	"time"
 
 	merkle_dag "github.com/HORNET-Storage/Scionic-Merkle-Tree/v2/dag"
 	merkle_tree "github.com/HORNET-Storage/Scionic-Merkle-Tree/v2/tree"
@@ -13,6 +14,9 @@ import (
 	"github.com/nbd-wtf/go-nostr"
 )
 
+// DefaultMaxLimit is the number of events returned for a filter without a limit
+const DefaultMaxLimit = 500
+
 type Store interface {
 	Cleanup() error
 
@@ -87,6 +91,12 @@ type Store interface {
 	UnblockPubkey(pubkey string) error
 	ListBlockedPubkeys() ([]types.BlockedPubkey, error)
 
+	// Outbound Mirroring
+	SaveMirrorDelivery(delivery *types.MirrorDelivery) error
+	GetDueMirrorDeliveries(now time.Time, limit int) ([]types.MirrorDelivery, error)
+	ListMirrorDeliveries(status string, limit int) ([]types.MirrorDelivery, error)
+	DeleteFinishedMirrorDeliveries(before time.Time) (int, error)
+
 	// Blossom
 	StoreBlob(data []byte, hash []byte, publicKey string) error
 	GetBlob(hash string) ([]byte, error)
@@ -46,6 +46,11 @@ func ScheduleBatchUpdateAfter(delay time.Duration) {
 			} else {
 				logging.Infof("Successfully completed batch update of kind 11888 events")
 			}
+
+			// Tier changes can shrink storage limits, so re-check for due lifecycle notifications
+			if err := manager.CheckSubscriptionLifecycles(); err != nil {
+				logging.Infof("Error checking subscription lifecycles: %v", err)
+			}
 		} else {
 			logging.Infof("ERROR: Global subscription manager is nil, cannot run batch update")
 		}
@@ -545,7 +550,7 @@ func (m *SubscriptionManager) deleteSubscriptionEvent(event *nostr.Event) error
 
 // isFreeMode checks if a mode is a free mode
 func isFreeMode(mode string) bool {
-	return mode == "public" || mode == "invite-only" || mode == "only-me"
+	return mode == "public" || mode == "invite-only" || mode == "only-me" || mode == "wot"
 }
 
 // applyModeTransitionRules applies specific rules for mode transitions
@@ -36,6 +36,18 @@ func (m *SubscriptionManager) createEvent(
 		creditSats = 0
 	}
 
+	// On-chain payments still waiting for confirmations hold an inactive subscription as pending
+	var pendingPayments []types.OnchainPayment
+	if hexKey, npubKey, err := normalizePubkey(subscriber.Npub); err == nil {
+		pendingPayments, err = m.store.GetStatsStore().GetPendingOnchainPayments(hexKey, npubKey)
+		if err != nil {
+			logging.Infof("Warning: could not get pending on-chain payments for subscriber: %v", err)
+		}
+	}
+	if len(pendingPayments) > 0 && status != "active" {
+		status = "pending"
+	}
+
 	// Create storage tag value
 	totalBytesStr := func() string {
 		if storageInfo.IsUnlimited {
@@ -75,6 +87,32 @@ func (m *SubscriptionManager) createEvent(
 		})
 	}
 
+	for _, payment := range pendingPayments {
+		tags = append(tags, nostr.Tag{
+			"pending_payment",
+			payment.TxID,
+			fmt.Sprintf("%d", payment.AmountSats),
+			fmt.Sprintf("%d", payment.Confirmations),
+			fmt.Sprintf("%d", payment.RequiredConfirmations),
+		})
+	}
+
+	// Advertise a pending Lightning invoice so clients can pay for the tier directly
+	if hexKey, _, err := normalizePubkey(subscriber.Npub); err == nil {
+		invoice, err := m.store.GetStatsStore().GetLatestPendingLightningInvoice(hexKey)
+		if err != nil {
+			logging.Infof("Warning: could not get pending lightning invoice for subscriber: %v", err)
+		} else if invoice != nil {
+			tags = append(tags, nostr.Tag{
+				"lightning_invoice",
+				invoice.Bolt11,
+				invoice.Tier,
+				fmt.Sprintf("%d", invoice.AmountSats),
+				fmt.Sprintf("%d", invoice.ExpiresAt.Unix()),
+			})
+		}
+	}
+
 	// Add tier information if tier is assigned
 	if activeTier != "" {
 		tags = append(tags, nostr.Tag{
@@ -156,6 +194,45 @@ func (m *SubscriptionManager) createOrUpdateNIP88Event(
 	return m.store.StoreEvent(event)
 }
 
+// RefreshSubscriptionEvent re-creates a subscriber's existing kind 11888 event with the same
+// tier, expiration and storage so derived tags (credit, pending invoices) are up to date
+func (m *SubscriptionManager) RefreshSubscriptionEvent(npub string) error {
+	hexKey, npubKey, err := normalizePubkey(npub)
+	if err != nil {
+		return fmt.Errorf("failed to normalize pubkey: %v", err)
+	}
+
+	events, err := m.store.QueryEvents(nostr.Filter{
+		Kinds: []int{11888},
+		Tags: nostr.TagMap{
+			"p": []string{npubKey, hexKey}, // Check both formats
+		},
+		Limit: 1,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to query events: %v", err)
+	}
+	if len(events) == 0 {
+		return fmt.Errorf("no NIP-88 event found for user")
+	}
+	currentEvent := events[0]
+
+	storageInfo, err := m.extractStorageInfo(currentEvent)
+	if err != nil {
+		return fmt.Errorf("failed to extract storage info: %v", err)
+	}
+
+	var expirationDate time.Time
+	if expirationUnix := getTagUnixValue(currentEvent.Tags, "active_subscription"); expirationUnix > 0 {
+		expirationDate = time.Unix(expirationUnix, 0)
+	}
+
+	return m.createOrUpdateNIP88Event(&types.Subscriber{
+		Npub:    getTagValue(currentEvent.Tags, "p"),
+		Address: getTagValue(currentEvent.Tags, "relay_bitcoin_address"),
+	}, getTagValue(currentEvent.Tags, "active_subscription"), expirationDate, &storageInfo)
+}
+
 // createNIP88EventIfNotExists creates a new NIP-88 event for a subscriber if none exists
 func (m *SubscriptionManager) createNIP88EventIfNotExists(
 	subscriber *types.Subscriber,
@@ -0,0 +1,18 @@
+package subscription
+
+import (
+	"time"
+
+	"github.com/HORNET-Storage/hornet-storage/lib/types"
+)
+
+// NotifyCreditAdded exposes notifyCreditAdded to the external test package,
+// which is needed because the stores import this package
+func (m *SubscriptionManager) NotifyCreditAdded(npub string, transactionID string, amountSats int64, creditSats int64) {
+	m.notifyCreditAdded(npub, transactionID, amountSats, creditSats)
+}
+
+// CreateSubscriptionEvent creates a subscriber's initial kind 11888 event for tests
+func (m *SubscriptionManager) CreateSubscriptionEvent(npub string, address string) error {
+	return m.createNIP88EventIfNotExists(&types.Subscriber{Npub: npub, Address: address}, "", time.Time{}, &StorageInfo{UpdatedAt: time.Now()})
+}
@@ -36,6 +36,11 @@ func InitDailyFreeSubscriptionRenewal() {
 				} else {
 					logging.Infof("Successfully completed daily free tier renewal")
 				}
+
+				// Send expiry reminders, expiry notices and quota warnings that are now due
+				if err := manager.CheckSubscriptionLifecycles(); err != nil {
+					logging.Infof("Error checking subscription lifecycles: %v", err)
+				}
 			}
 		}
 	}()
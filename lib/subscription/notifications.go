@@ -0,0 +1,366 @@
+// notifications.go - Subscription lifecycle DMs (NIP-17)
+
+package subscription
+
+import (
+	cryptorand "crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/nbd-wtf/go-nostr/nip44"
+
+	"github.com/HORNET-Storage/hornet-storage/lib/config"
+	"github.com/HORNET-Storage/hornet-storage/lib/logging"
+	"github.com/HORNET-Storage/hornet-storage/lib/types"
+	"github.com/HORNET-Storage/hornet-storage/services/push"
+)
+
+// NIP-17 / NIP-59 event kinds
+const (
+	chatMessageKind = 14
+	sealKind        = 13
+	giftWrapKind    = 1059
+)
+
+const (
+	// expiredNotificationWindow limits expiry DMs to recently expired subscriptions
+	// so enabling notifications doesn't message every subscriber that ever lapsed
+	expiredNotificationWindow = 7 * 24 * time.Hour
+
+	// giftWrapTimestampJitter is how far seal and wrap timestamps are randomized into the past (NIP-59)
+	giftWrapTimestampJitter = 2 * 24 * time.Hour
+
+	lifecycleBatchSize = 100
+)
+
+// CheckSubscriptionLifecycles scans kind 11888 events and sends any expiry and quota notifications that are due.
+// Notifications are recorded before sending, so running this repeatedly never sends the same one twice.
+func (m *SubscriptionManager) CheckSubscriptionLifecycles() error {
+	cfg, err := config.GetConfig()
+	if err != nil {
+		return fmt.Errorf("failed to get config: %v", err)
+	}
+	if !cfg.SubscriptionNotifications.Enabled {
+		return nil
+	}
+
+	tiers := make(map[string]types.SubscriptionTier)
+	for _, tier := range cfg.AllowedUsersSettings.Tiers {
+		tiers[tier.Name] = tier
+	}
+
+	leadDays := append([]int(nil), cfg.SubscriptionNotifications.ExpiringLeadDays...)
+	sort.Ints(leadDays)
+
+	now := time.Now()
+	seen := make(map[string]bool)
+	var until *nostr.Timestamp
+	checked := 0
+
+	for {
+		events, err := m.store.QueryEvents(nostr.Filter{
+			Kinds: []int{11888},
+			Until: until,
+			Limit: lifecycleBatchSize,
+		})
+		if err != nil {
+			return fmt.Errorf("error querying events: %v", err)
+		}
+
+		newEvents := 0
+		for _, event := range events {
+			if seen[event.ID] {
+				continue
+			}
+			seen[event.ID] = true
+			newEvents++
+
+			m.checkSubscriptionLifecycle(event, tiers, leadDays, cfg.SubscriptionNotifications.QuotaThresholdPercent, now)
+			checked++
+
+			if until == nil || event.CreatedAt < *until {
+				createdAt := event.CreatedAt
+				until = &createdAt
+			}
+		}
+
+		if newEvents == 0 || len(events) < lifecycleBatchSize {
+			break
+		}
+	}
+
+	logging.Infof("Subscription lifecycle check complete: %d subscriptions checked", checked)
+	return nil
+}
+
+// checkSubscriptionLifecycle sends the expiry and quota notifications due for a single kind 11888 event
+func (m *SubscriptionManager) checkSubscriptionLifecycle(
+	event *nostr.Event,
+	tiers map[string]types.SubscriptionTier,
+	leadDays []int,
+	quotaThresholdPercent int,
+	now time.Time,
+) {
+	pubkey := getTagValue(event.Tags, "p")
+	if pubkey == "" {
+		return
+	}
+
+	tierName := getTagValue(event.Tags, "active_subscription")
+	expirationUnix := getTagUnixValue(event.Tags, "active_subscription")
+	expiration := time.Unix(expirationUnix, 0)
+	expires := expiration.Format("2006-01-02")
+
+	// Free tiers renew automatically, so only paid tiers get expiry notifications
+	if tier, ok := tiers[tierName]; ok && tier.PriceSats > 0 && expirationUnix > 0 {
+		if remaining := expiration.Sub(now); remaining > 0 {
+			// Only the closest lead time applies, e.g. the 1 day reminder supersedes the 7 day one
+			for _, days := range leadDays {
+				if days > 0 && remaining <= time.Duration(days)*24*time.Hour {
+					m.notifyLifecycle(pubkey, types.LifecycleExpiring, fmt.Sprintf("%d:%d", expirationUnix, days), map[string]string{
+						"tier":    tierName,
+						"expires": expires,
+						"days":    fmt.Sprintf("%d", int(remaining.Hours()/24)+1),
+					})
+					break
+				}
+			}
+		} else if -remaining < expiredNotificationWindow {
+			m.notifyLifecycle(pubkey, types.LifecycleExpired, fmt.Sprintf("%d", expirationUnix), map[string]string{
+				"tier":    tierName,
+				"expires": expires,
+			})
+		}
+	}
+
+	if quotaThresholdPercent <= 0 {
+		return
+	}
+	storageInfo, err := m.extractStorageInfo(event)
+	if err != nil || storageInfo.IsUnlimited || storageInfo.TotalBytes <= 0 {
+		return
+	}
+	percent := storageInfo.UsedBytes * 100 / storageInfo.TotalBytes
+	if percent >= int64(quotaThresholdPercent) {
+		// One warning per subscription period
+		m.notifyLifecycle(pubkey, types.LifecycleQuotaWarning, fmt.Sprintf("%d:%d", expirationUnix, quotaThresholdPercent), map[string]string{
+			"tier":    tierName,
+			"expires": expires,
+			"percent": fmt.Sprintf("%d", percent),
+			"used":    formatStorageBytes(storageInfo.UsedBytes),
+			"limit":   formatStorageBytes(storageInfo.TotalBytes),
+		})
+	}
+}
+
+// notifyPaymentReceived tells a subscriber their payment was applied to a tier.
+// Payment processing calls it from its own goroutine so sending never holds up a payment.
+func (m *SubscriptionManager) notifyPaymentReceived(npub string, transactionID string, amountSats int64, tierName string, endDate time.Time) {
+	m.notifyLifecycle(npub, types.LifecyclePaymentReceived, transactionID, map[string]string{
+		"tier":    tierName,
+		"expires": endDate.Format("2006-01-02"),
+		"amount":  fmt.Sprintf("%d", amountSats),
+	})
+}
+
+// notifyCreditAdded tells a subscriber a payment was added to their credit.
+// Payment processing calls it from its own goroutine so sending never holds up a payment.
+func (m *SubscriptionManager) notifyCreditAdded(npub string, transactionID string, amountSats int64, creditSats int64) {
+	m.notifyLifecycle(npub, types.LifecycleCreditAdded, transactionID, map[string]string{
+		"amount": fmt.Sprintf("%d", amountSats),
+		"credit": fmt.Sprintf("%d", creditSats),
+	})
+}
+
+// notifyLifecycle sends a lifecycle DM to a subscriber at most once per (type, key).
+// Failures are logged rather than returned so they never interrupt payment processing.
+func (m *SubscriptionManager) notifyLifecycle(pubkey string, notificationType string, key string, values map[string]string) {
+	cfg, err := config.GetConfig()
+	if err != nil || !cfg.SubscriptionNotifications.Enabled {
+		return
+	}
+
+	template := lifecycleTemplate(&cfg.SubscriptionNotifications.Templates, notificationType)
+	if template == "" {
+		return
+	}
+
+	hexKey, _, err := normalizePubkey(pubkey)
+	if err != nil {
+		logging.Infof("Warning: cannot send %s notification to invalid pubkey %s: %v", notificationType, pubkey, err)
+		return
+	}
+
+	statsStore := m.store.GetStatsStore()
+	record := &types.SubscriptionLifecycleNotification{
+		PubKey: hexKey,
+		Type:   notificationType,
+		Key:    key,
+	}
+	claimed, err := statsStore.ClaimSubscriptionNotification(record)
+	if err != nil {
+		logging.Infof("Warning: failed to record %s notification for %s: %v", notificationType, hexKey, err)
+		return
+	}
+	if !claimed {
+		return // Already sent
+	}
+
+	values["relay"] = cfg.Relay.Name
+	message := renderLifecycleTemplate(template, values)
+
+	wrap, err := m.createGiftWrappedDM(hexKey, message)
+	if err == nil {
+		err = m.store.StoreEvent(wrap)
+	}
+	if err != nil {
+		logging.Infof("Warning: failed to send %s notification to %s: %v", notificationType, hexKey, err)
+		// Release the claim so the notification is retried on the next trigger
+		if releaseErr := statsStore.ReleaseSubscriptionNotification(record.ID); releaseErr != nil {
+			logging.Infof("Warning: failed to release %s notification claim: %v", notificationType, releaseErr)
+		}
+		return
+	}
+
+	if err := statsStore.UpdateSubscriptionNotificationGiftWrap(record.ID, wrap.ID); err != nil {
+		logging.Infof("Warning: failed to record gift wrap for %s notification: %v", notificationType, err)
+	}
+
+	logging.Infof("Sent %s notification to %s (gift wrap %s)", notificationType, hexKey, wrap.ID)
+
+	// The DM is end-to-end encrypted, so the push only says one arrived rather than
+	// handing its plaintext to APNs or FCM
+	if cfg.SubscriptionNotifications.Push {
+		push.GetGlobalPushService().NotifyPubkey(hexKey, wrap, &push.PushMessage{
+			Title:    "Subscription update",
+			Body:     "You have a new message",
+			Badge:    1,
+			Sound:    "default",
+			Category: "subscription_" + notificationType,
+			Data: map[string]interface{}{
+				"event_id":          wrap.ID,
+				"event_kind":        wrap.Kind,
+				"notification_type": notificationType,
+			},
+		})
+	}
+}
+
+// createGiftWrappedDM builds a NIP-17 direct message from the relay, sealed with the relay key
+// and gift wrapped with a one-time key so only the recipient can read it
+func (m *SubscriptionManager) createGiftWrappedDM(recipient string, message string) (*nostr.Event, error) {
+	relaySecret := hex.EncodeToString(m.relayPrivateKey.Serialize())
+	relayPubkey, err := nostr.GetPublicKey(relaySecret)
+	if err != nil {
+		return nil, fmt.Errorf("invalid relay key: %v", err)
+	}
+
+	// The rumor is the unsigned chat message itself
+	rumor := nostr.Event{
+		PubKey:    relayPubkey,
+		CreatedAt: nostr.Now(),
+		Kind:      chatMessageKind,
+		Tags:      nostr.Tags{{"p", recipient}},
+		Content:   message,
+	}
+	rumor.ID = rumor.GetID()
+
+	sealKey, err := nip44.GenerateConversationKey(recipient, relaySecret)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive seal key: %v", err)
+	}
+	sealContent, err := nip44Encrypt(rumor.String(), sealKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt rumor: %v", err)
+	}
+	seal := nostr.Event{
+		CreatedAt: randomPastTimestamp(),
+		Kind:      sealKind,
+		Tags:      nostr.Tags{},
+		Content:   sealContent,
+	}
+	if err := seal.Sign(relaySecret); err != nil {
+		return nil, fmt.Errorf("failed to sign seal: %v", err)
+	}
+
+	wrapSecret := nostr.GeneratePrivateKey()
+	wrapKey, err := nip44.GenerateConversationKey(recipient, wrapSecret)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive gift wrap key: %v", err)
+	}
+	wrapContent, err := nip44Encrypt(seal.String(), wrapKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt seal: %v", err)
+	}
+	wrap := &nostr.Event{
+		CreatedAt: randomPastTimestamp(),
+		Kind:      giftWrapKind,
+		Tags:      nostr.Tags{{"p", recipient}},
+		Content:   wrapContent,
+	}
+	if err := wrap.Sign(wrapSecret); err != nil {
+		return nil, fmt.Errorf("failed to sign gift wrap: %v", err)
+	}
+
+	return wrap, nil
+}
+
+// nip44Encrypt encrypts with a fresh random salt. The go-nostr nip44 package only
+// generates a salt internally when one is supplied, so it has to be passed explicitly.
+func nip44Encrypt(plaintext string, conversationKey []byte) (string, error) {
+	salt := make([]byte, 32)
+	if _, err := cryptorand.Read(salt); err != nil {
+		return "", err
+	}
+	return nip44.Encrypt(plaintext, conversationKey, nip44.WithCustomSalt(salt))
+}
+
+func lifecycleTemplate(templates *types.SubscriptionNotificationTemplates, notificationType string) string {
+	switch notificationType {
+	case types.LifecycleExpiring:
+		return templates.Expiring
+	case types.LifecycleExpired:
+		return templates.Expired
+	case types.LifecyclePaymentReceived:
+		return templates.PaymentReceived
+	case types.LifecycleCreditAdded:
+		return templates.CreditAdded
+	case types.LifecycleQuotaWarning:
+		return templates.QuotaWarning
+	default:
+		return ""
+	}
+}
+
+// renderLifecycleTemplate replaces {name} placeholders with their values
+func renderLifecycleTemplate(template string, values map[string]string) string {
+	replacements := make([]string, 0, len(values)*2)
+	for name, value := range values {
+		replacements = append(replacements, "{"+name+"}", value)
+	}
+	return strings.NewReplacer(replacements...).Replace(template)
+}
+
+// randomPastTimestamp hides the real send time of seals and gift wraps (NIP-59)
+func randomPastTimestamp() nostr.Timestamp {
+	jitter := time.Duration(rand.Int63n(int64(giftWrapTimestampJitter)))
+	return nostr.Timestamp(time.Now().Add(-jitter).Unix())
+}
+
+func formatStorageBytes(bytes int64) string {
+	const unit = 1024
+	if bytes < unit {
+		return fmt.Sprintf("%d B", bytes)
+	}
+	div, exp := int64(unit), 0
+	for n := bytes / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(bytes)/float64(div), "KMGTPE"[exp])
+}
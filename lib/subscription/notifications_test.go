@@ -0,0 +1,117 @@
+package subscription_test
+
+import (
+	"encoding/hex"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/nbd-wtf/go-nostr/nip44"
+	"github.com/spf13/viper"
+
+	"github.com/HORNET-Storage/hornet-storage/lib/config"
+	"github.com/HORNET-Storage/hornet-storage/lib/stores/badgerhold"
+	"github.com/HORNET-Storage/hornet-storage/lib/subscription"
+)
+
+func unwrap(t *testing.T, wrap *nostr.Event, recipientSecret string) (*nostr.Event, *nostr.Event) {
+	t.Helper()
+
+	wrapKey, err := nip44.GenerateConversationKey(wrap.PubKey, recipientSecret)
+	if err != nil {
+		t.Fatalf("GenerateConversationKey: %v", err)
+	}
+	sealJSON, err := nip44.Decrypt(wrap.Content, wrapKey)
+	if err != nil {
+		t.Fatalf("decrypt gift wrap: %v", err)
+	}
+	var seal nostr.Event
+	if err := seal.UnmarshalJSON([]byte(sealJSON)); err != nil {
+		t.Fatalf("unmarshal seal: %v", err)
+	}
+	if ok, _ := seal.CheckSignature(); !ok {
+		t.Fatal("seal signature is invalid")
+	}
+
+	sealKey, err := nip44.GenerateConversationKey(seal.PubKey, recipientSecret)
+	if err != nil {
+		t.Fatalf("GenerateConversationKey: %v", err)
+	}
+	rumorJSON, err := nip44.Decrypt(seal.Content, sealKey)
+	if err != nil {
+		t.Fatalf("decrypt seal: %v", err)
+	}
+	var rumor nostr.Event
+	if err := rumor.UnmarshalJSON([]byte(rumorJSON)); err != nil {
+		t.Fatalf("unmarshal rumor: %v", err)
+	}
+	return &seal, &rumor
+}
+
+func TestLifecycleNotificationIsGiftWrappedOnce(t *testing.T) {
+	viper.Reset()
+	viper.Set("relay.name", "Test Relay")
+	viper.Set("subscription_notifications.enabled", true)
+	viper.Set("subscription_notifications.templates.credit_added", "{amount} sats added on {relay}, credit {credit}")
+	config.InitConfigForTesting()
+	t.Cleanup(viper.Reset)
+
+	tempDir := t.TempDir()
+	store, err := badgerhold.InitStore(filepath.Join(tempDir, "store"), filepath.Join(tempDir, "stats.db"))
+	if err != nil {
+		t.Fatalf("InitStore: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := store.Cleanup(); err != nil {
+			t.Fatalf("Cleanup: %v", err)
+		}
+	})
+
+	relayKey, err := btcec.NewPrivateKey()
+	if err != nil {
+		t.Fatalf("NewPrivateKey: %v", err)
+	}
+	manager := subscription.NewSubscriptionManager(store, relayKey, "", nil)
+
+	recipientSecret := nostr.GeneratePrivateKey()
+	recipient, _ := nostr.GetPublicKey(recipientSecret)
+
+	// The same transaction notified twice (e.g. after a restart) is only delivered once
+	manager.NotifyCreditAdded(recipient, "tx1", 500, 1500)
+	manager.NotifyCreditAdded(recipient, "tx1", 500, 1500)
+
+	wraps, err := store.QueryEvents(nostr.Filter{
+		Kinds: []int{1059},
+		Tags:  nostr.TagMap{"p": []string{recipient}},
+	})
+	if err != nil {
+		t.Fatalf("QueryEvents: %v", err)
+	}
+	if len(wraps) != 1 {
+		t.Fatalf("expected one gift wrap, got %d", len(wraps))
+	}
+
+	seal, rumor := unwrap(t, wraps[0], recipientSecret)
+	relayPubkey, _ := nostr.GetPublicKey(hex.EncodeToString(relayKey.Serialize()))
+	if seal.PubKey != relayPubkey || rumor.PubKey != relayPubkey {
+		t.Errorf("expected seal and rumor to be from the relay %s, got %s and %s", relayPubkey, seal.PubKey, rumor.PubKey)
+	}
+	if rumor.Kind != 14 {
+		t.Errorf("expected kind 14 rumor, got %d", rumor.Kind)
+	}
+	if !strings.Contains(rumor.Content, "500 sats added on Test Relay, credit 1500") {
+		t.Errorf("unexpected message %q", rumor.Content)
+	}
+
+	// A different transaction is a new notification
+	manager.NotifyCreditAdded(recipient, "tx2", 200, 1700)
+	wraps, _ = store.QueryEvents(nostr.Filter{
+		Kinds: []int{1059},
+		Tags:  nostr.TagMap{"p": []string{recipient}},
+	})
+	if len(wraps) != 2 {
+		t.Fatalf("expected two gift wraps, got %d", len(wraps))
+	}
+}
@@ -0,0 +1,370 @@
+// onchain.go - On-chain payment confirmation policy and rollback
+
+package subscription
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/nbd-wtf/go-nostr"
+
+	"github.com/HORNET-Storage/hornet-storage/lib/config"
+	"github.com/HORNET-Storage/hornet-storage/lib/logging"
+	"github.com/HORNET-Storage/hornet-storage/lib/types"
+)
+
+// Transaction states reported by the wallet for transactions that will never confirm
+const (
+	OnchainTxDropped  = "dropped"
+	OnchainTxReplaced = "replaced"
+)
+
+// UnknownConfirmations marks a wallet report without a confirmation count. Such a report
+// leaves the payment as it was, so it is re-checked on the next report with a count.
+const UnknownConfirmations = -1
+
+// onchainPaymentMutex serialises state transitions so concurrent wallet reports
+// for the same transaction cannot grant or roll back a payment twice
+var onchainPaymentMutex sync.Mutex
+
+// OnchainPaymentUpdate is a wallet report about a transaction output paying a subscriber address
+type OnchainPaymentUpdate struct {
+	Npub          string // Subscriber that owns the receiving address
+	TxID          string
+	Outpoint      string // txid:vout, unique per payment
+	Address       string // Receiving address
+	AmountSats    int64
+	Confirmations int    // UnknownConfirmations if the wallet did not report a count
+	Status        string // Empty for a live transaction, OnchainTxDropped or OnchainTxReplaced otherwise
+	ReplacedBy    string // Replacing transaction ID for OnchainTxReplaced
+}
+
+// subscriptionState is a snapshot of the parts of a subscription a payment can change
+type subscriptionState struct {
+	address    string
+	tier       string
+	expiration time.Time
+	storage    StorageInfo
+	credit     int64
+}
+
+// RequiredConfirmations returns the confirmations needed for a payment of amountSats.
+// Bands are matched from the smallest MaxSats up, with a MaxSats of 0 matching any amount.
+func RequiredConfirmations(amountSats int64, bands []types.ConfirmationBand) int {
+	sorted := make([]types.ConfirmationBand, len(bands))
+	copy(sorted, bands)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].MaxSats == 0 || sorted[j].MaxSats == 0 {
+			return sorted[j].MaxSats == 0 && sorted[i].MaxSats != 0
+		}
+		return sorted[i].MaxSats < sorted[j].MaxSats
+	})
+
+	for _, band := range sorted {
+		if band.MaxSats == 0 || amountSats <= band.MaxSats {
+			return band.Confirmations
+		}
+	}
+	return 0
+}
+
+// ProcessOnchainPayment applies a wallet report to the payment it describes. Payments are held
+// pending until they reach the configured confirmation depth, granted once, and rolled back
+// if they are replaced, dropped or fall below the threshold again after a reorg.
+func (m *SubscriptionManager) ProcessOnchainPayment(update OnchainPaymentUpdate) error {
+	if update.Status == OnchainTxDropped || update.Status == OnchainTxReplaced {
+		return m.RevertOnchainTransaction(update.TxID, update.Status, update.ReplacedBy)
+	}
+	if update.AmountSats <= 0 {
+		return fmt.Errorf("invalid payment amount: %d", update.AmountSats)
+	}
+
+	onchainPaymentMutex.Lock()
+	defer onchainPaymentMutex.Unlock()
+
+	statsStore := m.store.GetStatsStore()
+	payment, err := statsStore.GetOnchainPayment(update.Outpoint)
+	if err != nil {
+		return fmt.Errorf("failed to get on-chain payment: %v", err)
+	}
+
+	isNew := payment == nil
+	if isNew {
+		cfg, err := config.GetConfig()
+		if err != nil {
+			return fmt.Errorf("error getting config: %v", err)
+		}
+		payment = &types.OnchainPayment{
+			Outpoint:              update.Outpoint,
+			TxID:                  update.TxID,
+			Npub:                  update.Npub,
+			Address:               update.Address,
+			AmountSats:            update.AmountSats,
+			RequiredConfirmations: RequiredConfirmations(update.AmountSats, cfg.OnchainPayments.ConfirmationBands),
+			Status:                types.OnchainPaymentPending,
+		}
+	}
+
+	if payment.Status == types.OnchainPaymentReverted {
+		logging.Infof("Ignoring update for reverted on-chain payment %s", payment.Outpoint)
+		return nil
+	}
+
+	// Without a count a new payment is treated as unconfirmed and a tracked one keeps its last count
+	if update.Confirmations != UnknownConfirmations {
+		payment.Confirmations = update.Confirmations
+	}
+	thresholdMet := payment.Confirmations >= payment.RequiredConfirmations
+
+	switch {
+	case payment.Status == types.OnchainPaymentPending && thresholdMet:
+		return m.grantOnchainPayment(payment)
+
+	case payment.Status == types.OnchainPaymentConfirmed && !thresholdMet:
+		// The transaction was reorged out of the chain it confirmed in, hold it again until it reconfirms
+		logging.Infof("On-chain payment %s dropped to %d confirmations, rolling back", payment.Outpoint, payment.Confirmations)
+		payment.Status = types.OnchainPaymentPending
+		if err := statsStore.SaveOnchainPayment(payment); err != nil {
+			return fmt.Errorf("failed to save on-chain payment: %v", err)
+		}
+		if err := m.rollbackOnchainPayment(payment); err != nil {
+			return err
+		}
+		m.createOnchainPaymentNotification(payment, types.PaymentStatusReverted, false)
+		return nil
+	}
+
+	if err := statsStore.SaveOnchainPayment(payment); err != nil {
+		return fmt.Errorf("failed to save on-chain payment: %v", err)
+	}
+
+	if isNew {
+		logging.Infof("Holding on-chain payment %s for %s until %d confirmations",
+			payment.Outpoint, payment.Npub, payment.RequiredConfirmations)
+		m.createOnchainPaymentNotification(payment, types.PaymentStatusPending, false)
+		if err := m.RefreshSubscriptionEvent(payment.Npub); err != nil {
+			logging.Infof("Warning: failed to mark subscription pending for %s: %v", payment.Npub, err)
+		}
+	}
+
+	return nil
+}
+
+// RevertOnchainTransaction marks every tracked output of a replaced or dropped transaction as
+// reverted and rolls back whatever the confirmed outputs granted
+func (m *SubscriptionManager) RevertOnchainTransaction(txID string, reason string, replacedBy string) error {
+	onchainPaymentMutex.Lock()
+	defer onchainPaymentMutex.Unlock()
+
+	statsStore := m.store.GetStatsStore()
+	payments, err := statsStore.GetOnchainPaymentsByTxID(txID)
+	if err != nil {
+		return fmt.Errorf("failed to get on-chain payments: %v", err)
+	}
+
+	for i := range payments {
+		payment := &payments[i]
+		if payment.Status == types.OnchainPaymentReverted {
+			continue
+		}
+
+		logging.Infof("On-chain payment %s was %s, reverting", payment.Outpoint, reason)
+		wasConfirmed := payment.Status == types.OnchainPaymentConfirmed
+		payment.Status = types.OnchainPaymentReverted
+		payment.ReplacedBy = replacedBy
+		if err := statsStore.SaveOnchainPayment(payment); err != nil {
+			return fmt.Errorf("failed to save on-chain payment: %v", err)
+		}
+
+		if wasConfirmed {
+			if err := m.rollbackOnchainPayment(payment); err != nil {
+				return err
+			}
+			m.createOnchainPaymentNotification(payment, types.PaymentStatusReverted, false)
+			continue
+		}
+
+		m.createOnchainPaymentNotification(payment, types.PaymentStatusCancelled, false)
+		if err := m.RefreshSubscriptionEvent(payment.Npub); err != nil {
+			logging.Infof("Warning: failed to clear pending payment for %s: %v", payment.Npub, err)
+		}
+	}
+
+	return nil
+}
+
+// grantOnchainPayment credits a payment that reached its confirmation threshold and records
+// the changes it made so a later rollback can undo them
+func (m *SubscriptionManager) grantOnchainPayment(payment *types.OnchainPayment) error {
+	statsStore := m.store.GetStatsStore()
+
+	before, err := m.loadSubscriptionState(payment.Npub)
+	if err != nil {
+		return err
+	}
+	paidSubscriber, err := statsStore.GetPaidSubscriberByNpub(payment.Npub)
+	isNewSubscriber := err != nil || paidSubscriber == nil
+
+	// Mark the payment confirmed first so the updated kind 11888 event no longer lists it as pending
+	payment.Status = types.OnchainPaymentConfirmed
+	if err := statsStore.SaveOnchainPayment(payment); err != nil {
+		return fmt.Errorf("failed to save on-chain payment: %v", err)
+	}
+
+	if err := m.ProcessPayment(payment.Npub, payment.TxID, payment.AmountSats); err != nil {
+		payment.Status = types.OnchainPaymentPending
+		if saveErr := statsStore.SaveOnchainPayment(payment); saveErr != nil {
+			logging.Infof("Warning: failed to reset on-chain payment %s: %v", payment.Outpoint, saveErr)
+		}
+		return fmt.Errorf("failed to process subscription: %v", err)
+	}
+
+	after, err := m.loadSubscriptionState(payment.Npub)
+	if err != nil {
+		return err
+	}
+
+	payment.PreviousTier = before.tier
+	payment.GrantedTier = after.tier
+	payment.GrantedBytes = after.storage.TotalBytes - before.storage.TotalBytes
+	payment.GrantedSeconds = after.expiration.Unix() - before.expiration.Unix()
+	payment.GrantedCredit = after.credit - before.credit
+	if err := statsStore.SaveOnchainPayment(payment); err != nil {
+		return fmt.Errorf("failed to save on-chain payment: %v", err)
+	}
+
+	logging.Infof("Granted on-chain payment %s for %s after %d confirmations",
+		payment.Outpoint, payment.Npub, payment.Confirmations)
+	m.createOnchainPaymentNotification(payment, types.PaymentStatusConfirmed, isNewSubscriber)
+	return nil
+}
+
+// rollbackOnchainPayment undoes the storage, time, tier and credit a payment granted
+func (m *SubscriptionManager) rollbackOnchainPayment(payment *types.OnchainPayment) error {
+	state, err := m.loadSubscriptionState(payment.Npub)
+	if err != nil {
+		return err
+	}
+
+	storage := state.storage
+	if !storage.IsUnlimited {
+		storage.TotalBytes -= payment.GrantedBytes
+		if storage.TotalBytes < 0 {
+			storage.TotalBytes = 0
+		}
+	}
+	storage.UpdatedAt = time.Now()
+
+	expiration := time.Unix(state.expiration.Unix()-payment.GrantedSeconds, 0)
+	tier := state.tier
+	if tier == payment.GrantedTier {
+		tier = payment.PreviousTier
+	}
+
+	credit := state.credit - payment.GrantedCredit
+	if credit < 0 {
+		credit = 0
+	}
+	if credit != state.credit {
+		if err := m.store.GetStatsStore().UpdateSubscriberCredit(payment.Npub, credit); err != nil {
+			return fmt.Errorf("failed to restore credit: %v", err)
+		}
+	}
+
+	if err := m.createOrUpdateNIP88Event(&types.Subscriber{
+		Npub:    payment.Npub,
+		Address: state.address,
+	}, tier, expiration, &storage); err != nil {
+		return fmt.Errorf("failed to update NIP-88 event: %v", err)
+	}
+
+	if expiration.After(time.Now()) {
+		m.updatePaidSubscriberRecord(payment.Npub, tier, expiration, &storage)
+	} else if err := m.store.GetStatsStore().DeletePaidSubscriber(payment.Npub); err != nil {
+		logging.Infof("Warning: failed to remove paid subscriber record for %s: %v", payment.Npub, err)
+	}
+
+	logging.Infof("Rolled back on-chain payment %s for %s: tier %s, %d bytes, credit %d sats",
+		payment.Outpoint, payment.Npub, tier, storage.TotalBytes, credit)
+	return nil
+}
+
+// loadSubscriptionState reads a subscriber's current kind 11888 event and credit
+func (m *SubscriptionManager) loadSubscriptionState(npub string) (*subscriptionState, error) {
+	hexKey, npubKey, err := normalizePubkey(npub)
+	if err != nil {
+		return nil, fmt.Errorf("failed to normalize pubkey: %v", err)
+	}
+
+	events, err := m.store.QueryEvents(nostr.Filter{
+		Kinds: []int{11888},
+		Tags: nostr.TagMap{
+			"p": []string{npubKey, hexKey}, // Check both formats
+		},
+		Limit: 1,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query events: %v", err)
+	}
+	if len(events) == 0 {
+		return nil, fmt.Errorf("no NIP-88 event found for user")
+	}
+	event := events[0]
+
+	storage, err := m.extractStorageInfo(event)
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract storage info: %v", err)
+	}
+
+	state := &subscriptionState{
+		address: getTagValue(event.Tags, "relay_bitcoin_address"),
+		tier:    getTagValue(event.Tags, "active_subscription"),
+		storage: storage,
+	}
+	if expirationUnix := getTagUnixValue(event.Tags, "active_subscription"); expirationUnix > 0 {
+		state.expiration = time.Unix(expirationUnix, 0)
+	} else {
+		state.expiration = time.Unix(0, 0)
+	}
+
+	// Subscribers without an address record have no credit
+	if credit, err := m.store.GetStatsStore().GetSubscriberCredit(npub); err == nil {
+		state.credit = credit
+	}
+
+	return state, nil
+}
+
+// createOnchainPaymentNotification records a payment notification for an on-chain state transition.
+// Reversals of granted payments carry a negative amount so revenue totals stay net.
+func (m *SubscriptionManager) createOnchainPaymentNotification(payment *types.OnchainPayment, status string, isNewSubscriber bool) {
+	statsStore := m.store.GetStatsStore()
+
+	notification := &types.PaymentNotification{
+		PubKey:           payment.Npub,
+		TxID:             payment.TxID,
+		Amount:           payment.AmountSats,
+		SubscriptionTier: payment.GrantedTier,
+		Status:           status,
+	}
+
+	switch status {
+	case types.PaymentStatusConfirmed:
+		notification.IsNewSubscriber = isNewSubscriber
+		if paidSubscriber, err := statsStore.GetPaidSubscriberByNpub(payment.Npub); err == nil && paidSubscriber != nil {
+			notification.ExpirationDate = paidSubscriber.ExpirationDate
+		}
+	case types.PaymentStatusReverted:
+		notification.Amount = -payment.AmountSats
+	}
+
+	if err := statsStore.CreatePaymentNotification(notification); err != nil {
+		logging.Infof("Warning: failed to create payment notification: %v", err)
+		return
+	}
+
+	logging.Infof("Created %s payment notification for %s: %d sats (tx %s)",
+		status, payment.Npub, notification.Amount, payment.TxID)
+}
@@ -0,0 +1,176 @@
+package subscription_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/nbd-wtf/go-nostr/nip19"
+	"github.com/spf13/viper"
+
+	"github.com/HORNET-Storage/hornet-storage/lib/config"
+	"github.com/HORNET-Storage/hornet-storage/lib/stores/badgerhold"
+	"github.com/HORNET-Storage/hornet-storage/lib/subscription"
+	"github.com/HORNET-Storage/hornet-storage/lib/types"
+)
+
+func TestRequiredConfirmations(t *testing.T) {
+	// Bands are deliberately out of order, the unbounded band must still match last
+	bands := []types.ConfirmationBand{
+		{MaxSats: 0, Confirmations: 6},
+		{MaxSats: 1000000, Confirmations: 2},
+		{MaxSats: 10000, Confirmations: 0},
+	}
+
+	cases := []struct {
+		amount int64
+		want   int
+	}{
+		{5000, 0},
+		{10000, 0},
+		{10001, 2},
+		{1000000, 2},
+		{5000000, 6},
+	}
+	for _, c := range cases {
+		if got := subscription.RequiredConfirmations(c.amount, bands); got != c.want {
+			t.Errorf("RequiredConfirmations(%d) = %d, want %d", c.amount, got, c.want)
+		}
+	}
+
+	if got := subscription.RequiredConfirmations(5000, nil); got != 0 {
+		t.Errorf("expected no confirmations without bands, got %d", got)
+	}
+}
+
+func TestOnchainPaymentConfirmationAndRollback(t *testing.T) {
+	viper.Reset()
+	viper.Set("allowed_users.mode", "subscription")
+	viper.Set("onchain_payments.confirmation_bands", []map[string]interface{}{
+		{"max_sats": 0, "confirmations": 2},
+	})
+	config.InitConfigForTesting()
+	t.Cleanup(viper.Reset)
+
+	tempDir := t.TempDir()
+	store, err := badgerhold.InitStore(filepath.Join(tempDir, "store"), filepath.Join(tempDir, "stats.db"))
+	if err != nil {
+		t.Fatalf("InitStore: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := store.Cleanup(); err != nil {
+			t.Fatalf("Cleanup: %v", err)
+		}
+	})
+
+	relayKey, err := btcec.NewPrivateKey()
+	if err != nil {
+		t.Fatalf("NewPrivateKey: %v", err)
+	}
+	manager := subscription.NewSubscriptionManager(store, relayKey, "", []types.SubscriptionTier{
+		{Name: "Basic", MonthlyLimitBytes: 1 << 30, PriceSats: 1000},
+	})
+
+	pubkey, _ := nostr.GetPublicKey(nostr.GeneratePrivateKey())
+	npub, _ := nip19.EncodePublicKey(pubkey)
+	address := "bc1qsubscriber"
+	if err := store.GetStatsStore().SaveSubscriberAddress(&types.SubscriberAddress{
+		IndexHornets: "0",
+		Address:      address,
+		WalletName:   "default",
+		Status:       subscription.AddressStatusAllocated,
+		Npub:         &npub,
+	}); err != nil {
+		t.Fatalf("SaveSubscriberAddress: %v", err)
+	}
+	if err := manager.CreateSubscriptionEvent(npub, address); err != nil {
+		t.Fatalf("CreateSubscriptionEvent: %v", err)
+	}
+
+	payment := func(confirmations int) subscription.OnchainPaymentUpdate {
+		return subscription.OnchainPaymentUpdate{
+			Npub:          npub,
+			TxID:          "tx1",
+			Outpoint:      "tx1:0",
+			Address:       address,
+			AmountSats:    1000,
+			Confirmations: confirmations,
+		}
+	}
+
+	// A fake wallet feed: the payment confirms, some reports omit the count, it is reorged out, reconfirms and is finally replaced
+	feed := []struct {
+		name    string
+		update  subscription.OnchainPaymentUpdate
+		status  string
+		tier    string
+		pending bool
+	}{
+		{"seen in mempool", payment(0), "pending", "", true},
+		{"count not reported", payment(subscription.UnknownConfirmations), "pending", "", true},
+		{"first confirmation", payment(1), "pending", "", true},
+		{"threshold met", payment(2), "active", "Basic", false},
+		{"count not reported once confirmed", payment(subscription.UnknownConfirmations), "active", "Basic", false},
+		{"reorged out", payment(0), "pending", "", true},
+		{"reconfirmed", payment(3), "active", "Basic", false},
+		{"replaced", subscription.OnchainPaymentUpdate{TxID: "tx1", Status: subscription.OnchainTxReplaced, ReplacedBy: "tx2"}, "inactive", "", false},
+	}
+
+	for _, step := range feed {
+		if err := manager.ProcessOnchainPayment(step.update); err != nil {
+			t.Fatalf("%s: ProcessOnchainPayment: %v", step.name, err)
+		}
+
+		events, err := store.QueryEvents(nostr.Filter{
+			Kinds: []int{11888},
+			Tags:  nostr.TagMap{"p": []string{npub}},
+		})
+		if err != nil || len(events) != 1 {
+			t.Fatalf("%s: expected one kind 11888 event, got %d (%v)", step.name, len(events), err)
+		}
+		tags := events[0].Tags
+
+		if status := tags.GetFirst([]string{"subscription_status"}); status == nil || (*status)[1] != step.status {
+			t.Errorf("%s: expected status %q, got %v", step.name, step.status, status)
+		}
+		tier := ""
+		if active := tags.GetFirst([]string{"active_subscription"}); active != nil {
+			tier = (*active)[1]
+		}
+		if tier != step.tier {
+			t.Errorf("%s: expected tier %q, got %q", step.name, step.tier, tier)
+		}
+		if pending := tags.GetFirst([]string{"pending_payment"}) != nil; pending != step.pending {
+			t.Errorf("%s: expected pending_payment tag %t, got %t", step.name, step.pending, pending)
+		}
+	}
+
+	notifications, _, err := store.GetStatsStore().GetUserPaymentNotifications(npub, 1, 20)
+	if err != nil {
+		t.Fatalf("GetUserPaymentNotifications: %v", err)
+	}
+	statuses := map[string]int{}
+	for _, notification := range notifications {
+		statuses[notification.Status]++
+	}
+	expected := map[string]int{
+		types.PaymentStatusPending:   1,
+		types.PaymentStatusConfirmed: 2,
+		types.PaymentStatusReverted:  2,
+	}
+	for status, count := range expected {
+		if statuses[status] != count {
+			t.Errorf("expected %d %s notifications, got %d (%v)", count, status, statuses[status], statuses)
+		}
+	}
+
+	// Every grant was rolled back, so no revenue remains
+	revenue, err := store.GetStatsStore().GetTotalRevenue()
+	if err != nil {
+		t.Fatalf("GetTotalRevenue: %v", err)
+	}
+	if revenue != 0 {
+		t.Errorf("expected net revenue of 0, got %d", revenue)
+	}
+}
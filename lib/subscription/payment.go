@@ -9,6 +9,7 @@ import (
 
 	"github.com/nbd-wtf/go-nostr"
 
+	"github.com/HORNET-Storage/hornet-storage/lib/backfill"
 	"github.com/HORNET-Storage/hornet-storage/lib/config"
 	"github.com/HORNET-Storage/hornet-storage/lib/logging"
 	"github.com/HORNET-Storage/hornet-storage/lib/types"
@@ -81,6 +82,8 @@ func (m *SubscriptionManager) ProcessPayment(
 			logging.Infof("Added %d sats to credit for %s (total credit: %d)",
 				amountSats, npub, newCredit)
 
+			go m.notifyCreditAdded(npub, transactionID, amountSats, newCredit)
+
 			// Update the NIP-88 event to reflect the new credit amount
 			events, err := m.store.QueryEvents(nostr.Filter{
 				Kinds: []int{11888},
@@ -299,6 +302,9 @@ func (m *SubscriptionManager) ProcessPayment(
 	logging.Infof("Successfully processed payment for %s: %d sats for tier %s",
 		npub, amountSats, tier.Name)
 
+	go m.notifyPaymentReceived(npub, transactionID, amountSats, tier.Name, endDate)
+	backfill.Trigger(npub, "payment")
+
 	return nil
 }
 
@@ -508,6 +514,9 @@ func (m *SubscriptionManager) processHighTierPayment(
 	logging.Infof("Successfully processed high-tier payment: %d sats for %d months of %s tier",
 		amountSats, fullPeriods, highestTier.Name)
 
+	go m.notifyPaymentReceived(npub, transactionID, amountSats, highestTier.Name, endDate)
+	backfill.Trigger(npub, "payment")
+
 	return nil
 }
 
@@ -178,6 +178,34 @@ func (m *SubscriptionManager) UpdateStorageUsage(npub string, newBytes int64) er
 	return nil
 }
 
+// StorageQuota returns the bytes a subscriber has used and may use according to their
+// NIP-88 event. limited is false for unlimited tiers and for pubkeys without a NIP-88
+// event or storage tag, such as allowed users whose event has not been created yet.
+func (m *SubscriptionManager) StorageQuota(npub string) (used int64, limit int64, limited bool, err error) {
+	events, err := m.store.QueryEvents(nostr.Filter{
+		Kinds: []int{11888},
+		Tags: nostr.TagMap{
+			"p": []string{npub},
+		},
+		Limit: 1,
+	})
+	if err != nil {
+		return 0, 0, false, fmt.Errorf("failed to query NIP-88 event: %v", err)
+	}
+	if len(events) == 0 {
+		return 0, 0, false, nil
+	}
+
+	storageInfo, err := m.extractStorageInfo(events[0])
+	if err != nil {
+		return 0, 0, false, fmt.Errorf("failed to extract storage info: %v", err)
+	}
+	if storageInfo.IsUnlimited || storageInfo.TotalBytes <= 0 {
+		return storageInfo.UsedBytes, 0, false, nil
+	}
+	return storageInfo.UsedBytes, storageInfo.TotalBytes, true, nil
+}
+
 // CheckStorageAvailability checks if a subscriber has enough available storage for a given number of bytes.
 // It retrieves storage data from the user's NIP-88 event and validates against their current usage and limits.
 func (m *SubscriptionManager) CheckStorageAvailability(npub string, requestedBytes int64) error {
@@ -59,6 +59,8 @@ func (m *SubscriptionManager) getRelayMode() string {
 		return "invite-only"
 	case "only-me":
 		return "only-me"
+	case "wot":
+		return "wot"
 
 	default:
 		logging.Infof("[DEBUG] Unknown mode '%s', defaulting to 'unknown'", mode)
@@ -123,8 +125,8 @@ func (m *SubscriptionManager) findAppropriateTierForUser(pubkey string, currentT
 	// logging.Infof("DEBUG: Available tiers in findAppropriateTierForUser: %d", len(allowedUsersSettings.Tiers))
 
 	switch mode {
-	case "public", "free":
-		// In public/free mode, assign users to the first available free tier
+	case "public", "free", "wot":
+		// In public/free/wot mode, assign users to the first available free tier
 		logging.Infof("DEBUG: Looking for free tier (PriceSats <= 0)")
 		for i := range allowedUsersSettings.Tiers {
 			tier := &allowedUsersSettings.Tiers[i]
@@ -73,6 +73,7 @@ func UpdateAccessControlSettings(settings *types.AllowedUsersSettings) error {
 
 	// Update settings
 	globalAccessControl.UpdateSettings(settings)
+	InvalidateRelayInfo()
 
 	logging.Infof("Access control settings successfully updated to %s mode with Read: %s, Write: %s",
 		settings.Mode, settings.Read, settings.Write)
@@ -45,5 +45,5 @@ func handleAuthMessage(c *websocket.Conn, env *nostr.AuthEnvelope, challenge str
 	// are dispatched to this connection. Safe to ignore the error — it just
 	// means no subscriptions exist yet; the next REQ will pick up the
 	// auth state from connectionState.
-	AuthenticateConnection(c)
+	AuthenticateConnection(c, state.pubkey)
 }
@@ -7,13 +7,13 @@ import (
 	jsoniter "github.com/json-iterator/go"
 	"github.com/nbd-wtf/go-nostr"
 
+	"github.com/HORNET-Storage/hornet-storage/lib/eventbus"
 	lib_nostr "github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr"
 	"github.com/HORNET-Storage/hornet-storage/lib/logging"
 	"github.com/HORNET-Storage/hornet-storage/lib/stores"
-	"github.com/HORNET-Storage/hornet-storage/services/push"
 )
 
-func handleEventMessage(c *websocket.Conn, env *nostr.EventEnvelope, _ *connectionState, store stores.Store) {
+func handleEventMessage(c *websocket.Conn, env *nostr.EventEnvelope, state *connectionState, store stores.Store) {
 	// Always check if the event is from a blocked pubkey regardless of authentication
 	// Note: We use the pubkey from the event itself, not the connection state,
 	// as events could be relayed from other pubkeys or unauthenticated users
@@ -45,6 +45,15 @@ func handleEventMessage(c *websocket.Conn, env *nostr.EventEnvelope, _ *connecti
 			write("OK", env.Event.ID, false, "Event rejected: Write access denied")
 			return
 		}
+
+		authPubkey := ""
+		if state != nil && state.authenticated {
+			authPubkey = state.pubkey
+		}
+		if err := accessControl.CheckWritePolicy(&env.Event, authPubkey); err != nil {
+			write("OK", env.Event.ID, false, err.Error())
+			return
+		}
 	}
 
 	// Try to get specific handler for this kind
@@ -82,23 +91,20 @@ func handleEventWithHandler(c *websocket.Conn, env *nostr.EventEnvelope, handler
 		return json.Marshal(env)
 	}
 
-	write := func(messageType string, params ...interface{}) {
+	write, accepted := lib_nostr.TrackAccepted(func(messageType string, params ...interface{}) {
 		response := lib_nostr.BuildResponse(messageType, params)
 		if len(response) > 0 {
 			handleIncomingMessage(c, response)
 		}
-	}
+	})
 
-	// Store the event first, then notify. This ensures subscribers who
-	// re-query after receiving the notification will always find the event.
+	// Store the event first, then publish. This ensures subscribers who
+	// re-query after receiving the event will always find it.
 	handler(read, write)
 
-	// Notify live WebSocket subscribers (async — pushed to a buffered channel
-	// and processed by a dedicated goroutine, so this is non-blocking).
-	notifyListeners(&env.Event)
-
-	// Process event for push notifications
-	if pushService := push.GetGlobalPushService(); pushService != nil {
-		pushService.ProcessEvent(&env.Event)
+	// Publish accepted events to live subscribers on every transport and to
+	// internal consumers such as push notifications.
+	if accepted() && !lib_nostr.IsPrivateKind(env.Event.Kind) {
+		eventbus.Publish(&env.Event)
 	}
 }
@@ -1,7 +1,6 @@
 package websocket
 
 import (
-	"context"
 	"crypto/rand"
 	"encoding/hex"
 	"fmt"
@@ -9,31 +8,26 @@ import (
 
 	"sync/atomic"
 
-	"github.com/HORNET-Storage/hornet-storage/lib/logging"
+	"github.com/HORNET-Storage/hornet-storage/lib/eventbus"
+	"github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr/contentfilter"
 	"github.com/gofiber/contrib/websocket"
 	"github.com/nbd-wtf/go-nostr"
 	"github.com/puzpuzpuz/xsync/v3"
 )
 
 // Global map to hold all listeners indexed by WebSocket connections and subscription IDs.
+// The subscriptions themselves live on the shared event bus.
 var listeners = xsync.NewMapOf[*websocket.Conn, ListenerData]()
 
 // Per-connection write mutexes to prevent concurrent websocket writes
-// between the notification processor goroutine and connection handler goroutines.
+// between event bus deliveries and connection handler goroutines.
 var connWriteMu = xsync.NewMapOf[*websocket.Conn, *sync.Mutex]()
 
-// Buffered channel for async event notifications.
-// Events are queued here by notifyListeners and processed by a dedicated goroutine.
-var notificationChan = make(chan nostr.Event, 1000)
-
 // Global challenge variable
 var globalChallenge atomic.Value
 
 const challengeLength = 32
 
-// notificationProcessorOnce ensures the notification processor starts exactly once.
-var notificationProcessorOnce sync.Once
-
 // getConnWriteMutex returns (or creates) the write mutex for a given connection.
 func getConnWriteMutex(ws *websocket.Conn) *sync.Mutex {
 	mu, _ := connWriteMu.LoadOrCompute(ws, func() *sync.Mutex {
@@ -42,96 +36,66 @@ func getConnWriteMutex(ws *websocket.Conn) *sync.Mutex {
 	return mu
 }
 
-// StartNotificationProcessor starts the background goroutine that processes
-// event notifications asynchronously. Safe to call multiple times — only starts once.
-func StartNotificationProcessor() {
-	notificationProcessorOnce.Do(func() {
-		go func() {
-			for {
-				select {
-				case event := <-notificationChan:
-					processNotification(&event)
-				case <-shutdownChan:
-					// Drain any remaining notifications before exiting
-					for {
-						select {
-						case event := <-notificationChan:
-							processNotification(&event)
-						default:
-							return
-						}
-					}
-				}
-			}
-		}()
-		logging.Info("Async notification processor started")
+// setListener registers a live subscription for the connection on the event bus,
+// replacing any existing subscription with the same ID.
+func setListener(id string, ws *websocket.Conn, filters nostr.Filters) {
+	conData, _ := listeners.LoadOrCompute(ws, func() ListenerData {
+		return ListenerData{
+			challenge:     "",
+			subscriptions: xsync.NewMapOf[string, *eventbus.Subscription](),
+		}
 	})
-}
 
-// processNotification handles the actual fan-out to all matching listeners.
-// Runs on the dedicated notification goroutine — never on the event handler path.
-func processNotification(event *nostr.Event) {
-	listeners.Range(func(ws *websocket.Conn, conData ListenerData) bool {
-		if !conData.authenticated {
-			return true // Skip unauthenticated connections
+	sub := eventbus.Subscribe(id, filters, func(event *nostr.Event) error {
+		// Live events are only delivered once the connection has authenticated
+		current, ok := listeners.Load(ws)
+		if !ok || !current.authenticated {
+			return nil
 		}
-		conData.subscriptions.Range(func(id string, listener *Subscription) bool {
-			if !listener.filters.Match(event) {
-				return true
-			}
-			mu := getConnWriteMutex(ws)
-			mu.Lock()
-			err := ws.WriteJSON(nostr.EventEnvelope{SubscriptionID: &id, Event: *event})
-			mu.Unlock()
-			if err != nil {
-				if !isConnectionClosedError(err) {
-					logging.Infof("Error notifying listener: %v", err)
-				}
-			}
-			return true
+		// Apply the user's mute words and AI filter instructions
+		if feed := contentfilter.GetFeed(); feed != nil && !feed.AllowLive(current.pubkey, event) {
+			return nil
+		}
+		return sendWebSocketMessage(ws, nostr.EventEnvelope{SubscriptionID: &id, Event: *event})
+	}, func(reason string) {
+		// The bus closed a subscription that fell behind, forget it and tell the client
+		conData.subscriptions.Compute(id, func(current *eventbus.Subscription, loaded bool) (*eventbus.Subscription, bool) {
+			return current, loaded && isClosed(current)
 		})
-		return true
+		sendWebSocketMessage(ws, nostr.ClosedEnvelope{SubscriptionID: id, Reason: reason})
 	})
-}
 
-// notifyListeners queues an event for async notification to all matching listeners.
-// This is non-blocking — the event is pushed to a channel and processed by a
-// dedicated background goroutine, so the calling event handler is never held up.
-func notifyListeners(event *nostr.Event) {
-	select {
-	case notificationChan <- *event:
-		// Event queued successfully
-	default:
-		// Channel is full — drop the notification to prevent blocking.
-		// This should be rare with a 1000-event buffer; if it happens
-		// frequently, increase the buffer size.
-		logging.Infof("Warning: notification channel full, dropping notification for event %s", event.ID)
+	if previous, loaded := conData.subscriptions.LoadAndStore(id, sub); loaded {
+		previous.Close()
 	}
-}
-
-// SetListener sets a new listener with given ID, WebSocket connection, filters, and cancel function.
-func setListener(id string, ws *websocket.Conn, filters nostr.Filters, cancel context.CancelFunc) {
-	conData, _ := listeners.LoadOrCompute(ws, func() ListenerData {
-		return ListenerData{
-			challenge:     "",
-			subscriptions: xsync.NewMapOf[string, *Subscription](),
-		}
-	})
-
-	conData.subscriptions.Store(id, &Subscription{filters: filters, cancel: cancel})
 	// Preserve the connection's existing auth state — don't reset it.
 	// New entries default to authenticated=false (Go zero value);
 	// AuthenticateConnection() sets it to true after successful auth.
 	listeners.Store(ws, conData)
 }
 
-// RemoveListenerId removes a listener by its ID and cancels its context.
+// openSubscriptions counts the connection's open subscriptions other than id,
+// so replacing an existing subscription is never refused
+func openSubscriptions(ws *websocket.Conn, id string) int {
+	conData, ok := listeners.Load(ws)
+	if !ok {
+		return 0
+	}
+
+	open := conData.subscriptions.Size()
+	if _, exists := conData.subscriptions.Load(id); exists {
+		open--
+	}
+	return open
+}
+
+// RemoveListenerId closes a subscription by its ID.
 // Returns true if a listener was successfully found and removed, false otherwise.
 func removeListenerId(ws *websocket.Conn, id string) bool {
 	removed := false
 	if conData, ok := listeners.Load(ws); ok {
-		if listener, ok := conData.subscriptions.LoadAndDelete(id); ok {
-			listener.cancel()
+		if sub, ok := conData.subscriptions.LoadAndDelete(id); ok {
+			sub.Close()
 			removed = true
 		}
 		if conData.subscriptions.Size() == 0 {
@@ -142,12 +106,27 @@ func removeListenerId(ws *websocket.Conn, id string) bool {
 	return removed
 }
 
-// RemoveListener removes all listeners associated with a WebSocket connection.
+// RemoveListener closes all subscriptions associated with a WebSocket connection.
 func removeListener(ws *websocket.Conn) {
-	listeners.Delete(ws)
+	if conData, ok := listeners.LoadAndDelete(ws); ok {
+		conData.subscriptions.Range(func(id string, sub *eventbus.Subscription) bool {
+			sub.Close()
+			return true
+		})
+	}
 	connWriteMu.Delete(ws)
 }
 
+// isClosed reports whether the bus has stopped delivering to a subscription
+func isClosed(sub *eventbus.Subscription) bool {
+	select {
+	case <-sub.Done():
+		return true
+	default:
+		return false
+	}
+}
+
 func GetListenerChallenge(ws *websocket.Conn) (*string, error) {
 	conData, ok := listeners.Load(ws)
 	if !ok {
@@ -157,13 +136,14 @@ func GetListenerChallenge(ws *websocket.Conn) (*string, error) {
 	return &conData.challenge, nil
 }
 
-func AuthenticateConnection(ws *websocket.Conn) error {
+func AuthenticateConnection(ws *websocket.Conn, pubkey string) error {
 	conData, ok := listeners.Load(ws)
 	if !ok {
 		return fmt.Errorf("no listeners found for this WebSocket connection")
 	}
 
 	conData.authenticated = true
+	conData.pubkey = pubkey
 	listeners.Store(ws, conData)
 
 	return nil
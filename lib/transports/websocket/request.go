@@ -1,8 +1,6 @@
 package websocket
 
 import (
-	"context"
-
 	jsoniter "github.com/json-iterator/go"
 
 	"github.com/gofiber/contrib/websocket"
@@ -23,14 +21,17 @@ func handleReqMessage(c *websocket.Conn, env *nostr.ReqEnvelope, state *connecti
 	handler := lib_nostr.GetHandler("filter")
 
 	if handler != nil {
-		_, cancelFunc := context.WithCancel(context.Background())
+		if reason := lib_nostr.SubscriptionLimitReason(openSubscriptions(c, env.SubscriptionID)); reason != "" {
+			sendWebSocketMessage(c, nostr.ClosedEnvelope{SubscriptionID: env.SubscriptionID, Reason: reason})
+			return
+		}
 
-		setListener(env.SubscriptionID, c, env.Filters, cancelFunc)
+		setListener(env.SubscriptionID, c, env.Filters)
 
 		// If the connection authenticated before this REQ, sync that state
 		// to the listener data so live notifications reach this subscriber.
 		if state.authenticated {
-			AuthenticateConnection(c)
+			AuthenticateConnection(c, state.pubkey)
 		}
 
 		read := func() ([]byte, error) {
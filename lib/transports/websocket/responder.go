@@ -12,7 +12,7 @@ import (
 
 func sendWebSocketMessage(ws *websocket.Conn, msg interface{}) error {
 	// Acquire per-connection write mutex to prevent concurrent writes
-	// between the notification processor goroutine and connection handler goroutines.
+	// between event bus deliveries and connection handler goroutines.
 	mu := getConnWriteMutex(ws)
 	mu.Lock()
 	defer mu.Unlock()
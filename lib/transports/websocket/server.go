@@ -8,6 +8,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"sort"
+	"strings"
 	"sync"
 	"time"
 
@@ -23,10 +24,14 @@ import (
 
 	"github.com/HORNET-Storage/hornet-storage/lib/config"
 	"github.com/HORNET-Storage/hornet-storage/lib/handlers/blossom"
+	lib_nostr "github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr"
 	"github.com/HORNET-Storage/hornet-storage/lib/logging"
 	"github.com/HORNET-Storage/hornet-storage/lib/stores"
+	"github.com/HORNET-Storage/hornet-storage/lib/types"
 	"github.com/HORNET-Storage/hornet-storage/lib/upnp"
+	access_handlers "github.com/HORNET-Storage/hornet-storage/lib/web/handlers/access"
 	"github.com/HORNET-Storage/hornet-storage/lib/web/handlers/push"
+	"github.com/HORNET-Storage/hornet-storage/lib/web/handlers/wot"
 	"github.com/HORNET-Storage/hornet-storage/lib/web/middleware"
 )
 
@@ -117,11 +122,6 @@ func terminateIfBlocked(c *websocket.Conn, state *connectionState, store stores.
 }
 
 func BuildServer(store stores.Store) *fiber.App {
-	// Start the async notification processor before accepting connections.
-	// Events are queued via notifyListeners() and fan-out happens on a
-	// dedicated goroutine, so websocket handlers are never blocked.
-	StartNotificationProcessor()
-
 	app := fiber.New()
 
 	// Middleware for handling relay information requests
@@ -149,6 +149,48 @@ func BuildServer(store stores.Store) *fiber.App {
 	pushRoutes.Post("/register", push.RegisterDeviceHandler(store))
 	pushRoutes.Post("/unregister", push.UnregisterDeviceHandler(store))
 	pushRoutes.Post("/test", push.TestNotificationHandler(store))
+	pushRoutes.Get("/preferences", push.GetPreferencesHandler(store))
+	pushRoutes.Put("/preferences", push.UpdatePreferencesHandler(store))
+	pushRoutes.Get("/vapid", push.VAPIDKeyHandler())
+
+	// ================================
+	// WOT EXPORT ROUTES (NIP-98 AUTH)
+	// ================================
+
+	canRead := func(pubkey string) error {
+		if ac := GetAccessControl(); ac != nil {
+			return ac.CanRead(pubkey)
+		}
+		return nil
+	}
+
+	relaySeeds := func() []string {
+		if ac := GetAccessControl(); ac != nil {
+			return ac.RelayWotSeeds()
+		}
+		return nil
+	}
+
+	// Exports walk up to tens of thousands of contact lists, so each pubkey gets a few a minute
+	wotRoutes := app.Group("/wot")
+	wotRoutes.Use(middleware.NIP98Middleware())
+	wotRoutes.Use(middleware.PubkeyRateLimiterMiddleware(5, time.Minute))
+	wotRoutes.Get("/export", wot.ExportHandler(store, canRead, relaySeeds))
+	wotRoutes.Post("/publish", wot.PublishHandler(store, canRead))
+
+	// ================================
+	// INVITE ROUTES (NIP-98 AUTH)
+	// ================================
+
+	inviteRoutes := app.Group("/invite")
+	inviteRoutes.Use(middleware.NIP98Middleware())
+	inviteRoutes.Post("/redeem", access_handlers.RedeemInviteHandler(func(code string, pubkey string) (*types.AllowedUser, error) {
+		ac := GetAccessControl()
+		if ac == nil {
+			return nil, fmt.Errorf("access control is not available")
+		}
+		return ac.RedeemInvite(code, pubkey)
+	}))
 
 	app.Get("/", websocket.New(func(c *websocket.Conn) {
 		// Track this connection for graceful shutdown
@@ -163,6 +205,11 @@ func BuildServer(store stores.Store) *fiber.App {
 			return
 		}
 
+		// Oversized messages fail the read and close the connection
+		if limit := lib_nostr.MaxMessageLength(); limit > 0 {
+			c.SetReadLimit(int64(limit))
+		}
+
 		challenge := getGlobalChallenge()
 
 		// Initialize state with empty pubkey and current time for blocked check
@@ -332,7 +379,38 @@ func handleRelayInfoRequests(c *fiber.Ctx) error {
 	return c.Next()
 }
 
+// relayInfoCache holds the signed NIP-11 document and the config it was built from
+var relayInfoCache struct {
+	mu     sync.Mutex
+	config *types.Config
+	info   *NIP11RelayInfo
+}
+
+// GetRelayInfo returns the NIP-11 document, rebuilding it when the config has been
+// reloaded or the cached document was invalidated
 func GetRelayInfo() NIP11RelayInfo {
+	cfg, _ := config.GetConfig()
+
+	relayInfoCache.mu.Lock()
+	defer relayInfoCache.mu.Unlock()
+
+	if relayInfoCache.info == nil || relayInfoCache.config != cfg {
+		info := buildRelayInfo()
+		relayInfoCache.info = &info
+		relayInfoCache.config = cfg
+	}
+	return *relayInfoCache.info
+}
+
+// InvalidateRelayInfo drops the cached NIP-11 document so the next request rebuilds it.
+// Call this when settings that feed the document change.
+func InvalidateRelayInfo() {
+	relayInfoCache.mu.Lock()
+	relayInfoCache.info = nil
+	relayInfoCache.mu.Unlock()
+}
+
+func buildRelayInfo() NIP11RelayInfo {
 	// Format contact as "email | npub"
 	var contact string
 	email := viper.GetString("relay.contact")
@@ -376,6 +454,12 @@ func GetRelayInfo() NIP11RelayInfo {
 
 	// Build services map for external services only (services not derivable from offset)
 	relayInfo.Services = buildServicesMap()
+	relayInfo.Fees = buildFees()
+	relayInfo.Limitation = buildLimitation()
+	relayInfo.Retention = buildRetention()
+	relayInfo.Banner = viper.GetString("relay.banner")
+	relayInfo.TermsOfService = viper.GetString("relay.terms_of_service")
+	relayInfo.PostingPolicy = viper.GetString("relay.posting_policy")
 
 	privKey, _, err := signing.DeserializePrivateKey(viper.GetString("relay.private_key"))
 	dhtPubkey := viper.GetString("DHTPublicKey")
@@ -441,6 +525,72 @@ func buildServicesMap() RelayServices {
 	return services
 }
 
+// subscriptionPeriodSeconds is the length of one paid subscription period (one month)
+const subscriptionPeriodSeconds = 30 * 24 * 60 * 60
+
+// buildFees advertises the paid subscription tiers as NIP-11 subscription fees
+// Returns nil unless the relay is in subscription mode with at least one paid tier
+func buildFees() *NIP11Fees {
+	settings, err := config.GetAllowedUsersSettings()
+	if err != nil || settings.Mode != "subscription" {
+		return nil
+	}
+
+	var fees []NIP11Fee
+	for _, tier := range settings.Tiers {
+		if tier.PriceSats <= 0 {
+			continue
+		}
+		fees = append(fees, NIP11Fee{
+			Amount: int64(tier.PriceSats) * 1000,
+			Unit:   "msats",
+			Period: subscriptionPeriodSeconds,
+		})
+	}
+
+	if len(fees) == 0 {
+		return nil
+	}
+
+	return &NIP11Fees{Subscription: fees}
+}
+
+// buildLimitation advertises the limits the relay enforces in the NIP-11 limitation object
+func buildLimitation() *NIP11Limitation {
+	limitation := &NIP11Limitation{
+		MaxMessageLength: lib_nostr.MaxMessageLength(),
+		MaxSubscriptions: lib_nostr.MaxSubscriptions(),
+		DefaultLimit:     stores.DefaultMaxLimit,
+		MinPowDifficulty: lib_nostr.MinPowDifficulty(),
+	}
+
+	settings, err := config.GetAllowedUsersSettings()
+	if err != nil {
+		return limitation
+	}
+
+	read := strings.ToLower(settings.Read)
+	write := strings.ToLower(settings.Write)
+
+	// Reads other than all_users only return events to authenticated connections
+	limitation.AuthRequired = read != "all_users"
+	limitation.PaymentRequired = settings.Mode == "subscription" || read == "paid_users" || write == "paid_users"
+	limitation.RestrictedWrites = write != "all_users" || limitation.PaymentRequired ||
+		limitation.MinPowDifficulty > 0 || len(settings.WritePolicies) > 0
+
+	return limitation
+}
+
+// buildRetention advertises the relay's retention in the NIP-11 retention format.
+// Ephemeral kinds are never stored; everything else is kept until deleted.
+func buildRetention() []NIP11Retention {
+	notStored := int64(0)
+	return []NIP11Retention{{
+		Kinds: []interface{}{[]int{20000, 29999}},
+		Time:  &notStored,
+	}}
+}
+
 func SignRelay(relay *NIP11RelayInfo, privKey *btcec.PrivateKey) error {
 	relayBytes := PackRelayForSig(relay)
 	hash := sha256.Sum256(relayBytes)
@@ -8,6 +8,8 @@ import (
 	"github.com/gofiber/contrib/websocket"
 	"github.com/nbd-wtf/go-nostr"
 	"github.com/puzpuzpuz/xsync/v3"
+
+	"github.com/HORNET-Storage/hornet-storage/lib/eventbus"
 )
 
 // TODO: maybe we should move this into a different package since we use it in the sync package as well
@@ -28,6 +30,46 @@ type NIP11RelayInfo struct {
 	BasePort        int              `json:"base_port,omitempty"`        // Base port for service offset calculations
 	Services        RelayServices    `json:"services,omitempty"`         // External/non-offset service endpoints
 	HornetExtension *HornetExtension `json:"hornet_extension,omitempty"` // custom extension for p2p context
+	Fees            *NIP11Fees       `json:"fees,omitempty"`
+	Limitation      *NIP11Limitation `json:"limitation,omitempty"`
+	Retention       []NIP11Retention `json:"retention,omitempty"`
+	Banner          string           `json:"banner,omitempty"`
+	TermsOfService  string           `json:"terms_of_service,omitempty"`
+	PostingPolicy   string           `json:"posting_policy,omitempty"`
+}
+
+// NIP11Limitation advertises the restrictions the relay places on clients, as described in NIP-11
+type NIP11Limitation struct {
+	MaxMessageLength int  `json:"max_message_length,omitempty"`
+	MaxSubscriptions int  `json:"max_subscriptions,omitempty"`
+	MaxLimit         int  `json:"max_limit,omitempty"`
+	DefaultLimit     int  `json:"default_limit,omitempty"`
+	MinPowDifficulty int  `json:"min_pow_difficulty,omitempty"`
+	AuthRequired     bool `json:"auth_required"`
+	PaymentRequired  bool `json:"payment_required"`
+	RestrictedWrites bool `json:"restricted_writes"`
+}
+
+// NIP11Retention describes how long events of some kinds are kept. Kinds holds
+// single kinds and [from, to] ranges, and a Time of 0 means the events are not stored.
+type NIP11Retention struct {
+	Kinds []interface{} `json:"kinds,omitempty"`
+	Time  *int64        `json:"time,omitempty"`
+}
+
+// NIP11Fees advertises what the relay charges, as described in NIP-11
+type NIP11Fees struct {
+	Admission    []NIP11Fee `json:"admission,omitempty"`
+	Subscription []NIP11Fee `json:"subscription,omitempty"`
+	Publication  []NIP11Fee `json:"publication,omitempty"`
+}
+
+// NIP11Fee is a single fee entry; Period is in seconds for subscriptions
+type NIP11Fee struct {
+	Amount int64  `json:"amount"`
+	Unit   string `json:"unit"`
+	Period int64  `json:"period,omitempty"`
+	Kinds  []int  `json:"kinds,omitempty"`
 }
 
 // Port offset constants from Nostr base port
@@ -78,15 +120,11 @@ type Event interface {
 	HandleEvent(c *websocket.Conn, ctx context.Context) error
 }
 
-type Subscription struct {
-	filters nostr.Filters
-	cancel  context.CancelFunc
-}
-
 type ListenerData struct {
 	authenticated bool
+	pubkey        string
 	challenge     string
-	subscriptions *xsync.MapOf[string, *Subscription]
+	subscriptions *xsync.MapOf[string, *eventbus.Subscription]
 }
 
 type EventMessage struct {
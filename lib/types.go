@@ -52,6 +52,11 @@ type (
 	ReportSummary            = types.ReportSummary
 )
 
+// Mirroring types
+type (
+	MirrorDelivery = types.MirrorDelivery
+)
+
 // Auth types
 type (
 	UserProfile   = types.UserProfile
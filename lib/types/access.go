@@ -1,13 +1,22 @@
 package types
 
-import "time"
+import (
+	"errors"
+	"time"
+)
 
 type AllowedUser struct {
-	Npub      string    `gorm:"primaryKey;size:128" json:"npub"`
-	Tier      string    `gorm:"size:64" json:"tier"`
-	ReadOnly  bool      `gorm:"not null;default:false" json:"read_only"`
-	CreatedAt time.Time `gorm:"autoCreateTime" json:"created_at"`
-	CreatedBy string    `gorm:"size:128" json:"created_by"`
+	Npub      string     `gorm:"primaryKey;size:128" json:"npub"`
+	Tier      string     `gorm:"size:64" json:"tier"`
+	ReadOnly  bool       `gorm:"not null;default:false" json:"read_only"`
+	CreatedAt time.Time  `gorm:"autoCreateTime" json:"created_at"`
+	CreatedBy string     `gorm:"size:128" json:"created_by"`
+	ExpiresAt *time.Time `gorm:"index" json:"expires_at,omitempty"` // Nil for a permanent grant
+}
+
+// Expired reports whether a time-limited grant has lapsed
+func (u *AllowedUser) Expired(now time.Time) bool {
+	return u.ExpiresAt != nil && !now.Before(*u.ExpiresAt)
 }
 
 type RelayOwner struct {
@@ -15,3 +24,36 @@ type RelayOwner struct {
 	CreatedAt time.Time `gorm:"autoCreateTime" json:"created_at"`
 	CreatedBy string    `gorm:"size:128" json:"created_by"`
 }
+
+// InviteCode is an admin-generated code that adds the redeemer to the allowed users
+type InviteCode struct {
+	ID            uint       `gorm:"primaryKey" json:"id"`
+	Code          string     `gorm:"size:64;uniqueIndex" json:"code"`
+	Tier          string     `gorm:"size:64" json:"tier"`
+	ReadOnly      bool       `gorm:"not null;default:false" json:"read_only"`
+	MaxUses       int        `gorm:"not null;default:0" json:"max_uses"` // 0 means unlimited
+	Uses          int        `gorm:"not null;default:0" json:"uses"`
+	ExpiresAt     *time.Time `json:"expires_at,omitempty"`                     // When the code stops being redeemable
+	GrantDuration int64      `gorm:"not null;default:0" json:"grant_duration"` // Seconds of access per redemption, 0 for permanent
+	Revoked       bool       `gorm:"not null;default:false" json:"revoked"`
+	Note          string     `gorm:"size:256" json:"note"`
+	CreatedAt     time.Time  `gorm:"autoCreateTime" json:"created_at"`
+	CreatedBy     string     `gorm:"size:128" json:"created_by"`
+}
+
+// InviteRedemption records a pubkey redeeming an invite code
+type InviteRedemption struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	Code       string    `gorm:"size:64;uniqueIndex:idx_invite_redemption" json:"code"`
+	Npub       string    `gorm:"size:128;uniqueIndex:idx_invite_redemption" json:"npub"`
+	RedeemedAt time.Time `gorm:"autoCreateTime" json:"redeemed_at"`
+}
+
+// Invite code redemption failures
+var (
+	ErrInviteNotFound        = errors.New("invite code not found")
+	ErrInviteRevoked         = errors.New("invite code has been revoked")
+	ErrInviteExpired         = errors.New("invite code has expired")
+	ErrInviteExhausted       = errors.New("invite code has no uses left")
+	ErrInviteAlreadyRedeemed = errors.New("invite code already redeemed by this pubkey")
+)
@@ -1,16 +1,38 @@
 // Configuration and settings types
 package types
 
+import (
+	"math"
+	"strings"
+)
+
 // Config represents the complete application configuration
 type Config struct {
-	Server               ServerConfig           `mapstructure:"server"`
-	ExternalServices     ExternalServicesConfig `mapstructure:"external_services"`
-	Logging              LoggingConfig          `mapstructure:"logging"`
-	Relay                RelayConfig            `mapstructure:"relay"`
-	ContentFiltering     ContentFilteringConfig `mapstructure:"content_filtering"`
-	EventFiltering       EventFilteringConfig   `mapstructure:"event_filtering"`
-	AllowedUsersSettings AllowedUsersSettings   `mapstructure:"allowed_users"`
-	PushNotifications    PushNotificationConfig `mapstructure:"push_notifications"`
+	Server                    ServerConfig                    `mapstructure:"server"`
+	ExternalServices          ExternalServicesConfig          `mapstructure:"external_services"`
+	Logging                   LoggingConfig                   `mapstructure:"logging"`
+	Relay                     RelayConfig                     `mapstructure:"relay"`
+	ContentFiltering          ContentFilteringConfig          `mapstructure:"content_filtering"`
+	EventFiltering            EventFilteringConfig            `mapstructure:"event_filtering"`
+	AllowedUsersSettings      AllowedUsersSettings            `mapstructure:"allowed_users"`
+	PushNotifications         PushNotificationConfig          `mapstructure:"push_notifications"`
+	Lightning                 LightningConfig                 `mapstructure:"lightning"`
+	OnchainPayments           OnchainPaymentsConfig           `mapstructure:"onchain_payments"`
+	SubscriptionNotifications SubscriptionNotificationsConfig `mapstructure:"subscription_notifications"`
+	Mirroring                 MirroringConfig                 `mapstructure:"mirroring"`
+	Backfill                  BackfillConfig                  `mapstructure:"backfill"`
+}
+
+// BackfillConfig controls importing a user's history from their other relays
+type BackfillConfig struct {
+	Enabled             bool     `mapstructure:"enabled"`
+	AutoTrigger         bool     `mapstructure:"auto_trigger"`    // Start a job when a payment or allowed user grant gives write access
+	FallbackRelays      []string `mapstructure:"fallback_relays"` // Used to find the user's kind 10002, or as sources without one
+	MaxRelays           int      `mapstructure:"max_relays"`
+	MaxEvents           int      `mapstructure:"max_events"` // Per job
+	MaxBytes            int64    `mapstructure:"max_bytes"`  // Per job
+	RelayTimeoutSeconds int      `mapstructure:"relay_timeout_seconds"`
+	Negentropy          bool     `mapstructure:"negentropy"` // Try NIP-77 before paginated REQ
 }
 
 // ServerConfig holds server-related configuration
@@ -76,30 +98,88 @@ type RelayConfig struct {
 	DHTSeed       string `mapstructure:"dht_seed"`
 	DHTPublicKey  string `mapstructure:"dht_public_key"`
 	DHTPrivateKey string `mapstructure:"dht_private_key"`
+
+	// NIP-11 document fields
+	Banner         string                `mapstructure:"banner"`
+	TermsOfService string                `mapstructure:"terms_of_service"`
+	PostingPolicy  string                `mapstructure:"posting_policy"`
+	Limitation     RelayLimitationConfig `mapstructure:"limitation"`
+
+	// NIP-66 self announcement
+	Discovery RelayDiscoveryConfig `mapstructure:"discovery"`
+}
+
+// RelayDiscoveryConfig controls the NIP-66 kind 30166 event the relay publishes about itself
+type RelayDiscoveryConfig struct {
+	Enabled                bool     `mapstructure:"enabled"`
+	URL                    string   `mapstructure:"url"` // Public websocket URL, used as the d tag
+	Topics                 []string `mapstructure:"topics"`
+	BootstrapRelays        []string `mapstructure:"bootstrap_relays"` // Relays the announcement is also published to
+	RefreshIntervalSeconds int      `mapstructure:"refresh_interval_seconds"`
+}
+
+// RelayLimitationConfig holds the client limits enforced by the relay and advertised in NIP-11
+type RelayLimitationConfig struct {
+	MaxMessageLength int `mapstructure:"max_message_length"` // Bytes per websocket or DHT message, 0 for unlimited
+	MaxSubscriptions int `mapstructure:"max_subscriptions"`  // Open REQ subscriptions per connection, 0 for unlimited
 }
 
 // ContentFilteringConfig holds content filtering configuration
 type ContentFilteringConfig struct {
 	TextFilter      TextFilterConfig      `mapstructure:"text_filter"`
 	ImageModeration ImageModerationConfig `mapstructure:"image_moderation"`
+	Reports         ReportsConfig         `mapstructure:"reports"`
 }
 
 // TextFilterConfig holds text filtering configuration
 type TextFilterConfig struct {
-	Enabled             bool  `mapstructure:"enabled"`
-	CacheSize           int   `mapstructure:"cache_size"`
-	CacheTTLSeconds     int   `mapstructure:"cache_ttl_seconds"`
-	FullTextSearchKinds []int `mapstructure:"full_text_search_kinds"`
+	Enabled             bool   `mapstructure:"enabled"`
+	CacheSize           int    `mapstructure:"cache_size"`
+	CacheTTLSeconds     int    `mapstructure:"cache_ttl_seconds"`
+	FullTextSearchKinds []int  `mapstructure:"full_text_search_kinds"`
+	LatencyBudgetMs     int    `mapstructure:"latency_budget_ms"`  // How long a REQ waits for AI verdicts
+	FailMode            string `mapstructure:"fail_mode"`          // "open" passes events without a verdict, "closed" drops them
+	PrecomputeWorkers   int    `mapstructure:"precompute_workers"` // Workers judging new events ahead of time, 0 disables
+	PaidOnly            bool   `mapstructure:"paid_only"`          // Only run AI instructions for paid subscribers
 }
 
 // ImageModerationConfig holds image moderation configuration
 type ImageModerationConfig struct {
-	Enabled              bool    `mapstructure:"enabled"`
-	Mode                 string  `mapstructure:"mode"`
-	Threshold            float64 `mapstructure:"threshold"`
-	TimeoutSeconds       int     `mapstructure:"timeout_seconds"`
-	CheckIntervalSeconds int     `mapstructure:"check_interval_seconds"`
-	Concurrency          int     `mapstructure:"concurrency"`
+	Enabled              bool     `mapstructure:"enabled"`
+	Mode                 string   `mapstructure:"mode"`
+	Threshold            float64  `mapstructure:"threshold"`
+	TimeoutSeconds       int      `mapstructure:"timeout_seconds"`
+	CheckIntervalSeconds int      `mapstructure:"check_interval_seconds"`
+	Concurrency          int      `mapstructure:"concurrency"`
+	Providers            []string `mapstructure:"providers"`               // "phash" (local hash blocklist) and/or "api", asked in order
+	Combine              string   `mapstructure:"combine"`                 // "first_block" or "max_level"
+	HashThreshold        int      `mapstructure:"hash_threshold"`          // Hamming distance, out of 64 bits, for a blocklist match
+	VerdictCache         bool     `mapstructure:"verdict_cache"`           // Reuse verdicts for identical media (by sha256)
+	VerdictCacheTTLHours int      `mapstructure:"verdict_cache_ttl_hours"` // ALLOW/FLAG verdicts are judged again after this, 0 = never
+	BlossomUpload        string   `mapstructure:"blossom_upload"`          // Moderate Blossom uploads: "off", "sync" (refuse) or "async" (quarantine)
+}
+
+// ReportsConfig holds NIP-56 report aggregation configuration
+type ReportsConfig struct {
+	Enabled         bool                       `mapstructure:"enabled"`
+	WotRoot         string                     `mapstructure:"wot_root"` // DAG root of the WoT graph used for reporter weighting
+	Weights         ReportWeightsConfig        `mapstructure:"weights"`
+	Thresholds      map[string]ReportThreshold `mapstructure:"thresholds"` // keyed by NIP-56 report type, "default" applies to the rest
+	PubkeyThreshold ReportThreshold            `mapstructure:"pubkey_threshold"`
+}
+
+// ReportWeightsConfig holds the weight a reporter contributes based on their standing
+type ReportWeightsConfig struct {
+	Base           float64   `mapstructure:"base"`
+	AllowedUser    float64   `mapstructure:"allowed_user"`
+	PaidSubscriber float64   `mapstructure:"paid_subscriber"`
+	WotHops        []float64 `mapstructure:"wot_hops"` // weight for follow distance 1, 2, ...
+}
+
+// ReportThreshold holds the weighted score at which an automatic action is taken
+type ReportThreshold struct {
+	Score  float64 `mapstructure:"score" json:"score"`
+	Action string  `mapstructure:"action" json:"action"` // events: pending, block; pubkeys: notify, block
 }
 
 // EventFilteringConfig holds event filtering configuration
@@ -111,6 +191,15 @@ type EventFilteringConfig struct {
 	MediaDefinitions       map[string]MediaDefinition `mapstructure:"media_definitions"`
 	DynamicKinds           DynamicKindsConfig         `mapstructure:"dynamic_kinds"`
 	Protocols              ProtocolsConfig            `mapstructure:"protocols"`
+	ProofOfWork            ProofOfWorkConfig          `mapstructure:"proof_of_work"`
+}
+
+// ProofOfWorkConfig holds NIP-13 proof of work requirements for incoming events
+type ProofOfWorkConfig struct {
+	MinDifficulty        int            `mapstructure:"min_difficulty"`         // Leading zero bits required on every event, 0 disables
+	KindDifficulty       map[string]int `mapstructure:"kind_difficulty"`        // Per-kind overrides of min_difficulty keyed by kind number
+	RelaxForTrusted      bool           `mapstructure:"relax_for_trusted"`      // Apply trusted_min_difficulty to allowed and paid users authenticated as the author
+	TrustedMinDifficulty int            `mapstructure:"trusted_min_difficulty"` // Lower bar for trusted users, never raises the requirement
 }
 
 // MediaDefinition holds configuration for a specific media type
@@ -151,12 +240,56 @@ type SubscriptionTier struct {
 	Unlimited         bool   `mapstructure:"unlimited" json:"unlimited"`
 }
 
+// Rank orders tiers by the storage they grant, with unlimited tiers above every limited one
+func (t SubscriptionTier) Rank() int64 {
+	if t.Unlimited {
+		return math.MaxInt64
+	}
+	return t.MonthlyLimitBytes
+}
+
+// TierRank returns the rank of the named tier among tiers, or -1 if it isn't one of them
+func TierRank(tiers []SubscriptionTier, name string) int64 {
+	if name == "" {
+		return -1
+	}
+	for _, tier := range tiers {
+		if strings.EqualFold(tier.Name, name) {
+			return tier.Rank()
+		}
+	}
+	return -1
+}
+
 // AllowedUsersSettings represents the unified access control configuration
 type AllowedUsersSettings struct {
-	Mode                    string             `json:"mode" mapstructure:"mode"`   // only-me, invite-only, public, subscription
-	Read                    string             `json:"read" mapstructure:"read"`   // all_users, paid_users, allowed_users, only-me
-	Write                   string             `json:"write" mapstructure:"write"` // all_users, paid_users, allowed_users, only-me
+	Mode                    string             `json:"mode" mapstructure:"mode"`   // only-me, invite-only, public, subscription, wot
+	Read                    string             `json:"read" mapstructure:"read"`   // all_users, paid_users, allowed_users, wot_users, only-me
+	Write                   string             `json:"write" mapstructure:"write"` // all_users, paid_users, allowed_users, wot_users, only-me
 	RepoAccessOverrideKinds []int              `json:"repo_access_override_kinds" mapstructure:"repo_access_override_kinds"`
 	Tiers                   []SubscriptionTier `json:"tiers" mapstructure:"tiers"`
 	LastUpdated             int64              `json:"last_updated" mapstructure:"last_updated"`
+	WotHops                 int                `json:"wot_hops" mapstructure:"wot_hops"`   // Follow distance from the seeds that grants access in wot mode
+	WotSeeds                []string           `json:"wot_seeds" mapstructure:"wot_seeds"` // Seed pubkeys for wot mode, defaults to the relay owner
+	WritePolicies           []WritePolicyRule  `json:"write_policies" mapstructure:"write_policies"`
+}
+
+// WritePolicyRule adds write requirements for a set of kinds on top of the access mode.
+// A rule without kinds or kind ranges applies to every kind, zero values disable a requirement.
+type WritePolicyRule struct {
+	Name             string      `json:"name" mapstructure:"name"`
+	Kinds            []int       `json:"kinds" mapstructure:"kinds"`
+	KindRanges       []KindRange `json:"kind_ranges" mapstructure:"kind_ranges"`
+	MinTier          string      `json:"min_tier" mapstructure:"min_tier"`           // Lowest subscription tier, ranked by storage
+	AuthRequired     bool        `json:"auth_required" mapstructure:"auth_required"` // Connection must have completed NIP-42 auth
+	MaxWotHops       int         `json:"max_wot_hops" mapstructure:"max_wot_hops"`   // Follow distance from the wot seeds
+	MaxContentLength int         `json:"max_content_length" mapstructure:"max_content_length"`
+	MaxTags          int         `json:"max_tags" mapstructure:"max_tags"`
+	MinPowDifficulty int         `json:"min_pow_difficulty" mapstructure:"min_pow_difficulty"` // NIP-13 leading zero bits
+}
+
+// KindRange is an inclusive range of event kinds
+type KindRange struct {
+	From int `json:"from" mapstructure:"from"`
+	To   int `json:"to" mapstructure:"to"`
 }
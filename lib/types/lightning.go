@@ -0,0 +1,65 @@
+package types
+
+import "time"
+
+// Lightning invoice status constants
+const (
+	LightningInvoicePending   = "pending"
+	LightningInvoiceCrediting = "crediting" // Paid and claimed, moved to settled once credited
+	LightningInvoiceSettled   = "settled"
+	LightningInvoiceExpired   = "expired"
+)
+
+// LightningInvoice tracks a BOLT11 invoice issued for a subscription tier
+type LightningInvoice struct {
+	ID          uint       `gorm:"primaryKey" json:"id"`
+	PaymentHash string     `gorm:"size:64;uniqueIndex" json:"payment_hash"`
+	Bolt11      string     `gorm:"type:text" json:"bolt11"`
+	Npub        string     `gorm:"size:128;index" json:"npub"` // Hex pubkey of the subscriber the invoice pays for
+	Tier        string     `gorm:"size:64" json:"tier"`
+	AmountSats  int64      `json:"amount_sats"`
+	Status      string     `gorm:"size:16;index;default:pending" json:"status"`
+	Backend     string     `gorm:"size:16" json:"backend"` // Backend that issued the invoice (lnd, lnbits, nwc, mock), or zap
+	ExpiresAt   time.Time  `json:"expires_at"`
+	SettledAt   *time.Time `json:"settled_at,omitempty"`
+	CreatedAt   time.Time  `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt   time.Time  `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+// LightningConfig holds Lightning payment configuration
+type LightningConfig struct {
+	Enabled              bool         `mapstructure:"enabled"`
+	Backend              string       `mapstructure:"backend"` // lnd, lnbits, nwc or mock
+	InvoiceExpirySeconds int          `mapstructure:"invoice_expiry_seconds"`
+	PollIntervalSeconds  int          `mapstructure:"poll_interval_seconds"`
+	LND                  LNDConfig    `mapstructure:"lnd"`
+	LNbits               LNbitsConfig `mapstructure:"lnbits"`
+	NWC                  NWCConfig    `mapstructure:"nwc"`
+	Zaps                 ZapsConfig   `mapstructure:"zaps"`
+}
+
+// LNDConfig holds LND REST API configuration
+type LNDConfig struct {
+	URL          string `mapstructure:"url"`
+	MacaroonPath string `mapstructure:"macaroon_path"` // Invoice macaroon is sufficient
+	TLSCertPath  string `mapstructure:"tls_cert_path"` // Optional, for self-signed node certificates
+}
+
+// LNbitsConfig holds LNbits API configuration
+type LNbitsConfig struct {
+	URL    string `mapstructure:"url"`
+	APIKey string `mapstructure:"api_key"` // Invoice/read key of the receiving wallet
+}
+
+// NWCConfig holds Nostr Wallet Connect (NIP-47) configuration
+type NWCConfig struct {
+	ConnectionURI string `mapstructure:"connection_uri"` // nostr+walletconnect://...
+}
+
+// ZapsConfig holds zap-to-subscribe configuration
+type ZapsConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// Pubkeys of the LNURL providers allowed to sign zap receipts for the relay's lightning address.
+	// Receipts from any other pubkey are ignored since anyone can publish a kind 9735 event.
+	ZapperPubkeys []string `mapstructure:"zapper_pubkeys"`
+}
@@ -0,0 +1,38 @@
+package types
+
+import "time"
+
+// Mirror delivery states
+const (
+	MirrorStatusPending   = "pending"
+	MirrorStatusDelivered = "delivered"
+	MirrorStatusFailed    = "failed" // Gave up after the maximum number of attempts
+)
+
+// MirrorDelivery is one accepted event queued for delivery to one upstream relay
+type MirrorDelivery struct {
+	ID          string    `json:"id"` // {eventID}:{relay}
+	EventID     string    `json:"event_id"`
+	Pubkey      string    `json:"pubkey"`
+	Kind        int       `json:"kind"`
+	Relay       string    `json:"relay"`
+	Status      string    `json:"status"`
+	Attempts    int       `json:"attempts"`
+	LastError   string    `json:"last_error,omitempty"`
+	NextAttempt time.Time `json:"next_attempt"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// MirroringConfig controls outbound mirroring of accepted events to the author's write relays
+type MirroringConfig struct {
+	Enabled          bool     `mapstructure:"enabled"`
+	Pubkeys          []string `mapstructure:"pubkeys"`         // Authors whose events are mirrored
+	Kinds            []int    `mapstructure:"kinds"`           // Kinds mirrored for every author
+	FallbackRelays   []string `mapstructure:"fallback_relays"` // Used when the author has no kind 10002 write relays
+	MaxRelays        int      `mapstructure:"max_relays"`      // Destinations per event
+	MaxAttempts      int      `mapstructure:"max_attempts"`
+	RetryBaseSeconds int      `mapstructure:"retry_base_seconds"`
+	RetryMaxSeconds  int      `mapstructure:"retry_max_seconds"`
+	HistoryDays      int      `mapstructure:"history_days"` // How long finished deliveries are kept for the panel
+}
@@ -30,6 +30,66 @@ type PendingDisputeModeration struct {
 	AddedAt       time.Time `json:"added_at"`       // Timestamp when added to queue
 }
 
+// MediaHashBlock is an image banned by the relay admin. Its perceptual hashes also
+// match re-encoded, resized or lightly edited copies.
+type MediaHashBlock struct {
+	ID           uint      `gorm:"primaryKey" json:"id"`
+	PHash        string    `gorm:"size:16;not null;index" json:"phash"` // 64-bit DCT hash, hex
+	DHash        string    `gorm:"size:16;not null" json:"dhash"`       // 64-bit gradient hash, hex
+	Reason       string    `gorm:"type:text" json:"reason"`
+	ContentLevel int       `gorm:"default:5" json:"content_level"` // Level reported when a file matches
+	CreatedAt    time.Time `gorm:"autoCreateTime" json:"created_at"`
+}
+
+// MediaVerdict is a cached moderation result for media, keyed by the sha256 of its bytes
+// so the same file is judged once however many events or uploads reference it
+type MediaVerdict struct {
+	SHA256       string    `gorm:"primaryKey;size:64" json:"sha256"`
+	Decision     string    `gorm:"size:16;index" json:"decision"` // "ALLOW", "FLAG" or "BLOCK"
+	ContentLevel int       `json:"content_level"`
+	Confidence   float64   `json:"confidence"`
+	Category     string    `gorm:"size:64" json:"category"`
+	Explanation  string    `gorm:"type:text" json:"explanation"`
+	Provider     string    `gorm:"size:64" json:"provider"` // Moderation mode or provider that gave the verdict
+	CreatedAt    time.Time `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt    time.Time `gorm:"autoUpdateTime;index" json:"updated_at"`
+}
+
+// MediaVerdictURL remembers which file a URL served, so known URLs skip the download
+type MediaVerdictURL struct {
+	URL       string    `gorm:"primaryKey" json:"url"`
+	SHA256    string    `gorm:"size:64;index" json:"sha256"`
+	CreatedAt time.Time `gorm:"autoCreateTime" json:"created_at"`
+}
+
+// Review states for media that moderation flagged for a human decision
+const (
+	ReviewStatusPending  = "pending"  // Served with a content warning until reviewed
+	ReviewStatusApproved = "approved" // Served without a warning
+	ReviewStatusBlocked  = "blocked"  // Escalated to a block
+	ReviewStatusLabeled  = "labeled"  // Served with a content warning chosen by the reviewer
+)
+
+// ModerationReview is an event whose media got a FLAG decision. While it waits for review
+// the relay publishes a kind 1985 content-warning label so clients can blur it.
+type ModerationReview struct {
+	ID           uint       `gorm:"primaryKey" json:"id"`
+	EventID      string     `gorm:"size:128;uniqueIndex" json:"event_id"`
+	PubKey       string     `gorm:"size:128;index" json:"pubkey"` // Author of the event
+	MediaURL     string     `gorm:"size:512" json:"media_url"`    // Media that was flagged
+	ContentType  string     `gorm:"size:64" json:"content_type"`  // "image" or "video"
+	ContentLevel int        `json:"content_level"`
+	Confidence   float64    `json:"confidence"`
+	Category     string     `gorm:"size:64" json:"category"`
+	Explanation  string     `gorm:"type:text" json:"explanation"`
+	Status       string     `gorm:"size:16;index;default:pending" json:"status"`
+	Label        string     `gorm:"size:255" json:"label"`          // NIP-36 content-warning reason
+	LabelEventID string     `gorm:"size:128" json:"label_event_id"` // Relay-signed kind 1985 label, if published
+	ReviewedAt   *time.Time `json:"reviewed_at,omitempty"`
+	CreatedAt    time.Time  `gorm:"autoCreateTime;index" json:"created_at"`
+	UpdatedAt    time.Time  `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
 // BlockedPubkey represents a pubkey that is blocked from connecting to the relay
 type BlockedPubkey struct {
 	Pubkey    string    `json:"pubkey" badgerhold:"key"`       // Pubkey as the primary identifier
@@ -73,6 +133,40 @@ type ReportNotification struct {
 	IsRead         bool      `gorm:"default:false" json:"is_read"`         // Whether the notification has been read
 }
 
+// Report represents a single NIP-56 report counted towards automatic moderation.
+// Reports are deduplicated per (target, reporter, type) so repeated reports from
+// the same pubkey never inflate a target's score.
+type Report struct {
+	ID             uint      `gorm:"primaryKey" json:"id"`
+	Target         string    `gorm:"size:128;uniqueIndex:idx_report_target_reporter" json:"target"`           // Reported event ID or pubkey
+	TargetType     string    `gorm:"size:16" json:"target_type"`                                              // "event" or "pubkey"
+	TargetPubKey   string    `gorm:"size:128;index" json:"target_pubkey"`                                     // Author of the reported content
+	ReporterPubKey string    `gorm:"size:128;uniqueIndex:idx_report_target_reporter" json:"reporter_pubkey"`  // Pubkey that filed the report
+	ReportType     string    `gorm:"size:64;index;uniqueIndex:idx_report_target_reporter" json:"report_type"` // Type from NIP-56 (nudity, spam, etc.)
+	ReportEventID  string    `gorm:"size:128" json:"report_event_id"`                                         // ID of the kind 1984 event
+	Weight         float64   `gorm:"default:1" json:"weight"`                                                 // Reporter weight at the time of the report
+	CreatedAt      time.Time `gorm:"autoCreateTime" json:"created_at"`
+}
+
+// ReportAction records the automatic action taken against a target for a report type,
+// so a target is actioned once while its score keeps growing
+type ReportAction struct {
+	Target     string    `gorm:"primaryKey;size:128" json:"target"`
+	ReportType string    `gorm:"primaryKey;size:64" json:"report_type"`
+	Action     string    `gorm:"size:16" json:"action"`
+	CreatedAt  time.Time `gorm:"autoCreateTime" json:"created_at"`
+}
+
+// ReportScores are the weighted scores of a report's target right after it was saved
+type ReportScores struct {
+	Counted             bool    // False when the reporter had already reported the target as this type
+	NewReporter         bool    // True when this is the reporter's first report against the target
+	Action              string  // Action already recorded against the target for the report's type
+	Score               float64 // Score of the target for the report's type
+	PubkeyScore         float64 // Score of the target's pubkey across all of its content
+	PreviousPubkeyScore float64 // PubkeyScore without this report
+}
+
 // ReportStats represents statistics about reported content
 type ReportStats struct {
 	TotalReported      int             `json:"total_reported"`       // Total number of reported events
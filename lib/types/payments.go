@@ -48,8 +48,17 @@ type PaymentNotification struct {
 	ExpirationDate   time.Time `json:"expiration_date"`                        // When subscription expires
 	CreatedAt        time.Time `gorm:"autoCreateTime" json:"created_at"`       // When the notification was created
 	IsRead           bool      `gorm:"default:false" json:"is_read"`           // Whether notification is read
+	Status           string    `gorm:"size:16;index" json:"status,omitempty"`  // On-chain payment status, empty for instant payments
 }
 
+// Payment notification statuses for on-chain payments
+const (
+	PaymentStatusPending   = "pending"   // Seen by the wallet but below the confirmation threshold
+	PaymentStatusConfirmed = "confirmed" // Threshold met and the subscription granted
+	PaymentStatusReverted  = "reverted"  // A granted payment was replaced, dropped or reorged out and rolled back
+	PaymentStatusCancelled = "cancelled" // A pending payment was replaced or dropped before it was granted
+)
+
 // PaymentStats represents statistics about payments and subscriptions
 type PaymentStats struct {
 	TotalRevenue        int64       `json:"total_revenue"`         // Total sats received
@@ -74,3 +83,83 @@ type TxSummary struct {
 	Tier   string    `json:"tier"`   // Tier purchased
 	Date   time.Time `json:"date"`   // Transaction date
 }
+
+// Subscription lifecycle notification types
+const (
+	LifecycleExpiring        = "expiring"
+	LifecycleExpired         = "expired"
+	LifecyclePaymentReceived = "payment_received"
+	LifecycleCreditAdded     = "credit_added"
+	LifecycleQuotaWarning    = "quota_warning"
+)
+
+// SubscriptionLifecycleNotification records a lifecycle DM sent to a subscriber.
+// The (pubkey, type, key) triple is unique so a notification is never sent twice.
+type SubscriptionLifecycleNotification struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	PubKey     string    `gorm:"size:128;uniqueIndex:idx_lifecycle_notification" json:"pubkey"`
+	Type       string    `gorm:"size:32;uniqueIndex:idx_lifecycle_notification" json:"type"`
+	Key        string    `gorm:"size:128;uniqueIndex:idx_lifecycle_notification" json:"key"` // e.g. expiration timestamp or transaction ID
+	GiftWrapID string    `gorm:"size:64" json:"gift_wrap_id"`                                // ID of the kind 1059 event delivered to the subscriber
+	CreatedAt  time.Time `gorm:"autoCreateTime" json:"created_at"`
+}
+
+// SubscriptionNotificationsConfig holds configuration for subscription lifecycle DMs
+type SubscriptionNotificationsConfig struct {
+	Enabled               bool                              `mapstructure:"enabled"`
+	Push                  bool                              `mapstructure:"push"`                    // Also send through the push notification service
+	ExpiringLeadDays      []int                             `mapstructure:"expiring_lead_days"`      // Days before expiry to send a reminder
+	QuotaThresholdPercent int                               `mapstructure:"quota_threshold_percent"` // Storage usage that triggers a quota warning
+	Templates             SubscriptionNotificationTemplates `mapstructure:"templates"`
+}
+
+// SubscriptionNotificationTemplates holds the message templates for each lifecycle notification.
+// Placeholders: {relay}, {tier}, {expires}, {days}, {amount}, {credit}, {used}, {limit}, {percent}
+type SubscriptionNotificationTemplates struct {
+	Expiring        string `mapstructure:"expiring"`
+	Expired         string `mapstructure:"expired"`
+	PaymentReceived string `mapstructure:"payment_received"`
+	CreditAdded     string `mapstructure:"credit_added"`
+	QuotaWarning    string `mapstructure:"quota_warning"`
+}
+
+// On-chain payment states
+const (
+	OnchainPaymentPending   = "pending"
+	OnchainPaymentConfirmed = "confirmed"
+	OnchainPaymentReverted  = "reverted"
+)
+
+// OnchainPayment tracks an on-chain payment to a subscriber address through its confirmations.
+// The Granted* fields record what the payment changed so it can be rolled back.
+type OnchainPayment struct {
+	ID                    uint      `gorm:"primaryKey" json:"id"`
+	Outpoint              string    `gorm:"size:140;uniqueIndex" json:"outpoint"` // txid:vout as reported by the wallet
+	TxID                  string    `gorm:"size:128;index" json:"txid"`
+	Npub                  string    `gorm:"size:128;index" json:"npub"`
+	Address               string    `gorm:"size:128" json:"address"` // Subscriber address that received the payment
+	AmountSats            int64     `gorm:"not null" json:"amount_sats"`
+	Confirmations         int       `json:"confirmations"`
+	RequiredConfirmations int       `json:"required_confirmations"`
+	Status                string    `gorm:"size:16;index" json:"status"`
+	ReplacedBy            string    `gorm:"size:128" json:"replaced_by,omitempty"`
+	PreviousTier          string    `gorm:"size:64" json:"previous_tier,omitempty"`
+	GrantedTier           string    `gorm:"size:64" json:"granted_tier,omitempty"`
+	GrantedBytes          int64     `json:"granted_bytes"`
+	GrantedSeconds        int64     `json:"granted_seconds"`
+	GrantedCredit         int64     `json:"granted_credit"`
+	CreatedAt             time.Time `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt             time.Time `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+// OnchainPaymentsConfig holds the confirmation policy for on-chain subscription payments
+type OnchainPaymentsConfig struct {
+	ConfirmationBands []ConfirmationBand `mapstructure:"confirmation_bands"`
+}
+
+// ConfirmationBand sets the confirmations required for payments of up to MaxSats.
+// A MaxSats of 0 means no upper bound.
+type ConfirmationBand struct {
+	MaxSats       int64 `mapstructure:"max_sats" json:"max_sats"`
+	Confirmations int   `mapstructure:"confirmations" json:"confirmations"`
+}
@@ -1,17 +1,22 @@
 package types
 
-import "time"
+import (
+	"fmt"
+	"time"
+)
 
 // PushDevice represents a registered device for push notifications
 type PushDevice struct {
 	ID            uint      `gorm:"primaryKey" json:"id"`
 	Pubkey        string    `gorm:"size:64;not null;index" json:"pubkey"`
 	DeviceToken   string    `gorm:"size:255;not null" json:"device_token"`
-	Platform      string    `gorm:"size:10;not null" json:"platform"` // 'ios' or 'android'
+	Platform      string    `gorm:"size:10;not null" json:"platform"` // 'ios', 'android' or 'web'
 	AppIdentifier string    `gorm:"size:255" json:"app_identifier"`
 	CreatedAt     time.Time `gorm:"autoCreateTime" json:"created_at"`
 	UpdatedAt     time.Time `gorm:"autoUpdateTime" json:"updated_at"`
 	IsActive      bool      `gorm:"default:true" json:"is_active"`
+	P256dh        string    `gorm:"size:128" json:"p256dh,omitempty"` // Web push subscription keys, base64url
+	Auth          string    `gorm:"size:64" json:"auth,omitempty"`
 }
 
 // PushNotificationLog represents a log entry for sent push notifications
@@ -26,14 +31,25 @@ type PushNotificationLog struct {
 	SentAt           *time.Time `json:"sent_at"`
 	Delivered        bool       `gorm:"default:false" json:"delivered"`
 	ErrorMessage     string     `gorm:"type:text" json:"error_message"`
+	CollapsedCount   int        `gorm:"default:1" json:"collapsed_count"` // Events merged into this notification
 	CreatedAt        time.Time  `gorm:"autoCreateTime" json:"created_at"`
 }
 
+// PushNotificationStats summarises the notification log for the panel. Counts are per
+// device delivery attempt.
+type PushNotificationStats struct {
+	TotalNotifications int64 `json:"total_notifications"`
+	Delivered          int64 `json:"delivered"`
+	Summaries          int64 `json:"summaries"`         // Notifications that merged several events
+	EventsSuppressed   int64 `json:"events_suppressed"` // Events folded into summaries instead of sent on their own
+}
+
 // PushNotificationConfig holds push notification service configuration
 type PushNotificationConfig struct {
 	Enabled bool              `mapstructure:"enabled"`
 	APNS    APNSConfig        `mapstructure:"apns"`
 	FCM     FCMConfig         `mapstructure:"fcm"`
+	WebPush WebPushConfig     `mapstructure:"web_push"`
 	Service PushServiceConfig `mapstructure:"service"`
 }
 
@@ -50,15 +66,131 @@ type APNSConfig struct {
 // FCMConfig holds Firebase Cloud Messaging configuration
 type FCMConfig struct {
 	Enabled         bool   `mapstructure:"enabled"`
-	ProjectID       string `mapstructure:"project_id"`
-	CredentialsPath string `mapstructure:"credentials_path"`
+	ProjectID       string `mapstructure:"project_id"`       // Defaults to the project in the credentials file
+	CredentialsPath string `mapstructure:"credentials_path"` // Service account key JSON for the HTTP v1 API
+}
+
+// WebPushConfig holds Web Push (VAPID) configuration
+type WebPushConfig struct {
+	Enabled bool   `mapstructure:"enabled"`
+	Subject string `mapstructure:"subject"`  // mailto: or https: contact for push services
+	KeyPath string `mapstructure:"key_path"` // P-256 VAPID key, generated when missing. Defaults to the data path.
 }
 
 // PushServiceConfig holds general service configuration
 type PushServiceConfig struct {
-	QueueSize     int    `mapstructure:"queue_size"`
-	WorkerCount   int    `mapstructure:"worker_count"`
-	BatchSize     int    `mapstructure:"batch_size"`
-	RetryAttempts int    `mapstructure:"retry_attempts"`
-	RetryDelay    string `mapstructure:"retry_delay"`
+	QueueSize          int    `mapstructure:"queue_size"`
+	WorkerCount        int    `mapstructure:"worker_count"`
+	BatchSize          int    `mapstructure:"batch_size"`
+	RetryAttempts      int    `mapstructure:"retry_attempts"`
+	RetryDelay         string `mapstructure:"retry_delay"`
+	CoalesceWindow     string `mapstructure:"coalesce_window"`       // Bursts for the same recipient and subject become one summary, "0s" disables
+	RateLimitPerMinute int    `mapstructure:"rate_limit_per_minute"` // Per-recipient token bucket refill, 0 disables
+	RateLimitBurst     int    `mapstructure:"rate_limit_burst"`
+}
+
+// PushPreferences controls which events notify a recipient and when.
+// Users without a row get DefaultPushPreferences.
+type PushPreferences struct {
+	Pubkey         string             `gorm:"primaryKey;size:64" json:"pubkey"`
+	Replies        bool               `gorm:"not null" json:"replies"`         // Kind 1 and 1808 replies and mentions
+	Reposts        bool               `gorm:"not null" json:"reposts"`         // Kind 6 and 1809
+	Reactions      bool               `gorm:"not null" json:"reactions"`       // Kind 7
+	DirectMessages bool               `gorm:"not null" json:"direct_messages"` // Kind 1059 gift wraps
+	Zaps           bool               `gorm:"not null" json:"zaps"`            // Kind 9735 receipts
+	Follows        bool               `gorm:"not null" json:"follows"`         // New entries in someone's kind 3
+	Timezone       string             `gorm:"size:64" json:"timezone"`         // IANA name for quiet hours, UTC when empty
+	QuietHours     []QuietHoursWindow `gorm:"serializer:json" json:"quiet_hours"`
+	UpdatedAt      time.Time          `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+// QuietHoursWindow is a daily "HH:MM" range during which nothing is sent. End before start wraps past midnight.
+type QuietHoursWindow struct {
+	Start string `json:"start"`
+	End   string `json:"end"`
+}
+
+// DefaultPushPreferences notifies for everything that was notified before preferences
+// existed. Zaps and follows are noisy on nostr, so they are opt-in.
+func DefaultPushPreferences(pubkey string) PushPreferences {
+	return PushPreferences{
+		Pubkey:         pubkey,
+		Replies:        true,
+		Reposts:        true,
+		Reactions:      true,
+		DirectMessages: true,
+	}
+}
+
+// AllowsKind reports whether the recipient opted in to notifications for an event kind
+func (p *PushPreferences) AllowsKind(kind int) bool {
+	switch kind {
+	case 1, 1808:
+		return p.Replies
+	case 6, 1809:
+		return p.Reposts
+	case 7:
+		return p.Reactions
+	case 1059:
+		return p.DirectMessages
+	case 9735:
+		return p.Zaps
+	case 3:
+		return p.Follows
+	default:
+		return false
+	}
+}
+
+// Validate checks the timezone and every quiet hours window
+func (p *PushPreferences) Validate() error {
+	if _, err := time.LoadLocation(p.Timezone); err != nil {
+		return fmt.Errorf("unknown timezone %q", p.Timezone)
+	}
+	for _, window := range p.QuietHours {
+		if _, _, err := window.minutes(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// InQuietHours reports whether now falls in any quiet hours window in the recipient's timezone
+func (p *PushPreferences) InQuietHours(now time.Time) bool {
+	if len(p.QuietHours) == 0 {
+		return false
+	}
+
+	location, err := time.LoadLocation(p.Timezone)
+	if err != nil {
+		location = time.UTC
+	}
+	local := now.In(location)
+	current := local.Hour()*60 + local.Minute()
+
+	for _, window := range p.QuietHours {
+		start, end, err := window.minutes()
+		if err != nil || start == end {
+			continue
+		}
+		if start < end && current >= start && current < end {
+			return true
+		}
+		if start > end && (current >= start || current < end) {
+			return true
+		}
+	}
+	return false
+}
+
+func (w QuietHoursWindow) minutes() (int, int, error) {
+	start, err := time.Parse("15:04", w.Start)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid quiet hours start %q, expected HH:MM", w.Start)
+	}
+	end, err := time.Parse("15:04", w.End)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid quiet hours end %q, expected HH:MM", w.End)
+	}
+	return start.Hour()*60 + start.Minute(), end.Hour()*60 + end.Minute(), nil
 }
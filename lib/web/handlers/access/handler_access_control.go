@@ -2,8 +2,10 @@ package access
 
 import (
 	"strings"
+	"time"
 
 	"github.com/HORNET-Storage/hdk-nostr-go/lib/signing"
+	"github.com/HORNET-Storage/hornet-storage/lib/backfill"
 	"github.com/HORNET-Storage/hornet-storage/lib/config"
 	"github.com/HORNET-Storage/hornet-storage/lib/logging"
 	"github.com/HORNET-Storage/hornet-storage/lib/stores"
@@ -45,9 +47,10 @@ func GetAllowedUsersPaginated(c *fiber.Ctx, store stores.Store) error {
 
 func AddAllowedUser(c *fiber.Ctx, store stores.Store) error {
 	var req struct {
-		Npub     string `json:"npub"`
-		Tier     string `json:"tier"`
-		CanWrite *bool  `json:"can_write"`
+		Npub      string     `json:"npub"`
+		Tier      string     `json:"tier"`
+		CanWrite  *bool      `json:"can_write"`
+		ExpiresAt *time.Time `json:"expires_at"` // Optional, access is revoked once it passes
 	}
 
 	if err := c.BodyParser(&req); err != nil {
@@ -84,12 +87,22 @@ func AddAllowedUser(c *fiber.Ctx, store stores.Store) error {
 		canWrite = *req.CanWrite
 	}
 
-	if err := statsStore.AddAllowedUser(*serializedPubKey, canWrite, req.Tier, createdBy); err != nil {
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "expires_at must be in the future",
+		})
+	}
+
+	if err := statsStore.AddAllowedUserUntil(*serializedPubKey, canWrite, req.Tier, createdBy, req.ExpiresAt); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to add NPUB to read list",
 		})
 	}
 
+	if canWrite {
+		backfill.Trigger(*serializedPubKey, "allowed_user")
+	}
+
 	// Update the user's kind 11888 event if we're in invite-only mode
 	// This ensures their storage allocation reflects their new tier immediately
 	go func() {
@@ -0,0 +1,237 @@
+package access
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/HORNET-Storage/hornet-storage/lib/config"
+	"github.com/HORNET-Storage/hornet-storage/lib/logging"
+	"github.com/HORNET-Storage/hornet-storage/lib/stores"
+	"github.com/HORNET-Storage/hornet-storage/lib/types"
+	"github.com/HORNET-Storage/hornet-storage/lib/web/middleware"
+	"github.com/gofiber/fiber/v2"
+)
+
+// Redeemer grants pubkey access through an invite code
+type Redeemer func(code string, pubkey string) (*types.AllowedUser, error)
+
+// CreateInviteCodeRequest represents the request body for generating an invite code
+type CreateInviteCodeRequest struct {
+	Code          string     `json:"code"` // Optional, a random code is generated when empty
+	Tier          string     `json:"tier"`
+	CanWrite      *bool      `json:"can_write"`
+	MaxUses       int        `json:"max_uses"`
+	ExpiresAt     *time.Time `json:"expires_at"`
+	GrantDuration int64      `json:"grant_duration"` // Seconds, 0 for permanent access
+	Note          string     `json:"note"`
+}
+
+// RedeemInviteRequest represents the request body for redeeming an invite code
+type RedeemInviteRequest struct {
+	Code string `json:"code"`
+}
+
+func ListInviteCodes(c *fiber.Ctx, store stores.Store) error {
+	statsStore := store.GetStatsStore()
+	if statsStore == nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Statistics store not available",
+		})
+	}
+
+	invites, err := statsStore.ListInviteCodes()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to retrieve invite codes",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"invite_codes": invites,
+	})
+}
+
+func CreateInviteCode(c *fiber.Ctx, store stores.Store) error {
+	var req CreateInviteCodeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if req.MaxUses < 0 || req.GrantDuration < 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "max_uses and grant_duration must not be negative",
+		})
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "expires_at must be in the future",
+		})
+	}
+
+	if !isConfiguredTier(req.Tier) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "tier must be one of the configured tiers",
+		})
+	}
+
+	code := strings.TrimSpace(req.Code)
+	if code == "" {
+		generated, err := generateInviteCode()
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to generate invite code",
+			})
+		}
+		code = generated
+	} else if len(code) < 8 || len(code) > 64 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invite codes must be between 8 and 64 characters",
+		})
+	}
+
+	statsStore := store.GetStatsStore()
+	if statsStore == nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Statistics store not available",
+		})
+	}
+
+	if existing, err := statsStore.GetInviteCode(code); err == nil && existing != nil {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "Invite code already exists",
+		})
+	}
+
+	canWrite := true
+	if req.CanWrite != nil {
+		canWrite = *req.CanWrite
+	}
+
+	invite := &types.InviteCode{
+		Code:          code,
+		Tier:          req.Tier,
+		ReadOnly:      !canWrite,
+		MaxUses:       req.MaxUses,
+		ExpiresAt:     req.ExpiresAt,
+		GrantDuration: req.GrantDuration,
+		Note:          req.Note,
+		CreatedBy:     c.Get("userPubkey", "admin"),
+	}
+	if err := statsStore.CreateInviteCode(invite); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to create invite code",
+		})
+	}
+
+	logging.Infof("Created invite code for tier %s (max uses %d, grant duration %ds)", invite.Tier, invite.MaxUses, invite.GrantDuration)
+
+	return c.JSON(fiber.Map{
+		"success":     true,
+		"invite_code": invite,
+	})
+}
+
+func RevokeInviteCode(c *fiber.Ctx, store stores.Store) error {
+	code := strings.TrimSpace(c.Params("code"))
+	if code == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invite code is required",
+		})
+	}
+
+	statsStore := store.GetStatsStore()
+	if statsStore == nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Statistics store not available",
+		})
+	}
+
+	if err := statsStore.RevokeInviteCode(code); err != nil {
+		if errors.Is(err, types.ErrInviteNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "Invite code not found",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to revoke invite code",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "Invite code revoked",
+	})
+}
+
+// RedeemInviteHandler redeems an invite code for the NIP-98 authenticated pubkey
+func RedeemInviteHandler(redeem Redeemer) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		pubkey, err := middleware.GetNIP98Pubkey(c)
+		if err != nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "Authentication required",
+			})
+		}
+
+		var req RedeemInviteRequest
+		if err := c.BodyParser(&req); err != nil || strings.TrimSpace(req.Code) == "" {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invite code is required",
+			})
+		}
+
+		user, err := redeem(req.Code, pubkey)
+		if err != nil {
+			logging.Infof("Invite redemption failed for %s: %v", pubkey, err)
+			switch {
+			case errors.Is(err, types.ErrInviteNotFound):
+				return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+			case errors.Is(err, types.ErrInviteRevoked),
+				errors.Is(err, types.ErrInviteExpired),
+				errors.Is(err, types.ErrInviteExhausted):
+				return c.Status(fiber.StatusGone).JSON(fiber.Map{"error": err.Error()})
+			case errors.Is(err, types.ErrInviteAlreadyRedeemed):
+				return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+			default:
+				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+					"error": "Failed to redeem invite code",
+				})
+			}
+		}
+
+		return c.JSON(fiber.Map{
+			"success":    true,
+			"tier":       user.Tier,
+			"read_only":  user.ReadOnly,
+			"expires_at": user.ExpiresAt,
+		})
+	}
+}
+
+// isConfiguredTier reports whether name is one of the relay's subscription tiers
+func isConfiguredTier(name string) bool {
+	cfg, err := config.GetConfig()
+	if err != nil || name == "" {
+		return false
+	}
+	for _, tier := range cfg.AllowedUsersSettings.Tiers {
+		if strings.EqualFold(tier.Name, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// generateInviteCode returns a random 128-bit hex code
+func generateInviteCode() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,52 @@
+package access
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/nbd-wtf/go-nostr"
+
+	lib_access "github.com/HORNET-Storage/hornet-storage/lib/access"
+	"github.com/HORNET-Storage/hornet-storage/lib/stores"
+)
+
+// WritePolicyDryRunRequest represents an event to test against the write policy
+type WritePolicyDryRunRequest struct {
+	Event      nostr.Event `json:"event"`
+	AuthPubkey string      `json:"auth_pubkey"` // Optional, simulates a NIP-42 authenticated connection
+}
+
+// DryRunWritePolicy evaluates an event against the relay's write checks and write policy
+// without storing it. The event does not need to be signed, its id is computed when missing.
+func DryRunWritePolicy(c *fiber.Ctx, accessControl *lib_access.AccessControl, store stores.Store) error {
+	if accessControl == nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+			"error": "Access control not initialized",
+		})
+	}
+
+	var req WritePolicyDryRunRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if req.Event.PubKey == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Event pubkey is required",
+		})
+	}
+	if req.Event.ID == "" {
+		req.Event.ID = req.Event.GetID()
+	}
+
+	result := accessControl.EvaluateEventWrite(&req.Event, req.AuthPubkey, store)
+
+	response := fiber.Map{
+		"event_id": req.Event.ID,
+		"result":   result,
+	}
+	if !result.Allowed {
+		response["message"] = result.Violations[0].Error()
+	}
+	return c.JSON(response)
+}
@@ -0,0 +1,125 @@
+package access
+
+import (
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/spf13/viper"
+
+	lib_access "github.com/HORNET-Storage/hornet-storage/lib/access"
+	"github.com/HORNET-Storage/hornet-storage/lib/config"
+	"github.com/HORNET-Storage/hornet-storage/lib/stores"
+	"github.com/HORNET-Storage/hornet-storage/lib/types"
+)
+
+// blockingStore reports a fixed set of pubkeys as blocked
+type blockingStore struct {
+	stores.Store
+	blocked map[string]bool
+}
+
+func (s *blockingStore) IsBlockedPubkey(pubkey string) (bool, error) {
+	return s.blocked[pubkey], nil
+}
+
+func TestDryRunWritePolicy(t *testing.T) {
+	viper.Reset()
+	viper.Set("event_filtering.registered_kinds", []int{1, 4})
+	viper.Set("event_filtering.kind_whitelist", []string{"kind1", "kind4"})
+	viper.Set("event_filtering.proof_of_work.kind_difficulty", map[string]int{"4": 8})
+	config.InitConfigForTesting()
+	t.Cleanup(func() {
+		viper.Reset()
+		config.InitConfigForTesting()
+	})
+
+	author, _ := nostr.GetPublicKey(nostr.GeneratePrivateKey())
+	blocked, _ := nostr.GetPublicKey(nostr.GeneratePrivateKey())
+	store := &blockingStore{blocked: map[string]bool{blocked: true}}
+
+	accessControl := lib_access.NewAccessControl(nil, &types.AllowedUsersSettings{
+		Mode:  "public",
+		Read:  "all_users",
+		Write: "all_users",
+		WritePolicies: []types.WritePolicyRule{
+			{Name: "notes", Kinds: []int{1}, MaxContentLength: 5},
+		},
+	})
+
+	app := fiber.New()
+	app.Post("/write-policy/dry-run", func(c *fiber.Ctx) error {
+		return DryRunWritePolicy(c, accessControl, store)
+	})
+
+	tests := []struct {
+		name    string
+		body    string
+		status  int
+		allowed bool
+		message string
+	}{
+		{"allowed", `{"event":{"pubkey":"` + author + `","kind":1,"content":"hi","tags":[]}}`, fiber.StatusOK, true, ""},
+		{"rule violation", `{"event":{"pubkey":"` + author + `","kind":1,"content":"too long","tags":[]}}`, fiber.StatusOK, false, "invalid: content is 8 bytes, the limit for kind 1 is 5"},
+		{"proof of work", `{"event":{"pubkey":"` + author + `","kind":4,"content":"","tags":[]}}`, fiber.StatusOK, false, "pow: "},
+		{"blocked pubkey", `{"event":{"pubkey":"` + blocked + `","kind":1,"content":"hi","tags":[]}}`, fiber.StatusOK, false, "blocked: pubkey is blocked"},
+		{"kind outside the whitelist", `{"event":{"pubkey":"` + author + `","kind":7,"content":"","tags":[]}}`, fiber.StatusOK, false, "restricted: kind 7 not allowed"},
+		{"missing pubkey", `{"event":{"kind":1}}`, fiber.StatusBadRequest, false, ""},
+		{"invalid body", `{`, fiber.StatusBadRequest, false, ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/write-policy/dry-run", strings.NewReader(test.body))
+			req.Header.Set("Content-Type", "application/json")
+
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatalf("request failed: %v", err)
+			}
+			defer resp.Body.Close()
+
+			body, _ := io.ReadAll(resp.Body)
+			if resp.StatusCode != test.status {
+				t.Fatalf("expected status %d, got %d: %s", test.status, resp.StatusCode, body)
+			}
+			if test.status != fiber.StatusOK {
+				return
+			}
+
+			var response struct {
+				EventID string                  `json:"event_id"`
+				Result  lib_access.PolicyResult `json:"result"`
+				Message string                  `json:"message"`
+			}
+			if err := json.Unmarshal(body, &response); err != nil {
+				t.Fatalf("invalid response: %v", err)
+			}
+			if response.EventID == "" {
+				t.Errorf("expected the computed event id")
+			}
+			if response.Result.Allowed != test.allowed || !strings.HasPrefix(response.Message, test.message) {
+				t.Errorf("expected allowed=%v with message %q, got %s", test.allowed, test.message, body)
+			}
+		})
+	}
+}
+
+func TestDryRunWritePolicyWithoutAccessControl(t *testing.T) {
+	app := fiber.New()
+	app.Post("/write-policy/dry-run", func(c *fiber.Ctx) error {
+		return DryRunWritePolicy(c, nil, nil)
+	})
+
+	resp, err := app.Test(httptest.NewRequest("POST", "/write-policy/dry-run", strings.NewReader(`{}`)))
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	if resp.StatusCode != fiber.StatusServiceUnavailable {
+		t.Errorf("expected status 503, got %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,69 @@
+package backfill
+
+import (
+	"github.com/gofiber/fiber/v2"
+
+	backfillService "github.com/HORNET-Storage/hornet-storage/lib/backfill"
+)
+
+// GetBackfillJobs returns every tracked backfill job, newest first
+func GetBackfillJobs(c *fiber.Ctx) error {
+	service := backfillService.GetGlobalBackfillService()
+	if service == nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+			"error": "Backfill service not available",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"jobs": service.Jobs(),
+	})
+}
+
+// GetBackfillJob returns the progress of the latest backfill for a pubkey (hex or npub)
+func GetBackfillJob(c *fiber.Ctx) error {
+	service := backfillService.GetGlobalBackfillService()
+	if service == nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+			"error": "Backfill service not available",
+		})
+	}
+
+	job, ok := service.LatestJob(c.Params("pubkey"))
+	if !ok {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "No backfill found for this pubkey",
+		})
+	}
+
+	return c.JSON(job)
+}
+
+// StartBackfill queues a manual backfill for {"pubkey": "<hex or npub>"}
+func StartBackfill(c *fiber.Ctx) error {
+	var req struct {
+		Pubkey string `json:"pubkey"`
+	}
+
+	if err := c.BodyParser(&req); err != nil || req.Pubkey == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "pubkey is required",
+		})
+	}
+
+	service := backfillService.GetGlobalBackfillService()
+	if service == nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+			"error": "Backfill service not available",
+		})
+	}
+
+	job, err := service.Enqueue(req.Pubkey, "manual")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusAccepted).JSON(job)
+}
@@ -0,0 +1,27 @@
+package health
+
+import (
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/HORNET-Storage/hornet-storage/lib/health"
+)
+
+// GetLiveness reports whether the process should be restarted. It answers 503 only when a
+// component that a restart would fix, such as a wedged database, is down.
+func GetLiveness(c *fiber.Ctx) error {
+	return writeReport(c, health.Default().Liveness(c.UserContext()))
+}
+
+// GetReadiness reports whether the node should receive traffic. It answers 503 when any
+// critical component is down; degraded components are listed but keep the node in rotation.
+func GetReadiness(c *fiber.Ctx) error {
+	return writeReport(c, health.Default().Readiness(c.UserContext()))
+}
+
+func writeReport(c *fiber.Ctx, report *health.Report) error {
+	c.Set(fiber.HeaderCacheControl, "no-store")
+	if !report.Healthy() {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(report)
+	}
+	return c.JSON(report)
+}
@@ -0,0 +1,93 @@
+package lightning
+
+import (
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/HORNET-Storage/hornet-storage/lib/logging"
+	"github.com/HORNET-Storage/hornet-storage/lib/stores"
+	"github.com/HORNET-Storage/hornet-storage/lib/types"
+	lightningService "github.com/HORNET-Storage/hornet-storage/services/lightning"
+)
+
+// CreateInvoiceRequest is the request body for issuing a subscription invoice
+type CreateInvoiceRequest struct {
+	Npub string `json:"npub"`
+	Tier string `json:"tier"`
+}
+
+// CreateInvoice issues a BOLT11 invoice for a paid subscription tier
+func CreateInvoice(c *fiber.Ctx, store stores.Store) error {
+	service := lightningService.GetGlobalService()
+	if service == nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+			"error": "Lightning payments are not enabled on this relay",
+		})
+	}
+
+	var req CreateInvoiceRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if req.Npub == "" || req.Tier == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "npub and tier are required",
+		})
+	}
+
+	invoice, err := service.CreateSubscriptionInvoice(req.Npub, req.Tier)
+	if err != nil {
+		logging.Infof("Failed to create lightning invoice for %s: %v", req.Npub, err)
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(invoiceResponse(invoice))
+}
+
+// GetInvoiceStatus reports whether an invoice has been paid, crediting it if it just settled
+func GetInvoiceStatus(c *fiber.Ctx, store stores.Store) error {
+	service := lightningService.GetGlobalService()
+	if service == nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+			"error": "Lightning payments are not enabled on this relay",
+		})
+	}
+
+	paymentHash := c.Params("hash")
+	if paymentHash == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Payment hash is required",
+		})
+	}
+
+	invoice, err := service.CheckInvoice(paymentHash)
+	if err != nil {
+		logging.Infof("Failed to check lightning invoice %s: %v", paymentHash, err)
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(invoiceResponse(invoice))
+}
+
+func invoiceResponse(invoice *types.LightningInvoice) fiber.Map {
+	response := fiber.Map{
+		"payment_hash": invoice.PaymentHash,
+		"bolt11":       invoice.Bolt11,
+		"tier":         invoice.Tier,
+		"amount_sats":  invoice.AmountSats,
+		"status":       invoice.Status,
+		"expires_at":   invoice.ExpiresAt.Format(time.RFC3339),
+	}
+	if invoice.SettledAt != nil {
+		response["settled_at"] = invoice.SettledAt.Format(time.RFC3339)
+	}
+	return response
+}
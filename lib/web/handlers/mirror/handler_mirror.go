@@ -0,0 +1,48 @@
+package mirror
+
+import (
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/HORNET-Storage/hornet-storage/lib/stores"
+	"github.com/HORNET-Storage/hornet-storage/lib/types"
+	mirrorService "github.com/HORNET-Storage/hornet-storage/services/mirror"
+)
+
+const defaultDeliveryLimit = 100
+
+// GetMirrorStatus returns the health of each upstream relay and the most recent
+// deliveries, optionally filtered with ?status=pending|delivered|failed
+func GetMirrorStatus(c *fiber.Ctx, store stores.Store) error {
+	status := c.Query("status")
+	switch status {
+	case "", types.MirrorStatusPending, types.MirrorStatusDelivered, types.MirrorStatusFailed:
+	default:
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "status must be pending, delivered or failed",
+		})
+	}
+
+	limit := c.QueryInt("limit", defaultDeliveryLimit)
+	if limit <= 0 || limit > 1000 {
+		limit = defaultDeliveryLimit
+	}
+
+	deliveries, err := store.ListMirrorDeliveries(status, limit)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to retrieve mirror deliveries",
+		})
+	}
+
+	destinations := []mirrorService.DestinationStatus{}
+	service := mirrorService.GetGlobalMirrorService()
+	if service != nil {
+		destinations = service.GetDestinations()
+	}
+
+	return c.JSON(fiber.Map{
+		"enabled":      service != nil,
+		"destinations": destinations,
+		"deliveries":   deliveries,
+	})
+}
@@ -0,0 +1,141 @@
+package moderation
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/HORNET-Storage/hornet-storage/lib/logging"
+	"github.com/HORNET-Storage/hornet-storage/lib/moderation/image"
+	"github.com/HORNET-Storage/hornet-storage/lib/stores"
+	"github.com/HORNET-Storage/hornet-storage/lib/types"
+)
+
+// GetHashBlocklist lists the banned image hashes used by the phash moderation provider
+func GetHashBlocklist(c *fiber.Ctx, store stores.Store) error {
+	blocks, err := store.GetStatsStore().GetMediaHashBlocks()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch hash blocklist: " + err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"blocks": blocks,
+	})
+}
+
+// AddHashBlock bans an image by hash. Accepts either a multipart upload of the image
+// ("file", with optional "reason" and "content_level") or JSON with precomputed hashes.
+func AddHashBlock(c *fiber.Ctx, store stores.Store) error {
+	block := &types.MediaHashBlock{ContentLevel: int(image.Level5_Explicit)}
+
+	if strings.HasPrefix(c.Get(fiber.HeaderContentType), fiber.MIMEMultipartForm) {
+		fileHeader, err := c.FormFile("file")
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "An image file is required",
+			})
+		}
+
+		file, err := fileHeader.Open()
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to read image: " + err.Error(),
+			})
+		}
+		defer file.Close()
+
+		hashes, err := image.ComputeImageHashes(file)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Failed to hash image: " + err.Error(),
+			})
+		}
+
+		block.PHash = image.FormatHash(hashes.PHash)
+		block.DHash = image.FormatHash(hashes.DHash)
+		block.Reason = c.FormValue("reason")
+		if level, err := strconv.Atoi(c.FormValue("content_level")); err == nil {
+			block.ContentLevel = level
+		}
+	} else {
+		var req struct {
+			PHash        string `json:"phash"`
+			DHash        string `json:"dhash"`
+			Reason       string `json:"reason"`
+			ContentLevel int    `json:"content_level"`
+		}
+
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid request body",
+			})
+		}
+
+		if _, err := image.ParseHash(req.PHash); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid phash: " + err.Error(),
+			})
+		}
+		if _, err := image.ParseHash(req.DHash); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid dhash: " + err.Error(),
+			})
+		}
+
+		block.PHash = strings.ToLower(req.PHash)
+		block.DHash = strings.ToLower(req.DHash)
+		block.Reason = req.Reason
+		if req.ContentLevel != 0 {
+			block.ContentLevel = req.ContentLevel
+		}
+	}
+
+	if block.ContentLevel < int(image.Level0_Appropriate) || block.ContentLevel > int(image.Level5_Explicit) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "content_level must be between 0 and 5",
+		})
+	}
+
+	if err := store.GetStatsStore().AddMediaHashBlock(block); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to add hash block: " + err.Error(),
+		})
+	}
+
+	logging.Infof("Added image hash %s to the moderation blocklist", block.PHash)
+
+	// Media that was allowed before may match the new entry, so judge it again
+	if _, err := store.GetStatsStore().PurgeMediaVerdicts(time.Now()); err != nil {
+		logging.Infof("Error clearing cached moderation verdicts: %v", err)
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"block":   block,
+	})
+}
+
+// DeleteHashBlock removes an entry from the image hash blocklist
+func DeleteHashBlock(c *fiber.Ctx, store stores.Store) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil || id == 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid block ID",
+		})
+	}
+
+	if err := store.GetStatsStore().DeleteMediaHashBlock(uint(id)); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to delete hash block: " + err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "Hash block removed",
+	})
+}
@@ -0,0 +1,156 @@
+package moderation
+
+import (
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/HORNET-Storage/hornet-storage/lib/logging"
+	"github.com/HORNET-Storage/hornet-storage/lib/moderation/image"
+	"github.com/HORNET-Storage/hornet-storage/lib/stores"
+	"github.com/HORNET-Storage/hornet-storage/lib/types"
+)
+
+// GetModerationReviews lists flagged media awaiting review, or reviews with another status
+func GetModerationReviews(c *fiber.Ctx, store stores.Store) error {
+	page, err := strconv.Atoi(c.Query("page", "1"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+
+	limit, err := strconv.Atoi(c.Query("limit", "10"))
+	if err != nil || limit < 1 || limit > 100 {
+		limit = 10
+	}
+
+	status := c.Query("status", types.ReviewStatusPending) // pending, approved, blocked, labeled, all
+	if status == "all" {
+		status = ""
+	}
+
+	reviews, metadata, err := store.GetStatsStore().GetModerationReviews(status, page, limit)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch reviews: " + err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"reviews":    reviews,
+		"pagination": metadata,
+	})
+}
+
+// ApproveModerationReview serves a flagged event without a content warning
+func ApproveModerationReview(c *fiber.Ctx, store stores.Store) error {
+	review, err := lookupReview(c, store)
+	if review == nil {
+		return err
+	}
+
+	if err := image.ApproveReview(store, review); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to approve review: " + err.Error(),
+		})
+	}
+
+	logging.Infof("Moderation review %d approved for event %s", review.ID, review.EventID)
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"review":  review,
+	})
+}
+
+// BlockModerationReview escalates a flagged event to a block
+func BlockModerationReview(c *fiber.Ctx, store stores.Store) error {
+	var req struct {
+		Reason string `json:"reason"`
+	}
+
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid request body",
+			})
+		}
+	}
+
+	review, err := lookupReview(c, store)
+	if review == nil {
+		return err
+	}
+
+	if err := image.EscalateReview(store, review, req.Reason); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to block event: " + err.Error(),
+		})
+	}
+
+	logging.Infof("Moderation review %d escalated, event %s blocked", review.ID, review.EventID)
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"review":  review,
+	})
+}
+
+// LabelModerationReview keeps serving a flagged event with a chosen content warning
+func LabelModerationReview(c *fiber.Ctx, store stores.Store) error {
+	var req struct {
+		Label string `json:"label"`
+	}
+
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if len(req.Label) > 255 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Label must be at most 255 characters",
+		})
+	}
+
+	review, err := lookupReview(c, store)
+	if review == nil {
+		return err
+	}
+
+	if err := image.LabelReview(store, review, req.Label); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to label event: " + err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"review":  review,
+	})
+}
+
+// lookupReview loads the review named in the route, writing the error response if it
+// can't be found
+func lookupReview(c *fiber.Ctx, store stores.Store) (*types.ModerationReview, error) {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil || id == 0 {
+		return nil, c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid review ID",
+		})
+	}
+
+	review, err := store.GetStatsStore().GetModerationReview(uint(id))
+	if err != nil {
+		return nil, c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch review: " + err.Error(),
+		})
+	}
+	if review == nil {
+		return nil, c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Review not found",
+		})
+	}
+
+	return review, nil
+}
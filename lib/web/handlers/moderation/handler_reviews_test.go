@@ -0,0 +1,171 @@
+package moderation
+
+import (
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/spf13/viper"
+
+	"github.com/HORNET-Storage/hornet-storage/lib/stores"
+	"github.com/HORNET-Storage/hornet-storage/lib/stores/statistics"
+	"github.com/HORNET-Storage/hornet-storage/lib/stores/statistics/gorm/sqlite"
+	"github.com/HORNET-Storage/hornet-storage/lib/types"
+)
+
+// reviewStore keeps events and blocks in memory on top of a real statistics store
+type reviewStore struct {
+	stores.Store
+	stats   statistics.StatisticsStore
+	events  map[string]*nostr.Event
+	blocked map[string]string
+}
+
+func (s *reviewStore) GetStatsStore() statistics.StatisticsStore { return s.stats }
+
+func (s *reviewStore) StoreEvent(event *nostr.Event) error {
+	s.events[event.ID] = event
+	return nil
+}
+
+func (s *reviewStore) DeleteEvent(eventID string) error {
+	delete(s.events, eventID)
+	return nil
+}
+
+func (s *reviewStore) MarkEventBlockedWithDetails(eventID string, timestamp int64, reason string, contentLevel int, mediaURL string) error {
+	s.blocked[eventID] = reason
+	return nil
+}
+
+// newReviewApp serves the review routes over a store holding one pending review
+func newReviewApp(t *testing.T) (*fiber.App, *reviewStore, *types.ModerationReview) {
+	t.Helper()
+
+	viper.Reset()
+	viper.Set("relay.private_key", nostr.GeneratePrivateKey())
+	t.Cleanup(viper.Reset)
+
+	stats, err := sqlite.InitStore(filepath.Join(t.TempDir(), "stats.db"))
+	if err != nil {
+		t.Fatalf("InitStore: %v", err)
+	}
+	store := &reviewStore{stats: stats, events: map[string]*nostr.Event{}, blocked: map[string]string{}}
+
+	review := &types.ModerationReview{
+		EventID:     "event-1",
+		PubKey:      "author",
+		MediaURL:    "https://example.com/a.jpg",
+		ContentType: "image",
+		Category:    "suggestive",
+		Explanation: "flagged by the model",
+		Status:      types.ReviewStatusPending,
+		Label:       "suggestive",
+	}
+	if err := stats.CreateModerationReview(review); err != nil {
+		t.Fatalf("CreateModerationReview: %v", err)
+	}
+
+	app := fiber.New()
+	app.Get("/moderation/reviews", func(c *fiber.Ctx) error { return GetModerationReviews(c, store) })
+	app.Post("/moderation/reviews/:id/approve", func(c *fiber.Ctx) error { return ApproveModerationReview(c, store) })
+	app.Post("/moderation/reviews/:id/block", func(c *fiber.Ctx) error { return BlockModerationReview(c, store) })
+	app.Post("/moderation/reviews/:id/label", func(c *fiber.Ctx) error { return LabelModerationReview(c, store) })
+	return app, store, review
+}
+
+func doRequest(t *testing.T, app *fiber.App, method, path, body string, status int) []byte {
+	t.Helper()
+
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	data, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != status {
+		t.Fatalf("%s %s: expected status %d, got %d: %s", method, path, status, resp.StatusCode, data)
+	}
+	return data
+}
+
+func TestGetModerationReviews(t *testing.T) {
+	app, _, _ := newReviewApp(t)
+
+	var resp struct {
+		Reviews []types.ModerationReview `json:"reviews"`
+	}
+
+	body := doRequest(t, app, "GET", "/moderation/reviews", "", fiber.StatusOK)
+	if err := json.Unmarshal(body, &resp); err != nil || len(resp.Reviews) != 1 {
+		t.Fatalf("expected the pending review, got %s", body)
+	}
+
+	body = doRequest(t, app, "GET", "/moderation/reviews?status=approved", "", fiber.StatusOK)
+	if err := json.Unmarshal(body, &resp); err != nil || len(resp.Reviews) != 0 {
+		t.Fatalf("expected no approved reviews, got %s", body)
+	}
+}
+
+func TestReviewActions(t *testing.T) {
+	tests := []struct {
+		name   string
+		action string
+		body   string
+		status string
+	}{
+		{"approve", "approve", "", types.ReviewStatusApproved},
+		{"block", "block", `{"reason":"explicit"}`, types.ReviewStatusBlocked},
+		{"block with the model's reason", "block", "", types.ReviewStatusBlocked},
+		{"label", "label", `{"label":"spoilers"}`, types.ReviewStatusLabeled},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			app, store, review := newReviewApp(t)
+
+			doRequest(t, app, "POST", "/moderation/reviews/1/"+test.action, test.body, fiber.StatusOK)
+
+			saved, err := store.stats.GetModerationReview(review.ID)
+			if err != nil || saved.Status != test.status {
+				t.Fatalf("expected status %s, got %+v (%v)", test.status, saved, err)
+			}
+
+			switch test.status {
+			case types.ReviewStatusBlocked:
+				reason := "flagged by the model"
+				if test.body != "" {
+					reason = "explicit"
+				}
+				if store.blocked["event-1"] != reason {
+					t.Errorf("expected the event to be blocked with %q, got %q", reason, store.blocked["event-1"])
+				}
+			case types.ReviewStatusLabeled:
+				label := store.events[saved.LabelEventID]
+				if label == nil || saved.Label != "spoilers" {
+					t.Errorf("expected a spoilers content warning, got %+v", saved)
+				}
+			}
+		})
+	}
+}
+
+func TestReviewActionErrors(t *testing.T) {
+	app, _, _ := newReviewApp(t)
+
+	doRequest(t, app, "POST", "/moderation/reviews/abc/approve", "", fiber.StatusBadRequest)
+	doRequest(t, app, "POST", "/moderation/reviews/42/approve", "", fiber.StatusNotFound)
+	doRequest(t, app, "POST", "/moderation/reviews/1/block", "{", fiber.StatusBadRequest)
+	doRequest(t, app, "POST", "/moderation/reviews/1/label", `{"label":"`+strings.Repeat("x", 256)+`"}`, fiber.StatusBadRequest)
+}
@@ -0,0 +1,78 @@
+package push
+
+import (
+	"github.com/HORNET-Storage/hornet-storage/lib/logging"
+	"github.com/HORNET-Storage/hornet-storage/lib/stores"
+	"github.com/HORNET-Storage/hornet-storage/lib/types"
+	"github.com/HORNET-Storage/hornet-storage/lib/web/middleware"
+	"github.com/gofiber/fiber/v2"
+)
+
+// GetPreferencesHandler returns the authenticated user's notification preferences
+func GetPreferencesHandler(store stores.Store) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		// Get authenticated pubkey from NIP-98 middleware
+		pubkey, err := middleware.GetNIP98Pubkey(c)
+		if err != nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "Authentication required",
+			})
+		}
+
+		statsStore := store.GetStatsStore()
+		preferences, err := statsStore.GetPushPreferences(pubkey)
+		if err != nil {
+			logging.Errorf("Failed to get push preferences: %v", err)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to get preferences",
+			})
+		}
+
+		if preferences == nil {
+			defaults := types.DefaultPushPreferences(pubkey)
+			preferences = &defaults
+		}
+
+		return c.JSON(preferences)
+	}
+}
+
+// UpdatePreferencesHandler replaces the authenticated user's notification preferences
+func UpdatePreferencesHandler(store stores.Store) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		// Get authenticated pubkey from NIP-98 middleware
+		pubkey, err := middleware.GetNIP98Pubkey(c)
+		if err != nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "Authentication required",
+			})
+		}
+
+		// Start from the defaults so fields left out of the request keep their default
+		preferences := types.DefaultPushPreferences(pubkey)
+		if err := c.BodyParser(&preferences); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid request body",
+			})
+		}
+		preferences.Pubkey = pubkey
+
+		if err := preferences.Validate(); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
+		statsStore := store.GetStatsStore()
+		if err := statsStore.SavePushPreferences(&preferences); err != nil {
+			logging.Errorf("Failed to save push preferences: %v", err)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to save preferences",
+			})
+		}
+
+		logging.Infof("Updated push preferences for pubkey %s", pubkey)
+
+		return c.JSON(preferences)
+	}
+}
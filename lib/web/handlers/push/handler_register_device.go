@@ -1,16 +1,28 @@
 package push
 
 import (
+	"net/url"
+
 	"github.com/HORNET-Storage/hornet-storage/lib/logging"
 	"github.com/HORNET-Storage/hornet-storage/lib/stores"
 	"github.com/HORNET-Storage/hornet-storage/lib/web/middleware"
+	pushService "github.com/HORNET-Storage/hornet-storage/services/push"
 	"github.com/gofiber/fiber/v2"
 )
 
-// RegisterDeviceRequest represents the request body for device registration
+// RegisterDeviceRequest represents the request body for device registration.
+// Web clients send their PushSubscription's endpoint and keys instead of a device token.
 type RegisterDeviceRequest struct {
-	DeviceToken string `json:"device_token" validate:"required"`
-	Platform    string `json:"platform" validate:"required,oneof=ios android"`
+	DeviceToken string              `json:"device_token"`
+	Platform    string              `json:"platform" validate:"required,oneof=ios android web"`
+	Endpoint    string              `json:"endpoint,omitempty"`
+	Keys        *WebPushKeysRequest `json:"keys,omitempty"`
+}
+
+// WebPushKeysRequest holds the keys of a browser PushSubscription
+type WebPushKeysRequest struct {
+	P256dh string `json:"p256dh"`
+	Auth   string `json:"auth"`
 }
 
 // RegisterDeviceHandler handles push device registration
@@ -50,12 +62,16 @@ func RegisterDeviceHandler(store stores.Store) fiber.Handler {
 		}
 
 		// Validate platform
-		if req.Platform != "ios" && req.Platform != "android" {
+		if req.Platform != "ios" && req.Platform != "android" && req.Platform != "web" {
 			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"error": "Platform must be 'ios' or 'android'",
+				"error": "Platform must be 'ios', 'android' or 'web'",
 			})
 		}
 
+		if req.Platform == "web" {
+			return registerWebPushDevice(c, store, pubkey, &req)
+		}
+
 		// Validate device token
 		if req.DeviceToken == "" {
 			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
@@ -81,3 +97,36 @@ func RegisterDeviceHandler(store stores.Store) fiber.Handler {
 		})
 	}
 }
+
+// registerWebPushDevice validates and stores a browser PushSubscription
+func registerWebPushDevice(c *fiber.Ctx, store stores.Store, pubkey string, req *RegisterDeviceRequest) error {
+	if err := pushService.ValidateWebPushEndpoint(req.Endpoint); err != nil {
+		logging.Warnf("Rejected web push endpoint for pubkey %s: %v", pubkey, err)
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Web push endpoint must be a public https URL",
+		})
+	}
+	endpoint, _ := url.Parse(req.Endpoint)
+
+	if req.Keys == nil || req.Keys.P256dh == "" || req.Keys.Auth == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Web push subscription keys p256dh and auth are required",
+		})
+	}
+
+	statsStore := store.GetStatsStore()
+	err := statsStore.RegisterWebPushDevice(pubkey, req.Endpoint, req.Keys.P256dh, req.Keys.Auth)
+	if err != nil {
+		logging.Errorf("Failed to register web push device: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to register device",
+		})
+	}
+
+	logging.Infof("Registered web push device for pubkey %s: %s", pubkey, endpoint.Host)
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "Device registered successfully",
+	})
+}
@@ -0,0 +1,22 @@
+package push
+
+import (
+	"github.com/HORNET-Storage/hornet-storage/lib/logging"
+	"github.com/HORNET-Storage/hornet-storage/lib/stores"
+	"github.com/gofiber/fiber/v2"
+)
+
+// GetStatsHandler returns notification delivery and coalescing counts for the panel
+func GetStatsHandler(store stores.Store) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		stats, err := store.GetStatsStore().GetPushNotificationStats()
+		if err != nil {
+			logging.Errorf("Failed to get push notification stats: %v", err)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to fetch push notification statistics",
+			})
+		}
+
+		return c.JSON(stats)
+	}
+}
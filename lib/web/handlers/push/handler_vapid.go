@@ -0,0 +1,23 @@
+package push
+
+import (
+	"github.com/gofiber/fiber/v2"
+
+	pushService "github.com/HORNET-Storage/hornet-storage/services/push"
+)
+
+// VAPIDKeyHandler returns the relay's VAPID public key for PushManager.subscribe
+func VAPIDKeyHandler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ps := pushService.GetGlobalPushService()
+		if ps == nil || ps.VAPIDPublicKey() == "" {
+			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+				"error": "Web push notifications are not enabled",
+			})
+		}
+
+		return c.JSON(fiber.Map{
+			"public_key": ps.VAPIDPublicKey(),
+		})
+	}
+}
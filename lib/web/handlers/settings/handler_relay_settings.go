@@ -1,6 +1,7 @@
 package settings
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"strconv"
@@ -12,11 +13,14 @@ import (
 	"github.com/HORNET-Storage/hdk-nostr-go/lib/signing"
 	"github.com/HORNET-Storage/hornet-storage/lib/config"
 	"github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr/kind10411"
+	"github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr/kind30166"
 	"github.com/HORNET-Storage/hornet-storage/lib/logging"
 	"github.com/HORNET-Storage/hornet-storage/lib/stores"
 	"github.com/HORNET-Storage/hornet-storage/lib/subscription"
 	"github.com/HORNET-Storage/hornet-storage/lib/transports/websocket"
 	"github.com/HORNET-Storage/hornet-storage/lib/types"
+	"github.com/HORNET-Storage/hornet-storage/services/lightning"
+	"github.com/HORNET-Storage/hornet-storage/services/mirror"
 	"github.com/HORNET-Storage/hornet-storage/services/push"
 )
 
@@ -366,6 +370,20 @@ func UpdateSettings(c *fiber.Ctx, store stores.Store) error {
 		logging.Info("Push notification settings updated, will reload push service...")
 	}
 
+	// Check if outbound mirroring settings are being updated
+	mirroringUpdated := false
+	if _, exists := settings["mirroring"]; exists {
+		mirroringUpdated = true
+		logging.Info("Mirroring settings updated, will reload mirror service...")
+	}
+
+	// Check if lightning payment settings are being updated
+	lightningUpdated := false
+	if _, exists := settings["lightning"]; exists {
+		lightningUpdated = true
+		logging.Info("Lightning settings updated, will reload lightning service...")
+	}
+
 	// Use the new intelligent update function that only saves changed values
 	// This prevents overwriting unchanged configuration
 	logging.Info("Applying configuration changes intelligently...")
@@ -376,6 +394,9 @@ func UpdateSettings(c *fiber.Ctx, store stores.Store) error {
 		})
 	}
 
+	// The NIP-11 document is built from these settings
+	websocket.InvalidateRelayInfo()
+
 	// If allowed_users settings were updated, update access control and trigger event regeneration
 	if allowedUsersUpdated {
 		logging.Info("Allowed users settings updated, updating access control and triggering event regeneration...")
@@ -414,8 +435,21 @@ func UpdateSettings(c *fiber.Ctx, store stores.Store) error {
 		subscription.ScheduleBatchUpdateAfter(5 * time.Second)
 	}
 
-	// If either allowed_users, relay settings, or event filtering were updated, regenerate kind 10411 event
-	if allowedUsersUpdated || relaySettingsUpdated || eventFilteringUpdated {
+	// If lightning settings were updated, reload the lightning service before kind 10411 is regenerated
+	if lightningUpdated {
+		logging.Info("Reloading lightning payment service with new configuration...")
+
+		var processor lightning.PaymentProcessor
+		if manager := subscription.GetGlobalManager(); manager != nil {
+			processor = manager
+		}
+		if err := lightning.ReloadGlobalService(store, processor); err != nil {
+			logging.Infof("Warning: Failed to reload lightning payment service: %v", err)
+		}
+	}
+
+	// If either allowed_users, relay settings, event filtering or lightning were updated, regenerate kind 10411 event
+	if allowedUsersUpdated || relaySettingsUpdated || eventFilteringUpdated || lightningUpdated {
 		// Regenerate kind 10411 event immediately in a goroutine
 		if store != nil {
 			go func() {
@@ -430,6 +464,9 @@ func UpdateSettings(c *fiber.Ctx, store stores.Store) error {
 				if eventFilteringUpdated {
 					reasons = append(reasons, "event filtering")
 				}
+				if lightningUpdated {
+					reasons = append(reasons, "lightning")
+				}
 				if len(reasons) > 0 {
 					reason = strings.Join(reasons, " and ") + " changes"
 				} else {
@@ -464,6 +501,11 @@ func UpdateSettings(c *fiber.Ctx, store stores.Store) error {
 				} else {
 					logging.Infof("Successfully regenerated kind 10411 event")
 				}
+
+				// Keep the NIP-66 announcement in step with the relay information
+				if err := kind30166.RefreshKind30166Event(context.Background(), privateKey, publicKey, store); err != nil {
+					logging.Infof("Error regenerating kind 30166 event: %v", err)
+				}
 			}()
 		} else {
 			logging.Infof("Warning: Store not available, skipping kind 10411 regeneration")
@@ -484,6 +526,13 @@ func UpdateSettings(c *fiber.Ctx, store stores.Store) error {
 		}
 	}
 
+	// If mirroring settings were updated, restart the mirror service. Queued deliveries are kept.
+	if mirroringUpdated {
+		if err := mirror.ReloadGlobalMirrorService(store); err != nil {
+			logging.Infof("Warning: Failed to reload mirror service: %v", err)
+		}
+	}
+
 	logging.Info("Settings updated successfully")
 	return c.JSON(fiber.Map{
 		"success": true,
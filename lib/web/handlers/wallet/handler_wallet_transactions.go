@@ -21,11 +21,14 @@ import (
 
 // transactionDetails holds parsed transaction information
 type transactionDetails struct {
-	address  string
-	date     time.Time
-	output   string
-	value    float64
-	valueStr string
+	address       string
+	date          time.Time
+	output        string
+	value         float64
+	valueStr      string
+	confirmations int    // subscription.UnknownConfirmations when not reported
+	status        string // "dropped" or "replaced" for transactions that will never confirm
+	replacedBy    string
 }
 
 // Refactored getPendingTransactions function
@@ -98,6 +101,13 @@ func ReplaceTransaction(c *fiber.Ctx, store stores.Store) error {
 		})
 	}
 
+	// Roll back anything the original transaction was credited with
+	if subManager := subscription.GetGlobalManager(); subManager != nil {
+		if err := subManager.RevertOnchainTransaction(replaceRequest.OriginalTxID, subscription.OnchainTxReplaced, replaceRequest.NewTxID); err != nil {
+			logging.Infof("Error reverting payments for replaced transaction %s: %v", replaceRequest.OriginalTxID, err)
+		}
+	}
+
 	// Respond with success
 	return c.JSON(fiber.Map{
 		"status":  "success",
@@ -233,7 +243,12 @@ func ProcessWalletTransaction(store stores.Store, subManager *subscription.Subsc
 		logging.Infof("Warning: could not delete pending transaction: %v", err)
 	}
 
-	// Check if transaction already exists
+	// Dropped and replaced transactions roll back whatever they were credited
+	if txDetails.status == subscription.OnchainTxDropped || txDetails.status == subscription.OnchainTxReplaced {
+		return subManager.RevertOnchainTransaction(txID, txDetails.status, txDetails.replacedBy)
+	}
+
+	// The wallet reports a transaction again as it gains confirmations, only record it once
 	exists, err := store.GetStatsStore().TransactionExists(
 		txDetails.address,
 		txDetails.date,
@@ -244,18 +259,24 @@ func ProcessWalletTransaction(store stores.Store, subManager *subscription.Subsc
 		return fmt.Errorf("error checking existing transaction: %v", err)
 	}
 	if exists {
-		return fmt.Errorf("transaction already processed")
-	}
-
-	// Save transaction record
-	newTransaction := types.WalletTransactions{
-		Address: txDetails.address,
-		Date:    txDetails.date,
-		Output:  txDetails.output,
-		Value:   fmt.Sprintf("%.8f", txDetails.value),
-	}
-	if err := store.GetStatsStore().SaveWalletTransaction(newTransaction); err != nil {
-		return fmt.Errorf("failed to save transaction: %v", err)
+		// Transactions processed before confirmations were tracked have no payment record
+		payment, err := store.GetStatsStore().GetOnchainPayment(txDetails.address)
+		if err != nil {
+			return fmt.Errorf("error checking on-chain payment: %v", err)
+		}
+		if payment == nil {
+			return fmt.Errorf("transaction already processed")
+		}
+	} else {
+		newTransaction := types.WalletTransactions{
+			Address: txDetails.address,
+			Date:    txDetails.date,
+			Output:  txDetails.output,
+			Value:   fmt.Sprintf("%.8f", txDetails.value),
+		}
+		if err := store.GetStatsStore().SaveWalletTransaction(newTransaction); err != nil {
+			return fmt.Errorf("failed to save transaction: %v", err)
+		}
 	}
 
 	// After subscriber retrieval in processTransaction
@@ -276,66 +297,21 @@ func ProcessWalletTransaction(store stores.Store, subManager *subscription.Subsc
 	// Convert BTC value to satoshis for subscription processing
 	satoshis := int64(math.Round(txDetails.value * 100_000_000))
 
-	// Process the subscription payment
-	if err := subManager.ProcessPayment(*subscriber.Npub, txID, satoshis); err != nil {
+	// Hold the payment until it meets the confirmation policy, then grant the subscription.
+	// Payment notifications are created for each state transition.
+	if err := subManager.ProcessOnchainPayment(subscription.OnchainPaymentUpdate{
+		Npub:          *subscriber.Npub,
+		TxID:          txID,
+		Outpoint:      txDetails.address,
+		Address:       txDetails.output,
+		AmountSats:    satoshis,
+		Confirmations: txDetails.confirmations,
+	}); err != nil {
 		return fmt.Errorf("failed to process subscription: %v", err)
 	}
 
-	// Determine if this is a new subscriber or a renewal
-	paidSubscriber, err := store.GetStatsStore().GetPaidSubscriberByNpub(*subscriber.Npub)
-	isNewSubscriber := err != nil || paidSubscriber == nil // If error or nil, it's a new subscriber
-
-	// Get the subscription tier
-	// We'll extract the tier from paid subscriber data or try to determine from amount
-	var tier string
-	expirationDate := time.Now().Add(30 * 24 * time.Hour) // Default to 30 days
-
-	if paidSubscriber != nil {
-		tier = paidSubscriber.Tier
-		expirationDate = paidSubscriber.ExpirationDate
-	} else {
-		// Try to determine the tier based on the payment amount
-		settings, err := config.GetConfig()
-		if err != nil {
-			return fmt.Errorf("error getting config: %v", err)
-		}
-
-		for _, tierInfo := range settings.AllowedUsersSettings.Tiers {
-			// Extract price in satoshis
-			price := int64(tierInfo.PriceSats)
-			// If payment amount is within 10% of the tier price, consider it that tier
-			if satoshis >= price*9/10 && satoshis <= price*11/10 {
-				tier = tierInfo.Name
-				break
-			}
-		}
-
-		if tier == "" {
-			tier = "unknown" // Fallback if we couldn't determine the tier
-		}
-	}
-
-	// Create payment notification
-	notification := &types.PaymentNotification{
-		PubKey:           *subscriber.Npub,
-		TxID:             txID,
-		Amount:           satoshis,
-		SubscriptionTier: tier,
-		IsNewSubscriber:  isNewSubscriber,
-		ExpirationDate:   expirationDate,
-		// CreatedAt and IsRead will be set automatically
-	}
-
-	if err := store.GetStatsStore().CreatePaymentNotification(notification); err != nil {
-		logging.Infof("Warning: failed to create payment notification: %v", err)
-		// Continue processing - non-fatal error
-	} else {
-		logging.Infof("Created payment notification for %s: %d sats, tier: %s",
-			*subscriber.Npub, satoshis, tier)
-	}
-
-	logging.Infof("Successfully processed subscription payment for %s: %s sats",
-		*subscriber.Npub, txDetails.valueStr)
+	logging.Infof("Processed subscription payment for %s: %d sats (%d confirmations)",
+		*subscriber.Npub, satoshis, txDetails.confirmations)
 	return nil
 }
 
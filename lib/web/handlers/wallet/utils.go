@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/HORNET-Storage/hornet-storage/lib/config"
+	"github.com/HORNET-Storage/hornet-storage/lib/subscription"
 	"github.com/spf13/viper"
 )
 
@@ -57,12 +58,23 @@ func ExtractTransactionDetails(transaction map[string]interface{}) (*transaction
 		return nil, fmt.Errorf("error parsing value: %v", err)
 	}
 
+	// Confirmations and status are optional, payments without a count wait for a later report
+	confirmations := subscription.UnknownConfirmations
+	if raw, ok := transaction["confirmations"].(float64); ok {
+		confirmations = int(raw)
+	}
+	status, _ := transaction["status"].(string)
+	replacedBy, _ := transaction["replaced_by"].(string)
+
 	return &transactionDetails{
-		address:  address,
-		date:     date,
-		output:   output,
-		value:    value,
-		valueStr: valueStr,
+		address:       address,
+		date:          date,
+		output:        output,
+		value:         value,
+		valueStr:      valueStr,
+		confirmations: confirmations,
+		status:        status,
+		replacedBy:    replacedBy,
 	}, nil
 }
 
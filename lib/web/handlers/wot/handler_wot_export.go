@@ -0,0 +1,228 @@
+package wot
+
+import (
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	merkle_dag "github.com/HORNET-Storage/Scionic-Merkle-Tree/v2/dag"
+	"github.com/HORNET-Storage/hdk-nostr-go/lib/signing"
+	"github.com/gofiber/fiber/v2"
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/nbd-wtf/go-nostr/nip19"
+	"github.com/spf13/viper"
+
+	"github.com/HORNET-Storage/hornet-storage/lib/logging"
+	"github.com/HORNET-Storage/hornet-storage/lib/stores"
+	"github.com/HORNET-Storage/hornet-storage/lib/types"
+	"github.com/HORNET-Storage/hornet-storage/lib/web/middleware"
+	lib_wot "github.com/HORNET-Storage/hornet-storage/lib/wot"
+)
+
+// ReadCheck reports whether a pubkey may read from the relay
+type ReadCheck func(pubkey string) error
+
+// SeedList returns the relay's WOT seed pubkeys as hex
+type SeedList func() []string
+
+// PublishRequest represents the request body for publishing a WOT file
+type PublishRequest struct {
+	Hops int `json:"hops"`
+}
+
+// ExportHandler serves the follow graph rooted at the authenticated pubkey as a
+// nostr-social-graph v2 binary. A root query parameter may instead name one of
+// the relay's seeds, other pubkeys can only export their own graph.
+func ExportHandler(store stores.Store, canRead ReadCheck, seeds SeedList) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		pubkey, ok := authorize(c, canRead)
+		if !ok {
+			return nil
+		}
+
+		root := pubkey
+		if requested := strings.TrimSpace(c.Query("root")); requested != "" {
+			decoded, err := decodePubkey(requested)
+			if err != nil {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+					"error": "Invalid root pubkey",
+				})
+			}
+			root = decoded
+			if root != pubkey && !isSeed(seeds, root) {
+				return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+					"error": "Only your own graph or a relay seed's graph can be exported",
+				})
+			}
+		}
+
+		data, reached, err := exportGraph(store, root, c.QueryInt("hops", lib_wot.DefaultMaxHops))
+		if err != nil {
+			logging.Errorf("Failed to export WOT graph for %s: %v", root, err)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to export graph",
+			})
+		}
+
+		logging.Infof("Exported WOT graph for %s to %s (%d pubkeys, %d bytes)", root, pubkey, reached, len(data))
+
+		c.Set(fiber.HeaderContentType, "application/octet-stream")
+		c.Set(fiber.HeaderContentDisposition, fmt.Sprintf("attachment; filename=\"wot-%s.bin\"", root[:8]))
+		c.Set("X-WOT-Pubkeys", fmt.Sprintf("%d", reached))
+		if reached >= lib_wot.DefaultMaxExportPubkeys {
+			c.Set("X-WOT-Truncated", "true")
+		}
+		return c.Send(data)
+	}
+}
+
+// PublishHandler builds the authenticated pubkey's follow graph and stores it as a
+// WOT DAG signed by the relay, ready to be referenced from a repository
+// permission event's wot_file tag.
+func PublishHandler(store stores.Store, canRead ReadCheck) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		pubkey, ok := authorize(c, canRead)
+		if !ok {
+			return nil
+		}
+
+		var req PublishRequest
+		if len(c.Body()) > 0 {
+			if err := c.BodyParser(&req); err != nil {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+					"error": "Invalid request body",
+				})
+			}
+		}
+
+		data, reached, err := exportGraph(store, pubkey, req.Hops)
+		if err != nil {
+			logging.Errorf("Failed to export WOT graph for %s: %v", pubkey, err)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to export graph",
+			})
+		}
+
+		root, err := storeWotDag(store, pubkey, data)
+		if err != nil {
+			logging.Errorf("Failed to publish WOT DAG for %s: %v", pubkey, err)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to publish graph",
+			})
+		}
+
+		logging.Infof("Published WOT DAG %s for %s (%d pubkeys, %d bytes)", root, pubkey, reached, len(data))
+
+		return c.JSON(fiber.Map{
+			"success": true,
+			"root":    root,
+			"pubkeys": reached,
+			"size":    len(data),
+		})
+	}
+}
+
+// authorize returns the NIP-98 pubkey if it may read from the relay, otherwise it
+// writes the error response and returns false
+func authorize(c *fiber.Ctx, canRead ReadCheck) (string, bool) {
+	pubkey, err := middleware.GetNIP98Pubkey(c)
+	if err != nil {
+		c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Authentication required",
+		})
+		return "", false
+	}
+
+	if canRead != nil {
+		if err := canRead(pubkey); err != nil {
+			c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "Read access denied",
+			})
+			return "", false
+		}
+	}
+
+	return strings.ToLower(pubkey), true
+}
+
+// isSeed reports whether pubkey is one of the relay's seeds
+func isSeed(seeds SeedList, pubkey string) bool {
+	if seeds == nil {
+		return false
+	}
+	for _, seed := range seeds() {
+		if seed == pubkey {
+			return true
+		}
+	}
+	return false
+}
+
+func exportGraph(store stores.Store, root string, hops int) ([]byte, int, error) {
+	return lib_wot.ExportGraph(func(kind int, authors []string) ([]*nostr.Event, error) {
+		return store.QueryEvents(nostr.Filter{Kinds: []int{kind}, Authors: authors})
+	}, root, hops, lib_wot.DefaultMaxExportPubkeys)
+}
+
+// storeWotDag wraps the binary in a single file DAG tagged for the WOT cache and stores it
+func storeWotDag(store stores.Store, owner string, data []byte) (string, error) {
+	privateKey, _, err := signing.DeserializePrivateKey(viper.GetString("relay.private_key"))
+	if err != nil {
+		return "", fmt.Errorf("failed to load relay key: %w", err)
+	}
+	publicKey, err := signing.SerializePublicKey(privateKey.PubKey())
+	if err != nil {
+		return "", fmt.Errorf("failed to serialize relay key: %w", err)
+	}
+
+	tempDir, err := os.MkdirTemp("", "wot-export-")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(tempDir)
+
+	path := filepath.Join(tempDir, fmt.Sprintf("wot-%s.bin", owner[:8]))
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return "", err
+	}
+
+	dag, err := merkle_dag.CreateDagAdvanced(path, map[string]string{
+		"wot_file":  "true",
+		"wot_owner": owner,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to build DAG: %w", err)
+	}
+
+	signature, err := signing.SignSerializedCid(dag.Root, privateKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign DAG root: %w", err)
+	}
+
+	if err := store.StoreDag(&types.DagData{
+		PublicKey: *publicKey,
+		Signature: hex.EncodeToString(signature.Serialize()),
+		Dag:       *dag,
+	}); err != nil {
+		return "", err
+	}
+
+	return dag.Root, nil
+}
+
+func decodePubkey(value string) (string, error) {
+	if strings.HasPrefix(value, "npub1") {
+		_, decoded, err := nip19.Decode(value)
+		if err != nil {
+			return "", err
+		}
+		return decoded.(string), nil
+	}
+
+	if raw, err := hex.DecodeString(value); err != nil || len(raw) != 32 {
+		return "", fmt.Errorf("expected a 64 character hex pubkey")
+	}
+	return strings.ToLower(value), nil
+}
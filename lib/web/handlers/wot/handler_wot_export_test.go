@@ -0,0 +1,112 @@
+package wot
+
+import (
+	"fmt"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/nbd-wtf/go-nostr"
+
+	"github.com/HORNET-Storage/hornet-storage/lib/stores"
+	"github.com/HORNET-Storage/hornet-storage/lib/web/middleware"
+	lib_wot "github.com/HORNET-Storage/hornet-storage/lib/wot"
+)
+
+// eventStore answers QueryEvents from a fixed set of events
+type eventStore struct {
+	stores.Store
+	events []*nostr.Event
+}
+
+func (s *eventStore) QueryEvents(filter nostr.Filter) ([]*nostr.Event, error) {
+	var events []*nostr.Event
+	for _, event := range s.events {
+		if filter.Matches(event) {
+			events = append(events, event)
+		}
+	}
+	return events, nil
+}
+
+func testPubkey(name string) string {
+	return strings.Repeat(name[:1], 64)
+}
+
+func contactList(author string, targets ...string) *nostr.Event {
+	tags := nostr.Tags{}
+	for _, target := range targets {
+		tags = append(tags, nostr.Tag{"p", target})
+	}
+	return &nostr.Event{Kind: lib_wot.ContactListKind, PubKey: author, CreatedAt: 1, Tags: tags}
+}
+
+// newExportApp serves ExportHandler with the caller's pubkey taken from a header
+// in place of a NIP-98 signature
+func newExportApp(store stores.Store, canRead ReadCheck, seeds SeedList) *fiber.App {
+	app := fiber.New()
+	app.Use(func(c *fiber.Ctx) error {
+		if pubkey := c.Get("X-Test-Pubkey"); pubkey != "" {
+			c.Locals(middleware.NIP98PubkeyKey, pubkey)
+		}
+		return c.Next()
+	})
+	app.Get("/wot/export", ExportHandler(store, canRead, seeds))
+	return app
+}
+
+func TestExportHandlerRestrictsRoots(t *testing.T) {
+	caller, seed, stranger, friend := testPubkey("a"), testPubkey("b"), testPubkey("c"), testPubkey("d")
+	store := &eventStore{events: []*nostr.Event{
+		contactList(caller, friend),
+		contactList(seed, friend),
+		contactList(stranger, friend),
+	}}
+
+	app := newExportApp(store, func(pubkey string) error {
+		if pubkey == stranger {
+			return fmt.Errorf("not allowed")
+		}
+		return nil
+	}, func() []string { return []string{seed} })
+
+	tests := []struct {
+		name   string
+		caller string
+		root   string
+		status int
+	}{
+		{"unauthenticated", "", "", fiber.StatusUnauthorized},
+		{"no read access", stranger, "", fiber.StatusForbidden},
+		{"own graph", caller, "", fiber.StatusOK},
+		{"own graph by root", caller, strings.ToUpper(caller), fiber.StatusOK},
+		{"relay seed", caller, seed, fiber.StatusOK},
+		{"another pubkey", caller, stranger, fiber.StatusForbidden},
+		{"invalid root", caller, "not-a-pubkey", fiber.StatusBadRequest},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/wot/export?root="+test.root, nil)
+			if test.caller != "" {
+				req.Header.Set("X-Test-Pubkey", test.caller)
+			}
+
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatalf("request failed: %v", err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != test.status {
+				body, _ := io.ReadAll(resp.Body)
+				t.Fatalf("expected status %d, got %d: %s", test.status, resp.StatusCode, body)
+			}
+			if test.status == fiber.StatusOK && resp.Header.Get("X-WOT-Pubkeys") != "2" {
+				t.Errorf("expected the root and its follow, got %s pubkeys", resp.Header.Get("X-WOT-Pubkeys"))
+			}
+		})
+	}
+}
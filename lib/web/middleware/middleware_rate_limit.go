@@ -7,6 +7,26 @@ import (
 	"github.com/gofiber/fiber/v2/middleware/limiter"
 )
 
+// PubkeyRateLimiterMiddleware allows max requests per expiration for each NIP-98
+// pubkey, falling back to the client IP, so it must run after NIP98Middleware.
+func PubkeyRateLimiterMiddleware(max int, expiration time.Duration) fiber.Handler {
+	return limiter.New(limiter.Config{
+		Max:        max,
+		Expiration: expiration,
+		KeyGenerator: func(c *fiber.Ctx) string {
+			if pubkey, err := GetNIP98Pubkey(c); err == nil {
+				return pubkey
+			}
+			return c.IP()
+		},
+		LimitReached: func(c *fiber.Ctx) error {
+			return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
+				"error": "Rate limit exceeded. Please try again later.",
+			})
+		},
+	})
+}
+
 func RateLimiterMiddleware() fiber.Handler {
 	return limiter.New(limiter.Config{
 		Max:        5,
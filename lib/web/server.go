@@ -15,13 +15,19 @@ import (
 	"github.com/HORNET-Storage/hornet-storage/lib/handlers/blossom"
 	"github.com/HORNET-Storage/hornet-storage/lib/logging"
 	"github.com/HORNET-Storage/hornet-storage/lib/stores"
+	"github.com/HORNET-Storage/hornet-storage/lib/transports/websocket"
 
 	// Import the organized handlers
 	"github.com/HORNET-Storage/hornet-storage/lib/web/handlers"
 	"github.com/HORNET-Storage/hornet-storage/lib/web/handlers/access"
 	"github.com/HORNET-Storage/hornet-storage/lib/web/handlers/auth"
+	"github.com/HORNET-Storage/hornet-storage/lib/web/handlers/backfill"
 	"github.com/HORNET-Storage/hornet-storage/lib/web/handlers/bitcoin"
+	"github.com/HORNET-Storage/hornet-storage/lib/web/handlers/health"
+	"github.com/HORNET-Storage/hornet-storage/lib/web/handlers/lightning"
+	"github.com/HORNET-Storage/hornet-storage/lib/web/handlers/mirror"
 	"github.com/HORNET-Storage/hornet-storage/lib/web/handlers/moderation"
+	"github.com/HORNET-Storage/hornet-storage/lib/web/handlers/push"
 	"github.com/HORNET-Storage/hornet-storage/lib/web/handlers/settings"
 	"github.com/HORNET-Storage/hornet-storage/lib/web/handlers/statistics"
 	"github.com/HORNET-Storage/hornet-storage/lib/web/handlers/wallet"
@@ -111,6 +117,26 @@ func StartServer(store stores.Store, ctx context.Context) error {
 		return auth.LogoutUser(c, store)
 	})
 
+	// ================================
+	// LIGHTNING PAYMENT ROUTES (PUBLIC)
+	// ================================
+
+	app.Post("/api/lightning/invoice", middleware.RateLimiterMiddleware(), func(c *fiber.Ctx) error {
+		return lightning.CreateInvoice(c, store)
+	})
+
+	app.Get("/api/lightning/invoice/:hash", middleware.RateLimiterMiddleware(), func(c *fiber.Ctx) error {
+		return lightning.GetInvoiceStatus(c, store)
+	})
+
+	// ================================
+	// HEALTH ROUTES (PUBLIC)
+	// ================================
+
+	// Not rate limited: orchestrators probe often, and reports are cached briefly
+	app.Get("/health/live", health.GetLiveness)
+	app.Get("/health/ready", health.GetReadiness)
+
 	// ================================
 	// WALLET PROXY ROUTES (MUST BE BEFORE /api/wallet ROUTES)
 	// ================================
@@ -320,6 +346,34 @@ func StartServer(store stores.Store, ctx context.Context) error {
 		return moderation.DeleteModeratedEvent(c, store)
 	})
 
+	secured.Get("/moderation/reviews", func(c *fiber.Ctx) error {
+		return moderation.GetModerationReviews(c, store)
+	})
+
+	secured.Post("/moderation/reviews/:id/approve", func(c *fiber.Ctx) error {
+		return moderation.ApproveModerationReview(c, store)
+	})
+
+	secured.Post("/moderation/reviews/:id/block", func(c *fiber.Ctx) error {
+		return moderation.BlockModerationReview(c, store)
+	})
+
+	secured.Post("/moderation/reviews/:id/label", func(c *fiber.Ctx) error {
+		return moderation.LabelModerationReview(c, store)
+	})
+
+	secured.Get("/moderation/hash-blocklist", func(c *fiber.Ctx) error {
+		return moderation.GetHashBlocklist(c, store)
+	})
+
+	secured.Post("/moderation/hash-blocklist", func(c *fiber.Ctx) error {
+		return moderation.AddHashBlock(c, store)
+	})
+
+	secured.Delete("/moderation/hash-blocklist/:id", func(c *fiber.Ctx) error {
+		return moderation.DeleteHashBlock(c, store)
+	})
+
 	secured.Get("/blocked-pubkeys", func(c *fiber.Ctx) error {
 		return handlers.GetBlockedPubkeys(c, store)
 	})
@@ -394,6 +448,37 @@ func StartServer(store stores.Store, ctx context.Context) error {
 		return access.RemoveAllowedUser(c, store)
 	})
 
+	// Invite codes
+	secured.Get("/invites", func(c *fiber.Ctx) error {
+		return access.ListInviteCodes(c, store)
+	})
+
+	secured.Post("/invites", func(c *fiber.Ctx) error {
+		return access.CreateInviteCode(c, store)
+	})
+
+	secured.Delete("/invites/:code", func(c *fiber.Ctx) error {
+		return access.RevokeInviteCode(c, store)
+	})
+
+	// Write policy
+	secured.Post("/write-policy/dry-run", func(c *fiber.Ctx) error {
+		return access.DryRunWritePolicy(c, websocket.GetAccessControl(), store)
+	})
+
+	// Outbound mirroring
+	secured.Get("/mirroring", func(c *fiber.Ctx) error {
+		return mirror.GetMirrorStatus(c, store)
+	})
+
+	// History backfill
+	secured.Get("/backfill", backfill.GetBackfillJobs)
+	secured.Get("/backfill/:pubkey", backfill.GetBackfillJob)
+	secured.Post("/backfill", backfill.StartBackfill)
+
+	// Push notification delivery and coalescing counts
+	secured.Get("/push/stats", push.GetStatsHandler(store))
+
 	// Relay owner management
 	secured.Get("/admin/owner", func(c *fiber.Ctx) error {
 		return access.GetRelayOwner(c, store)
@@ -45,6 +45,9 @@ type Cache struct {
 	lru    *lru.Cache[string, *CachedGraph]
 	loader LoaderFunc
 	mu     sync.Mutex // serializes loader calls for the same key
+
+	// pinned holds graphs that must never be evicted, such as the relay-wide graph.
+	pinned sync.Map
 }
 
 // NewCache creates an LRU-bounded WOT cache.
@@ -103,6 +106,10 @@ func (c *Cache) Store(dagRootHash string, ownerPubkey string, binaryData []byte)
 func (c *Cache) Lookup(dagRootHash string) *CachedGraph {
 	dagRootHash = strings.TrimSpace(dagRootHash)
 
+	if entry, ok := c.pinned.Load(dagRootHash); ok {
+		return entry.(*CachedGraph)
+	}
+
 	// Fast path: LRU hit
 	if entry, ok := c.lru.Get(dagRootHash); ok {
 		return entry
@@ -138,10 +145,23 @@ func (c *Cache) Lookup(dagRootHash string) *CachedGraph {
 	return entry
 }
 
+// Pin stores a pre-computed distance map under key outside the LRU, so it is
+// never evicted by repository graphs. Pinning the same key again replaces it.
+func (c *Cache) Pin(key string, ownerPubkey string, distances map[string]int) {
+	if distances == nil {
+		distances = make(map[string]int)
+	}
+	c.pinned.Store(strings.TrimSpace(key), &CachedGraph{
+		OwnerPubkey: strings.ToLower(strings.TrimSpace(ownerPubkey)),
+		Distances:   distances,
+	})
+}
+
 // Invalidate removes a cached WOT graph by its DAG root hash.
 func (c *Cache) Invalidate(dagRootHash string) {
 	dagRootHash = strings.TrimSpace(dagRootHash)
 	c.lru.Remove(dagRootHash)
+	c.pinned.Delete(dagRootHash)
 	logging.Infof("[WOT] Invalidated WOT cache for root hash: %s", dagRootHash)
 }
 
@@ -170,6 +190,24 @@ func (c *Cache) IsWithinHops(dagRootHash string, targetPubkey string, maxHops in
 	return distance <= maxHops
 }
 
+// Distance returns the follow-distance of targetPubkey from the owner of the
+// WOT graph identified by dagRootHash. The second return value is false if the
+// graph cannot be loaded or the target is unreachable.
+func (c *Cache) Distance(dagRootHash string, targetPubkey string) (int, bool) {
+	cached := c.Lookup(dagRootHash)
+	if cached == nil {
+		return 0, false
+	}
+
+	targetPubkey = strings.ToLower(strings.TrimSpace(targetPubkey))
+	if targetPubkey == cached.OwnerPubkey {
+		return 0, true
+	}
+
+	distance, ok := cached.Distances[targetPubkey]
+	return distance, ok
+}
+
 // Len returns the number of entries currently in the cache.
 func (c *Cache) Len() int {
 	return c.lru.Len()
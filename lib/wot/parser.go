@@ -38,7 +38,8 @@ type FollowGraph struct {
 //	  for each: [32 bytes] pubkey, [varint] internal ID
 //	[varint] follow lists count
 //	  for each: [varint] owner ID, [varint] timestamp, [varint] count, then count x [varint] target ID
-//	[varint] mute lists count (skipped — mutes are not used for follow-distance)
+//	[varint] mute lists count
+//	  for each: same layout as follow lists (skipped — mutes are not used for follow-distance)
 func ParseBinary(data []byte) (*FollowGraph, error) {
 	if len(data) == 0 {
 		return nil, fmt.Errorf("wot: empty binary data")
@@ -0,0 +1,224 @@
+package wot
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/nbd-wtf/go-nostr"
+
+	"github.com/HORNET-Storage/hornet-storage/lib/logging"
+)
+
+const (
+	// RelayGraphKey is the cache key the relay-wide graph is pinned under.
+	RelayGraphKey = "relay"
+
+	// ContactListKind is the NIP-02 follow list kind.
+	ContactListKind = 3
+
+	// MuteListKind is the NIP-51 mute list kind. Public p tags act as negative edges.
+	MuteListKind = 10000
+)
+
+// RelayGraph is a follow graph built from the contact and mute lists stored on
+// the relay. It is seeded by the relay owner (or configured seed pubkeys) and
+// publishes its distance map to a Cache under RelayGraphKey.
+//
+// Mutes are applied level by level: a pubkey muted by anyone closer to the
+// seeds is dropped from the graph and none of its follows are expanded. Blocked
+// pubkeys are dropped the same way. Seeds themselves are never dropped.
+type RelayGraph struct {
+	mu      sync.Mutex
+	cache   *Cache
+	seeds   []string
+	maxHops int
+
+	follows   map[string][]string
+	followsAt map[string]nostr.Timestamp
+	mutes     map[string][]string
+	mutesAt   map[string]nostr.Timestamp
+
+	distances map[string]int
+	blocked   func(pubkey string) bool
+	onChange  func()
+}
+
+// NewRelayGraph creates an empty relay graph that publishes to cache.
+// maxHops is clamped to MaxAllowedHops and defaults to DefaultMaxHops.
+func NewRelayGraph(cache *Cache, seeds []string, maxHops int) *RelayGraph {
+	if maxHops <= 0 {
+		maxHops = DefaultMaxHops
+	}
+	if maxHops > MaxAllowedHops {
+		maxHops = MaxAllowedHops
+	}
+
+	normalized := make([]string, 0, len(seeds))
+	for _, seed := range seeds {
+		seed = strings.ToLower(strings.TrimSpace(seed))
+		if seed != "" {
+			normalized = append(normalized, seed)
+		}
+	}
+
+	return &RelayGraph{
+		cache:     cache,
+		seeds:     normalized,
+		maxHops:   maxHops,
+		follows:   make(map[string][]string),
+		followsAt: make(map[string]nostr.Timestamp),
+		mutes:     make(map[string][]string),
+		mutesAt:   make(map[string]nostr.Timestamp),
+		distances: make(map[string]int),
+	}
+}
+
+// SetBlocked sets the function used to exclude blocked pubkeys from the graph.
+func (g *RelayGraph) SetBlocked(blocked func(pubkey string) bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.blocked = blocked
+}
+
+// OnChange sets a callback that runs after the distance map changes.
+func (g *RelayGraph) OnChange(fn func()) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.onChange = fn
+}
+
+// MaxHops returns the follow-distance limit the graph was built with.
+func (g *RelayGraph) MaxHops() int {
+	return g.maxHops
+}
+
+// Load applies a batch of contact and mute lists and recomputes the graph once.
+func (g *RelayGraph) Load(events []*nostr.Event) {
+	g.mu.Lock()
+	for _, event := range events {
+		g.applyLocked(event)
+	}
+	g.recomputeLocked()
+	onChange := g.onChange
+	g.mu.Unlock()
+
+	if onChange != nil {
+		onChange()
+	}
+}
+
+// Apply records a newly stored contact or mute list. The graph is only
+// recomputed when the author is close enough to the seeds for the list to
+// change anyone's distance. Returns true if the graph was recomputed.
+func (g *RelayGraph) Apply(event *nostr.Event) bool {
+	g.mu.Lock()
+	if !g.applyLocked(event) {
+		g.mu.Unlock()
+		return false
+	}
+
+	// Lists from pubkeys at the hop limit (or outside the graph) can only
+	// affect pubkeys beyond the limit, so the distances cannot change.
+	author := strings.ToLower(event.PubKey)
+	if distance, ok := g.distances[author]; !ok || distance >= g.maxHops {
+		g.mu.Unlock()
+		return false
+	}
+
+	g.recomputeLocked()
+	onChange := g.onChange
+	g.mu.Unlock()
+
+	if onChange != nil {
+		onChange()
+	}
+	return true
+}
+
+// applyLocked stores the event's edges if it is newer than what is held.
+func (g *RelayGraph) applyLocked(event *nostr.Event) bool {
+	if event == nil {
+		return false
+	}
+	author := strings.ToLower(event.PubKey)
+
+	var edges map[string][]string
+	var updatedAt map[string]nostr.Timestamp
+	switch event.Kind {
+	case ContactListKind:
+		edges, updatedAt = g.follows, g.followsAt
+	case MuteListKind:
+		edges, updatedAt = g.mutes, g.mutesAt
+	default:
+		return false
+	}
+
+	if last, ok := updatedAt[author]; ok && event.CreatedAt <= last {
+		return false
+	}
+
+	targets := make([]string, 0, len(event.Tags))
+	for _, tag := range event.Tags {
+		if len(tag) >= 2 && tag[0] == "p" && len(tag[1]) == 64 {
+			targets = append(targets, strings.ToLower(tag[1]))
+		}
+	}
+
+	edges[author] = targets
+	updatedAt[author] = event.CreatedAt
+	return true
+}
+
+// recomputeLocked runs a breadth-first search from the seeds and pins the result.
+func (g *RelayGraph) recomputeLocked() {
+	distances := make(map[string]int)
+	excluded := make(map[string]bool)
+
+	frontier := make([]string, 0, len(g.seeds))
+	for _, seed := range g.seeds {
+		if _, ok := distances[seed]; !ok {
+			distances[seed] = 0
+			frontier = append(frontier, seed)
+		}
+	}
+
+	for distance := 1; distance <= g.maxHops && len(frontier) > 0; distance++ {
+		// Mutes from this level apply before anyone further out is admitted
+		for _, current := range frontier {
+			for _, muted := range g.mutes[current] {
+				if _, reached := distances[muted]; !reached {
+					excluded[muted] = true
+				}
+			}
+		}
+
+		next := make([]string, 0, len(frontier)*2)
+		for _, current := range frontier {
+			for _, followed := range g.follows[current] {
+				if _, reached := distances[followed]; reached || excluded[followed] {
+					continue
+				}
+				if g.blocked != nil && g.blocked(followed) {
+					excluded[followed] = true
+					continue
+				}
+				distances[followed] = distance
+				next = append(next, followed)
+			}
+		}
+		frontier = next
+	}
+
+	g.distances = distances
+
+	owner := ""
+	if len(g.seeds) > 0 {
+		owner = g.seeds[0]
+	}
+	if g.cache != nil {
+		g.cache.Pin(RelayGraphKey, owner, distances)
+	}
+
+	logging.Debugf("[WOT] Relay graph recomputed: %d seeds, %d trusted pubkeys within %d hops, %d excluded",
+		len(g.seeds), len(distances), g.maxHops, len(excluded))
+}
@@ -0,0 +1,65 @@
+package wot
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/nbd-wtf/go-nostr"
+)
+
+func testPubkey(name string) string {
+	return strings.Repeat(name[:1], 64)
+}
+
+func listEvent(kind int, author string, createdAt nostr.Timestamp, targets ...string) *nostr.Event {
+	tags := nostr.Tags{}
+	for _, target := range targets {
+		tags = append(tags, nostr.Tag{"p", target})
+	}
+	return &nostr.Event{Kind: kind, PubKey: author, CreatedAt: createdAt, Tags: tags}
+}
+
+func TestRelayGraphDistancesAndMutes(t *testing.T) {
+	owner, a, b, c, d, e := testPubkey("0"), testPubkey("a"), testPubkey("b"), testPubkey("c"), testPubkey("d"), testPubkey("e")
+
+	cache := NewCache()
+	graph := NewRelayGraph(cache, []string{owner}, 2)
+	graph.SetBlocked(func(pubkey string) bool { return pubkey == e })
+
+	graph.Load([]*nostr.Event{
+		listEvent(ContactListKind, owner, 1, a, e),
+		listEvent(ContactListKind, a, 1, b, c),
+		listEvent(ContactListKind, b, 1, d), // Beyond two hops
+		listEvent(MuteListKind, owner, 1, c),
+	})
+
+	cases := map[string]bool{owner: true, a: true, b: true, c: false, d: false, e: false}
+	for pubkey, want := range cases {
+		if got := cache.IsWithinHops(RelayGraphKey, pubkey, graph.MaxHops()); got != want {
+			t.Errorf("IsWithinHops(%s) = %t, want %t", pubkey[:1], got, want)
+		}
+	}
+
+	// A contact list from outside the graph cannot change any distance
+	if graph.Apply(listEvent(ContactListKind, d, 2, c)) {
+		t.Error("expected a list from outside the graph to be ignored")
+	}
+
+	// A newer owner list replaces the old one
+	changed := false
+	graph.OnChange(func() { changed = true })
+	if !graph.Apply(listEvent(ContactListKind, owner, 2, b)) || !changed {
+		t.Fatal("expected the owner's new contact list to recompute the graph")
+	}
+	if cache.IsWithinHops(RelayGraphKey, a, graph.MaxHops()) {
+		t.Error("expected a to drop out after the owner unfollowed them")
+	}
+	if distance, ok := cache.Distance(RelayGraphKey, d); !ok || distance != 2 {
+		t.Errorf("expected d at distance 2, got %d (%t)", distance, ok)
+	}
+
+	// Stale lists are ignored
+	if graph.Apply(listEvent(ContactListKind, owner, 1, a)) {
+		t.Error("expected an older contact list to be ignored")
+	}
+}
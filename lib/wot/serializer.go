@@ -0,0 +1,242 @@
+package wot
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/nbd-wtf/go-nostr"
+)
+
+// exportQueryBatchSize bounds the number of authors in a single store query during export.
+const exportQueryBatchSize = 500
+
+// DefaultMaxExportPubkeys bounds the number of pubkeys ExportGraph walks to when
+// no limit is given, which keeps a single export to a few megabytes.
+const DefaultMaxExportPubkeys = 50000
+
+// ListEntry is a single follow or mute list in a nostr-social-graph binary.
+type ListEntry struct {
+	// Owner is the hex-encoded pubkey that published the list.
+	Owner string
+
+	// CreatedAt is the created_at of the list event.
+	CreatedAt nostr.Timestamp
+
+	// Targets are the hex-encoded pubkeys on the list.
+	Targets []string
+}
+
+// ListQuery returns the stored events of kind authored by any of authors.
+type ListQuery func(kind int, authors []string) ([]*nostr.Event, error)
+
+// SerializeBinary encodes follow and mute lists as a nostr-social-graph v2 binary
+// that ParseBinary can read back. root is always assigned the first internal ID.
+// Pubkeys that are not valid 32-byte hex are skipped.
+func SerializeBinary(root string, follows []ListEntry, mutes []ListEntry) ([]byte, error) {
+	root = strings.ToLower(strings.TrimSpace(root))
+	if _, err := decodePubkey(root); err != nil {
+		return nil, fmt.Errorf("wot: invalid root pubkey: %w", err)
+	}
+
+	ids := make(map[string]uint32)
+	order := make([]string, 0)
+	idFor := func(pubkey string) (uint32, bool) {
+		pubkey = strings.ToLower(pubkey)
+		if id, ok := ids[pubkey]; ok {
+			return id, true
+		}
+		if _, err := decodePubkey(pubkey); err != nil {
+			return 0, false
+		}
+		id := uint32(len(order))
+		ids[pubkey] = id
+		order = append(order, pubkey)
+		return id, true
+	}
+	idFor(root)
+
+	// Resolve every list to internal IDs first so the ID table is complete
+	type encodedList struct {
+		owner     uint32
+		createdAt uint32
+		targets   []uint32
+	}
+	encodeLists := func(entries []ListEntry) []encodedList {
+		lists := make([]encodedList, 0, len(entries))
+		for _, entry := range entries {
+			owner, ok := idFor(entry.Owner)
+			if !ok {
+				continue
+			}
+			list := encodedList{owner: owner, createdAt: uint32(entry.CreatedAt)}
+			for _, target := range entry.Targets {
+				if id, ok := idFor(target); ok {
+					list.targets = append(list.targets, id)
+				}
+			}
+			lists = append(lists, list)
+		}
+		return lists
+	}
+	followLists := encodeLists(follows)
+	muteLists := encodeLists(mutes)
+
+	buf := make([]byte, 0, len(order)*(pubkeyByteLen+3))
+	buf = encodeVarint(buf, supportedBinaryVersion)
+
+	buf = encodeVarint(buf, uint32(len(order)))
+	for id, pubkey := range order {
+		raw, _ := decodePubkey(pubkey)
+		buf = append(buf, raw...)
+		buf = encodeVarint(buf, uint32(id))
+	}
+
+	for _, lists := range [][]encodedList{followLists, muteLists} {
+		buf = encodeVarint(buf, uint32(len(lists)))
+		for _, list := range lists {
+			buf = encodeVarint(buf, list.owner)
+			buf = encodeVarint(buf, list.createdAt)
+			buf = encodeVarint(buf, uint32(len(list.targets)))
+			for _, target := range list.targets {
+				buf = encodeVarint(buf, target)
+			}
+		}
+	}
+
+	return buf, nil
+}
+
+// ExportGraph walks the stored contact lists outward from root and serializes
+// everything within maxHops as a nostr-social-graph v2 binary. Follow lists are
+// included for every pubkey closer than maxHops, along with their mute lists.
+// The walk stops once maxPubkeys have been reached (DefaultMaxExportPubkeys when
+// zero), leaving follows of further pubkeys out of the lists already walked.
+// It returns the binary and the number of pubkeys reached.
+func ExportGraph(query ListQuery, root string, maxHops int, maxPubkeys int) ([]byte, int, error) {
+	root = strings.ToLower(strings.TrimSpace(root))
+	if maxHops <= 0 {
+		maxHops = DefaultMaxHops
+	}
+	if maxHops > MaxAllowedHops {
+		maxHops = MaxAllowedHops
+	}
+	if maxPubkeys <= 0 {
+		maxPubkeys = DefaultMaxExportPubkeys
+	}
+
+	reached := map[string]bool{root: true}
+	frontier := []string{root}
+	var listOwners []string
+	var follows []ListEntry
+
+	for distance := 0; distance < maxHops && len(frontier) > 0; distance++ {
+		lists, err := latestLists(query, ContactListKind, frontier)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		next := make([]string, 0)
+		truncated := false
+		for _, author := range frontier {
+			list, ok := lists[author]
+			if !ok {
+				continue
+			}
+			targets := make([]string, 0, len(list.Targets))
+			for _, target := range list.Targets {
+				if !reached[target] {
+					if len(reached) >= maxPubkeys {
+						truncated = true
+						continue
+					}
+					reached[target] = true
+					next = append(next, target)
+				}
+				targets = append(targets, target)
+			}
+			list.Targets = targets
+			follows = append(follows, list)
+			listOwners = append(listOwners, author)
+		}
+		if truncated {
+			break
+		}
+		frontier = next
+	}
+
+	muteLists, err := latestLists(query, MuteListKind, listOwners)
+	if err != nil {
+		return nil, 0, err
+	}
+	mutes := make([]ListEntry, 0, len(muteLists))
+	for _, owner := range listOwners {
+		if list, ok := muteLists[owner]; ok {
+			mutes = append(mutes, list)
+		}
+	}
+
+	data, err := SerializeBinary(root, follows, mutes)
+	if err != nil {
+		return nil, 0, err
+	}
+	return data, len(reached), nil
+}
+
+// latestLists queries lists of kind for authors in batches and keeps the newest per author
+func latestLists(query ListQuery, kind int, authors []string) (map[string]ListEntry, error) {
+	lists := make(map[string]ListEntry)
+	for start := 0; start < len(authors); start += exportQueryBatchSize {
+		end := start + exportQueryBatchSize
+		if end > len(authors) {
+			end = len(authors)
+		}
+
+		events, err := query(kind, authors[start:end])
+		if err != nil {
+			return nil, fmt.Errorf("wot: failed to query kind %d lists: %w", kind, err)
+		}
+
+		for _, event := range events {
+			author := strings.ToLower(event.PubKey)
+			if existing, ok := lists[author]; ok && existing.CreatedAt >= event.CreatedAt {
+				continue
+			}
+			entry := ListEntry{Owner: author, CreatedAt: event.CreatedAt}
+			seen := make(map[string]bool)
+			for _, tag := range event.Tags {
+				if len(tag) < 2 || tag[0] != "p" || len(tag[1]) != 64 {
+					continue
+				}
+				target := strings.ToLower(tag[1])
+				if !seen[target] {
+					seen[target] = true
+					entry.Targets = append(entry.Targets, target)
+				}
+			}
+			lists[author] = entry
+		}
+	}
+	return lists, nil
+}
+
+// decodePubkey decodes a hex-encoded 32-byte pubkey
+func decodePubkey(pubkey string) ([]byte, error) {
+	raw, err := hex.DecodeString(pubkey)
+	if err != nil {
+		return nil, err
+	}
+	if len(raw) != pubkeyByteLen {
+		return nil, fmt.Errorf("expected %d bytes, got %d", pubkeyByteLen, len(raw))
+	}
+	return raw, nil
+}
+
+// encodeVarint appends value to buf in the same 7-bit varint encoding decodeVarint reads.
+func encodeVarint(buf []byte, value uint32) []byte {
+	for value >= 0x80 {
+		buf = append(buf, byte(value)|0x80)
+		value >>= 7
+	}
+	return append(buf, byte(value))
+}
@@ -0,0 +1,109 @@
+package wot
+
+import (
+	"testing"
+
+	"github.com/nbd-wtf/go-nostr"
+)
+
+func TestExportGraphRoundTrip(t *testing.T) {
+	root, a, b, c, d := testPubkey("0"), testPubkey("a"), testPubkey("b"), testPubkey("c"), testPubkey("d")
+
+	stored := []*nostr.Event{
+		listEvent(ContactListKind, root, 1, a),
+		listEvent(ContactListKind, root, 2, a, b), // Newer list wins
+		listEvent(ContactListKind, a, 1, c),
+		listEvent(ContactListKind, c, 1, d), // c is at the hop limit, so this list is left out
+		listEvent(MuteListKind, root, 1, d),
+		{Kind: ContactListKind, PubKey: b, CreatedAt: 1, Tags: nostr.Tags{{"p", "not-a-pubkey"}}},
+	}
+
+	data, reached, err := ExportGraph(listQuery(stored), root, 2, 0)
+	if err != nil {
+		t.Fatalf("ExportGraph: %v", err)
+	}
+	if reached != 4 {
+		t.Errorf("expected 4 reached pubkeys, got %d", reached)
+	}
+
+	graph, err := ParseBinary(data)
+	if err != nil {
+		t.Fatalf("ParseBinary: %v", err)
+	}
+	if graph.pubkeyToID[root] != 0 {
+		t.Errorf("expected root to have ID 0, got %d", graph.pubkeyToID[root])
+	}
+
+	distances := graph.ComputeAllDistances(root, MaxAllowedHops)
+	expected := map[string]int{root: 0, a: 1, b: 1, c: 2}
+	for pubkey, want := range expected {
+		if got, ok := distances[pubkey]; !ok || got != want {
+			t.Errorf("distance(%s) = %d (%t), want %d", pubkey[:1], got, ok, want)
+		}
+	}
+	if _, ok := distances[d]; ok {
+		t.Error("expected d to be unreachable in the exported graph")
+	}
+
+	// The muted pubkey still needs an ID for the mute list
+	if _, ok := graph.pubkeyToID[d]; !ok {
+		t.Error("expected the muted pubkey in the ID table")
+	}
+}
+
+func TestExportGraphStopsAtPubkeyLimit(t *testing.T) {
+	root, a, b, c, d := testPubkey("0"), testPubkey("a"), testPubkey("b"), testPubkey("c"), testPubkey("d")
+
+	stored := []*nostr.Event{
+		listEvent(ContactListKind, root, 1, a, b, c),
+		listEvent(ContactListKind, a, 1, d),
+	}
+
+	data, reached, err := ExportGraph(listQuery(stored), root, 3, 3)
+	if err != nil {
+		t.Fatalf("ExportGraph: %v", err)
+	}
+	if reached != 3 {
+		t.Errorf("expected the walk to stop at 3 pubkeys, got %d", reached)
+	}
+
+	graph, err := ParseBinary(data)
+	if err != nil {
+		t.Fatalf("ParseBinary: %v", err)
+	}
+	if len(graph.idToPubkey) != 3 {
+		t.Errorf("expected 3 pubkeys in the binary, got %d", len(graph.idToPubkey))
+	}
+	for _, pubkey := range []string{c, d} {
+		if _, ok := graph.pubkeyToID[pubkey]; ok {
+			t.Errorf("expected %s past the limit to be left out", pubkey[:1])
+		}
+	}
+}
+
+// listQuery answers ExportGraph queries from stored
+func listQuery(stored []*nostr.Event) ListQuery {
+	return func(kind int, authors []string) ([]*nostr.Event, error) {
+		wanted := make(map[string]bool)
+		for _, author := range authors {
+			wanted[author] = true
+		}
+		var events []*nostr.Event
+		for _, event := range stored {
+			if event.Kind == kind && wanted[event.PubKey] {
+				events = append(events, event)
+			}
+		}
+		return events, nil
+	}
+}
+
+func TestVarintRoundTrip(t *testing.T) {
+	for _, value := range []uint32{0, 1, 127, 128, 300, 1 << 21, 1<<32 - 1} {
+		buf := encodeVarint(nil, value)
+		decoded, n, err := decodeVarint(buf, 0)
+		if err != nil || n != len(buf) || decoded != value {
+			t.Errorf("varint %d decoded as %d (%d bytes, %v)", value, decoded, n, err)
+		}
+	}
+}
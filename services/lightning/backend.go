@@ -0,0 +1,56 @@
+package lightning
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/HORNET-Storage/hornet-storage/lib/types"
+)
+
+// Supported backend names
+const (
+	BackendLND    = "lnd"
+	BackendLNbits = "lnbits"
+	BackendNWC    = "nwc"
+	BackendMock   = "mock"
+)
+
+// Invoice is a BOLT11 invoice issued by a backend
+type Invoice struct {
+	PaymentHash string
+	Bolt11      string
+	AmountSats  int64
+	ExpiresAt   time.Time
+}
+
+// InvoiceStatus is the settlement state of an invoice as reported by a backend
+type InvoiceStatus struct {
+	Settled        bool
+	AmountPaidSats int64
+	SettledAt      time.Time
+}
+
+// Backend issues and looks up Lightning invoices on a node or wallet
+type Backend interface {
+	Name() string
+	CreateInvoice(ctx context.Context, amountSats int64, memo string, expiry time.Duration) (*Invoice, error)
+	LookupInvoice(ctx context.Context, paymentHash string) (*InvoiceStatus, error)
+}
+
+// NewBackend creates the backend selected in the Lightning configuration
+func NewBackend(cfg *types.LightningConfig) (Backend, error) {
+	switch strings.ToLower(cfg.Backend) {
+	case BackendLND:
+		return NewLNDBackend(&cfg.LND)
+	case BackendLNbits:
+		return NewLNbitsBackend(&cfg.LNbits)
+	case BackendNWC:
+		return NewNWCBackend(&cfg.NWC)
+	case BackendMock:
+		return NewMockBackend(), nil
+	default:
+		return nil, fmt.Errorf("unknown lightning backend: %s", cfg.Backend)
+	}
+}
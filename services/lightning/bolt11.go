@@ -0,0 +1,122 @@
+package lightning
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/btcsuite/btcd/btcutil/bech32"
+)
+
+// BOLT11 tagged field types (the bech32 value of the field's letter)
+const (
+	bolt11FieldPaymentHash     = 1  // p
+	bolt11FieldDescriptionHash = 23 // h
+)
+
+// bolt11 signature is 520 bits, i.e. 104 five-bit groups at the end of the data part
+const bolt11SignatureGroups = 104
+
+// Bolt11 holds the parts of a BOLT11 payment request needed to credit a payment
+type Bolt11 struct {
+	AmountMsat      int64
+	PaymentHash     string
+	DescriptionHash string
+}
+
+// DecodeBolt11 extracts the amount, payment hash and description hash from a BOLT11 invoice.
+// The invoice signature is not verified; callers should only trust invoices from a trusted source.
+func DecodeBolt11(invoice string) (*Bolt11, error) {
+	hrp, data, err := bech32.DecodeNoLimit(strings.ToLower(invoice))
+	if err != nil {
+		return nil, fmt.Errorf("invalid bolt11 encoding: %w", err)
+	}
+	if !strings.HasPrefix(hrp, "ln") {
+		return nil, fmt.Errorf("invalid bolt11 prefix %q", hrp)
+	}
+
+	amountMsat, err := parseBolt11Amount(hrp[2:])
+	if err != nil {
+		return nil, err
+	}
+
+	// 35-bit timestamp followed by tagged fields, then the signature
+	if len(data) < 7+bolt11SignatureGroups {
+		return nil, fmt.Errorf("bolt11 invoice too short")
+	}
+	fields := data[7 : len(data)-bolt11SignatureGroups]
+
+	decoded := &Bolt11{AmountMsat: amountMsat}
+	for len(fields) >= 3 {
+		fieldType := fields[0]
+		length := int(fields[1])<<5 | int(fields[2])
+		if len(fields) < 3+length {
+			return nil, fmt.Errorf("truncated bolt11 field")
+		}
+		value := fields[3 : 3+length]
+		fields = fields[3+length:]
+
+		// Hash fields are always 52 groups; other lengths must be skipped per BOLT11
+		if length != 52 || (fieldType != bolt11FieldPaymentHash && fieldType != bolt11FieldDescriptionHash) {
+			continue
+		}
+		hash, err := bech32.ConvertBits(value, 5, 8, false)
+		if err != nil || len(hash) != 32 {
+			return nil, fmt.Errorf("invalid bolt11 hash field")
+		}
+		if fieldType == bolt11FieldPaymentHash {
+			decoded.PaymentHash = hex.EncodeToString(hash)
+		} else {
+			decoded.DescriptionHash = hex.EncodeToString(hash)
+		}
+	}
+
+	if decoded.PaymentHash == "" {
+		return nil, fmt.Errorf("bolt11 invoice has no payment hash")
+	}
+
+	return decoded, nil
+}
+
+// parseBolt11Amount parses the currency and amount part of the human readable prefix, e.g. "bc2500u"
+func parseBolt11Amount(value string) (int64, error) {
+	// Skip the currency prefix (bc, tb, tbs, bcrt, ...)
+	start := strings.IndexAny(value, "0123456789")
+	if start < 0 {
+		return 0, nil // Amount is optional
+	}
+	amount := value[start:]
+
+	multiplier := amount[len(amount)-1]
+	digits := amount
+	if multiplier >= 'a' && multiplier <= 'z' {
+		digits = amount[:len(amount)-1]
+	} else {
+		multiplier = 0
+	}
+
+	n, err := strconv.ParseInt(digits, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid bolt11 amount %q", amount)
+	}
+
+	// Amounts are denominated in BTC; 1 BTC = 100,000,000,000 msat
+	switch multiplier {
+	case 0:
+		return n * 100_000_000_000, nil
+	case 'm':
+		return n * 100_000_000, nil
+	case 'u':
+		return n * 100_000, nil
+	case 'n':
+		return n * 100, nil
+	case 'p':
+		if n%10 != 0 {
+			return 0, fmt.Errorf("invalid sub-millisatoshi bolt11 amount %q", amount)
+		}
+		return n / 10, nil
+	default:
+		return 0, fmt.Errorf("invalid bolt11 amount multiplier %q", string(multiplier))
+	}
+}
@@ -0,0 +1,128 @@
+package lightning
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/HORNET-Storage/hornet-storage/lib/types"
+)
+
+// LNbitsBackend issues invoices through an LNbits wallet
+type LNbitsBackend struct {
+	url    string
+	apiKey string
+	client *http.Client
+}
+
+type lnbitsCreateInvoiceResponse struct {
+	PaymentHash    string `json:"payment_hash"`
+	PaymentRequest string `json:"payment_request"`
+	Bolt11         string `json:"bolt11"` // Newer LNbits versions use this name
+}
+
+type lnbitsPaymentResponse struct {
+	Paid    bool `json:"paid"`
+	Details struct {
+		Amount int64 `json:"amount"` // msats
+	} `json:"details"`
+}
+
+// NewLNbitsBackend creates an LNbits backend
+func NewLNbitsBackend(cfg *types.LNbitsConfig) (*LNbitsBackend, error) {
+	if cfg.URL == "" || cfg.APIKey == "" {
+		return nil, fmt.Errorf("lnbits url and api key must be configured")
+	}
+
+	return &LNbitsBackend{
+		url:    strings.TrimRight(cfg.URL, "/"),
+		apiKey: cfg.APIKey,
+		client: &http.Client{Timeout: 30 * time.Second},
+	}, nil
+}
+
+// Name returns the backend name
+func (b *LNbitsBackend) Name() string {
+	return BackendLNbits
+}
+
+// CreateInvoice creates an incoming payment on the wallet
+func (b *LNbitsBackend) CreateInvoice(ctx context.Context, amountSats int64, memo string, expiry time.Duration) (*Invoice, error) {
+	body := map[string]interface{}{
+		"out":    false,
+		"amount": amountSats,
+		"memo":   memo,
+		"expiry": int64(expiry.Seconds()),
+	}
+
+	var response lnbitsCreateInvoiceResponse
+	if err := b.do(ctx, http.MethodPost, "/api/v1/payments", body, &response); err != nil {
+		return nil, err
+	}
+
+	bolt11 := response.PaymentRequest
+	if bolt11 == "" {
+		bolt11 = response.Bolt11
+	}
+
+	return &Invoice{
+		PaymentHash: response.PaymentHash,
+		Bolt11:      bolt11,
+		AmountSats:  amountSats,
+		ExpiresAt:   time.Now().Add(expiry),
+	}, nil
+}
+
+// LookupInvoice returns the state of a payment by payment hash
+func (b *LNbitsBackend) LookupInvoice(ctx context.Context, paymentHash string) (*InvoiceStatus, error) {
+	var response lnbitsPaymentResponse
+	if err := b.do(ctx, http.MethodGet, "/api/v1/payments/"+paymentHash, nil, &response); err != nil {
+		return nil, err
+	}
+
+	status := &InvoiceStatus{
+		Settled: response.Paid,
+	}
+	if status.Settled {
+		status.AmountPaidSats = response.Details.Amount / 1000
+		status.SettledAt = time.Now()
+	}
+
+	return status, nil
+}
+
+func (b *LNbitsBackend) do(ctx context.Context, method, path string, body interface{}, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, b.url+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Api-Key", b.apiKey)
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("lnbits request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("lnbits returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(message)))
+	}
+
+	return json.NewDecoder(resp.Body).Decode(out)
+}
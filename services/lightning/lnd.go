@@ -0,0 +1,161 @@
+package lightning
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/HORNET-Storage/hornet-storage/lib/types"
+)
+
+// LNDBackend issues invoices through the LND REST API
+type LNDBackend struct {
+	url      string
+	macaroon string
+	client   *http.Client
+}
+
+// lndAddInvoiceResponse is the subset of the LND AddInvoice response we use.
+// LND encodes 64-bit integers as strings and bytes as base64.
+type lndAddInvoiceResponse struct {
+	RHash          string `json:"r_hash"`
+	PaymentRequest string `json:"payment_request"`
+}
+
+// lndInvoice is the subset of the LND Invoice message we use
+type lndInvoice struct {
+	State      string `json:"state"`
+	AmtPaidSat string `json:"amt_paid_sat"`
+	SettleDate string `json:"settle_date"`
+}
+
+// NewLNDBackend creates an LND REST backend
+func NewLNDBackend(cfg *types.LNDConfig) (*LNDBackend, error) {
+	if cfg.URL == "" {
+		return nil, fmt.Errorf("lnd url is not configured")
+	}
+
+	backend := &LNDBackend{
+		url:    strings.TrimRight(cfg.URL, "/"),
+		client: &http.Client{Timeout: 30 * time.Second},
+	}
+
+	if cfg.MacaroonPath != "" {
+		macaroon, err := os.ReadFile(cfg.MacaroonPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read lnd macaroon: %w", err)
+		}
+		backend.macaroon = hex.EncodeToString(macaroon)
+	}
+
+	if cfg.TLSCertPath != "" {
+		cert, err := os.ReadFile(cfg.TLSCertPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read lnd tls certificate: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(cert) {
+			return nil, fmt.Errorf("invalid lnd tls certificate")
+		}
+		backend.client.Transport = &http.Transport{
+			TLSClientConfig: &tls.Config{RootCAs: pool},
+		}
+	}
+
+	return backend, nil
+}
+
+// Name returns the backend name
+func (b *LNDBackend) Name() string {
+	return BackendLND
+}
+
+// CreateInvoice adds a new invoice to the node
+func (b *LNDBackend) CreateInvoice(ctx context.Context, amountSats int64, memo string, expiry time.Duration) (*Invoice, error) {
+	body := map[string]interface{}{
+		"value":  strconv.FormatInt(amountSats, 10),
+		"memo":   memo,
+		"expiry": strconv.FormatInt(int64(expiry.Seconds()), 10),
+	}
+
+	var response lndAddInvoiceResponse
+	if err := b.do(ctx, http.MethodPost, "/v1/invoices", body, &response); err != nil {
+		return nil, err
+	}
+
+	paymentHash, err := base64.StdEncoding.DecodeString(response.RHash)
+	if err != nil {
+		return nil, fmt.Errorf("invalid r_hash in lnd response: %w", err)
+	}
+
+	return &Invoice{
+		PaymentHash: hex.EncodeToString(paymentHash),
+		Bolt11:      response.PaymentRequest,
+		AmountSats:  amountSats,
+		ExpiresAt:   time.Now().Add(expiry),
+	}, nil
+}
+
+// LookupInvoice returns the state of an invoice by payment hash
+func (b *LNDBackend) LookupInvoice(ctx context.Context, paymentHash string) (*InvoiceStatus, error) {
+	var invoice lndInvoice
+	if err := b.do(ctx, http.MethodGet, "/v1/invoice/"+paymentHash, nil, &invoice); err != nil {
+		return nil, err
+	}
+
+	status := &InvoiceStatus{
+		Settled: invoice.State == "SETTLED",
+	}
+	if status.Settled {
+		status.AmountPaidSats, _ = strconv.ParseInt(invoice.AmtPaidSat, 10, 64)
+		if settleDate, err := strconv.ParseInt(invoice.SettleDate, 10, 64); err == nil && settleDate > 0 {
+			status.SettledAt = time.Unix(settleDate, 0)
+		}
+	}
+
+	return status, nil
+}
+
+func (b *LNDBackend) do(ctx context.Context, method, path string, body interface{}, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, b.url+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if b.macaroon != "" {
+		req.Header.Set("Grpc-Metadata-macaroon", b.macaroon)
+	}
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("lnd request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("lnd returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(message)))
+	}
+
+	return json.NewDecoder(resp.Body).Decode(out)
+}
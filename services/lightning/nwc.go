@@ -0,0 +1,214 @@
+package lightning
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/nbd-wtf/go-nostr/nip04"
+
+	"github.com/HORNET-Storage/hornet-storage/lib/types"
+)
+
+// NIP-47 event kinds
+const (
+	nwcRequestKind  = 23194
+	nwcResponseKind = 23195
+)
+
+// NWCBackend issues invoices through a Nostr Wallet Connect (NIP-47) wallet
+type NWCBackend struct {
+	walletPubkey string
+	relayURL     string
+	secret       string
+	clientPubkey string
+	sharedSecret []byte
+	timeout      time.Duration
+}
+
+type nwcRequest struct {
+	Method string                 `json:"method"`
+	Params map[string]interface{} `json:"params"`
+}
+
+type nwcResponse struct {
+	ResultType string `json:"result_type"`
+	Error      *struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+	Result json.RawMessage `json:"result"`
+}
+
+type nwcTransaction struct {
+	Invoice     string `json:"invoice"`
+	PaymentHash string `json:"payment_hash"`
+	Amount      int64  `json:"amount"` // msats
+	State       string `json:"state"`
+	ExpiresAt   int64  `json:"expires_at"`
+	SettledAt   int64  `json:"settled_at"`
+}
+
+// NewNWCBackend creates a NIP-47 backend from a nostr+walletconnect:// connection URI
+func NewNWCBackend(cfg *types.NWCConfig) (*NWCBackend, error) {
+	uri, err := url.Parse(cfg.ConnectionURI)
+	if err != nil || uri.Scheme != "nostr+walletconnect" {
+		return nil, fmt.Errorf("invalid nwc connection uri")
+	}
+
+	walletPubkey := uri.Host
+	if walletPubkey == "" {
+		walletPubkey = strings.TrimPrefix(uri.Opaque, "//")
+	}
+	relayURL := uri.Query().Get("relay")
+	secret := uri.Query().Get("secret")
+	if !nostr.IsValidPublicKey(walletPubkey) || relayURL == "" || secret == "" {
+		return nil, fmt.Errorf("nwc connection uri must include wallet pubkey, relay and secret")
+	}
+
+	clientPubkey, err := nostr.GetPublicKey(secret)
+	if err != nil {
+		return nil, fmt.Errorf("invalid nwc secret: %w", err)
+	}
+
+	sharedSecret, err := nip04.ComputeSharedSecret(walletPubkey, secret)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute nwc shared secret: %w", err)
+	}
+
+	return &NWCBackend{
+		walletPubkey: walletPubkey,
+		relayURL:     relayURL,
+		secret:       secret,
+		clientPubkey: clientPubkey,
+		sharedSecret: sharedSecret,
+		timeout:      30 * time.Second,
+	}, nil
+}
+
+// Name returns the backend name
+func (b *NWCBackend) Name() string {
+	return BackendNWC
+}
+
+// CreateInvoice requests a new invoice from the wallet with make_invoice
+func (b *NWCBackend) CreateInvoice(ctx context.Context, amountSats int64, memo string, expiry time.Duration) (*Invoice, error) {
+	var tx nwcTransaction
+	err := b.call(ctx, "make_invoice", map[string]interface{}{
+		"amount":      amountSats * 1000,
+		"description": memo,
+		"expiry":      int64(expiry.Seconds()),
+	}, &tx)
+	if err != nil {
+		return nil, err
+	}
+
+	expiresAt := time.Now().Add(expiry)
+	if tx.ExpiresAt > 0 {
+		expiresAt = time.Unix(tx.ExpiresAt, 0)
+	}
+
+	return &Invoice{
+		PaymentHash: tx.PaymentHash,
+		Bolt11:      tx.Invoice,
+		AmountSats:  amountSats,
+		ExpiresAt:   expiresAt,
+	}, nil
+}
+
+// LookupInvoice queries the wallet for an invoice with lookup_invoice
+func (b *NWCBackend) LookupInvoice(ctx context.Context, paymentHash string) (*InvoiceStatus, error) {
+	var tx nwcTransaction
+	err := b.call(ctx, "lookup_invoice", map[string]interface{}{
+		"payment_hash": paymentHash,
+	}, &tx)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &InvoiceStatus{
+		Settled: tx.SettledAt > 0 || tx.State == "settled",
+	}
+	if status.Settled {
+		status.AmountPaidSats = tx.Amount / 1000
+		status.SettledAt = time.Now()
+		if tx.SettledAt > 0 {
+			status.SettledAt = time.Unix(tx.SettledAt, 0)
+		}
+	}
+
+	return status, nil
+}
+
+// call sends an encrypted NIP-47 request and waits for the wallet's response
+func (b *NWCBackend) call(ctx context.Context, method string, params map[string]interface{}, out interface{}) error {
+	ctx, cancel := context.WithTimeout(ctx, b.timeout)
+	defer cancel()
+
+	payload, err := json.Marshal(nwcRequest{Method: method, Params: params})
+	if err != nil {
+		return err
+	}
+	content, err := nip04.Encrypt(string(payload), b.sharedSecret)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt nwc request: %w", err)
+	}
+
+	request := nostr.Event{
+		PubKey:    b.clientPubkey,
+		CreatedAt: nostr.Now(),
+		Kind:      nwcRequestKind,
+		Tags:      nostr.Tags{{"p", b.walletPubkey}},
+		Content:   content,
+	}
+	if err := request.Sign(b.secret); err != nil {
+		return fmt.Errorf("failed to sign nwc request: %w", err)
+	}
+
+	relay, err := nostr.RelayConnect(ctx, b.relayURL)
+	if err != nil {
+		return fmt.Errorf("failed to connect to nwc relay: %w", err)
+	}
+	defer relay.Close()
+
+	// Subscribe before publishing so the response cannot be missed
+	sub, err := relay.Subscribe(ctx, nostr.Filters{{
+		Kinds:   []int{nwcResponseKind},
+		Authors: []string{b.walletPubkey},
+		Tags:    nostr.TagMap{"e": []string{request.ID}},
+	}})
+	if err != nil {
+		return fmt.Errorf("failed to subscribe to nwc responses: %w", err)
+	}
+	defer sub.Unsub()
+
+	if err := relay.Publish(ctx, request); err != nil {
+		return fmt.Errorf("failed to publish nwc request: %w", err)
+	}
+
+	select {
+	case event := <-sub.Events:
+		if event == nil {
+			return fmt.Errorf("nwc subscription closed")
+		}
+		plaintext, err := nip04.Decrypt(event.Content, b.sharedSecret)
+		if err != nil {
+			return fmt.Errorf("failed to decrypt nwc response: %w", err)
+		}
+
+		var response nwcResponse
+		if err := json.Unmarshal([]byte(plaintext), &response); err != nil {
+			return fmt.Errorf("invalid nwc response: %w", err)
+		}
+		if response.Error != nil {
+			return fmt.Errorf("nwc %s failed: %s (%s)", method, response.Error.Message, response.Error.Code)
+		}
+		return json.Unmarshal(response.Result, out)
+	case <-ctx.Done():
+		return fmt.Errorf("timed out waiting for nwc %s response", method)
+	}
+}
@@ -0,0 +1,467 @@
+package lightning
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/nbd-wtf/go-nostr/nip19"
+
+	"github.com/HORNET-Storage/hornet-storage/lib/config"
+	"github.com/HORNET-Storage/hornet-storage/lib/logging"
+	"github.com/HORNET-Storage/hornet-storage/lib/stores"
+	"github.com/HORNET-Storage/hornet-storage/lib/stores/statistics"
+	"github.com/HORNET-Storage/hornet-storage/lib/types"
+)
+
+const (
+	DefaultInvoiceExpiry = time.Hour
+	DefaultPollInterval  = 10 * time.Second
+
+	// expiryGrace keeps an invoice pending briefly after expiry so a payment
+	// that lands right at the deadline is still picked up by the next poll
+	expiryGrace = time.Minute
+)
+
+// PaymentProcessor credits a settled payment to a subscriber.
+// It is satisfied by *subscription.SubscriptionManager.
+type PaymentProcessor interface {
+	ProcessPayment(npub string, transactionID string, amountSats int64) error
+}
+
+// SubscriptionRefresher re-publishes a subscriber's kind 11888 event
+type SubscriptionRefresher interface {
+	RefreshSubscriptionEvent(npub string) error
+}
+
+// Service issues Lightning invoices for subscription tiers and credits them once settled
+type Service struct {
+	store        stores.Store
+	backend      Backend
+	processor    PaymentProcessor
+	expiry       time.Duration
+	pollInterval time.Duration
+	ctx          context.Context
+	cancel       context.CancelFunc
+	wg           sync.WaitGroup
+	settleMutex  sync.Mutex // serializes settlement so polling and on-demand checks don't race
+}
+
+// NewService creates a Lightning payment service using the given backend
+func NewService(store stores.Store, backend Backend, processor PaymentProcessor, cfg *types.LightningConfig) *Service {
+	expiry := time.Duration(cfg.InvoiceExpirySeconds) * time.Second
+	if expiry <= 0 {
+		expiry = DefaultInvoiceExpiry
+	}
+	pollInterval := time.Duration(cfg.PollIntervalSeconds) * time.Second
+	if pollInterval <= 0 {
+		pollInterval = DefaultPollInterval
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &Service{
+		store:        store,
+		backend:      backend,
+		processor:    processor,
+		expiry:       expiry,
+		pollInterval: pollInterval,
+		ctx:          ctx,
+		cancel:       cancel,
+	}
+}
+
+// Backend returns the backend used to issue invoices
+func (s *Service) Backend() Backend {
+	return s.backend
+}
+
+// CreateSubscriptionInvoice issues an invoice for the named paid tier on behalf of a subscriber
+func (s *Service) CreateSubscriptionInvoice(pubkey string, tierName string) (*types.LightningInvoice, error) {
+	hexKey, err := normalizePubkey(pubkey)
+	if err != nil {
+		return nil, err
+	}
+
+	tier, err := findPaidTier(tierName)
+	if err != nil {
+		return nil, err
+	}
+
+	memo := fmt.Sprintf("Relay subscription: %s", tier.Name)
+	if cfg, err := config.GetConfig(); err == nil && cfg.Relay.Name != "" {
+		memo = fmt.Sprintf("%s subscription: %s", cfg.Relay.Name, tier.Name)
+	}
+
+	issued, err := s.backend.CreateInvoice(s.ctx, int64(tier.PriceSats), memo, s.expiry)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create invoice: %w", err)
+	}
+
+	invoice := &types.LightningInvoice{
+		PaymentHash: issued.PaymentHash,
+		Bolt11:      issued.Bolt11,
+		Npub:        hexKey,
+		Tier:        tier.Name,
+		AmountSats:  issued.AmountSats,
+		Status:      types.LightningInvoicePending,
+		Backend:     s.backend.Name(),
+		ExpiresAt:   issued.ExpiresAt,
+	}
+	if err := s.store.GetStatsStore().CreateLightningInvoice(invoice); err != nil {
+		return nil, fmt.Errorf("failed to save invoice: %w", err)
+	}
+
+	logging.Infof("Issued lightning invoice %s for %s (%s, %d sats)", invoice.PaymentHash, hexKey, tier.Name, tier.PriceSats)
+
+	// Advertise the invoice on the subscriber's kind 11888 event
+	s.refreshSubscriptionEvent(hexKey)
+
+	return invoice, nil
+}
+
+// CheckInvoice looks up an invoice with the backend and credits it if it has settled
+func (s *Service) CheckInvoice(paymentHash string) (*types.LightningInvoice, error) {
+	statsStore := s.store.GetStatsStore()
+
+	invoice, err := statsStore.GetLightningInvoice(paymentHash)
+	if err != nil {
+		return nil, err
+	}
+	if invoice == nil {
+		return nil, fmt.Errorf("invoice not found")
+	}
+	if invoice.Status != types.LightningInvoicePending {
+		return invoice, nil
+	}
+
+	status, err := s.backend.LookupInvoice(s.ctx, paymentHash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up invoice: %w", err)
+	}
+	if status.Settled {
+		if err := s.settle(invoice, status); err != nil {
+			return nil, err
+		}
+	}
+
+	return statsStore.GetLightningInvoice(paymentHash)
+}
+
+// settle credits a settled invoice exactly once. The invoice is claimed by moving it to
+// crediting before it is credited, so a failed update can never credit it twice; the claim
+// is released if crediting fails so the next poll retries it, and an invoice left claimed
+// by a crash is credited by the startup sweep.
+func (s *Service) settle(invoice *types.LightningInvoice, status *InvoiceStatus) error {
+	s.settleMutex.Lock()
+	defer s.settleMutex.Unlock()
+
+	if s.processor == nil {
+		return fmt.Errorf("no payment processor available for invoice %s", invoice.PaymentHash)
+	}
+
+	statsStore := s.store.GetStatsStore()
+
+	// Re-read under the lock in case another check already credited this invoice
+	current, err := statsStore.GetLightningInvoice(invoice.PaymentHash)
+	if err != nil {
+		return err
+	}
+	if current == nil || current.Status != types.LightningInvoicePending {
+		return nil
+	}
+
+	if status.AmountPaidSats > 0 {
+		current.AmountSats = status.AmountPaidSats
+	}
+
+	settledAt := status.SettledAt
+	if settledAt.IsZero() {
+		settledAt = time.Now()
+	}
+	claimed, err := statsStore.ClaimLightningInvoice(current.PaymentHash, current.AmountSats, settledAt)
+	if err != nil {
+		return fmt.Errorf("failed to claim invoice: %w", err)
+	}
+	if !claimed {
+		return nil
+	}
+
+	logging.Infof("Lightning invoice %s settled for %s (%d sats)", current.PaymentHash, current.Npub, current.AmountSats)
+
+	if err := creditInvoice(statsStore, s.processor, current); err != nil {
+		if releaseErr := statsStore.ReleaseLightningInvoice(current.PaymentHash); releaseErr != nil {
+			logging.Infof("Warning: failed to release lightning invoice %s: %v", current.PaymentHash, releaseErr)
+		}
+		return err
+	}
+
+	return nil
+}
+
+// resumeCrediting credits invoices that were claimed but never marked settled, which only
+// happens when the relay stopped part way through crediting them. One that had already been
+// credited when the relay stopped is credited again, since that is preferable to losing a payment.
+func (s *Service) resumeCrediting() {
+	s.settleMutex.Lock()
+	defer s.settleMutex.Unlock()
+
+	if s.processor == nil {
+		return
+	}
+
+	statsStore := s.store.GetStatsStore()
+	invoices, err := statsStore.GetCreditingLightningInvoices()
+	if err != nil {
+		logging.Infof("Error loading uncredited lightning invoices: %v", err)
+		return
+	}
+
+	for i := range invoices {
+		invoice := &invoices[i]
+		logging.Infof("Resuming credit for lightning invoice %s (%s, %d sats)", invoice.PaymentHash, invoice.Npub, invoice.AmountSats)
+		// Failures stay claimed and are retried on the next start
+		if err := creditInvoice(statsStore, s.processor, invoice); err != nil {
+			logging.Infof("Error crediting lightning invoice %s: %v", invoice.PaymentHash, err)
+		}
+	}
+}
+
+// creditInvoice credits a claimed invoice to its subscriber and marks it settled
+func creditInvoice(statsStore statistics.StatisticsStore, processor PaymentProcessor, invoice *types.LightningInvoice) error {
+	paidSubscriber, _ := statsStore.GetPaidSubscriberByNpub(invoice.Npub)
+	isNewSubscriber := paidSubscriber == nil
+
+	if err := processor.ProcessPayment(invoice.Npub, invoice.PaymentHash, invoice.AmountSats); err != nil {
+		return fmt.Errorf("failed to process lightning payment: %w", err)
+	}
+
+	// The payment is credited either way, so a failure here must not release the claim
+	if err := statsStore.MarkLightningInvoiceSettled(invoice.PaymentHash); err != nil {
+		logging.Infof("Warning: lightning invoice %s was credited but not marked settled: %v", invoice.PaymentHash, err)
+	}
+
+	createPaymentNotification(statsStore, invoice.Npub, invoice.PaymentHash, invoice.AmountSats, invoice.Tier, isNewSubscriber)
+
+	return nil
+}
+
+// createPaymentNotification records a payment notification using the subscriber's tier after crediting
+func createPaymentNotification(statsStore statistics.StatisticsStore, npub string, txID string, amount int64, tier string, isNewSubscriber bool) {
+	notification := &types.PaymentNotification{
+		PubKey:           npub,
+		TxID:             txID,
+		Amount:           amount,
+		SubscriptionTier: tier,
+		IsNewSubscriber:  isNewSubscriber,
+		ExpirationDate:   time.Now().AddDate(0, 1, 0),
+	}
+	if paidSubscriber, err := statsStore.GetPaidSubscriberByNpub(npub); err == nil && paidSubscriber != nil {
+		notification.SubscriptionTier = paidSubscriber.Tier
+		notification.ExpirationDate = paidSubscriber.ExpirationDate
+	}
+	if err := statsStore.CreatePaymentNotification(notification); err != nil {
+		logging.Infof("Warning: failed to create payment notification: %v", err)
+	}
+}
+
+// Start credits invoices interrupted by the last shutdown and begins polling pending
+// invoices for settlement
+func (s *Service) Start() {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+
+		s.resumeCrediting()
+
+		ticker := time.NewTicker(s.pollInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-s.ctx.Done():
+				return
+			case <-ticker.C:
+				s.pollPendingInvoices()
+			}
+		}
+	}()
+
+	logging.Infof("Lightning payment service started (backend: %s, poll interval: %s)", s.backend.Name(), s.pollInterval)
+}
+
+// Stop stops polling and waits for the poller to exit
+func (s *Service) Stop() {
+	s.cancel()
+	s.wg.Wait()
+	logging.Infof("Lightning payment service stopped")
+}
+
+// pollPendingInvoices checks every pending invoice and expires the ones that can no longer be paid
+func (s *Service) pollPendingInvoices() {
+	statsStore := s.store.GetStatsStore()
+
+	invoices, err := statsStore.GetPendingLightningInvoices()
+	if err != nil {
+		logging.Infof("Error loading pending lightning invoices: %v", err)
+		return
+	}
+
+	for i := range invoices {
+		if s.ctx.Err() != nil {
+			return
+		}
+
+		invoice := &invoices[i]
+		status, err := s.backend.LookupInvoice(s.ctx, invoice.PaymentHash)
+		if err != nil {
+			logging.Debugf("Error looking up lightning invoice %s: %v", invoice.PaymentHash, err)
+			continue
+		}
+		if status.Settled {
+			if err := s.settle(invoice, status); err != nil {
+				logging.Infof("Error settling lightning invoice %s: %v", invoice.PaymentHash, err)
+			}
+		}
+	}
+
+	expired, err := statsStore.ExpireLightningInvoices(time.Now().Add(-expiryGrace))
+	if err != nil {
+		logging.Infof("Error expiring lightning invoices: %v", err)
+	} else if expired > 0 {
+		logging.Infof("Expired %d unpaid lightning invoices", expired)
+	}
+}
+
+func (s *Service) refreshSubscriptionEvent(npub string) {
+	refresher, ok := s.processor.(SubscriptionRefresher)
+	if !ok {
+		return
+	}
+	if err := refresher.RefreshSubscriptionEvent(npub); err != nil {
+		logging.Debugf("Could not refresh kind 11888 event for %s: %v", npub, err)
+	}
+}
+
+// findPaidTier returns the configured paid tier with the given name
+func findPaidTier(name string) (*types.SubscriptionTier, error) {
+	settings, err := config.GetAllowedUsersSettings()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load subscription tiers: %w", err)
+	}
+
+	for i := range settings.Tiers {
+		tier := settings.Tiers[i]
+		if tier.Name != name {
+			continue
+		}
+		if tier.PriceSats <= 0 {
+			return nil, fmt.Errorf("tier %s is free", name)
+		}
+		return &tier, nil
+	}
+
+	return nil, fmt.Errorf("tier %s not found", name)
+}
+
+// normalizePubkey accepts an npub or hex pubkey and returns the hex form
+func normalizePubkey(pubkey string) (string, error) {
+	if prefix, value, err := nip19.Decode(pubkey); err == nil && prefix == "npub" {
+		return value.(string), nil
+	}
+	if nostr.IsValidPublicKey(pubkey) {
+		return pubkey, nil
+	}
+	return "", fmt.Errorf("invalid pubkey")
+}
+
+// Global service instance
+var globalService *Service
+var serviceMutex sync.RWMutex
+
+// InitGlobalService initializes the global Lightning service from configuration.
+// Does nothing when Lightning payments are disabled.
+func InitGlobalService(store stores.Store, processor PaymentProcessor) error {
+	serviceMutex.Lock()
+	defer serviceMutex.Unlock()
+
+	if globalService != nil {
+		return fmt.Errorf("lightning service already initialized")
+	}
+
+	service, err := newServiceFromConfig(store, processor)
+	if err != nil {
+		return err
+	}
+	if service != nil {
+		service.Start()
+	}
+
+	globalService = service
+	return nil
+}
+
+// GetGlobalService returns the global Lightning service, or nil if Lightning is disabled
+func GetGlobalService() *Service {
+	serviceMutex.RLock()
+	defer serviceMutex.RUnlock()
+	return globalService
+}
+
+// StopGlobalService stops the global Lightning service
+func StopGlobalService() {
+	serviceMutex.Lock()
+	defer serviceMutex.Unlock()
+
+	if globalService != nil {
+		globalService.Stop()
+		globalService = nil
+	}
+}
+
+// ReloadGlobalService restarts the global Lightning service with updated configuration
+func ReloadGlobalService(store stores.Store, processor PaymentProcessor) error {
+	serviceMutex.Lock()
+	defer serviceMutex.Unlock()
+
+	if globalService != nil {
+		logging.Infof("Stopping existing lightning service for reload...")
+		globalService.Stop()
+		globalService = nil
+	}
+
+	service, err := newServiceFromConfig(store, processor)
+	if err != nil {
+		return fmt.Errorf("failed to create new lightning service: %w", err)
+	}
+	if service != nil {
+		service.Start()
+		logging.Infof("Lightning service reloaded successfully")
+	} else {
+		logging.Infof("Lightning payments are disabled in configuration")
+	}
+
+	globalService = service
+	return nil
+}
+
+func newServiceFromConfig(store stores.Store, processor PaymentProcessor) (*Service, error) {
+	cfg, err := config.GetConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get config: %w", err)
+	}
+
+	if !cfg.Lightning.Enabled {
+		logging.Infof("Lightning payments are disabled")
+		return nil, nil
+	}
+
+	backend, err := NewBackend(&cfg.Lightning)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize lightning backend: %w", err)
+	}
+
+	return NewService(store, backend, processor, &cfg.Lightning), nil
+}
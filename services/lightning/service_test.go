@@ -0,0 +1,208 @@
+package lightning
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/spf13/viper"
+
+	"github.com/HORNET-Storage/hornet-storage/lib/config"
+	"github.com/HORNET-Storage/hornet-storage/lib/stores/badgerhold"
+	"github.com/HORNET-Storage/hornet-storage/lib/types"
+)
+
+type recordingProcessor struct {
+	calls []string
+	err   error // Returned instead of crediting when set
+}
+
+func (p *recordingProcessor) ProcessPayment(npub string, transactionID string, amountSats int64) error {
+	if p.err != nil {
+		return p.err
+	}
+	p.calls = append(p.calls, transactionID)
+	return nil
+}
+
+func setupService(t *testing.T) (*Service, *MockBackend, *recordingProcessor, *badgerhold.BadgerholdStore) {
+	t.Helper()
+
+	viper.Reset()
+	viper.Set("allowed_users.mode", "subscription")
+	viper.Set("allowed_users.tiers", []map[string]interface{}{
+		{"name": "Basic", "price_sats": 1000, "monthly_limit_bytes": 1073741824},
+		{"name": "Free", "price_sats": 0, "monthly_limit_bytes": 104857600},
+	})
+	config.InitConfigForTesting()
+	t.Cleanup(viper.Reset)
+
+	tempDir := t.TempDir()
+	store, err := badgerhold.InitStore(filepath.Join(tempDir, "store"), filepath.Join(tempDir, "stats.db"))
+	if err != nil {
+		t.Fatalf("InitStore: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := store.Cleanup(); err != nil {
+			t.Fatalf("Cleanup: %v", err)
+		}
+	})
+
+	backend := NewMockBackend()
+	processor := &recordingProcessor{}
+	service := NewService(store, backend, processor, &types.LightningConfig{})
+
+	return service, backend, processor, store
+}
+
+func TestInvoiceSettlementCreditsOnce(t *testing.T) {
+	service, backend, processor, store := setupService(t)
+	pubkey, _ := nostr.GetPublicKey(nostr.GeneratePrivateKey())
+
+	invoice, err := service.CreateSubscriptionInvoice(pubkey, "Basic")
+	if err != nil {
+		t.Fatalf("CreateSubscriptionInvoice: %v", err)
+	}
+	if invoice.AmountSats != 1000 || invoice.Status != types.LightningInvoicePending {
+		t.Fatalf("unexpected invoice: %+v", invoice)
+	}
+
+	// Unpaid invoices stay pending and credit nothing
+	checked, err := service.CheckInvoice(invoice.PaymentHash)
+	if err != nil {
+		t.Fatalf("CheckInvoice: %v", err)
+	}
+	if checked.Status != types.LightningInvoicePending || len(processor.calls) != 0 {
+		t.Fatalf("unpaid invoice should stay pending, got %s with %d payments", checked.Status, len(processor.calls))
+	}
+
+	if err := backend.Settle(invoice.PaymentHash); err != nil {
+		t.Fatalf("Settle: %v", err)
+	}
+
+	// Both the on-demand check and the poller see the settlement, but it is only credited once
+	checked, err = service.CheckInvoice(invoice.PaymentHash)
+	if err != nil {
+		t.Fatalf("CheckInvoice: %v", err)
+	}
+	service.pollPendingInvoices()
+
+	if checked.Status != types.LightningInvoiceSettled || checked.SettledAt == nil {
+		t.Fatalf("expected settled invoice, got %+v", checked)
+	}
+	if len(processor.calls) != 1 || processor.calls[0] != invoice.PaymentHash {
+		t.Fatalf("expected exactly one payment for %s, got %v", invoice.PaymentHash, processor.calls)
+	}
+
+	notifications, _, err := store.GetStatsStore().GetUserPaymentNotifications(pubkey, 1, 10)
+	if err != nil {
+		t.Fatalf("GetUserPaymentNotifications: %v", err)
+	}
+	if len(notifications) != 1 || notifications[0].TxID != invoice.PaymentHash {
+		t.Fatalf("expected one payment notification, got %+v", notifications)
+	}
+}
+
+func TestSettleTwiceCreditsOnce(t *testing.T) {
+	service, backend, processor, store := setupService(t)
+	pubkey, _ := nostr.GetPublicKey(nostr.GeneratePrivateKey())
+
+	invoice, err := service.CreateSubscriptionInvoice(pubkey, "Basic")
+	if err != nil {
+		t.Fatalf("CreateSubscriptionInvoice: %v", err)
+	}
+	if err := backend.Settle(invoice.PaymentHash); err != nil {
+		t.Fatalf("Settle: %v", err)
+	}
+	status, err := backend.LookupInvoice(service.ctx, invoice.PaymentHash)
+	if err != nil {
+		t.Fatalf("LookupInvoice: %v", err)
+	}
+
+	// A failed credit releases the claim so the next attempt can retry it
+	processor.err = errors.New("subscription store unavailable")
+	if err := service.settle(invoice, status); err == nil {
+		t.Fatal("expected the failed credit to be reported")
+	}
+	pending, err := store.GetStatsStore().GetLightningInvoice(invoice.PaymentHash)
+	if err != nil {
+		t.Fatalf("GetLightningInvoice: %v", err)
+	}
+	if pending.Status != types.LightningInvoicePending {
+		t.Fatalf("expected the invoice to stay pending after a failed credit, got %s", pending.Status)
+	}
+
+	processor.err = nil
+	for i := 0; i < 2; i++ {
+		if err := service.settle(invoice, status); err != nil {
+			t.Fatalf("settle %d: %v", i, err)
+		}
+	}
+
+	if len(processor.calls) != 1 {
+		t.Fatalf("expected the invoice to be credited once, got %v", processor.calls)
+	}
+	settled, err := store.GetStatsStore().GetLightningInvoice(invoice.PaymentHash)
+	if err != nil {
+		t.Fatalf("GetLightningInvoice: %v", err)
+	}
+	if settled.Status != types.LightningInvoiceSettled {
+		t.Fatalf("expected a settled invoice, got %s", settled.Status)
+	}
+}
+
+func TestCreateInvoiceRejectsFreeAndUnknownTiers(t *testing.T) {
+	service, _, _, _ := setupService(t)
+	pubkey, _ := nostr.GetPublicKey(nostr.GeneratePrivateKey())
+
+	if _, err := service.CreateSubscriptionInvoice(pubkey, "Free"); err == nil {
+		t.Fatal("expected free tier to be rejected")
+	}
+	if _, err := service.CreateSubscriptionInvoice(pubkey, "Gold"); err == nil {
+		t.Fatal("expected unknown tier to be rejected")
+	}
+	if _, err := service.CreateSubscriptionInvoice("not-a-pubkey", "Basic"); err == nil {
+		t.Fatal("expected invalid pubkey to be rejected")
+	}
+}
+
+func TestStartupSweepCreditsInterruptedInvoices(t *testing.T) {
+	service, backend, processor, store := setupService(t)
+	pubkey, _ := nostr.GetPublicKey(nostr.GeneratePrivateKey())
+
+	invoice, err := service.CreateSubscriptionInvoice(pubkey, "Basic")
+	if err != nil {
+		t.Fatalf("CreateSubscriptionInvoice: %v", err)
+	}
+	if err := backend.Settle(invoice.PaymentHash); err != nil {
+		t.Fatalf("Settle: %v", err)
+	}
+
+	// The relay stopped after claiming the invoice but before crediting it
+	claimed, err := store.GetStatsStore().ClaimLightningInvoice(invoice.PaymentHash, invoice.AmountSats, time.Now())
+	if err != nil || !claimed {
+		t.Fatalf("ClaimLightningInvoice: %t, %v", claimed, err)
+	}
+
+	// Polling only looks at pending invoices, so the claim is never credited by it
+	service.pollPendingInvoices()
+	if len(processor.calls) != 0 {
+		t.Fatalf("expected polling to skip claimed invoices, got %v", processor.calls)
+	}
+
+	service.resumeCrediting()
+	service.resumeCrediting()
+
+	if len(processor.calls) != 1 || processor.calls[0] != invoice.PaymentHash {
+		t.Fatalf("expected the interrupted invoice to be credited once, got %v", processor.calls)
+	}
+	settled, err := store.GetStatsStore().GetLightningInvoice(invoice.PaymentHash)
+	if err != nil {
+		t.Fatalf("GetLightningInvoice: %v", err)
+	}
+	if settled.Status != types.LightningInvoiceSettled || settled.SettledAt == nil {
+		t.Fatalf("expected a settled invoice, got %+v", settled)
+	}
+}
@@ -0,0 +1,163 @@
+package lightning
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/nbd-wtf/go-nostr"
+
+	"github.com/HORNET-Storage/hornet-storage/lib/config"
+	"github.com/HORNET-Storage/hornet-storage/lib/logging"
+	"github.com/HORNET-Storage/hornet-storage/lib/stores"
+	"github.com/HORNET-Storage/hornet-storage/lib/types"
+)
+
+// BackendZap marks payments that arrived as zaps rather than invoices issued by the relay
+const BackendZap = "zap"
+
+// NIP-57 event kinds
+const (
+	zapRequestKind = 9734
+	zapReceiptKind = 9735
+)
+
+// ProcessZapReceipt credits a zap sent to the relay pubkey to the zap sender's subscription.
+// Returns false without an error when the receipt is not a subscription payment for this relay.
+// Each payment hash is credited at most once, however many times the receipt is published.
+func ProcessZapReceipt(store stores.Store, processor PaymentProcessor, receipt *nostr.Event) (bool, error) {
+	cfg, err := config.GetConfig()
+	if err != nil {
+		return false, fmt.Errorf("failed to get config: %w", err)
+	}
+	if !cfg.Lightning.Zaps.Enabled || cfg.AllowedUsersSettings.Mode != "subscription" {
+		return false, nil
+	}
+
+	relayPubkey := cfg.Relay.PublicKey
+	if receipt.Kind != zapReceiptKind || relayPubkey == "" || tagValue(receipt.Tags, "p") != relayPubkey {
+		return false, nil
+	}
+
+	if !isTrustedZapper(cfg.Lightning.Zaps.ZapperPubkeys, receipt.PubKey) {
+		return false, fmt.Errorf("zap receipt signed by untrusted pubkey %s", receipt.PubKey)
+	}
+
+	zap, err := validateZapReceipt(receipt, relayPubkey)
+	if err != nil {
+		return false, err
+	}
+
+	if processor == nil {
+		return false, fmt.Errorf("no payment processor available for zap %s", zap.paymentHash)
+	}
+
+	statsStore := store.GetStatsStore()
+
+	// Claim the payment hash before crediting so duplicate receipts are ignored
+	now := time.Now()
+	claim := &types.LightningInvoice{
+		PaymentHash: zap.paymentHash,
+		Bolt11:      zap.bolt11,
+		Npub:        zap.sender,
+		AmountSats:  zap.amountSats,
+		Status:      types.LightningInvoiceCrediting,
+		Backend:     BackendZap,
+		ExpiresAt:   now,
+		SettledAt:   &now,
+	}
+	claimed, err := statsStore.RecordLightningPayment(claim)
+	if err != nil {
+		return false, fmt.Errorf("failed to record zap payment: %w", err)
+	}
+	if !claimed {
+		logging.Debugf("Zap %s already credited, skipping", zap.paymentHash)
+		return false, nil
+	}
+
+	if err := creditInvoice(statsStore, processor, claim); err != nil {
+		// Release the claim so the zap can be credited if the receipt is seen again
+		if deleteErr := statsStore.DeleteLightningInvoice(zap.paymentHash); deleteErr != nil {
+			logging.Infof("Warning: failed to release zap claim %s: %v", zap.paymentHash, deleteErr)
+		}
+		return false, err
+	}
+
+	logging.Infof("Credited zap %s from %s (%d sats)", zap.paymentHash, zap.sender, zap.amountSats)
+
+	return true, nil
+}
+
+type validatedZap struct {
+	sender      string
+	bolt11      string
+	paymentHash string
+	amountSats  int64
+}
+
+// validateZapReceipt checks a receipt against its embedded zap request as described in NIP-57 Appendix F
+func validateZapReceipt(receipt *nostr.Event, relayPubkey string) (*validatedZap, error) {
+	bolt11 := tagValue(receipt.Tags, "bolt11")
+	description := tagValue(receipt.Tags, "description")
+	if bolt11 == "" || description == "" {
+		return nil, fmt.Errorf("zap receipt is missing bolt11 or description")
+	}
+
+	invoice, err := DecodeBolt11(bolt11)
+	if err != nil {
+		return nil, err
+	}
+	if invoice.AmountMsat < 1000 {
+		return nil, fmt.Errorf("zap invoice has no amount")
+	}
+
+	// The invoice must commit to the zap request it was issued for
+	descriptionHash := sha256.Sum256([]byte(description))
+	if invoice.DescriptionHash == "" {
+		return nil, fmt.Errorf("zap invoice has no description hash")
+	}
+	if invoice.DescriptionHash != hex.EncodeToString(descriptionHash[:]) {
+		return nil, fmt.Errorf("zap invoice description hash does not match zap request")
+	}
+
+	var request nostr.Event
+	if err := request.UnmarshalJSON([]byte(description)); err != nil {
+		return nil, fmt.Errorf("invalid zap request: %w", err)
+	}
+	if request.Kind != zapRequestKind {
+		return nil, fmt.Errorf("zap request has kind %d", request.Kind)
+	}
+	if ok, err := request.CheckSignature(); err != nil || !ok {
+		return nil, fmt.Errorf("invalid zap request signature")
+	}
+	if tagValue(request.Tags, "p") != relayPubkey {
+		return nil, fmt.Errorf("zap request is not addressed to the relay")
+	}
+	if amount := tagValue(request.Tags, "amount"); amount != "" && amount != fmt.Sprintf("%d", invoice.AmountMsat) {
+		return nil, fmt.Errorf("zap invoice amount does not match zap request")
+	}
+
+	return &validatedZap{
+		sender:      request.PubKey,
+		bolt11:      bolt11,
+		paymentHash: invoice.PaymentHash,
+		amountSats:  invoice.AmountMsat / 1000,
+	}, nil
+}
+
+func isTrustedZapper(zappers []string, pubkey string) bool {
+	for _, zapper := range zappers {
+		if hexKey, err := normalizePubkey(zapper); err == nil && hexKey == pubkey {
+			return true
+		}
+	}
+	return false
+}
+
+func tagValue(tags nostr.Tags, name string) string {
+	if tag := tags.GetFirst([]string{name, ""}); tag != nil && len(*tag) > 1 {
+		return (*tag)[1]
+	}
+	return ""
+}
@@ -0,0 +1,175 @@
+package lightning
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcutil/bech32"
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/spf13/viper"
+
+	"github.com/HORNET-Storage/hornet-storage/lib/config"
+)
+
+// testBolt11 encodes an unsigned invoice carrying only the fields DecodeBolt11 reads.
+// Nil hashes are left out of the invoice.
+func testBolt11(t *testing.T, hrp string, paymentHash []byte, descriptionHash []byte) string {
+	t.Helper()
+
+	data := make([]byte, 7) // timestamp
+	for fieldType, value := range map[byte][]byte{bolt11FieldPaymentHash: paymentHash, bolt11FieldDescriptionHash: descriptionHash} {
+		if value == nil {
+			continue
+		}
+		groups, err := bech32.ConvertBits(value, 8, 5, true)
+		if err != nil {
+			t.Fatalf("ConvertBits: %v", err)
+		}
+		data = append(data, fieldType, byte(len(groups)>>5), byte(len(groups)&31))
+		data = append(data, groups...)
+	}
+	data = append(data, make([]byte, bolt11SignatureGroups)...)
+
+	invoice, err := bech32.Encode(hrp, data)
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	return invoice
+}
+
+func TestDecodeBolt11(t *testing.T) {
+	paymentHash := sha256.Sum256([]byte("preimage"))
+	descriptionHash := sha256.Sum256([]byte("description"))
+
+	decoded, err := DecodeBolt11(testBolt11(t, "lnbc2500u", paymentHash[:], descriptionHash[:]))
+	if err != nil {
+		t.Fatalf("DecodeBolt11: %v", err)
+	}
+	if decoded.AmountMsat != 250_000_000 {
+		t.Errorf("expected 250000000 msat, got %d", decoded.AmountMsat)
+	}
+	if decoded.PaymentHash != hex.EncodeToString(paymentHash[:]) {
+		t.Errorf("unexpected payment hash %s", decoded.PaymentHash)
+	}
+	if decoded.DescriptionHash != hex.EncodeToString(descriptionHash[:]) {
+		t.Errorf("unexpected description hash %s", decoded.DescriptionHash)
+	}
+
+	for hrp, expected := range map[string]int64{"lnbcrt10n": 1000, "lntb1m": 100_000_000, "lnbc": 0} {
+		amount, err := parseBolt11Amount(hrp[2:])
+		if err != nil || amount != expected {
+			t.Errorf("%s: expected %d msat, got %d (%v)", hrp, expected, amount, err)
+		}
+	}
+	if _, err := parseBolt11Amount("bc15p"); err == nil {
+		t.Error("expected sub-millisatoshi amount to be rejected")
+	}
+}
+
+func TestProcessZapReceiptCreditsOnce(t *testing.T) {
+	_, _, processor, store := setupService(t)
+
+	relayKey := nostr.GeneratePrivateKey()
+	relayPubkey, _ := nostr.GetPublicKey(relayKey)
+	zapperKey := nostr.GeneratePrivateKey()
+	zapperPubkey, _ := nostr.GetPublicKey(zapperKey)
+
+	viper.Set("relay.public_key", relayPubkey)
+	viper.Set("lightning.zaps.enabled", true)
+	viper.Set("lightning.zaps.zapper_pubkeys", []string{zapperPubkey})
+	config.InitConfigForTesting()
+
+	senderKey := nostr.GeneratePrivateKey()
+	request := nostr.Event{
+		CreatedAt: nostr.Now(),
+		Kind:      zapRequestKind,
+		Tags:      nostr.Tags{{"p", relayPubkey}, {"amount", "1000000"}},
+	}
+	if err := request.Sign(senderKey); err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+	description := request.String()
+
+	paymentHash := sha256.Sum256([]byte("zap preimage"))
+	descriptionHash := sha256.Sum256([]byte(description))
+	bolt11 := testBolt11(t, "lnbc10u", paymentHash[:], descriptionHash[:])
+
+	receipt := func(signer string) *nostr.Event {
+		event := &nostr.Event{
+			CreatedAt: nostr.Now(),
+			Kind:      zapReceiptKind,
+			Tags:      nostr.Tags{{"p", relayPubkey}, {"bolt11", bolt11}, {"description", description}},
+		}
+		if err := event.Sign(signer); err != nil {
+			t.Fatalf("Sign: %v", err)
+		}
+		return event
+	}
+
+	// Anyone can publish a receipt, so only trusted zappers are credited
+	if credited, err := ProcessZapReceipt(store, processor, receipt(nostr.GeneratePrivateKey())); credited || err == nil {
+		t.Fatalf("expected receipt from untrusted zapper to be rejected, got %v, %v", credited, err)
+	}
+
+	for i := 0; i < 2; i++ {
+		credited, err := ProcessZapReceipt(store, processor, receipt(zapperKey))
+		if err != nil {
+			t.Fatalf("ProcessZapReceipt: %v", err)
+		}
+		if credited != (i == 0) {
+			t.Fatalf("receipt %d: expected credited=%v", i, i == 0)
+		}
+	}
+
+	if len(processor.calls) != 1 || processor.calls[0] != hex.EncodeToString(paymentHash[:]) {
+		t.Fatalf("expected exactly one payment, got %v", processor.calls)
+	}
+
+	notifications, _, err := store.GetStatsStore().GetUserPaymentNotifications(request.PubKey, 1, 10)
+	if err != nil {
+		t.Fatalf("GetUserPaymentNotifications: %v", err)
+	}
+	if len(notifications) != 1 || notifications[0].Amount != 1000 {
+		t.Fatalf("expected one 1000 sat payment notification, got %+v", notifications)
+	}
+}
+
+func TestValidateZapReceiptRequiresDescriptionHash(t *testing.T) {
+	relayPubkey, _ := nostr.GetPublicKey(nostr.GeneratePrivateKey())
+
+	request := nostr.Event{
+		CreatedAt: nostr.Now(),
+		Kind:      zapRequestKind,
+		Tags:      nostr.Tags{{"p", relayPubkey}},
+	}
+	if err := request.Sign(nostr.GeneratePrivateKey()); err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+	description := request.String()
+
+	paymentHash := sha256.Sum256([]byte("zap preimage"))
+	descriptionHash := sha256.Sum256([]byte(description))
+	otherHash := sha256.Sum256([]byte("another zap request"))
+
+	receipt := func(bolt11 string) *nostr.Event {
+		return &nostr.Event{
+			Kind: zapReceiptKind,
+			Tags: nostr.Tags{{"p", relayPubkey}, {"bolt11", bolt11}, {"description", description}},
+		}
+	}
+
+	if _, err := validateZapReceipt(receipt(testBolt11(t, "lnbc10u", paymentHash[:], nil)), relayPubkey); err == nil {
+		t.Error("expected an invoice without a description hash to be rejected")
+	}
+	if _, err := validateZapReceipt(receipt(testBolt11(t, "lnbc10u", paymentHash[:], otherHash[:])), relayPubkey); err == nil {
+		t.Error("expected an invoice committing to another zap request to be rejected")
+	}
+	zap, err := validateZapReceipt(receipt(testBolt11(t, "lnbc10u", paymentHash[:], descriptionHash[:])), relayPubkey)
+	if err != nil {
+		t.Fatalf("validateZapReceipt: %v", err)
+	}
+	if zap.sender != request.PubKey || zap.amountSats != 1000 {
+		t.Errorf("unexpected zap %+v", zap)
+	}
+}
@@ -0,0 +1,494 @@
+// Package mirror publishes accepted events from opted-in authors or kinds to the
+// author's NIP-65 write relays, retrying failed deliveries from a persistent queue.
+// Accepted events are queued from an event bus hook, which runs before the event is
+// handed to subscriptions, so a delivery is stored even if the bus falls behind.
+package mirror
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nbd-wtf/go-nostr"
+
+	"github.com/HORNET-Storage/hornet-storage/lib/config"
+	"github.com/HORNET-Storage/hornet-storage/lib/eventbus"
+	"github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr/kind10002"
+	"github.com/HORNET-Storage/hornet-storage/lib/logging"
+	"github.com/HORNET-Storage/hornet-storage/lib/stores"
+	"github.com/HORNET-Storage/hornet-storage/lib/types"
+)
+
+const (
+	pollInterval   = 5 * time.Second
+	pruneInterval  = time.Hour
+	connectTimeout = 15 * time.Second
+	publishTimeout = 15 * time.Second
+	batchSize      = 100
+)
+
+// DestinationStatus is the delivery health of one upstream relay
+type DestinationStatus struct {
+	Relay       string     `json:"relay"`
+	Failures    int        `json:"consecutive_failures"`
+	RetryAt     *time.Time `json:"retry_at,omitempty"`
+	LastError   string     `json:"last_error,omitempty"`
+	LastSuccess *time.Time `json:"last_success,omitempty"`
+	Delivered   int        `json:"delivered"`
+}
+
+// MirrorService queues and delivers events to upstream relays
+type MirrorService struct {
+	store     stores.Store
+	config    *types.MirroringConfig
+	ownRelay  string // Never mirror back to ourselves
+	ctx       context.Context
+	cancel    context.CancelFunc
+	wg        sync.WaitGroup
+	wake      chan struct{}
+	mutex     sync.Mutex
+	isRunning bool
+
+	destinations map[string]*DestinationStatus
+	destMutex    sync.RWMutex
+	lastPrune    time.Time
+}
+
+// NewMirrorService creates the mirroring service, or returns nil when mirroring is disabled
+func NewMirrorService(store stores.Store) (*MirrorService, error) {
+	cfg, err := config.GetConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get config: %w", err)
+	}
+
+	if !cfg.Mirroring.Enabled {
+		logging.Infof("Outbound mirroring is disabled")
+		return nil, nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	mirroring := cfg.Mirroring
+	service := &MirrorService{
+		store:        store,
+		config:       &mirroring,
+		ctx:          ctx,
+		cancel:       cancel,
+		wake:         make(chan struct{}, 1),
+		destinations: make(map[string]*DestinationStatus),
+	}
+	if cfg.Relay.Discovery.URL != "" {
+		service.ownRelay = nostr.NormalizeURL(cfg.Relay.Discovery.URL)
+	}
+
+	return service, nil
+}
+
+// Start queues accepted events and starts the delivery worker
+func (ms *MirrorService) Start() error {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	if ms.isRunning {
+		return fmt.Errorf("mirror service is already running")
+	}
+
+	ms.isRunning = true
+	eventbus.AddHook("mirror", ms.Enqueue)
+
+	ms.wg.Add(1)
+	go ms.run()
+
+	logging.Infof("Outbound mirroring started for %d pubkeys and %d kinds", len(ms.config.Pubkeys), len(ms.config.Kinds))
+	return nil
+}
+
+// Stop stops the delivery worker. Queued deliveries stay in the store for the next start.
+func (ms *MirrorService) Stop() {
+	ms.mutex.Lock()
+	if !ms.isRunning {
+		ms.mutex.Unlock()
+		return
+	}
+	ms.isRunning = false
+	eventbus.RemoveHook("mirror")
+	ms.mutex.Unlock()
+
+	ms.cancel()
+	ms.wg.Wait()
+	logging.Infof("Outbound mirroring stopped")
+}
+
+// ShouldMirror reports whether the event is from an opted-in author or of an opted-in kind.
+// Ephemeral events are never stored, so they cannot be retried and are not mirrored.
+func (ms *MirrorService) ShouldMirror(event *nostr.Event) bool {
+	if event.Kind >= 20000 && event.Kind < 30000 {
+		return false
+	}
+	return slices.Contains(ms.config.Pubkeys, event.PubKey) || slices.Contains(ms.config.Kinds, event.Kind)
+}
+
+// Enqueue queues one delivery per destination relay for an opted-in event
+func (ms *MirrorService) Enqueue(event *nostr.Event) {
+	if !ms.ShouldMirror(event) {
+		return
+	}
+
+	relays := ms.Destinations(event.PubKey)
+	if len(relays) == 0 {
+		logging.Debugf("No write relays to mirror event %s from %s", event.ID, event.PubKey)
+		return
+	}
+
+	now := time.Now()
+	for _, relay := range relays {
+		delivery := &types.MirrorDelivery{
+			ID:          event.ID + ":" + relay,
+			EventID:     event.ID,
+			Pubkey:      event.PubKey,
+			Kind:        event.Kind,
+			Relay:       relay,
+			Status:      types.MirrorStatusPending,
+			NextAttempt: now,
+			CreatedAt:   now,
+			UpdatedAt:   now,
+		}
+		if err := ms.store.SaveMirrorDelivery(delivery); err != nil {
+			logging.Infof("Failed to queue mirror delivery of %s to %s: %v", event.ID, relay, err)
+		}
+	}
+
+	select {
+	case ms.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Destinations returns the write relays from the author's latest kind 10002, falling
+// back to the configured relays, normalized, deduplicated and capped at max_relays.
+func (ms *MirrorService) Destinations(pubkey string) []string {
+	relays := WriteRelays(ms.store, pubkey)
+	if len(relays) == 0 {
+		relays = ms.config.FallbackRelays
+	}
+
+	destinations := make([]string, 0, len(relays))
+	for _, relay := range relays {
+		relay = strings.TrimSpace(relay)
+		if relay == "" {
+			continue
+		}
+		relay = nostr.NormalizeURL(relay)
+		if relay == ms.ownRelay || slices.Contains(destinations, relay) {
+			continue
+		}
+		destinations = append(destinations, relay)
+		if ms.config.MaxRelays > 0 && len(destinations) >= ms.config.MaxRelays {
+			break
+		}
+	}
+	return destinations
+}
+
+// WriteRelays returns the write relays in the author's latest stored kind 10002
+func WriteRelays(store stores.Store, pubkey string) []string {
+	relayList, err := kind10002.LatestRelayList(store, pubkey)
+	if err != nil {
+		return nil
+	}
+	return kind10002.WriteRelays(relayList)
+}
+
+// GetDestinations returns the delivery health of every relay the service has tried
+func (ms *MirrorService) GetDestinations() []DestinationStatus {
+	ms.destMutex.RLock()
+	defer ms.destMutex.RUnlock()
+
+	statuses := make([]DestinationStatus, 0, len(ms.destinations))
+	for _, status := range ms.destinations {
+		statuses = append(statuses, *status)
+	}
+	slices.SortFunc(statuses, func(a, b DestinationStatus) int {
+		return strings.Compare(a.Relay, b.Relay)
+	})
+	return statuses
+}
+
+func (ms *MirrorService) run() {
+	defer ms.wg.Done()
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		ms.ProcessDue(time.Now())
+
+		select {
+		case <-ms.wake:
+		case <-ticker.C:
+		case <-ms.ctx.Done():
+			return
+		}
+	}
+}
+
+// ProcessDue delivers every pending delivery whose next attempt is due, one
+// connection per destination relay, and prunes old finished deliveries.
+func (ms *MirrorService) ProcessDue(now time.Time) {
+	if now.Sub(ms.lastPrune) >= pruneInterval && ms.config.HistoryDays > 0 {
+		ms.lastPrune = now
+		if pruned, err := ms.store.DeleteFinishedMirrorDeliveries(now.AddDate(0, 0, -ms.config.HistoryDays)); err != nil {
+			logging.Infof("Error pruning mirror deliveries: %v", err)
+		} else if pruned > 0 {
+			logging.Infof("Pruned %d finished mirror deliveries", pruned)
+		}
+	}
+
+	due, err := ms.store.GetDueMirrorDeliveries(now, batchSize)
+	if err != nil {
+		logging.Infof("Error loading due mirror deliveries: %v", err)
+		return
+	}
+
+	byRelay := make(map[string][]types.MirrorDelivery)
+	for _, delivery := range due {
+		byRelay[delivery.Relay] = append(byRelay[delivery.Relay], delivery)
+	}
+
+	for relay, deliveries := range byRelay {
+		if ms.ctx.Err() != nil {
+			return
+		}
+
+		// Hold everything for a relay that is backing off until it may be retried
+		if retryAt := ms.retryAt(relay); retryAt.After(now) {
+			for _, delivery := range deliveries {
+				delivery.NextAttempt = retryAt
+				ms.save(&delivery)
+			}
+			continue
+		}
+
+		ms.deliver(relay, deliveries)
+	}
+}
+
+// deliver publishes the deliveries' events to one relay over a single connection
+func (ms *MirrorService) deliver(relay string, deliveries []types.MirrorDelivery) {
+	ids := make([]string, 0, len(deliveries))
+	for _, delivery := range deliveries {
+		ids = append(ids, delivery.EventID)
+	}
+
+	stored, err := ms.store.QueryEvents(nostr.Filter{IDs: ids})
+	if err != nil {
+		logging.Infof("Error loading events to mirror: %v", err)
+		return
+	}
+	events := make(map[string]*nostr.Event, len(stored))
+	for _, event := range stored {
+		events[event.ID] = event
+	}
+
+	connectCtx, cancel := context.WithTimeout(ms.ctx, connectTimeout)
+	conn, err := nostr.RelayConnect(connectCtx, relay)
+	cancel()
+	if err != nil {
+		// One failed connection is one failure for the destination, however many
+		// deliveries were waiting on it
+		err = fmt.Errorf("connect: %w", err)
+		retryAt := ms.backOff(relay, err)
+		for _, delivery := range deliveries {
+			ms.retry(&delivery, err, retryAt)
+		}
+		return
+	}
+	defer conn.Close()
+
+	var failed []types.MirrorDelivery
+	var errs []error
+	for _, delivery := range deliveries {
+		event, ok := events[delivery.EventID]
+		if !ok {
+			// Deleted locally since it was queued, nothing left to send
+			delivery.Status = types.MirrorStatusFailed
+			delivery.LastError = "event is no longer stored"
+			ms.save(&delivery)
+			continue
+		}
+
+		publishCtx, cancel := context.WithTimeout(ms.ctx, publishTimeout)
+		err := conn.Publish(publishCtx, *event)
+		cancel()
+		if err != nil {
+			failed = append(failed, delivery)
+			errs = append(errs, err)
+			continue
+		}
+		ms.succeed(&delivery)
+	}
+
+	if len(failed) == 0 {
+		return
+	}
+	retryAt := ms.backOff(relay, errs[len(errs)-1])
+	for i, delivery := range failed {
+		ms.retry(&delivery, errs[i], retryAt)
+	}
+}
+
+func (ms *MirrorService) succeed(delivery *types.MirrorDelivery) {
+	now := time.Now()
+	delivery.Attempts++
+	delivery.Status = types.MirrorStatusDelivered
+	delivery.LastError = ""
+	ms.save(delivery)
+
+	status := ms.destination(delivery.Relay)
+	ms.destMutex.Lock()
+	status.Failures = 0
+	status.RetryAt = nil
+	status.LastError = ""
+	status.LastSuccess = &now
+	status.Delivered++
+	ms.destMutex.Unlock()
+}
+
+// backOff records a failed connection to a destination and backs it off exponentially,
+// returning when it may be retried
+func (ms *MirrorService) backOff(relay string, err error) time.Time {
+	status := ms.destination(relay)
+	ms.destMutex.Lock()
+	defer ms.destMutex.Unlock()
+
+	status.Failures++
+	retryAt := time.Now().Add(ms.backoff(status.Failures))
+	status.RetryAt = &retryAt
+	status.LastError = err.Error()
+	return retryAt
+}
+
+// retry records a failed attempt, holding the delivery until retryAt
+func (ms *MirrorService) retry(delivery *types.MirrorDelivery, err error, retryAt time.Time) {
+	delivery.Attempts++
+	delivery.LastError = err.Error()
+	delivery.NextAttempt = retryAt
+	if ms.config.MaxAttempts > 0 && delivery.Attempts >= ms.config.MaxAttempts {
+		delivery.Status = types.MirrorStatusFailed
+		logging.Infof("Giving up mirroring %s to %s after %d attempts: %v", delivery.EventID, delivery.Relay, delivery.Attempts, err)
+	}
+	ms.save(delivery)
+}
+
+// backoff doubles the retry delay for each consecutive failure, up to retry_max_seconds
+func (ms *MirrorService) backoff(failures int) time.Duration {
+	base := time.Duration(max(ms.config.RetryBaseSeconds, 1)) * time.Second
+	limit := time.Duration(max(ms.config.RetryMaxSeconds, ms.config.RetryBaseSeconds, 1)) * time.Second
+
+	delay := base
+	for i := 1; i < failures && delay < limit; i++ {
+		delay *= 2
+	}
+	return min(delay, limit)
+}
+
+func (ms *MirrorService) retryAt(relay string) time.Time {
+	ms.destMutex.RLock()
+	defer ms.destMutex.RUnlock()
+	if status, ok := ms.destinations[relay]; ok && status.RetryAt != nil {
+		return *status.RetryAt
+	}
+	return time.Time{}
+}
+
+func (ms *MirrorService) destination(relay string) *DestinationStatus {
+	ms.destMutex.Lock()
+	defer ms.destMutex.Unlock()
+	status, ok := ms.destinations[relay]
+	if !ok {
+		status = &DestinationStatus{Relay: relay}
+		ms.destinations[relay] = status
+	}
+	return status
+}
+
+func (ms *MirrorService) save(delivery *types.MirrorDelivery) {
+	delivery.UpdatedAt = time.Now()
+	if err := ms.store.SaveMirrorDelivery(delivery); err != nil {
+		logging.Infof("Failed to update mirror delivery %s: %v", delivery.ID, err)
+	}
+}
+
+// Global service instance
+var globalMirrorService *MirrorService
+var serviceMutex sync.RWMutex
+
+// InitGlobalMirrorService initializes the global mirror service instance
+func InitGlobalMirrorService(store stores.Store) error {
+	serviceMutex.Lock()
+	defer serviceMutex.Unlock()
+
+	if globalMirrorService != nil {
+		return fmt.Errorf("mirror service already initialized")
+	}
+
+	service, err := NewMirrorService(store)
+	if err != nil {
+		return err
+	}
+
+	if service != nil {
+		if err := service.Start(); err != nil {
+			return err
+		}
+	}
+
+	globalMirrorService = service
+	return nil
+}
+
+// GetGlobalMirrorService returns the global mirror service instance, nil when disabled
+func GetGlobalMirrorService() *MirrorService {
+	serviceMutex.RLock()
+	defer serviceMutex.RUnlock()
+	return globalMirrorService
+}
+
+// StopGlobalMirrorService stops the global mirror service
+func StopGlobalMirrorService() {
+	serviceMutex.Lock()
+	defer serviceMutex.Unlock()
+
+	if globalMirrorService != nil {
+		globalMirrorService.Stop()
+		globalMirrorService = nil
+	}
+}
+
+// ReloadGlobalMirrorService restarts the global mirror service with updated configuration
+func ReloadGlobalMirrorService(store stores.Store) error {
+	serviceMutex.Lock()
+	defer serviceMutex.Unlock()
+
+	if globalMirrorService != nil {
+		globalMirrorService.Stop()
+		globalMirrorService = nil
+	}
+
+	service, err := NewMirrorService(store)
+	if err != nil {
+		return fmt.Errorf("failed to create new mirror service: %w", err)
+	}
+
+	if service != nil {
+		if err := service.Start(); err != nil {
+			return fmt.Errorf("failed to start new mirror service: %w", err)
+		}
+	}
+
+	globalMirrorService = service
+	return nil
+}
@@ -0,0 +1,218 @@
+package mirror
+
+import (
+	"net"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gofiber/contrib/websocket"
+	"github.com/gofiber/fiber/v2"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/spf13/viper"
+
+	"github.com/HORNET-Storage/hornet-storage/lib/config"
+	"github.com/HORNET-Storage/hornet-storage/lib/stores"
+	"github.com/HORNET-Storage/hornet-storage/lib/stores/badgerhold"
+	"github.com/HORNET-Storage/hornet-storage/lib/types"
+)
+
+// standInRelay accepts every EVENT and records the ids it received
+type standInRelay struct {
+	url      string
+	mu       sync.Mutex
+	received []string
+}
+
+func startStandInRelay(t *testing.T) *standInRelay {
+	relay := &standInRelay{}
+
+	app := fiber.New(fiber.Config{DisableStartupMessage: true})
+	app.Get("/", websocket.New(func(c *websocket.Conn) {
+		for {
+			_, message, err := c.ReadMessage()
+			if err != nil {
+				return
+			}
+
+			var envelope nostr.EventEnvelope
+			if err := envelope.UnmarshalJSON(message); err != nil {
+				continue
+			}
+
+			relay.mu.Lock()
+			relay.received = append(relay.received, envelope.Event.ID)
+			relay.mu.Unlock()
+
+			response, _ := jsoniter.Marshal([]interface{}{"OK", envelope.Event.ID, true, ""})
+			if err := c.WriteMessage(websocket.TextMessage, response); err != nil {
+				return
+			}
+		}
+	}))
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	go app.Listener(listener)
+	t.Cleanup(func() { app.Shutdown() })
+
+	relay.url = "ws://" + listener.Addr().String()
+	return relay
+}
+
+func (r *standInRelay) Received() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.received...)
+}
+
+func TestMirrorDeliversToWriteRelaysWithBackoff(t *testing.T) {
+	standIn := startStandInRelay(t)
+
+	// Nothing listens on a just-closed port, so deliveries there fail to connect
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	deadRelay := "ws://" + closed.Addr().String()
+	closed.Close()
+
+	authorKey := nostr.GeneratePrivateKey()
+	author, _ := nostr.GetPublicKey(authorKey)
+
+	viper.Reset()
+	viper.Set("mirroring.enabled", true)
+	viper.Set("mirroring.pubkeys", []string{author})
+	viper.Set("mirroring.max_attempts", 3)
+	viper.Set("mirroring.retry_base_seconds", 60)
+	viper.Set("mirroring.retry_max_seconds", 3600)
+	config.InitConfigForTesting()
+	t.Cleanup(viper.Reset)
+
+	tempDir := t.TempDir()
+	store, err := badgerhold.InitStore(filepath.Join(tempDir, "store"), filepath.Join(tempDir, "stats.db"))
+	if err != nil {
+		t.Fatalf("InitStore: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := store.Cleanup(); err != nil {
+			t.Fatalf("Cleanup: %v", err)
+		}
+	})
+
+	sign := func(event *nostr.Event) {
+		if err := event.Sign(authorKey); err != nil {
+			t.Fatalf("Sign: %v", err)
+		}
+		if err := store.StoreEvent(event); err != nil {
+			t.Fatalf("StoreEvent: %v", err)
+		}
+	}
+
+	relayList := &nostr.Event{
+		Kind:      10002,
+		CreatedAt: nostr.Now(),
+		Tags: nostr.Tags{
+			{"r", standIn.url, "write"},
+			{"r", "wss://read-only.example.com", "read"},
+			{"r", deadRelay},
+		},
+	}
+	sign(relayList)
+
+	note := &nostr.Event{Kind: 1, CreatedAt: nostr.Now(), Content: "mirrored"}
+	sign(note)
+	reply := &nostr.Event{Kind: 1, CreatedAt: nostr.Now(), Content: "also mirrored"}
+	sign(reply)
+
+	service, err := NewMirrorService(store)
+	if err != nil || service == nil {
+		t.Fatalf("NewMirrorService: %v", err)
+	}
+	t.Cleanup(service.cancel)
+
+	if service.ShouldMirror(&nostr.Event{Kind: 1, PubKey: "someone-else"}) {
+		t.Error("expected events from authors who did not opt in to be skipped")
+	}
+
+	destinations := service.Destinations(author)
+	if len(destinations) != 2 {
+		t.Fatalf("expected the write and unmarked relays as destinations, got %v", destinations)
+	}
+
+	service.Enqueue(note)
+	service.Enqueue(reply)
+	service.ProcessDue(time.Now())
+
+	if received := standIn.Received(); len(received) != 2 {
+		t.Fatalf("expected the stand-in relay to receive both notes, got %v", received)
+	}
+
+	// Both deliveries waited on one failed connection, which is one failure for the relay
+	for _, status := range service.GetDestinations() {
+		if status.Relay == nostr.NormalizeURL(deadRelay) && status.Failures != 1 {
+			t.Fatalf("expected one failure per connection attempt, got %d", status.Failures)
+		}
+	}
+
+	deliveries := map[string]types.MirrorDelivery{}
+	all, err := store.ListMirrorDeliveries("", 0)
+	if err != nil {
+		t.Fatalf("ListMirrorDeliveries: %v", err)
+	}
+	for _, delivery := range all {
+		if delivery.EventID == note.ID {
+			deliveries[delivery.Relay] = delivery
+		}
+	}
+
+	if delivery := deliveries[nostr.NormalizeURL(standIn.url)]; delivery.Status != types.MirrorStatusDelivered {
+		t.Errorf("expected the stand-in delivery to be delivered, got %+v", delivery)
+	}
+	failed := deliveries[nostr.NormalizeURL(deadRelay)]
+	if failed.Status != types.MirrorStatusPending || failed.Attempts != 1 || !failed.NextAttempt.After(time.Now()) {
+		t.Fatalf("expected the dead relay delivery to be retried later, got %+v", failed)
+	}
+
+	// The dead relay is backing off, so nothing is attempted until its retry time
+	service.ProcessDue(time.Now())
+	if due, _ := store.GetDueMirrorDeliveries(time.Now(), 0); len(due) != 0 {
+		t.Errorf("expected no deliveries to be due while backing off, got %d", len(due))
+	}
+
+	// Once due again each failure doubles the destination back-off until the delivery gives up
+	for attempt := 2; attempt <= 3; attempt++ {
+		service.ProcessDue(time.Now().Add(24 * time.Hour))
+	}
+	failed, _ = findDelivery(store, failed.ID)
+	if failed.Status != types.MirrorStatusFailed || failed.Attempts != 3 {
+		t.Errorf("expected the dead relay delivery to give up after 3 attempts, got %+v", failed)
+	}
+
+	statuses := service.GetDestinations()
+	if len(statuses) != 2 {
+		t.Fatalf("expected two destination statuses, got %+v", statuses)
+	}
+	for _, status := range statuses {
+		if status.Relay == nostr.NormalizeURL(deadRelay) && status.Failures != 3 {
+			t.Errorf("expected 3 consecutive failures for the dead relay, got %d", status.Failures)
+		}
+	}
+	if backoff := service.backoff(3); backoff != 4*time.Minute {
+		t.Errorf("expected the third failure to back off 4 minutes, got %v", backoff)
+	}
+}
+
+func findDelivery(store stores.Store, id string) (types.MirrorDelivery, bool) {
+	deliveries, _ := store.ListMirrorDeliveries("", 0)
+	for _, delivery := range deliveries {
+		if delivery.ID == id {
+			return delivery, true
+		}
+	}
+	return types.MirrorDelivery{}, false
+}
@@ -0,0 +1,210 @@
+package push
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/nbd-wtf/go-nostr"
+
+	"github.com/HORNET-Storage/hornet-storage/lib/logging"
+)
+
+// Token buckets are pruned once this many recipients are tracked
+const maxRateLimitBuckets = 10000
+
+// coalesceKey groups notifications a recipient would otherwise get in a burst:
+// reactions to the same note, replies to the same thread, new followers and so on
+type coalesceKey struct {
+	recipient string
+	reference string // Referenced event ID, empty for kinds that don't reference one
+	category  string
+}
+
+// coalescedBatch is the set of events waiting to be delivered as one notification
+type coalescedBatch struct {
+	latest  *nostr.Event
+	count   int
+	senders []string // Distinct senders in arrival order
+	seen    map[string]bool
+	sats    int64 // Total of merged zaps
+}
+
+func (b *coalescedBatch) add(event *nostr.Event) {
+	b.latest = event
+	b.count++
+	if event.Kind == 9735 {
+		b.sats += zapAmountSats(event)
+	}
+
+	sender := eventSender(event)
+	if sender == "" || b.seen[sender] {
+		return
+	}
+	if b.seen == nil {
+		b.seen = make(map[string]bool)
+	}
+	b.seen[sender] = true
+	b.senders = append(b.senders, sender)
+}
+
+// coalesceGroup is an open window for a key. Events that arrive while it is open are
+// held and delivered together when it closes.
+type coalesceGroup struct {
+	pending *coalescedBatch
+	timer   *time.Timer
+}
+
+// tokenBucket limits how many notifications a recipient receives
+type tokenBucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// coalescer merges bursts of notifications for the same recipient and subject and
+// rate limits each recipient. The first event for a key is delivered immediately and
+// opens a window; anything arriving during the window is summarised when it closes.
+// A recipient who is out of tokens has the event held in the window instead of dropped.
+type coalescer struct {
+	window time.Duration
+	rate   float64 // Tokens per second, 0 disables rate limiting
+	burst  float64
+
+	// deliver sends a batch to the recipient's devices. Called with mu held so that
+	// stop can wait for in-flight deliveries before the queue is closed.
+	deliver func(recipient string, batch *coalescedBatch) bool
+
+	mu      sync.Mutex
+	groups  map[coalesceKey]*coalesceGroup
+	buckets map[string]*tokenBucket
+	stopped bool
+}
+
+func newCoalescer(window time.Duration, perMinute int, burst int, deliver func(string, *coalescedBatch) bool) *coalescer {
+	c := &coalescer{
+		window:  window,
+		deliver: deliver,
+		groups:  make(map[coalesceKey]*coalesceGroup),
+		buckets: make(map[string]*tokenBucket),
+	}
+	if perMinute > 0 {
+		c.rate = float64(perMinute) / 60
+		c.burst = float64(max(burst, 1))
+	}
+	return c
+}
+
+// add delivers or holds an event for the recipient. Returns false if the service is shutting down.
+func (c *coalescer) add(key coalesceKey, event *nostr.Event) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.stopped {
+		return false
+	}
+
+	// A window is open for this key, merge into it
+	if group, ok := c.groups[key]; ok {
+		if group.pending == nil {
+			group.pending = &coalescedBatch{}
+		}
+		group.pending.add(event)
+		return true
+	}
+
+	batch := &coalescedBatch{}
+	batch.add(event)
+
+	if c.window <= 0 {
+		if !c.allow(key.recipient, time.Now()) {
+			logging.Infof("🔕 Rate limit reached for %s, dropping kind %d notification", shortenPubkey(key.recipient), event.Kind)
+			return true
+		}
+		return c.deliver(key.recipient, batch)
+	}
+
+	group := &coalesceGroup{}
+	group.timer = time.AfterFunc(c.window, func() { c.flush(key) })
+	c.groups[key] = group
+
+	if !c.allow(key.recipient, time.Now()) {
+		logging.Infof("🔕 Rate limit reached for %s, holding kind %d notification", shortenPubkey(key.recipient), event.Kind)
+		group.pending = batch
+		return true
+	}
+	return c.deliver(key.recipient, batch)
+}
+
+// flush closes the window for a key, delivering whatever arrived during it. A window
+// that had events stays open for another round so a sustained burst produces one
+// notification per window.
+func (c *coalescer) flush(key coalesceKey) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	group, ok := c.groups[key]
+	if c.stopped || !ok {
+		return
+	}
+
+	if group.pending == nil {
+		delete(c.groups, key)
+		return
+	}
+
+	group.timer.Reset(c.window)
+	if !c.allow(key.recipient, time.Now()) {
+		// Try again when the next window closes
+		return
+	}
+
+	batch := group.pending
+	group.pending = nil
+	c.deliver(key.recipient, batch)
+}
+
+// allow takes a token from the recipient's bucket. Must be called with mu held.
+func (c *coalescer) allow(recipient string, now time.Time) bool {
+	if c.rate <= 0 {
+		return true
+	}
+
+	bucket, ok := c.buckets[recipient]
+	if !ok {
+		if len(c.buckets) >= maxRateLimitBuckets {
+			c.pruneBuckets(now)
+		}
+		bucket = &tokenBucket{tokens: c.burst, updated: now}
+		c.buckets[recipient] = bucket
+	}
+
+	bucket.tokens = math.Min(c.burst, bucket.tokens+now.Sub(bucket.updated).Seconds()*c.rate)
+	bucket.updated = now
+
+	if bucket.tokens < 1 {
+		return false
+	}
+	bucket.tokens--
+	return true
+}
+
+// pruneBuckets forgets recipients whose buckets have refilled, as they behave like new ones
+func (c *coalescer) pruneBuckets(now time.Time) {
+	for recipient, bucket := range c.buckets {
+		if bucket.tokens+now.Sub(bucket.updated).Seconds()*c.rate >= c.burst {
+			delete(c.buckets, recipient)
+		}
+	}
+}
+
+// stop discards held notifications and waits for any delivery in progress
+func (c *coalescer) stop() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.stopped = true
+	for key, group := range c.groups {
+		group.timer.Stop()
+		delete(c.groups, key)
+	}
+}
@@ -0,0 +1,152 @@
+package push
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/nbd-wtf/go-nostr"
+)
+
+// recordingCoalescer returns a coalescer whose deliveries are captured instead of queued.
+// The window is long enough that tests close it by calling flush.
+func recordingCoalescer(perMinute, burst int) (*coalescer, *[]*coalescedBatch) {
+	var delivered []*coalescedBatch
+	c := newCoalescer(time.Hour, perMinute, burst, func(recipient string, batch *coalescedBatch) bool {
+		delivered = append(delivered, batch)
+		return true
+	})
+	return c, &delivered
+}
+
+func reaction(sender, note string, n int) *nostr.Event {
+	return &nostr.Event{
+		ID:     fmt.Sprintf("reaction-%s-%d", sender, n),
+		Kind:   7,
+		PubKey: sender,
+		Tags:   nostr.Tags{{"e", note}, {"p", "recipient"}},
+	}
+}
+
+func TestCoalescerMergesBurstIntoOneSummary(t *testing.T) {
+	c, delivered := recordingCoalescer(0, 0)
+	defer c.stop()
+	key := coalesceKey{recipient: "recipient", reference: "note", category: "kind_7"}
+
+	for i := 0; i < 13; i++ {
+		c.add(key, reaction(fmt.Sprintf("sender-%d", i), "note", i))
+	}
+	// The same person reacting twice counts as one sender
+	c.add(key, reaction("sender-12", "note", 99))
+
+	if len(*delivered) != 1 || (*delivered)[0].count != 1 {
+		t.Fatalf("expected only the first reaction to be sent immediately, got %d deliveries", len(*delivered))
+	}
+
+	c.flush(key)
+	if len(*delivered) != 2 {
+		t.Fatalf("expected one summary when the window closed, got %d deliveries", len(*delivered))
+	}
+	summary := (*delivered)[1]
+	if summary.count != 13 || len(summary.senders) != 12 {
+		t.Errorf("expected 13 events from 12 senders, got %d from %d", summary.count, len(summary.senders))
+	}
+
+	// A quiet window closes the group, so the next reaction is sent straight away again
+	c.flush(key)
+	if len(c.groups) != 0 {
+		t.Fatalf("expected the group to close after a quiet window")
+	}
+	c.add(key, reaction("late", "note", 0))
+	if len(*delivered) != 3 || (*delivered)[2].count != 1 {
+		t.Errorf("expected a reaction after the burst to be sent on its own")
+	}
+}
+
+func TestCoalescerKeepsSubjectsApart(t *testing.T) {
+	c, delivered := recordingCoalescer(0, 0)
+	defer c.stop()
+
+	c.add(coalesceKey{recipient: "recipient", reference: "note-a", category: "kind_7"}, reaction("alice", "note-a", 0))
+	c.add(coalesceKey{recipient: "recipient", reference: "note-b", category: "kind_7"}, reaction("alice", "note-b", 0))
+	c.add(coalesceKey{recipient: "recipient", reference: "note-a", category: "kind_6"}, reaction("alice", "note-a", 1))
+	c.add(coalesceKey{recipient: "other", reference: "note-a", category: "kind_7"}, reaction("alice", "note-a", 2))
+
+	if len(*delivered) != 4 {
+		t.Errorf("expected different notes, categories and recipients not to be merged, got %d deliveries", len(*delivered))
+	}
+}
+
+func TestCoalescerRateLimitsRecipient(t *testing.T) {
+	c, delivered := recordingCoalescer(1, 2)
+	defer c.stop()
+
+	for i := 0; i < 3; i++ {
+		note := fmt.Sprintf("note-%d", i)
+		c.add(coalesceKey{recipient: "recipient", reference: note, category: "kind_7"}, reaction("alice", note, i))
+	}
+	if len(*delivered) != 2 {
+		t.Fatalf("expected the burst allowance of 2, got %d deliveries", len(*delivered))
+	}
+
+	// Out of tokens: the held reaction waits for another window instead of being dropped
+	held := coalesceKey{recipient: "recipient", reference: "note-2", category: "kind_7"}
+	c.flush(held)
+	if len(*delivered) != 2 || c.groups[held].pending == nil {
+		t.Fatalf("expected the held reaction to stay pending while the recipient is limited")
+	}
+
+	// A minute later a token has refilled
+	c.buckets["recipient"].updated = time.Now().Add(-time.Minute)
+	c.flush(held)
+	if len(*delivered) != 3 || (*delivered)[2].latest.ID != "reaction-alice-2" {
+		t.Errorf("expected the held reaction once a token refilled, got %d deliveries", len(*delivered))
+	}
+
+	// Other recipients have their own bucket
+	c.add(coalesceKey{recipient: "other", reference: "note-0", category: "kind_7"}, reaction("alice", "note-0", 9))
+	if len(*delivered) != 4 {
+		t.Errorf("expected a different recipient not to be limited")
+	}
+}
+
+func TestCoalescerWithoutWindowDropsLimitedEvents(t *testing.T) {
+	var delivered int
+	c := newCoalescer(0, 1, 1, func(string, *coalescedBatch) bool {
+		delivered++
+		return true
+	})
+
+	c.add(coalesceKey{recipient: "recipient", reference: "note", category: "kind_7"}, reaction("alice", "note", 0))
+	c.add(coalesceKey{recipient: "recipient", reference: "note", category: "kind_7"}, reaction("bob", "note", 0))
+	if delivered != 1 || len(c.groups) != 0 {
+		t.Errorf("expected every event to be handled on its own and limited ones dropped, got %d deliveries", delivered)
+	}
+}
+
+func TestSummaryMessage(t *testing.T) {
+	note := &nostr.Event{ID: "note", Kind: 1, PubKey: "recipient", Content: "my note"}
+	service := newTestPushService([]*nostr.Event{note})
+	service.nameCache["alice"] = "Alice"
+
+	batch := &coalescedBatch{}
+	for i := 0; i < 12; i++ {
+		batch.add(reaction(fmt.Sprintf("sender-%d", i), "note", i))
+	}
+	batch.add(reaction("alice", "note", 0))
+
+	message := service.formatSummaryMessage(batch, "recipient")
+	if message.Body != "Alice and 12 others reacted to your note" {
+		t.Errorf("unexpected summary: %q", message.Body)
+	}
+	if message.Data["collapsed_count"] != 13 || message.Data["referenced_event_id"] != "note" {
+		t.Errorf("unexpected summary data: %v", message.Data)
+	}
+
+	dms := &coalescedBatch{}
+	dms.add(&nostr.Event{ID: "wrap-1", Kind: 1059, PubKey: "ephemeral-1"})
+	dms.add(&nostr.Event{ID: "wrap-2", Kind: 1059, PubKey: "ephemeral-2"})
+	if message := service.formatSummaryMessage(dms, "recipient"); message.Body != "You have 2 new encrypted messages" {
+		t.Errorf("unexpected direct message summary: %q", message.Body)
+	}
+}
@@ -1,17 +1,266 @@
 package push
 
 import (
+	"bytes"
+	"crypto/rsa"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
 
 	"github.com/HORNET-Storage/hornet-storage/lib/logging"
 	"github.com/HORNET-Storage/hornet-storage/lib/types"
 )
 
-// NewFCMClient creates a new FCM client (simplified mock implementation for now)
+const (
+	fcmScope           = "https://www.googleapis.com/auth/firebase.messaging"
+	fcmEndpoint        = "https://fcm.googleapis.com"
+	defaultFCMTokenURI = "https://oauth2.googleapis.com/token"
+
+	// Access tokens are refreshed this long before Google says they expire
+	fcmTokenRefreshMargin = time.Minute
+)
+
+// ErrInvalidDeviceToken means the push provider rejected the device token for good,
+// so the device should be deactivated instead of retried
+var ErrInvalidDeviceToken = errors.New("device token is no longer valid")
+
+// fcmServiceAccount is the subset of a Google service account key file needed for OAuth2
+type fcmServiceAccount struct {
+	ProjectID    string `json:"project_id"`
+	PrivateKeyID string `json:"private_key_id"`
+	PrivateKey   string `json:"private_key"`
+	ClientEmail  string `json:"client_email"`
+	TokenURI     string `json:"token_uri"`
+}
+
+// NewFCMClient creates a new FCM client
 func NewFCMClient(config *types.FCMConfig) (FCMClient, error) {
-	// For now, return mock client until we add proper FCM dependencies
-	logging.Infof("Creating FCM client (mock mode) for project ID: %s", config.ProjectID)
-	return &MockFCMClient{projectID: config.ProjectID}, nil
+	// Check if we have the necessary configuration for real FCM
+	if config.CredentialsPath == "" {
+		logging.Warn("⚠️ FCM Configuration Missing - Using Mock Client", map[string]interface{}{
+			"message":    "Real FCM requires a service account credentials_path",
+			"project_id": config.ProjectID,
+		})
+		return &MockFCMClient{projectID: config.ProjectID}, nil
+	}
+
+	data, err := os.ReadFile(config.CredentialsPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read FCM credentials from %s: %w", config.CredentialsPath, err)
+	}
+
+	client, err := newRealFCMClient(data, config.ProjectID)
+	if err != nil {
+		return nil, err
+	}
+
+	logging.Infof("✅ FCM Client Initialized (Project: %s)", client.projectID)
+
+	return client, nil
+}
+
+func newRealFCMClient(credentials []byte, projectID string) (*RealFCMClient, error) {
+	var account fcmServiceAccount
+	if err := json.Unmarshal(credentials, &account); err != nil {
+		return nil, fmt.Errorf("failed to parse FCM credentials: %w", err)
+	}
+	if account.ClientEmail == "" || account.PrivateKey == "" {
+		return nil, fmt.Errorf("FCM credentials must include client_email and private_key")
+	}
+
+	privateKey, err := jwt.ParseRSAPrivateKeyFromPEM([]byte(account.PrivateKey))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse FCM private key: %w", err)
+	}
+
+	if projectID == "" {
+		projectID = account.ProjectID
+	}
+	if projectID == "" {
+		return nil, fmt.Errorf("FCM project_id is not configured")
+	}
+
+	tokenURI := account.TokenURI
+	if tokenURI == "" {
+		tokenURI = defaultFCMTokenURI
+	}
+
+	return &RealFCMClient{
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+		endpoint:   fcmEndpoint,
+		projectID:  projectID,
+		account:    account,
+		tokenURI:   tokenURI,
+		signer:     privateKey,
+	}, nil
+}
+
+// RealFCMClient sends notifications through the FCM HTTP v1 API using a service account
+type RealFCMClient struct {
+	httpClient *http.Client
+	endpoint   string
+	projectID  string
+	account    fcmServiceAccount
+	tokenURI   string
+	signer     *rsa.PrivateKey
+
+	tokenMutex  sync.Mutex
+	accessToken string
+	tokenExpiry time.Time
+}
+
+// SendNotification sends a push notification to an Android device
+func (c *RealFCMClient) SendNotification(deviceToken string, message *PushMessage) error {
+	accessToken, err := c.token()
+	if err != nil {
+		return err
+	}
+
+	body, err := json.Marshal(map[string]interface{}{
+		"message": message.ToFCMMessage(deviceToken),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode FCM message: %w", err)
+	}
+
+	sendURL := fmt.Sprintf("%s/v1/projects/%s/messages:send", c.endpoint, url.PathEscape(c.projectID))
+	req, err := http.NewRequest(http.MethodPost, sendURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := c.httpClient.Do(req)
+	if err != nil {
+		logging.Errorf("Failed to send FCM notification: %v", err)
+		return err
+	}
+	defer res.Body.Close()
+
+	response, _ := io.ReadAll(io.LimitReader(res.Body, 64*1024))
+
+	if res.StatusCode == http.StatusOK {
+		var sent struct {
+			Name string `json:"name"`
+		}
+		json.Unmarshal(response, &sent)
+		logging.Infof("🚀 FCM Notification Sent: %s", sent.Name)
+		return nil
+	}
+
+	if res.StatusCode == http.StatusUnauthorized {
+		// Force a fresh access token on the retry
+		c.tokenMutex.Lock()
+		c.accessToken = ""
+		c.tokenMutex.Unlock()
+	}
+
+	fcmErr := parseFCMError(response)
+	logging.Warn("⚠️ FCM Notification Failed", map[string]interface{}{
+		"status":     res.StatusCode,
+		"error":      fcmErr.Status,
+		"error_code": fcmErr.ErrorCode,
+		"message":    fcmErr.Message,
+	})
+
+	if fcmErr.ErrorCode == "UNREGISTERED" || fcmErr.ErrorCode == "INVALID_ARGUMENT" || fcmErr.Status == "INVALID_ARGUMENT" {
+		return fmt.Errorf("FCM notification failed: %s: %w", fcmErr.Message, ErrInvalidDeviceToken)
+	}
+	return fmt.Errorf("FCM notification failed: %d %s %s", res.StatusCode, fcmErr.Status, fcmErr.Message)
+}
+
+// token returns a cached OAuth2 access token, exchanging a fresh service account JWT when it expires
+func (c *RealFCMClient) token() (string, error) {
+	c.tokenMutex.Lock()
+	defer c.tokenMutex.Unlock()
+
+	if c.accessToken != "" && time.Now().Add(fcmTokenRefreshMargin).Before(c.tokenExpiry) {
+		return c.accessToken, nil
+	}
+
+	now := time.Now()
+	assertion := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss":   c.account.ClientEmail,
+		"scope": fcmScope,
+		"aud":   c.tokenURI,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	})
+	if c.account.PrivateKeyID != "" {
+		assertion.Header["kid"] = c.account.PrivateKeyID
+	}
+	signed, err := assertion.SignedString(c.signer)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign FCM token request: %w", err)
+	}
+
+	form := url.Values{
+		"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+		"assertion":  {signed},
+	}
+	res, err := c.httpClient.PostForm(c.tokenURI, form)
+	if err != nil {
+		return "", fmt.Errorf("failed to request FCM access token: %w", err)
+	}
+	defer res.Body.Close()
+
+	var token struct {
+		AccessToken      string `json:"access_token"`
+		ExpiresIn        int    `json:"expires_in"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.NewDecoder(io.LimitReader(res.Body, 64*1024)).Decode(&token); err != nil {
+		return "", fmt.Errorf("failed to decode FCM access token response: %w", err)
+	}
+	if res.StatusCode != http.StatusOK || token.AccessToken == "" {
+		return "", fmt.Errorf("FCM access token request failed: %d %s %s", res.StatusCode, token.Error, token.ErrorDescription)
+	}
+
+	c.accessToken = token.AccessToken
+	c.tokenExpiry = now.Add(time.Duration(token.ExpiresIn) * time.Second)
+	return c.accessToken, nil
+}
+
+// fcmError is the useful part of an FCM v1 error response
+type fcmError struct {
+	Status    string
+	Message   string
+	ErrorCode string // From the FcmError detail, e.g. UNREGISTERED
+}
+
+func parseFCMError(body []byte) fcmError {
+	var response struct {
+		Error struct {
+			Message string `json:"message"`
+			Status  string `json:"status"`
+			Details []struct {
+				Type      string `json:"@type"`
+				ErrorCode string `json:"errorCode"`
+			} `json:"details"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return fcmError{Message: strings.TrimSpace(string(body))}
+	}
+
+	parsed := fcmError{Status: response.Error.Status, Message: response.Error.Message}
+	for _, detail := range response.Error.Details {
+		if strings.HasSuffix(detail.Type, "google.firebase.fcm.v1.FcmError") {
+			parsed.ErrorCode = detail.ErrorCode
+		}
+	}
+	return parsed
 }
 
 // MockFCMClient is a mock implementation for testing
@@ -42,3 +291,47 @@ func (m *MockFCMClient) SendNotification(deviceToken string, message *PushMessag
 
 	return nil
 }
+
+// Helper to convert PushMessage to an FCM v1 message. FCM data values must be strings.
+func (m *PushMessage) ToFCMMessage(deviceToken string) map[string]interface{} {
+	data := make(map[string]string, len(m.Data))
+	for k, v := range m.Data {
+		switch value := v.(type) {
+		case string:
+			data[k] = value
+		default:
+			encoded, err := json.Marshal(value)
+			if err != nil {
+				continue
+			}
+			data[k] = string(encoded)
+		}
+	}
+
+	notification := map[string]interface{}{}
+	if m.Sound != "" {
+		notification["sound"] = m.Sound
+	}
+	if m.Badge > 0 {
+		notification["notification_count"] = m.Badge
+	}
+	if m.Category != "" {
+		notification["click_action"] = m.Category
+	}
+
+	message := map[string]interface{}{
+		"token": deviceToken,
+		"notification": map[string]interface{}{
+			"title": m.Title,
+			"body":  m.Body,
+		},
+		"android": map[string]interface{}{
+			"priority":     "high",
+			"notification": notification,
+		},
+	}
+	if len(data) > 0 {
+		message["data"] = data
+	}
+	return message
+}
@@ -0,0 +1,219 @@
+package push
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/nbd-wtf/go-nostr"
+
+	"github.com/HORNET-Storage/hornet-storage/lib/stores/statistics"
+	"github.com/HORNET-Storage/hornet-storage/lib/types"
+)
+
+// fcmStub stands in for Google's token endpoint and the FCM v1 send endpoint
+type fcmStub struct {
+	server       *httptest.Server
+	publicKey    *rsa.PublicKey
+	mu           sync.Mutex
+	tokenCalls   int
+	messages     []map[string]interface{}
+	unregistered map[string]bool
+}
+
+func startFCMStub(t *testing.T, publicKey *rsa.PublicKey) *fcmStub {
+	stub := &fcmStub{publicKey: publicKey, unregistered: map[string]bool{}}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		claims := jwt.MapClaims{}
+		_, err := jwt.ParseWithClaims(r.Form.Get("assertion"), claims, func(token *jwt.Token) (interface{}, error) {
+			return stub.publicKey, nil
+		})
+		if err != nil || claims["scope"] != fcmScope || claims["iss"] != "push@example.iam.gserviceaccount.com" {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+
+		stub.mu.Lock()
+		stub.tokenCalls++
+		stub.mu.Unlock()
+		json.NewEncoder(w).Encode(map[string]interface{}{"access_token": "stub-access-token", "expires_in": 3600})
+	})
+	mux.HandleFunc("/v1/projects/test-project/messages:send", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer stub-access-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		var body struct {
+			Message map[string]interface{} `json:"message"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+
+		if stub.unregistered[body.Message["token"].(string)] {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error":{"code":404,"message":"Requested entity was not found.","status":"NOT_FOUND",
+				"details":[{"@type":"type.googleapis.com/google.firebase.fcm.v1.FcmError","errorCode":"UNREGISTERED"}]}}`))
+			return
+		}
+
+		stub.mu.Lock()
+		stub.messages = append(stub.messages, body.Message)
+		stub.mu.Unlock()
+		w.Write([]byte(`{"name":"projects/test-project/messages/1"}`))
+	})
+
+	stub.server = httptest.NewServer(mux)
+	t.Cleanup(stub.server.Close)
+	return stub
+}
+
+func newTestFCMClient(t *testing.T) (*RealFCMClient, *fcmStub) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	stub := startFCMStub(t, &key.PublicKey)
+
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: mustPKCS8(t, key)})
+	credentials, _ := json.Marshal(map[string]string{
+		"type":           "service_account",
+		"project_id":     "test-project",
+		"private_key_id": "key-1",
+		"private_key":    string(keyPEM),
+		"client_email":   "push@example.iam.gserviceaccount.com",
+		"token_uri":      stub.server.URL + "/token",
+	})
+
+	client, err := newRealFCMClient(credentials, "")
+	if err != nil {
+		t.Fatalf("newRealFCMClient: %v", err)
+	}
+	client.endpoint = stub.server.URL
+	return client, stub
+}
+
+func mustPKCS8(t *testing.T, key *rsa.PrivateKey) []byte {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("MarshalPKCS8PrivateKey: %v", err)
+	}
+	return der
+}
+
+func TestFCMSendsV1MessagesWithCachedToken(t *testing.T) {
+	client, stub := newTestFCMClient(t)
+
+	message := &PushMessage{
+		Title:    "New reply",
+		Body:     "hello",
+		Badge:    2,
+		Sound:    "default",
+		Category: "REPLY",
+		Data:     map[string]interface{}{"event_id": "abc", "kind": 1},
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := client.SendNotification("device-token", message); err != nil {
+			t.Fatalf("SendNotification: %v", err)
+		}
+	}
+
+	if stub.tokenCalls != 1 {
+		t.Errorf("expected the access token to be cached, got %d token exchanges", stub.tokenCalls)
+	}
+	if len(stub.messages) != 2 {
+		t.Fatalf("expected two messages, got %d", len(stub.messages))
+	}
+
+	sent := stub.messages[0]
+	notification := sent["notification"].(map[string]interface{})
+	data := sent["data"].(map[string]interface{})
+	android := sent["android"].(map[string]interface{})["notification"].(map[string]interface{})
+	if sent["token"] != "device-token" || notification["title"] != "New reply" || notification["body"] != "hello" {
+		t.Errorf("unexpected message: %v", sent)
+	}
+	if data["event_id"] != "abc" || data["kind"] != "1" {
+		t.Errorf("expected data values to be strings, got %v", data)
+	}
+	if android["sound"] != "default" || android["notification_count"] != float64(2) || android["click_action"] != "REPLY" {
+		t.Errorf("unexpected android notification: %v", android)
+	}
+}
+
+// deviceStatusStore records device deactivations for the worker test
+type deviceStatusStore struct {
+	statistics.StatisticsStore
+	deactivated []string
+}
+
+func (s *deviceStatusStore) LogPushNotification(log *types.PushNotificationLog) error {
+	log.ID = 1
+	return nil
+}
+
+func (s *deviceStatusStore) UpdatePushNotificationDelivery(id uint, delivered bool, errorMessage string) error {
+	return nil
+}
+
+func (s *deviceStatusStore) UpdatePushDeviceStatus(deviceToken string, isActive bool) error {
+	if !isActive {
+		s.deactivated = append(s.deactivated, deviceToken)
+	}
+	return nil
+}
+
+type deviceStatusPushStore struct {
+	mockPushStore
+	stats *deviceStatusStore
+}
+
+func (s *deviceStatusPushStore) GetStatsStore() statistics.StatisticsStore {
+	return s.stats
+}
+
+func TestFCMUnregisteredTokenDeactivatesDevice(t *testing.T) {
+	client, stub := newTestFCMClient(t)
+	stub.unregistered["stale-token"] = true
+
+	err := client.SendNotification("stale-token", &PushMessage{Title: "t", Body: "b"})
+	if !errors.Is(err, ErrInvalidDeviceToken) || !strings.Contains(err.Error(), "not found") {
+		t.Fatalf("expected an invalid device token error, got %v", err)
+	}
+
+	stats := &deviceStatusStore{}
+	service := &PushService{
+		store:     &deviceStatusPushStore{stats: stats},
+		config:    &types.PushNotificationConfig{Service: types.PushServiceConfig{RetryAttempts: 3}},
+		queue:     make(chan *NotificationTask, 1),
+		fcmClient: client,
+	}
+	worker := NewWorker(1, service.queue, service)
+
+	worker.processTask(&NotificationTask{
+		Pubkey:      "recipient",
+		Event:       &nostr.Event{ID: "event", Kind: 1},
+		DeviceToken: "stale-token",
+		Platform:    "android",
+		Message:     &PushMessage{Title: "t", Body: "b"},
+	})
+
+	if len(stats.deactivated) != 1 || stats.deactivated[0] != "stale-token" {
+		t.Errorf("expected the stale token to be deactivated on the first failure, got %v", stats.deactivated)
+	}
+	if len(service.queue) != 0 {
+		t.Error("expected a rejected token not to be retried")
+	}
+}
@@ -0,0 +1,208 @@
+package push
+
+import (
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/nbd-wtf/go-nostr"
+
+	"github.com/HORNET-Storage/hornet-storage/lib/logging"
+	"github.com/HORNET-Storage/hornet-storage/lib/types"
+)
+
+const (
+	muteListKind = 10000
+
+	// Authors whose contact lists are remembered for follow notifications
+	maxFollowSnapshots = 10000
+	preferencesBatch   = 500
+)
+
+// preferences returns the recipient's stored preferences, or the defaults if they never set any
+func (ps *PushService) preferences(pubkey string) types.PushPreferences {
+	statsStore := ps.store.GetStatsStore()
+	if statsStore == nil {
+		return types.DefaultPushPreferences(pubkey)
+	}
+
+	preferences, err := statsStore.GetPushPreferences(pubkey)
+	if err != nil {
+		logging.Errorf("Failed to get push preferences for %s: %v", pubkey, err)
+	}
+	if preferences == nil {
+		return types.DefaultPushPreferences(pubkey)
+	}
+	return *preferences
+}
+
+// allowNotification applies the recipient's kind opt-ins, quiet hours and mute list
+func (ps *PushService) allowNotification(recipient string, event *nostr.Event, now time.Time) bool {
+	preferences := ps.preferences(recipient)
+
+	if !preferences.AllowsKind(event.Kind) {
+		logging.Infof("🔕 %s has not opted in to kind %d notifications", shortenPubkey(recipient), event.Kind)
+		return false
+	}
+
+	if preferences.InQuietHours(now) {
+		logging.Infof("🔕 Quiet hours for %s, skipping kind %d notification", shortenPubkey(recipient), event.Kind)
+		return false
+	}
+
+	if ps.isMuted(recipient, event) {
+		logging.Infof("🔕 %s muted the sender or thread of event %s", shortenPubkey(recipient), event.ID)
+		return false
+	}
+
+	return true
+}
+
+// isMuted checks the public p and e tags of the recipient's kind 10000 mute list against
+// the sender and every event the notification references. Private mutes are encrypted to
+// the user and cannot be read by the relay.
+func (ps *PushService) isMuted(recipient string, event *nostr.Event) bool {
+	events, err := ps.store.QueryEvents(nostr.Filter{
+		Kinds:   []int{muteListKind},
+		Authors: []string{recipient},
+	})
+	if err != nil || len(events) == 0 {
+		return false
+	}
+
+	muteList := events[0]
+	for _, candidate := range events[1:] {
+		if candidate.CreatedAt > muteList.CreatedAt {
+			muteList = candidate
+		}
+	}
+
+	mutedPubkeys := make(map[string]bool)
+	mutedEvents := make(map[string]bool)
+	for _, tag := range muteList.Tags {
+		if len(tag) < 2 {
+			continue
+		}
+		switch tag[0] {
+		case "p":
+			mutedPubkeys[tag[1]] = true
+		case "e":
+			mutedEvents[tag[1]] = true
+		}
+	}
+
+	if sender := eventSender(event); sender != "" && mutedPubkeys[sender] {
+		return true
+	}
+	if mutedEvents[event.ID] {
+		return true
+	}
+	for _, tag := range event.Tags {
+		if len(tag) >= 2 && tag[0] == "e" && mutedEvents[tag[1]] {
+			return true
+		}
+	}
+	return false
+}
+
+// eventSender returns the pubkey that caused a notification. Zap receipts are signed by
+// the recipient's wallet provider, so the sender comes from the embedded zap request.
+// Gift wraps are signed with a throwaway key, so their sender is unknown.
+func eventSender(event *nostr.Event) string {
+	switch event.Kind {
+	case 1059:
+		return ""
+	case 9735:
+		if request := zapRequest(event); request != nil {
+			return request.PubKey
+		}
+		if tag := event.Tags.GetFirst([]string{"P", ""}); tag != nil {
+			return (*tag)[1]
+		}
+		return ""
+	default:
+		return event.PubKey
+	}
+}
+
+// zapRequest decodes the NIP-57 zap request carried in a receipt's description tag
+func zapRequest(event *nostr.Event) *nostr.Event {
+	tag := event.Tags.GetFirst([]string{"description", ""})
+	if tag == nil {
+		return nil
+	}
+
+	var request nostr.Event
+	if err := json.Unmarshal([]byte((*tag)[1]), &request); err != nil {
+		return nil
+	}
+	return &request
+}
+
+// newFollows returns the users who opted in to follow notifications and appear in an
+// updated contact list but not in the author's previous one. Older lists are replaced in
+// the store before the event reaches us, so previous lists are remembered here, and only
+// for opted-in users. An author seen for the first time notifies every opted-in entry.
+func (ps *PushService) newFollows(event *nostr.Event) []string {
+	statsStore := ps.store.GetStatsStore()
+	if statsStore == nil {
+		return nil
+	}
+
+	var followed []string
+	for _, tag := range event.Tags {
+		if len(tag) >= 2 && tag[0] == "p" && tag[1] != event.PubKey {
+			followed = append(followed, tag[1])
+		}
+	}
+
+	optedIn := make(map[string]bool)
+	for start := 0; start < len(followed); start += preferencesBatch {
+		batch := followed[start:min(start+preferencesBatch, len(followed))]
+		preferences, err := statsStore.GetPushPreferencesByPubkeys(batch)
+		if err != nil {
+			logging.Errorf("Failed to get push preferences for contact list %s: %v", event.ID, err)
+			return nil
+		}
+		for _, preference := range preferences {
+			if preference.Follows {
+				optedIn[preference.Pubkey] = true
+			}
+		}
+	}
+
+	ps.followMutex.Lock()
+	defer ps.followMutex.Unlock()
+
+	if ps.followSnapshots == nil || len(ps.followSnapshots) >= maxFollowSnapshots {
+		ps.followSnapshots = make(map[string]map[string]bool)
+	}
+
+	previous := ps.followSnapshots[event.PubKey]
+	ps.followSnapshots[event.PubKey] = optedIn
+
+	var added []string
+	for pubkey := range optedIn {
+		if !previous[pubkey] {
+			added = append(added, pubkey)
+		}
+	}
+	return added
+}
+
+// zapAmountSats returns the amount from the zap request's msat amount tag, or 0 when absent
+func zapAmountSats(event *nostr.Event) int64 {
+	request := zapRequest(event)
+	if request == nil {
+		return 0
+	}
+	tag := request.Tags.GetFirst([]string{"amount", ""})
+	if tag == nil {
+		return 0
+	}
+	msats, err := strconv.ParseInt((*tag)[1], 10, 64)
+	if err != nil {
+		return 0
+	}
+	return msats / 1000
+}
@@ -0,0 +1,168 @@
+package push
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/nbd-wtf/go-nostr"
+
+	"github.com/HORNET-Storage/hornet-storage/lib/stores/statistics"
+	"github.com/HORNET-Storage/hornet-storage/lib/types"
+)
+
+// preferencesStatsStore serves stored push preferences for the preference tests
+type preferencesStatsStore struct {
+	statistics.StatisticsStore
+	preferences map[string]types.PushPreferences
+}
+
+func (s *preferencesStatsStore) GetPushPreferences(pubkey string) (*types.PushPreferences, error) {
+	if preferences, ok := s.preferences[pubkey]; ok {
+		return &preferences, nil
+	}
+	return nil, nil
+}
+
+func (s *preferencesStatsStore) GetPushPreferencesByPubkeys(pubkeys []string) ([]types.PushPreferences, error) {
+	var found []types.PushPreferences
+	for _, pubkey := range pubkeys {
+		if preferences, ok := s.preferences[pubkey]; ok {
+			found = append(found, preferences)
+		}
+	}
+	return found, nil
+}
+
+type preferencesPushStore struct {
+	mockPushStore
+	stats *preferencesStatsStore
+}
+
+func (s *preferencesPushStore) GetStatsStore() statistics.StatisticsStore {
+	return s.stats
+}
+
+func newPreferencesTestService(events []*nostr.Event, preferences ...types.PushPreferences) *PushService {
+	stats := &preferencesStatsStore{preferences: map[string]types.PushPreferences{}}
+	for _, preference := range preferences {
+		stats.preferences[preference.Pubkey] = preference
+	}
+	return &PushService{
+		store:     &preferencesPushStore{mockPushStore: mockPushStore{events: events}, stats: stats},
+		nameCache: map[string]string{},
+	}
+}
+
+func TestKindOptIns(t *testing.T) {
+	service := newPreferencesTestService(nil, types.PushPreferences{Pubkey: "zapper-fan", Zaps: true})
+	now := time.Now()
+
+	// Users without preferences keep the original behaviour: no zaps or follows
+	if !service.allowNotification("default-user", &nostr.Event{Kind: 1}, now) {
+		t.Error("expected replies to notify by default")
+	}
+	if service.allowNotification("default-user", &nostr.Event{Kind: 9735}, now) {
+		t.Error("expected zaps to be opt-in")
+	}
+	if service.allowNotification("default-user", &nostr.Event{Kind: 3}, now) {
+		t.Error("expected follows to be opt-in")
+	}
+
+	if !service.allowNotification("zapper-fan", &nostr.Event{Kind: 9735}, now) {
+		t.Error("expected zaps to notify a user who opted in")
+	}
+	if service.allowNotification("zapper-fan", &nostr.Event{Kind: 7}, now) {
+		t.Error("expected reactions to be off for a user who only enabled zaps")
+	}
+}
+
+func TestQuietHours(t *testing.T) {
+	preferences := types.DefaultPushPreferences("sleeper")
+	preferences.Timezone = "America/New_York"
+	preferences.QuietHours = []types.QuietHoursWindow{{Start: "22:00", End: "07:00"}}
+	if err := preferences.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+
+	service := newPreferencesTestService(nil, preferences)
+	newYork, _ := time.LoadLocation("America/New_York")
+
+	if service.allowNotification("sleeper", &nostr.Event{Kind: 1}, time.Date(2026, 1, 10, 23, 30, 0, 0, newYork)) {
+		t.Error("expected no notification late in the evening")
+	}
+	if service.allowNotification("sleeper", &nostr.Event{Kind: 1}, time.Date(2026, 1, 11, 6, 59, 0, 0, newYork)) {
+		t.Error("expected no notification before the window ends after midnight")
+	}
+	if !service.allowNotification("sleeper", &nostr.Event{Kind: 1}, time.Date(2026, 1, 11, 7, 0, 0, 0, newYork)) {
+		t.Error("expected notifications once the window ends")
+	}
+	// 23:30 UTC is 18:30 in New York, outside the window
+	if !service.allowNotification("sleeper", &nostr.Event{Kind: 1}, time.Date(2026, 1, 10, 23, 30, 0, 0, time.UTC)) {
+		t.Error("expected quiet hours to use the recipient's timezone")
+	}
+
+	invalid := types.PushPreferences{QuietHours: []types.QuietHoursWindow{{Start: "25:00", End: "07:00"}}}
+	if invalid.Validate() == nil {
+		t.Error("expected an invalid window to be rejected")
+	}
+}
+
+func TestMuteList(t *testing.T) {
+	muteList := &nostr.Event{
+		ID:        "mute-list",
+		Kind:      muteListKind,
+		PubKey:    "recipient",
+		CreatedAt: nostr.Now(),
+		Tags:      nostr.Tags{{"p", "muted-author"}, {"e", "muted-thread"}},
+	}
+	service := newPreferencesTestService([]*nostr.Event{muteList})
+	now := time.Now()
+
+	if service.allowNotification("recipient", &nostr.Event{ID: "a", Kind: 7, PubKey: "muted-author"}, now) {
+		t.Error("expected reactions from a muted pubkey to be dropped")
+	}
+	reply := &nostr.Event{ID: "b", Kind: 1, PubKey: "someone", Tags: nostr.Tags{{"e", "muted-thread", "", "root"}}}
+	if service.allowNotification("recipient", reply, now) {
+		t.Error("expected replies in a muted thread to be dropped")
+	}
+	if !service.allowNotification("recipient", &nostr.Event{ID: "c", Kind: 1, PubKey: "someone"}, now) {
+		t.Error("expected other replies to notify")
+	}
+
+	// A zap's sender is the zap request author, not the wallet provider that signed the receipt
+	request, _ := json.Marshal(nostr.Event{Kind: 9734, PubKey: "muted-author", Tags: nostr.Tags{{"amount", "21000"}}})
+	zap := &nostr.Event{ID: "d", Kind: 9735, PubKey: "wallet", Tags: nostr.Tags{{"p", "recipient"}, {"description", string(request)}}}
+	if sender := eventSender(zap); sender != "muted-author" {
+		t.Errorf("expected the zap sender to come from the zap request, got %q", sender)
+	}
+	if amount := zapAmountSats(zap); amount != 21 {
+		t.Errorf("expected 21 sats, got %d", amount)
+	}
+
+	zapFan := types.DefaultPushPreferences("recipient")
+	zapFan.Zaps = true
+	service = newPreferencesTestService([]*nostr.Event{muteList}, zapFan)
+	if service.allowNotification("recipient", zap, now) {
+		t.Error("expected zaps from a muted pubkey to be dropped")
+	}
+}
+
+func TestNewFollowsOnlyNotifiesAddedOptedInUsers(t *testing.T) {
+	fan := types.DefaultPushPreferences("fan")
+	fan.Follows = true
+	other := types.DefaultPushPreferences("other-fan")
+	other.Follows = true
+	service := newPreferencesTestService(nil, fan, other, types.DefaultPushPreferences("no-follows"))
+
+	first := &nostr.Event{Kind: 3, PubKey: "follower", Tags: nostr.Tags{{"p", "fan"}, {"p", "no-follows"}, {"p", "stranger"}}}
+	if recipients := service.getNotificationRecipients(first); len(recipients) != 1 || recipients[0] != "fan" {
+		t.Fatalf("expected only the opted-in user to be notified, got %v", recipients)
+	}
+
+	// Updating the list again only notifies users who were just added
+	second := &nostr.Event{Kind: 3, PubKey: "follower", Tags: nostr.Tags{{"p", "fan"}, {"p", "other-fan"}}}
+	if recipients := service.getNotificationRecipients(second); len(recipients) != 1 || recipients[0] != "other-fan" {
+		t.Fatalf("expected only the new follow to be notified, got %v", recipients)
+	}
+}
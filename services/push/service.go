@@ -5,8 +5,10 @@ import (
 	"encoding/json"
 	"fmt"
 	"sync"
+	"time"
 
 	"github.com/HORNET-Storage/hornet-storage/lib/config"
+	"github.com/HORNET-Storage/hornet-storage/lib/eventbus"
 	"github.com/HORNET-Storage/hornet-storage/lib/logging"
 	"github.com/HORNET-Storage/hornet-storage/lib/stores"
 	"github.com/HORNET-Storage/hornet-storage/lib/types"
@@ -15,31 +17,38 @@ import (
 
 // PushService manages push notifications
 type PushService struct {
-	store        stores.Store
-	config       *types.PushNotificationConfig
-	queue        chan *NotificationTask
-	workers      []*Worker
-	ctx          context.Context
-	cancel       context.CancelFunc
-	wg           sync.WaitGroup
-	mutex        sync.RWMutex
-	apnsClient   APNSClient
-	fcmClient    FCMClient
-	isRunning    bool
-	nameCache    map[string]string // Cache for author names (pubkey -> name)
-	cacheMutex   sync.RWMutex       // Mutex for cache access
-	processedIDs map[string]bool    // Track processed event IDs to prevent duplicates
-	idMutex      sync.RWMutex       // Mutex for processed IDs
+	store         stores.Store
+	config        *types.PushNotificationConfig
+	queue         chan *NotificationTask
+	workers       []*Worker
+	ctx           context.Context
+	cancel        context.CancelFunc
+	wg            sync.WaitGroup
+	mutex         sync.RWMutex
+	apnsClient    APNSClient
+	fcmClient     FCMClient
+	webPushClient WebPushClient
+	coalescer     *coalescer // Merges bursts and rate limits recipients, nil delivers every event
+	isRunning     bool
+	nameCache     map[string]string             // Cache for author names (pubkey -> name)
+	cacheMutex    sync.RWMutex                  // Mutex for cache access
+	processedIDs  map[string]bool               // Track processed event IDs to prevent duplicates
+	idMutex       sync.RWMutex                  // Mutex for processed IDs
+	subscription  *eventbus.DurableSubscription // Live feed of accepted events from every transport
+
+	followSnapshots map[string]map[string]bool // Author -> opted-in users in their last contact list
+	followMutex     sync.Mutex
 }
 
 // NotificationTask represents a push notification task
 type NotificationTask struct {
-	Pubkey      string
-	Event       *nostr.Event
-	DeviceToken string
-	Platform    string
-	Message     *PushMessage
-	Attempts    int
+	Pubkey       string
+	Event        *nostr.Event
+	DeviceToken  string
+	Platform     string
+	Subscription *WebPushSubscription // Web devices only
+	Message      *PushMessage
+	Attempts     int
 }
 
 // PushMessage represents the formatted push notification message
@@ -51,6 +60,7 @@ type PushMessage struct {
 	Category       string
 	Data           map[string]interface{}
 	MutableContent bool // iOS-specific: allows app to modify notification before display
+	Collapsed      int  // Number of events merged into this message, recorded in the log
 }
 
 // ReferencedEventInfo contains details about the event being reacted to, reposted, or replied to
@@ -95,6 +105,15 @@ func NewPushService(store stores.Store) (*PushService, error) {
 		processedIDs: make(map[string]bool),
 	}
 
+	coalesceWindow, err := time.ParseDuration(cfg.PushNotifications.Service.CoalesceWindow)
+	if err != nil {
+		coalesceWindow = 0 // Coalescing disabled
+	}
+	service.coalescer = newCoalescer(coalesceWindow,
+		cfg.PushNotifications.Service.RateLimitPerMinute,
+		cfg.PushNotifications.Service.RateLimitBurst,
+		service.deliverBatch)
+
 	// Initialize APNs client if enabled
 	if cfg.PushNotifications.APNS.Enabled {
 		apnsClient, err := NewAPNSClient(&cfg.PushNotifications.APNS)
@@ -115,6 +134,16 @@ func NewPushService(store stores.Store) (*PushService, error) {
 		service.fcmClient = fcmClient
 	}
 
+	// Initialize Web Push client if enabled
+	if cfg.PushNotifications.WebPush.Enabled {
+		webPushClient, err := NewWebPushClient(&cfg.PushNotifications.WebPush)
+		if err != nil {
+			cancel()
+			return nil, fmt.Errorf("failed to initialize Web Push client: %w", err)
+		}
+		service.webPushClient = webPushClient
+	}
+
 	return service, nil
 }
 
@@ -138,6 +167,11 @@ func (ps *PushService) Start() error {
 	}
 
 	ps.isRunning = true
+	// Push must keep receiving events even if it briefly falls behind
+	ps.subscription = eventbus.SubscribeDurable("push", nostr.Filters{{}}, func(event *nostr.Event) error {
+		ps.ProcessEvent(event)
+		return nil
+	})
 	logging.Infof("Push notification service started successfully")
 	return nil
 }
@@ -153,6 +187,16 @@ func (ps *PushService) Stop() {
 
 	logging.Infof("Stopping push notification service...")
 
+	if ps.subscription != nil {
+		ps.subscription.Close()
+		ps.subscription = nil
+	}
+
+	// Drop held notifications before the queue closes
+	if ps.coalescer != nil {
+		ps.coalescer.stop()
+	}
+
 	// Signal cancellation
 	ps.cancel()
 
@@ -210,54 +254,113 @@ func (ps *PushService) ProcessEvent(event *nostr.Event) {
 
 	for _, pubkey := range recipients {
 		// Avoid notifying yourself
-		if pubkey == event.PubKey {
+		if pubkey == event.PubKey || pubkey == eventSender(event) {
 			continue
 		}
 
-		// Get devices for this user
-		// Need to get StatsStore first
-		statsStore := ps.store.GetStatsStore()
-		if statsStore == nil {
-			logging.Errorf("Stats store not available")
+		// Respect the recipient's opt-ins, quiet hours and mute list
+		if !ps.allowNotification(pubkey, event, time.Now()) {
 			continue
 		}
 
-		devices, err := statsStore.GetPushDevicesByPubkey(pubkey)
-		if err != nil {
-			logging.Errorf("Failed to get devices for pubkey %s: %v", pubkey, err)
-			continue
+		if !ps.notify(pubkey, event) {
+			return
 		}
+	}
+}
 
-		// Format notification message
-		message := ps.formatNotificationMessage(event, pubkey)
+// notify hands an event to the coalescer, which merges it with others for the same
+// recipient and subject. Returns false if the service is shutting down.
+func (ps *PushService) notify(recipient string, event *nostr.Event) bool {
+	if ps.coalescer == nil {
+		batch := &coalescedBatch{}
+		batch.add(event)
+		return ps.deliverBatch(recipient, batch)
+	}
 
-		// Queue notification for each device
-		for _, device := range devices {
-			task := &NotificationTask{
-				Pubkey:      pubkey,
-				Event:       event,
-				DeviceToken: device.DeviceToken,
-				Platform:    device.Platform,
-				Message:     message,
-				Attempts:    0,
-			}
+	key := coalesceKey{
+		recipient: recipient,
+		reference: referencedEventID(event),
+		category:  fmt.Sprintf("kind_%d", event.Kind),
+	}
+	return ps.coalescer.add(key, event)
+}
+
+// deliverBatch formats a single event or a summary of several and queues it
+func (ps *PushService) deliverBatch(recipient string, batch *coalescedBatch) bool {
+	var message *PushMessage
+	if batch.count == 1 {
+		message = ps.formatNotificationMessage(batch.latest, recipient)
+	} else {
+		message = ps.formatSummaryMessage(batch, recipient)
+	}
+	message.Collapsed = batch.count
+
+	return ps.queueNotification(recipient, batch.latest, message)
+}
+
+// NotifyPubkey sends a relay-generated message to every device registered for a pubkey.
+// The event is the one the notification is about and is included in the payload data.
+func (ps *PushService) NotifyPubkey(pubkey string, event *nostr.Event, message *PushMessage) {
+	if ps == nil || !ps.isRunning {
+		return
+	}
+
+	ps.queueNotification(pubkey, event, message)
+}
+
+// queueNotification queues a message for each of the pubkey's devices.
+// Returns false if the service is shutting down.
+func (ps *PushService) queueNotification(pubkey string, event *nostr.Event, message *PushMessage) bool {
+	// Get devices for this user
+	// Need to get StatsStore first
+	statsStore := ps.store.GetStatsStore()
+	if statsStore == nil {
+		logging.Errorf("Stats store not available")
+		return true
+	}
 
-			select {
-			case ps.queue <- task:
-				// Successfully queued
-				logging.Infof("✉️ Queued push notification for %s on %s (Queue size: %d/%d)",
-					pubkey[:8], device.Platform, len(ps.queue), cap(ps.queue))
-				logging.Infof("✉️ Notification details - Event: %s, Kind: %d, Title: %s",
-					event.ID, event.Kind, message.Title)
-			case <-ps.ctx.Done():
-				logging.Infof("⚠️ Push service shutting down, notification not queued")
-				return
-			default:
-				logging.Warnf("⚠️ Push notification queue is full (%d/%d), dropping notification for %s",
-					cap(ps.queue), cap(ps.queue), pubkey)
+	devices, err := statsStore.GetPushDevicesByPubkey(pubkey)
+	if err != nil {
+		logging.Errorf("Failed to get devices for pubkey %s: %v", pubkey, err)
+		return true
+	}
+
+	// Queue notification for each device
+	for _, device := range devices {
+		task := &NotificationTask{
+			Pubkey:      pubkey,
+			Event:       event,
+			DeviceToken: device.DeviceToken,
+			Platform:    device.Platform,
+			Message:     message,
+			Attempts:    0,
+		}
+		if device.Platform == "web" {
+			task.Subscription = &WebPushSubscription{
+				Endpoint: device.DeviceToken,
+				P256dh:   device.P256dh,
+				Auth:     device.Auth,
 			}
 		}
+
+		select {
+		case ps.queue <- task:
+			// Successfully queued
+			logging.Infof("✉️ Queued push notification for %s on %s (Queue size: %d/%d)",
+				pubkey[:8], device.Platform, len(ps.queue), cap(ps.queue))
+			logging.Infof("✉️ Notification details - Event: %s, Kind: %d, Title: %s",
+				event.ID, event.Kind, message.Title)
+		case <-ps.ctx.Done():
+			logging.Infof("⚠️ Push service shutting down, notification not queued")
+			return false
+		default:
+			logging.Warnf("⚠️ Push notification queue is full (%d/%d), dropping notification for %s",
+				cap(ps.queue), cap(ps.queue), pubkey)
+		}
 	}
+
+	return true
 }
 
 // shouldNotify determines if an event should trigger push notifications
@@ -273,9 +376,9 @@ func (ps *PushService) shouldNotify(event *nostr.Event) bool {
 	case 1809: // Audio post repost
 		logging.Infof("✅ Event kind 1809 (Audio post repost) will trigger notifications")
 		return true
-	// case 3: // Contact lists (new followers) - DISABLED as too spammy on nostr
-	// 	logging.Infof("✅ Event kind 3 (Contact list) will trigger notifications")
-	// 	return true
+	case 3: // Contact lists (new followers), opt-in per recipient as it is spammy on nostr
+		logging.Infof("✅ Event kind 3 (Contact list) will trigger notifications")
+		return true
 	// case 4: // Traditional DMs - DISABLED to prevent duplicates with kind 1059
 	// We only use kind 1059 (Gift Wrap) for encrypted DMs now
 	// logging.Infof("✅ Event kind 4 (DM) will trigger notifications")
@@ -292,6 +395,9 @@ func (ps *PushService) shouldNotify(event *nostr.Event) bool {
 	case 1059: // Gift Wrap (NIP-59 encrypted DMs)
 		logging.Infof("✅ Event kind 1059 (Gift Wrap DM) will trigger notifications")
 		return true
+	case 9735: // Zap receipts, opt-in per recipient
+		logging.Infof("✅ Event kind 9735 (Zap receipt) will trigger notifications")
+		return true
 	default:
 		return false
 	}
@@ -359,13 +465,17 @@ func (ps *PushService) getNotificationRecipients(event *nostr.Event) []string {
 			logging.Infof("👤 Added recipient for reaction (original author): %s", author)
 		}
 
-	// case 3: // Contact list - DISABLED as too spammy on nostr
-	// 	for _, tag := range event.Tags {
-	// 		if len(tag) >= 2 && tag[0] == "p" {
-	// 			addRecipient(tag[1])
-	// 			logging.Infof("👤 Added recipient for new follower: %s", tag[1])
-	// 		}
-	// 	}
+	case 3: // Contact list - only users newly followed who opted in
+		for _, pubkey := range ps.newFollows(event) {
+			addRecipient(pubkey)
+			logging.Infof("👤 Added recipient for new follower: %s", pubkey)
+		}
+
+	case 9735: // Zap receipt - notify the zapped user
+		if tag := event.Tags.GetFirst([]string{"p", ""}); tag != nil {
+			addRecipient((*tag)[1])
+			logging.Infof("👤 Added recipient for zap: %s", (*tag)[1])
+		}
 
 	// case 4: // Traditional DM - DISABLED to prevent duplicates with kind 1059
 	// for _, tag := range event.Tags {
@@ -435,30 +545,28 @@ func (ps *PushService) getAuthorOfRefEvent(event *nostr.Event) string {
 	return ""
 }
 
-// getReferencedEventInfo returns details about the event referenced by an 'e' tag.
-// Used to include original event context in push notification payloads.
-func (ps *PushService) getReferencedEventInfo(event *nostr.Event) *ReferencedEventInfo {
-	var refEventID string
-
-	// 1. Look for 'e' tag with marker 'reply'
+// referencedEventID returns the event a reply, reaction or repost refers to: the e tag
+// marked "reply", or the last e tag (NIP-10 convention)
+func referencedEventID(event *nostr.Event) string {
 	for _, tag := range event.Tags {
 		if len(tag) >= 4 && tag[0] == "e" && tag[3] == "reply" {
-			refEventID = tag[1]
-			break
+			return tag[1]
 		}
 	}
 
-	// 2. If no reply marker, look for the last 'e' tag (NIP-10 convention)
-	if refEventID == "" {
-		for i := len(event.Tags) - 1; i >= 0; i-- {
-			tag := event.Tags[i]
-			if len(tag) >= 2 && tag[0] == "e" {
-				refEventID = tag[1]
-				break
-			}
+	for i := len(event.Tags) - 1; i >= 0; i-- {
+		tag := event.Tags[i]
+		if len(tag) >= 2 && tag[0] == "e" {
+			return tag[1]
 		}
 	}
+	return ""
+}
 
+// getReferencedEventInfo returns details about the event referenced by an 'e' tag.
+// Used to include original event context in push notification payloads.
+func (ps *PushService) getReferencedEventInfo(event *nostr.Event) *ReferencedEventInfo {
+	refEventID := referencedEventID(event)
 	if refEventID == "" {
 		return nil
 	}
@@ -633,9 +741,9 @@ func (ps *PushService) formatNotificationMessage(event *nostr.Event, recipient s
 		message.Title = "Audio Repost"
 		message.Body = fmt.Sprintf("%s reposted your audio post", authorName)
 
-	// case 3: // Contact list - DISABLED as too spammy on nostr
-	// 	message.Title = "New Follower"
-	// 	message.Body = fmt.Sprintf("%s started following you", authorName)
+	case 3: // Contact list
+		message.Title = "New Follower"
+		message.Body = fmt.Sprintf("%s started following you", authorName)
 
 	// case 4: // Traditional DM - DISABLED to prevent duplicates with kind 1059
 	// 	message.Title = "New Message"
@@ -667,6 +775,19 @@ func (ps *PushService) formatNotificationMessage(event *nostr.Event, recipient s
 		// Add recipient to data so iOS can decrypt properly
 		message.Data["recipient"] = recipient
 
+	case 9735: // Zap receipt
+		sender := eventSender(event)
+		senderName := "Someone"
+		if sender != "" {
+			senderName = ps.getAuthorName(sender)
+			message.Data["sender"] = sender
+		}
+		message.Title = "New Zap"
+		message.Body = fmt.Sprintf("%s zapped you", senderName)
+		if amount := zapAmountSats(event); amount > 0 {
+			message.Body = fmt.Sprintf("%s zapped you %d sats", senderName, amount)
+		}
+
 	default:
 		message.Title = "New Notification"
 		message.Body = "You have a new notification"
@@ -694,6 +815,73 @@ func (ps *PushService) formatNotificationMessage(event *nostr.Event, recipient s
 	return message
 }
 
+// formatSummaryMessage formats one notification standing for a burst of events with the
+// same subject, e.g. "Alice and 12 others reacted to your note"
+func (ps *PushService) formatSummaryMessage(batch *coalescedBatch, recipient string) *PushMessage {
+	// Start from the latest event so the payload points at the same subject
+	message := ps.formatNotificationMessage(batch.latest, recipient)
+	message.Badge = batch.count
+	message.Data["collapsed_count"] = batch.count
+
+	who := "Someone"
+	if len(batch.senders) > 0 {
+		who = ps.getAuthorName(batch.senders[len(batch.senders)-1])
+		switch others := len(batch.senders) - 1; others {
+		case 0:
+		case 1:
+			who = fmt.Sprintf("%s and 1 other", who)
+		default:
+			who = fmt.Sprintf("%s and %d others", who, others)
+		}
+	}
+
+	switch batch.latest.Kind {
+	case 1:
+		message.Body = fmt.Sprintf("%s replied to your note", who)
+	case 1808:
+		message.Body = fmt.Sprintf("%s mentioned you in audio notes", who)
+	case 1809:
+		message.Body = fmt.Sprintf("%s reposted your audio post", who)
+	case 3:
+		message.Title = "New Followers"
+		message.Body = fmt.Sprintf("%s started following you", who)
+	case 6:
+		message.Body = fmt.Sprintf("%s reposted your note", who)
+	case 7:
+		message.Title = "New Reactions"
+		message.Body = fmt.Sprintf("%s reacted to your note", who)
+	case 1059:
+		message.Title = "New Encrypted Messages"
+		message.Body = fmt.Sprintf("You have %d new encrypted messages", batch.count)
+	case 9735:
+		message.Title = "New Zaps"
+		message.Body = fmt.Sprintf("%s zapped you", who)
+		if batch.sats > 0 {
+			message.Body = fmt.Sprintf("%s zapped you %d sats", who, batch.sats)
+		}
+	default:
+		message.Body = fmt.Sprintf("You have %d new notifications", batch.count)
+	}
+
+	logging.Infof("📱 Formatted summary of %d events - Title: %s, Body: %s, Recipient: %s",
+		batch.count, message.Title, message.Body, recipient)
+
+	return message
+}
+
+// QueueDepth returns how many notifications are waiting for a worker and how many fit
+func (ps *PushService) QueueDepth() (depth int, capacity int) {
+	return len(ps.queue), cap(ps.queue)
+}
+
+// VAPIDPublicKey returns the key web clients subscribe with, or "" when Web Push is disabled
+func (ps *PushService) VAPIDPublicKey() string {
+	if ps.webPushClient == nil {
+		return ""
+	}
+	return ps.webPushClient.PublicKey()
+}
+
 // Global service instance
 var globalPushService *PushService
 var serviceMutex sync.RWMutex
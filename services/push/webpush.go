@@ -0,0 +1,397 @@
+package push
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+
+	"github.com/HORNET-Storage/hornet-storage/lib/config"
+	"github.com/HORNET-Storage/hornet-storage/lib/logging"
+	"github.com/HORNET-Storage/hornet-storage/lib/types"
+)
+
+const (
+	// RFC 8188 record size. Push services accept at most 4096 bytes of body, so each
+	// notification fits in a single record.
+	webPushRecordSize = 4096
+	webPushHeaderSize = 16 + 4 + 1 + 65
+	webPushMaxPayload = webPushRecordSize - webPushHeaderSize - 16 - 1
+
+	webPushTTL = 24 * time.Hour
+
+	// VAPID tokens are valid for 12 hours (RFC 8292 allows up to 24) and reused until
+	// this long before they expire
+	vapidTokenLifetime      = 12 * time.Hour
+	vapidTokenRefreshMargin = time.Hour
+)
+
+// WebPushSubscription is the part of a browser PushSubscription the relay needs to deliver to it
+type WebPushSubscription struct {
+	Endpoint string `json:"endpoint"`
+	P256dh   string `json:"p256dh"` // Browser's P-256 public key, base64url
+	Auth     string `json:"auth"`   // 16 byte authentication secret, base64url
+}
+
+// ValidateWebPushEndpoint checks that a PushSubscription endpoint is an https URL whose host
+// only resolves to public addresses, so subscribers can't point the relay at its own network
+func ValidateWebPushEndpoint(endpoint string) error {
+	parsed, err := url.Parse(endpoint)
+	if err != nil || parsed.Scheme != "https" || parsed.Hostname() == "" {
+		return fmt.Errorf("web push endpoint must be an https URL")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, parsed.Hostname())
+	if err != nil || len(addrs) == 0 {
+		return fmt.Errorf("failed to resolve web push endpoint host %s", parsed.Hostname())
+	}
+	for _, addr := range addrs {
+		if !isPublicIP(addr.IP) {
+			return fmt.Errorf("web push endpoint host %s resolves to a non-public address", parsed.Hostname())
+		}
+	}
+	return nil
+}
+
+func isPublicIP(ip net.IP) bool {
+	return !ip.IsLoopback() && !ip.IsPrivate() && !ip.IsUnspecified() &&
+		!ip.IsLinkLocalUnicast() && !ip.IsLinkLocalMulticast() &&
+		!ip.IsInterfaceLocalMulticast() && !ip.IsMulticast()
+}
+
+// publicDialControl refuses connections to non-public addresses, catching endpoints whose
+// DNS changed after they were registered
+func publicDialControl(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(host); ip == nil || !isPublicIP(ip) {
+		return fmt.Errorf("refusing to connect to non-public address %s: %w", host, ErrInvalidDeviceToken)
+	}
+	return nil
+}
+
+// WebPushClient interface for the Web Push protocol (RFC 8030)
+type WebPushClient interface {
+	SendNotification(subscription *WebPushSubscription, message *PushMessage) error
+	PublicKey() string
+}
+
+// NewWebPushClient creates a Web Push client, generating the relay's VAPID key on first use
+func NewWebPushClient(cfg *types.WebPushConfig) (WebPushClient, error) {
+	keyPath := cfg.KeyPath
+	if keyPath == "" {
+		keyPath = filepath.Join(config.GetDataDir(), "vapid_private_key.pem")
+	}
+
+	key, err := loadOrCreateVAPIDKey(keyPath)
+	if err != nil {
+		return nil, err
+	}
+
+	// Some push services reject tokens without a contact, so fall back to the relay's
+	subject := cfg.Subject
+	if subject == "" {
+		if relayConfig, err := config.GetConfig(); err == nil && relayConfig.Relay.Contact != "" {
+			subject = "mailto:" + relayConfig.Relay.Contact
+		}
+	}
+
+	client, err := newRealWebPushClient(key, subject)
+	if err != nil {
+		return nil, err
+	}
+
+	logging.Infof("✅ Web Push Client Initialized (VAPID key: %s)", keyPath)
+
+	return client, nil
+}
+
+// loadOrCreateVAPIDKey reads the relay's P-256 VAPID key, creating it if it doesn't exist yet
+func loadOrCreateVAPIDKey(path string) (*ecdsa.PrivateKey, error) {
+	data, err := os.ReadFile(path)
+	if err == nil {
+		key, err := jwt.ParseECPrivateKeyFromPEM(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse VAPID key %s: %w", path, err)
+		}
+		if key.Curve != elliptic.P256() {
+			return nil, fmt.Errorf("VAPID key %s must be a P-256 key", path)
+		}
+		return key, nil
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("failed to read VAPID key %s: %w", path, err)
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate VAPID key: %w", err)
+	}
+	der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode VAPID key: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create VAPID key directory: %w", err)
+	}
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}), 0600); err != nil {
+		return nil, fmt.Errorf("failed to write VAPID key %s: %w", path, err)
+	}
+
+	logging.Infof("Generated a new VAPID key at %s", path)
+	return key, nil
+}
+
+func newRealWebPushClient(key *ecdsa.PrivateKey, subject string) (*RealWebPushClient, error) {
+	publicKey, err := key.PublicKey.ECDH()
+	if err != nil {
+		return nil, fmt.Errorf("invalid VAPID key: %w", err)
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = (&net.Dialer{Timeout: 10 * time.Second, Control: publicDialControl}).DialContext
+
+	return &RealWebPushClient{
+		httpClient: &http.Client{Timeout: 10 * time.Second, Transport: transport},
+		signer:     key,
+		publicKey:  base64.RawURLEncoding.EncodeToString(publicKey.Bytes()),
+		subject:    subject,
+		tokens:     make(map[string]vapidToken),
+	}, nil
+}
+
+// RealWebPushClient delivers aes128gcm encrypted payloads (RFC 8291) to browser push
+// services, identifying the relay with a VAPID signature (RFC 8292)
+type RealWebPushClient struct {
+	httpClient *http.Client
+	signer     *ecdsa.PrivateKey
+	publicKey  string // Uncompressed public key, base64url, used as the browser's applicationServerKey
+	subject    string
+
+	tokenMutex sync.Mutex
+	tokens     map[string]vapidToken // Push service origin -> signed token
+}
+
+type vapidToken struct {
+	token  string
+	expiry time.Time
+}
+
+// PublicKey returns the VAPID public key clients pass to PushManager.subscribe
+func (c *RealWebPushClient) PublicKey() string {
+	return c.publicKey
+}
+
+// SendNotification encrypts the message for the subscription and posts it to the push service
+func (c *RealWebPushClient) SendNotification(subscription *WebPushSubscription, message *PushMessage) error {
+	endpoint, err := url.Parse(subscription.Endpoint)
+	if err != nil || endpoint.Host == "" {
+		return fmt.Errorf("invalid web push endpoint: %w", ErrInvalidDeviceToken)
+	}
+
+	payload, err := message.ToWebPushPayload()
+	if err != nil {
+		return fmt.Errorf("failed to encode web push payload: %w", err)
+	}
+
+	body, err := encryptWebPushPayload(subscription, payload)
+	if err != nil {
+		return err
+	}
+
+	token, err := c.token(endpoint.Scheme + "://" + endpoint.Host)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, subscription.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("vapid t=%s, k=%s", token, c.publicKey))
+	req.Header.Set("Content-Encoding", "aes128gcm")
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("TTL", fmt.Sprintf("%d", int(webPushTTL.Seconds())))
+
+	res, err := c.httpClient.Do(req)
+	if err != nil {
+		logging.Errorf("Failed to send web push notification: %v", err)
+		return err
+	}
+	defer res.Body.Close()
+
+	response, _ := io.ReadAll(io.LimitReader(res.Body, 64*1024))
+
+	if res.StatusCode >= 200 && res.StatusCode < 300 {
+		logging.Infof("🚀 Web Push Notification Sent: %s", endpoint.Host)
+		return nil
+	}
+
+	logging.Warn("⚠️ Web Push Notification Failed", map[string]interface{}{
+		"status":   res.StatusCode,
+		"host":     endpoint.Host,
+		"response": strings.TrimSpace(string(response)),
+	})
+
+	// The browser unsubscribed or the subscription expired
+	if res.StatusCode == http.StatusNotFound || res.StatusCode == http.StatusGone {
+		return fmt.Errorf("web push subscription expired: %d: %w", res.StatusCode, ErrInvalidDeviceToken)
+	}
+	return fmt.Errorf("web push notification failed: %d %s", res.StatusCode, strings.TrimSpace(string(response)))
+}
+
+// token returns a cached VAPID JWT for the push service origin, signing a new one when it nears expiry
+func (c *RealWebPushClient) token(audience string) (string, error) {
+	c.tokenMutex.Lock()
+	defer c.tokenMutex.Unlock()
+
+	if cached, ok := c.tokens[audience]; ok && time.Now().Add(vapidTokenRefreshMargin).Before(cached.expiry) {
+		return cached.token, nil
+	}
+
+	expiry := time.Now().Add(vapidTokenLifetime)
+	claims := jwt.MapClaims{
+		"aud": audience,
+		"exp": expiry.Unix(),
+	}
+	if c.subject != "" {
+		claims["sub"] = c.subject
+	}
+
+	signed, err := jwt.NewWithClaims(jwt.SigningMethodES256, claims).SignedString(c.signer)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign VAPID token: %w", err)
+	}
+
+	c.tokens[audience] = vapidToken{token: signed, expiry: expiry}
+	return signed, nil
+}
+
+// encryptWebPushPayload encrypts the payload for the subscription as a single aes128gcm
+// record (RFC 8188) using the key derivation from RFC 8291
+func encryptWebPushPayload(subscription *WebPushSubscription, payload []byte) ([]byte, error) {
+	if len(payload) > webPushMaxPayload {
+		return nil, fmt.Errorf("web push payload is %d bytes, the limit is %d", len(payload), webPushMaxPayload)
+	}
+
+	uaPublicBytes, err := decodeWebPushKey(subscription.P256dh)
+	if err != nil {
+		return nil, fmt.Errorf("invalid p256dh key: %w", ErrInvalidDeviceToken)
+	}
+	uaPublic, err := ecdh.P256().NewPublicKey(uaPublicBytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid p256dh key: %w", ErrInvalidDeviceToken)
+	}
+	authSecret, err := decodeWebPushKey(subscription.Auth)
+	if err != nil || len(authSecret) != 16 {
+		return nil, fmt.Errorf("invalid auth secret: %w", ErrInvalidDeviceToken)
+	}
+
+	// A fresh application server key pair and salt for every message
+	asPrivate, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	asPublicBytes := asPrivate.PublicKey().Bytes()
+
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+
+	ecdhSecret, err := asPrivate.ECDH(uaPublic)
+	if err != nil {
+		return nil, err
+	}
+
+	keyInfo := "WebPush: info\x00" + string(uaPublicBytes) + string(asPublicBytes)
+	ikm, err := hkdf.Key(sha256.New, ecdhSecret, authSecret, keyInfo, 32)
+	if err != nil {
+		return nil, err
+	}
+	prk, err := hkdf.Extract(sha256.New, ikm, salt)
+	if err != nil {
+		return nil, err
+	}
+	cek, err := hkdf.Expand(sha256.New, prk, "Content-Encoding: aes128gcm\x00", 16)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := hkdf.Expand(sha256.New, prk, "Content-Encoding: nonce\x00", 12)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(cek)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	// 0x02 marks the last (and only) record, with no further padding
+	plaintext := append(append([]byte{}, payload...), 0x02)
+
+	body := make([]byte, 0, webPushHeaderSize+len(plaintext)+gcm.Overhead())
+	body = append(body, salt...)
+	body = binary.BigEndian.AppendUint32(body, webPushRecordSize)
+	body = append(body, byte(len(asPublicBytes)))
+	body = append(body, asPublicBytes...)
+	return gcm.Seal(body, nonce, plaintext, nil), nil
+}
+
+// decodeWebPushKey decodes subscription keys, which browsers send as unpadded base64url
+func decodeWebPushKey(value string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(value, "="))
+}
+
+// ToWebPushPayload converts a PushMessage to the JSON the web client's service worker displays
+func (m *PushMessage) ToWebPushPayload() ([]byte, error) {
+	payload := map[string]interface{}{
+		"title": m.Title,
+		"body":  m.Body,
+	}
+	if m.Badge > 0 {
+		payload["badge"] = m.Badge
+	}
+	if m.Category != "" {
+		payload["category"] = m.Category
+	}
+	if len(m.Data) > 0 {
+		payload["data"] = m.Data
+	}
+	return json.Marshal(payload)
+}
@@ -0,0 +1,307 @@
+package push
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/nbd-wtf/go-nostr"
+
+	"github.com/HORNET-Storage/hornet-storage/lib/types"
+)
+
+// webPushEndpoint plays the browser's push service and service worker: it checks the
+// VAPID signature and decrypts the payload with the subscription's private key
+type webPushEndpoint struct {
+	t          *testing.T
+	server     *httptest.Server
+	private    *ecdh.PrivateKey
+	authSecret []byte
+	expired    bool
+
+	mu       sync.Mutex
+	payloads []map[string]interface{}
+}
+
+func startWebPushEndpoint(t *testing.T) *webPushEndpoint {
+	private, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	endpoint := &webPushEndpoint{t: t, private: private, authSecret: make([]byte, 16)}
+	rand.Read(endpoint.authSecret)
+
+	endpoint.server = httptest.NewServer(http.HandlerFunc(endpoint.handle))
+	t.Cleanup(endpoint.server.Close)
+	return endpoint
+}
+
+func (e *webPushEndpoint) subscription() *WebPushSubscription {
+	return &WebPushSubscription{
+		Endpoint: e.server.URL + "/push/subscription-1",
+		P256dh:   base64.RawURLEncoding.EncodeToString(e.private.PublicKey().Bytes()),
+		Auth:     base64.RawURLEncoding.EncodeToString(e.authSecret),
+	}
+}
+
+func (e *webPushEndpoint) handle(w http.ResponseWriter, r *http.Request) {
+	if e.expired {
+		w.WriteHeader(http.StatusGone)
+		return
+	}
+
+	if r.Header.Get("Content-Encoding") != "aes128gcm" || r.Header.Get("TTL") == "" {
+		e.t.Errorf("unexpected headers: %v", r.Header)
+	}
+	e.verifyVAPID(r.Header.Get("Authorization"))
+
+	body, _ := io.ReadAll(r.Body)
+	payload := e.decrypt(body)
+
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(payload, &decoded); err != nil {
+		e.t.Errorf("payload is not JSON: %q", payload)
+	}
+
+	e.mu.Lock()
+	e.payloads = append(e.payloads, decoded)
+	e.mu.Unlock()
+	w.WriteHeader(http.StatusCreated)
+}
+
+func (e *webPushEndpoint) verifyVAPID(header string) {
+	var token, key string
+	for _, part := range strings.Split(strings.TrimPrefix(header, "vapid "), ",") {
+		name, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch name {
+		case "t":
+			token = value
+		case "k":
+			key = value
+		}
+	}
+
+	keyBytes, err := base64.RawURLEncoding.DecodeString(key)
+	if err != nil {
+		e.t.Errorf("invalid VAPID public key %q", key)
+		return
+	}
+	if _, err := ecdh.P256().NewPublicKey(keyBytes); err != nil {
+		e.t.Errorf("invalid VAPID public key: %v", err)
+		return
+	}
+	publicKey := &ecdsa.PublicKey{
+		Curve: elliptic.P256(),
+		X:     new(big.Int).SetBytes(keyBytes[1:33]),
+		Y:     new(big.Int).SetBytes(keyBytes[33:]),
+	}
+
+	claims := jwt.MapClaims{}
+	_, err = jwt.ParseWithClaims(token, claims, func(token *jwt.Token) (interface{}, error) {
+		if token.Method != jwt.SigningMethodES256 {
+			return nil, errors.New("unexpected signing method")
+		}
+		return publicKey, nil
+	})
+	if err != nil {
+		e.t.Errorf("invalid VAPID token: %v", err)
+		return
+	}
+	if claims["aud"] != e.server.URL || claims["sub"] != "mailto:ops@example.com" {
+		e.t.Errorf("unexpected VAPID claims: %v", claims)
+	}
+}
+
+// decrypt reverses RFC 8291 with the user agent's keys
+func (e *webPushEndpoint) decrypt(body []byte) []byte {
+	salt := body[:16]
+	recordSize := binary.BigEndian.Uint32(body[16:20])
+	idLength := int(body[20])
+	asPublicBytes := body[21 : 21+idLength]
+	ciphertext := body[21+idLength:]
+	if recordSize != webPushRecordSize || idLength != 65 {
+		e.t.Errorf("unexpected header: rs=%d idlen=%d", recordSize, idLength)
+	}
+
+	asPublic, err := ecdh.P256().NewPublicKey(asPublicBytes)
+	if err != nil {
+		e.t.Fatalf("invalid application server key: %v", err)
+	}
+	ecdhSecret, _ := e.private.ECDH(asPublic)
+
+	keyInfo := "WebPush: info\x00" + string(e.private.PublicKey().Bytes()) + string(asPublicBytes)
+	ikm, _ := hkdf.Key(sha256.New, ecdhSecret, e.authSecret, keyInfo, 32)
+	cek, _ := hkdf.Key(sha256.New, ikm, salt, "Content-Encoding: aes128gcm\x00", 16)
+	nonce, _ := hkdf.Key(sha256.New, ikm, salt, "Content-Encoding: nonce\x00", 12)
+
+	block, _ := aes.NewCipher(cek)
+	gcm, _ := cipher.NewGCM(block)
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		e.t.Fatalf("failed to decrypt payload: %v", err)
+	}
+	if len(plaintext) == 0 || plaintext[len(plaintext)-1] != 0x02 {
+		e.t.Fatalf("expected the last record delimiter")
+	}
+	return plaintext[:len(plaintext)-1]
+}
+
+func newTestWebPushClient(t *testing.T) *RealWebPushClient {
+	keyPath := filepath.Join(t.TempDir(), "vapid.pem")
+	key, err := loadOrCreateVAPIDKey(keyPath)
+	if err != nil {
+		t.Fatalf("loadOrCreateVAPIDKey: %v", err)
+	}
+
+	// The key is persisted so subscriptions survive restarts
+	reloaded, err := loadOrCreateVAPIDKey(keyPath)
+	if err != nil || !reloaded.Equal(key) {
+		t.Fatalf("expected the stored VAPID key to be reused, err %v", err)
+	}
+
+	client, err := newRealWebPushClient(key, "mailto:ops@example.com")
+	if err != nil {
+		t.Fatalf("newRealWebPushClient: %v", err)
+	}
+	// The test push service listens on loopback, which the real client refuses to dial
+	client.httpClient = &http.Client{Timeout: 10 * time.Second}
+	return client
+}
+
+func TestWebPushEncryptsPayloadForSubscription(t *testing.T) {
+	endpoint := startWebPushEndpoint(t)
+	client := newTestWebPushClient(t)
+
+	message := &PushMessage{
+		Title:    "New reply",
+		Body:     "hello from the relay",
+		Badge:    3,
+		Category: "REPLY",
+		Data:     map[string]interface{}{"event_id": "abc", "kind": 1},
+	}
+	for i := 0; i < 2; i++ {
+		if err := client.SendNotification(endpoint.subscription(), message); err != nil {
+			t.Fatalf("SendNotification: %v", err)
+		}
+	}
+
+	if len(endpoint.payloads) != 2 {
+		t.Fatalf("expected two notifications, got %d", len(endpoint.payloads))
+	}
+	payload := endpoint.payloads[0]
+	data := payload["data"].(map[string]interface{})
+	if payload["title"] != "New reply" || payload["body"] != "hello from the relay" || payload["category"] != "REPLY" {
+		t.Errorf("unexpected payload: %v", payload)
+	}
+	if payload["badge"] != float64(3) || data["event_id"] != "abc" || data["kind"] != float64(1) {
+		t.Errorf("unexpected payload data: %v", payload)
+	}
+	if len(client.tokens) != 1 {
+		t.Errorf("expected one cached VAPID token per push service, got %d", len(client.tokens))
+	}
+}
+
+func TestWebPushExpiredSubscriptionDeactivatesDevice(t *testing.T) {
+	endpoint := startWebPushEndpoint(t)
+	endpoint.expired = true
+	client := newTestWebPushClient(t)
+	subscription := endpoint.subscription()
+
+	err := client.SendNotification(subscription, &PushMessage{Title: "t", Body: "b"})
+	if !errors.Is(err, ErrInvalidDeviceToken) {
+		t.Fatalf("expected an invalid device token error, got %v", err)
+	}
+
+	stats := &deviceStatusStore{}
+	service := &PushService{
+		store:         &deviceStatusPushStore{stats: stats},
+		config:        &types.PushNotificationConfig{Service: types.PushServiceConfig{RetryAttempts: 3}},
+		queue:         make(chan *NotificationTask, 1),
+		webPushClient: client,
+	}
+	worker := NewWorker(1, service.queue, service)
+
+	worker.processTask(&NotificationTask{
+		Pubkey:       "recipient",
+		Event:        &nostr.Event{ID: "event", Kind: 1},
+		DeviceToken:  subscription.Endpoint,
+		Platform:     "web",
+		Subscription: subscription,
+		Message:      &PushMessage{Title: "t", Body: "b"},
+	})
+
+	if len(stats.deactivated) != 1 || stats.deactivated[0] != subscription.Endpoint {
+		t.Errorf("expected the expired subscription to be deactivated, got %v", stats.deactivated)
+	}
+	if len(service.queue) != 0 {
+		t.Error("expected an expired subscription not to be retried")
+	}
+}
+
+func TestValidateWebPushEndpoint(t *testing.T) {
+	tests := []struct {
+		endpoint string
+		valid    bool
+	}{
+		{"https://8.8.8.8/push/1", true},
+		{"http://8.8.8.8/push/1", false},
+		{"not a url", false},
+		{"https://127.0.0.1/push/1", false},
+		{"https://localhost:8443/push/1", false},
+		{"https://[::1]/push/1", false},
+		{"https://10.0.0.5/push/1", false},
+		{"https://192.168.1.1/push/1", false},
+		{"https://169.254.169.254/latest/meta-data", false},
+		{"https://[fe80::1]/push/1", false},
+		{"https://0.0.0.0/push/1", false},
+	}
+
+	for _, test := range tests {
+		err := ValidateWebPushEndpoint(test.endpoint)
+		if test.valid && err != nil {
+			t.Errorf("expected %s to be accepted, got %v", test.endpoint, err)
+		}
+		if !test.valid && err == nil {
+			t.Errorf("expected %s to be rejected", test.endpoint)
+		}
+	}
+}
+
+func TestWebPushRefusesNonPublicAddresses(t *testing.T) {
+	endpoint := startWebPushEndpoint(t)
+	key, err := loadOrCreateVAPIDKey(filepath.Join(t.TempDir(), "vapid.pem"))
+	if err != nil {
+		t.Fatalf("loadOrCreateVAPIDKey: %v", err)
+	}
+	client, err := newRealWebPushClient(key, "mailto:ops@example.com")
+	if err != nil {
+		t.Fatalf("newRealWebPushClient: %v", err)
+	}
+
+	err = client.SendNotification(endpoint.subscription(), &PushMessage{Title: "t", Body: "b"})
+	if !errors.Is(err, ErrInvalidDeviceToken) {
+		t.Fatalf("expected a loopback endpoint to be refused as invalid, got %v", err)
+	}
+	if len(endpoint.payloads) != 0 {
+		t.Error("expected nothing to reach the loopback endpoint")
+	}
+}
@@ -1,6 +1,7 @@
 package push
 
 import (
+	"errors"
 	"fmt"
 	"sync"
 	"time"
@@ -63,6 +64,7 @@ func (w *Worker) processTask(task *NotificationTask) {
 		NotificationType: task.Message.Category,
 		DeviceToken:      task.DeviceToken,
 		Platform:         task.Platform,
+		CollapsedCount:   max(task.Message.Collapsed, 1),
 		Delivered:        false,
 	}
 
@@ -94,6 +96,14 @@ func (w *Worker) processTask(task *NotificationTask) {
 		} else {
 			err = fmt.Errorf("FCM client not initialized")
 		}
+	case "web":
+		if w.service.webPushClient == nil {
+			err = fmt.Errorf("Web Push client not initialized")
+		} else if task.Subscription == nil {
+			err = fmt.Errorf("web push subscription missing: %w", ErrInvalidDeviceToken)
+		} else {
+			err = w.service.webPushClient.SendNotification(task.Subscription, task.Message)
+		}
 	default:
 		err = fmt.Errorf("unsupported platform: %s", task.Platform)
 	}
@@ -112,7 +122,13 @@ func (w *Worker) processTask(task *NotificationTask) {
 		}
 
 		// Retry logic
-		if task.Attempts < w.service.config.Service.RetryAttempts {
+		if errors.Is(err, ErrInvalidDeviceToken) {
+			// The provider will never accept this token again, so stop using it now
+			logging.Infof("🔄 Device token rejected by %s, marking inactive: %s...", task.Platform, task.DeviceToken[:min(10, len(task.DeviceToken))])
+			if statsStore != nil {
+				statsStore.UpdatePushDeviceStatus(task.DeviceToken, false)
+			}
+		} else if task.Attempts < w.service.config.Service.RetryAttempts {
 			// Parse retry delay
 			retryDelay, parseErr := time.ParseDuration(w.service.config.Service.RetryDelay)
 			if parseErr != nil {
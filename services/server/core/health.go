@@ -0,0 +1,176 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/HORNET-Storage/hornet-storage/lib/health"
+	"github.com/HORNET-Storage/hornet-storage/lib/moderation"
+	"github.com/HORNET-Storage/hornet-storage/lib/stores/badgerhold"
+	"github.com/HORNET-Storage/hornet-storage/services/push"
+)
+
+const (
+	// moderationQueueWarnDepth is how many events may wait for image
+	// moderation before the node reports itself degraded; a growing queue
+	// usually means the moderation API is slow or unreachable.
+	moderationQueueWarnDepth = 1000
+
+	// pushQueueWarnPercent is how full the push notification queue may get
+	// before the node reports itself degraded; past 100% notifications are
+	// dropped.
+	pushQueueWarnPercent = 90
+)
+
+// registerHealthChecks registers a check for every component the relay
+// depends on. The stores and the sidecar are critical: without them the node
+// should be depooled. The stores are also liveness checks, since a wedged
+// database is only fixed by a restart, whereas the supervisor recovers the
+// sidecar on its own.
+func registerHealthChecks(store *badgerhold.BadgerholdStore, sidecarSup *sidecarSupervisor) {
+	health.Register(health.Component{
+		Name:     "store",
+		Critical: true,
+		Liveness: true,
+		Check: func(ctx context.Context) health.Result {
+			if err := store.Probe(); err != nil {
+				return health.Down(err, nil)
+			}
+			return health.OK(nil)
+		},
+	})
+
+	health.Register(health.Component{
+		Name:     "statistics_db",
+		Critical: true,
+		Liveness: true,
+		Check: func(ctx context.Context) health.Result {
+			statsStore := store.GetStatsStore()
+			if statsStore == nil {
+				return health.Down(errors.New("statistics store not initialized"), nil)
+			}
+			if err := statsStore.Ping(ctx); err != nil {
+				return health.Down(err, nil)
+			}
+			return health.OK(nil)
+		},
+	})
+
+	health.Register(health.Component{
+		Name:     "sidecar",
+		Critical: true,
+		Check: func(ctx context.Context) health.Result {
+			return sidecarHealth(sidecarSup.Status())
+		},
+	})
+
+	health.Register(health.Component{
+		Name: "upnp",
+		Check: func(ctx context.Context) health.Result {
+			return upnpHealth(sidecarSup.Status().UPnP)
+		},
+	})
+
+	health.Register(health.Component{
+		Name: "moderation",
+		Check: func(ctx context.Context) health.Result {
+			worker := moderation.GetWorker()
+			if worker == nil {
+				return health.OK(map[string]interface{}{"enabled": false})
+			}
+
+			events, disputes, err := store.CountPendingModeration()
+			if err != nil {
+				return health.Down(err, nil)
+			}
+			return moderationHealth(worker.Running, events, disputes)
+		},
+	})
+
+	health.Register(health.Component{
+		Name: "push",
+		Check: func(ctx context.Context) health.Result {
+			service := push.GetGlobalPushService()
+			if service == nil {
+				return health.OK(map[string]interface{}{"enabled": false})
+			}
+
+			depth, capacity := service.QueueDepth()
+			return pushHealth(depth, capacity)
+		},
+	})
+}
+
+func sidecarHealth(status sidecarStatus) health.Result {
+	details := map[string]interface{}{
+		"connected":  status.Connected,
+		"recoveries": status.Recoveries,
+	}
+	if !status.LastRecovery.IsZero() {
+		details["last_recovery"] = status.LastRecovery.UTC().Format(time.RFC3339)
+	}
+
+	if !status.Connected {
+		details["lost_at"] = status.LostAt.UTC().Format(time.RFC3339)
+		details["recovery_attempts"] = status.RecoveryAttempts
+		message := "sidecar connection lost, recovery in progress"
+		if status.LastError != "" {
+			message += ": " + status.LastError
+		}
+		return health.Down(errors.New(message), details)
+	}
+
+	if status.Client == nil {
+		return health.Down(errors.New("no sidecar client"), details)
+	}
+	if _, err := status.Client.Ping(); err != nil {
+		return health.Down(err, details)
+	}
+	return health.OK(details)
+}
+
+func upnpHealth(mapping upnpMapping) health.Result {
+	details := map[string]interface{}{
+		"enabled": mapping.Enabled,
+		"mapped":  mapping.Mapped,
+	}
+	if mapping.Port != 0 {
+		details["port"] = mapping.Port
+	}
+
+	if mapping.Enabled && !mapping.Mapped {
+		return health.Degraded("HyperDHT port is not mapped: "+mapping.Error, details)
+	}
+	return health.OK(details)
+}
+
+func moderationHealth(running bool, events, disputes uint64) health.Result {
+	details := map[string]interface{}{
+		"enabled":          true,
+		"worker_running":   running,
+		"pending_events":   events,
+		"pending_disputes": disputes,
+	}
+
+	if !running {
+		return health.Down(errors.New("moderation worker is not running"), details)
+	}
+	if events > moderationQueueWarnDepth {
+		return health.Degraded("moderation queue is backing up", details)
+	}
+	return health.OK(details)
+}
+
+func pushHealth(depth, capacity int) health.Result {
+	details := map[string]interface{}{
+		"enabled":        true,
+		"queue_depth":    depth,
+		"queue_capacity": capacity,
+	}
+
+	if capacity > 0 && depth*100 >= capacity*pushQueueWarnPercent {
+		return health.Degraded("push notification queue is nearly full", details)
+	}
+	return health.OK(details)
+}
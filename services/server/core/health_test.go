@@ -0,0 +1,61 @@
+package core
+
+import (
+	"testing"
+	"time"
+
+	"github.com/HORNET-Storage/hornet-storage/lib/health"
+)
+
+func TestSidecarHealthReportsRecovery(t *testing.T) {
+	lostAt := time.Now().Add(-time.Minute)
+	result := sidecarHealth(sidecarStatus{
+		LostAt:           lostAt,
+		Recoveries:       1,
+		RecoveryAttempts: 4,
+		LastError:        "connection refused",
+	})
+	if result.Status != health.StatusDown {
+		t.Fatalf("expected a lost sidecar to be down, got %+v", result)
+	}
+	if result.Details["recovery_attempts"] != 4 || result.Details["lost_at"] == nil {
+		t.Errorf("expected recovery progress in the details, got %+v", result.Details)
+	}
+
+	// Connected but with no client to ping
+	result = sidecarHealth(sidecarStatus{Connected: true, LastRecovery: lostAt})
+	if result.Status != health.StatusDown || result.Details["last_recovery"] == nil {
+		t.Errorf("expected a missing client to be down with the last recovery time, got %+v", result)
+	}
+}
+
+func TestUPnPHealth(t *testing.T) {
+	if result := upnpHealth(upnpMapping{}); result.Status != health.StatusOK {
+		t.Errorf("expected disabled UPnP to be ok, got %+v", result)
+	}
+	if result := upnpHealth(upnpMapping{Enabled: true, Mapped: true, Port: 49737}); result.Status != health.StatusOK || result.Details["port"] != uint16(49737) {
+		t.Errorf("expected a mapped port to be ok, got %+v", result)
+	}
+	if result := upnpHealth(upnpMapping{Enabled: true, Error: "no UPnP router discovered"}); result.Status != health.StatusDegraded {
+		t.Errorf("expected a failed mapping to be degraded, got %+v", result)
+	}
+}
+
+func TestQueueHealth(t *testing.T) {
+	if result := moderationHealth(true, 10, 2); result.Status != health.StatusOK {
+		t.Errorf("expected a short moderation queue to be ok, got %+v", result)
+	}
+	if result := moderationHealth(true, moderationQueueWarnDepth+1, 0); result.Status != health.StatusDegraded {
+		t.Errorf("expected a long moderation queue to be degraded, got %+v", result)
+	}
+	if result := moderationHealth(false, 0, 0); result.Status != health.StatusDown {
+		t.Errorf("expected a stopped worker to be down, got %+v", result)
+	}
+
+	if result := pushHealth(10, 1000); result.Status != health.StatusOK {
+		t.Errorf("expected a mostly empty push queue to be ok, got %+v", result)
+	}
+	if result := pushHealth(950, 1000); result.Status != health.StatusDegraded {
+		t.Errorf("expected a nearly full push queue to be degraded, got %+v", result)
+	}
+}
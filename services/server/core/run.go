@@ -20,9 +20,13 @@ import (
 	lib_types "github.com/HORNET-Storage/hdk-nostr-go/lib"
 	hsListener "github.com/HORNET-Storage/hdk-nostr-go/lib/connmgr/hyperswarm"
 	"github.com/HORNET-Storage/hdk-nostr-go/lib/signing"
+	"github.com/HORNET-Storage/hornet-storage/lib/access"
+	"github.com/HORNET-Storage/hornet-storage/lib/backfill"
 	"github.com/HORNET-Storage/hornet-storage/lib/config"
 	"github.com/HORNET-Storage/hornet-storage/lib/logging"
 	"github.com/HORNET-Storage/hornet-storage/lib/sidecar"
+	"github.com/HORNET-Storage/hornet-storage/services/lightning"
+	"github.com/HORNET-Storage/hornet-storage/services/mirror"
 	"github.com/HORNET-Storage/hornet-storage/services/push"
 	hsClient "github.com/hornet-storage/hornets-hyperswarm/clients/go/hyperswarm"
 
@@ -41,6 +45,7 @@ import (
 
 	"github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr"
 	"github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr/auth"
+	"github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr/contentfilter"
 	"github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr/count"
 	"github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr/filter"
 	"github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr/kind1809"
@@ -63,6 +68,7 @@ import (
 	"github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr/kind19841"
 	"github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr/kind19842"
 	"github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr/kind19843"
+	"github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr/kind28934"
 	"github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr/kind3"
 	"github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr/kind30000"
 	"github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr/kind30008"
@@ -70,6 +76,7 @@ import (
 	"github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr/kind30023"
 	"github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr/kind30078"
 	"github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr/kind30079"
+	"github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr/kind30166"
 	"github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr/kind443"
 	"github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr/kind444"
 	"github.com/HORNET-Storage/hornet-storage/lib/handlers/nostr/kind445"
@@ -202,15 +209,18 @@ func syncAirlockServiceDHTPubkey() (string, error) {
 	return deriveAirlockDHTPublicKeyFromPrivateKey(privateKey)
 }
 
-func forwardSidecarDHTPort(client *hsClient.Client) func() {
+// forwardSidecarDHTPort maps the sidecar's HyperDHT port on the router and
+// returns the function that removes the mapping, along with the outcome for
+// health reporting.
+func forwardSidecarDHTPort(client *hsClient.Client) (func(), upnpMapping) {
 	if !viper.GetBool("server.upnp") {
-		return func() {}
+		return func() {}, upnpMapping{}
 	}
 
 	upnpManager := upnp.Get()
 	if upnpManager == nil {
 		logging.Warn("UPnP is enabled but no router was discovered for HyperDHT port mapping", nil)
-		return func() {}
+		return func() {}, upnpMapping{Enabled: true, Error: "no UPnP router discovered"}
 	}
 
 	status, err := client.Status()
@@ -218,13 +228,13 @@ func forwardSidecarDHTPort(client *hsClient.Client) func() {
 		logging.Error("Failed to read sidecar status for HyperDHT UPnP mapping", map[string]interface{}{
 			"error": err,
 		})
-		return func() {}
+		return func() {}, upnpMapping{Enabled: true, Error: "failed to read sidecar status: " + err.Error()}
 	}
 	if status == nil || status.DHT == nil || status.DHT.Port <= 0 || status.DHT.Port > 65535 {
 		logging.Warn("Sidecar HyperDHT port unavailable for UPnP mapping", map[string]interface{}{
 			"status": status,
 		})
-		return func() {}
+		return func() {}, upnpMapping{Enabled: true, Error: "sidecar HyperDHT port unavailable"}
 	}
 
 	port := uint16(status.DHT.Port)
@@ -233,7 +243,7 @@ func forwardSidecarDHTPort(client *hsClient.Client) func() {
 			"port":  port,
 			"error": err,
 		})
-		return func() {}
+		return func() {}, upnpMapping{Enabled: true, Port: port, Error: err.Error()}
 	}
 
 	logging.Info("Forwarded HyperDHT port using UPnP", map[string]interface{}{
@@ -242,7 +252,7 @@ func forwardSidecarDHTPort(client *hsClient.Client) func() {
 
 	return func() {
 		upnpManager.RemovePort(port)
-	}
+	}, upnpMapping{Enabled: true, Mapped: true, Port: port}
 }
 
 // Run executes the full relay lifecycle and blocks until the relay stops.
@@ -498,7 +508,8 @@ func Run(ctx context.Context, opts Options) error {
 		})
 	}
 	defer sidecar.Close()
-	sidecarSup := newSidecarSupervisor(hsClient, forwardSidecarDHTPort(hsClient))
+	upnpCleanup, upnpState := forwardSidecarDHTPort(hsClient)
+	sidecarSup := newSidecarSupervisor(hsClient, upnpCleanup, upnpState)
 	defer sidecarSup.CleanupUPnP()
 
 	listener := hsListener.NewHyperswarmListener(hsClient)
@@ -582,6 +593,11 @@ func Run(ctx context.Context, opts Options) error {
 		checkInterval := time.Duration(viper.GetInt("content_filtering.image_moderation.check_interval_seconds")) * time.Second
 		tempDir := config.GetPath("moderation")
 		concurrency := viper.GetInt("content_filtering.image_moderation.concurrency")
+		providers := viper.GetStringSlice("content_filtering.image_moderation.providers")
+		combine := viper.GetString("content_filtering.image_moderation.combine")
+		hashThreshold := viper.GetInt("content_filtering.image_moderation.hash_threshold")
+		verdictCache := viper.GetBool("content_filtering.image_moderation.verdict_cache")
+		verdictTTL := time.Duration(viper.GetInt("content_filtering.image_moderation.verdict_cache_ttl_hours")) * time.Hour
 
 		// Make sure temp directory exists
 		if _, err := os.Stat(tempDir); os.IsNotExist(err) {
@@ -601,6 +617,10 @@ func Run(ctx context.Context, opts Options) error {
 			moderation.WithCheckInterval(checkInterval),
 			moderation.WithTempDir(tempDir),
 			moderation.WithConcurrency(concurrency),
+			moderation.WithProviders(providers),
+			moderation.WithCombine(combine),
+			moderation.WithHashThreshold(hashThreshold),
+			moderation.WithVerdictCache(verdictCache, verdictTTL),
 		)
 
 		if err != nil {
@@ -612,6 +632,26 @@ func Run(ctx context.Context, opts Options) error {
 		logging.Info("Image moderation system is disabled")
 	}
 
+	// Initialize per-user content filtering. Mute words always apply; AI instructions
+	// only run when the text filter is enabled.
+	contentFilter := contentfilter.NewService(contentfilter.ServiceConfig{
+		APIURL:            ollamaGenerateURL(viper.GetString("external_services.ollama.url")),
+		Model:             viper.GetString("external_services.ollama.model"),
+		Timeout:           time.Duration(viper.GetInt("external_services.ollama.timeout")) * time.Millisecond,
+		CacheSize:         viper.GetInt("content_filtering.text_filter.cache_size"),
+		CacheTTL:          time.Duration(viper.GetInt("content_filtering.text_filter.cache_ttl_seconds")) * time.Second,
+		Enabled:           viper.GetBool("content_filtering.text_filter.enabled"),
+		LatencyBudget:     time.Duration(viper.GetInt("content_filtering.text_filter.latency_budget_ms")) * time.Millisecond,
+		FailClosed:        viper.GetString("content_filtering.text_filter.fail_mode") == "closed",
+		PrecomputeWorkers: viper.GetInt("content_filtering.text_filter.precompute_workers"),
+	})
+	contentFeed := contentfilter.NewFeed(contentFilter, store, viper.GetBool("content_filtering.text_filter.paid_only"))
+	contentFeed.Start()
+	contentfilter.SetFeed(contentFeed)
+	if contentFilter.Enabled() {
+		logging.Infof("Content filter initialized with Ollama model %s", viper.GetString("external_services.ollama.model"))
+	}
+
 	// Initialize subscription manager with tiers from allowed_users
 	subscription.InitGlobalManager(
 		store,
@@ -684,6 +724,13 @@ func Run(ctx context.Context, opts Options) error {
 				logging.Info("Blacklist cache populated from stored events")
 			}
 
+			// Build the relay-wide web of trust from stored contact lists when in wot mode
+			// and revoke time-limited grants as they lapse
+			if ac := websocket.GetAccessControl(); ac != nil {
+				ac.StartRelayWot(store)
+				ac.StartExpirySweep(ctx, access.DefaultExpirySweepInterval)
+			}
+
 			// Set the WOT cache lazy-loader so WOT graphs are automatically
 			// reloaded from the DAG store on cache miss (after restart or LRU eviction).
 			if ac := websocket.GetAccessControl(); ac != nil && ac.WotCache != nil {
@@ -717,10 +764,37 @@ func Run(ctx context.Context, opts Options) error {
 		} else {
 			logging.Info("Push notification service initialized successfully")
 		}
+
+		// Initialize Lightning payment service (no-op when lightning is disabled)
+		var processor lightning.PaymentProcessor
+		if manager := subscription.GetGlobalManager(); manager != nil {
+			processor = manager
+		}
+		if err := lightning.InitGlobalService(store, processor); err != nil {
+			logging.Errorf("Failed to initialize lightning payment service: %v", err)
+		}
 	} else {
 		logging.Warn("Warning: Statistics store not available, access control and push notifications not initialized")
 	}
 
+	// Mirror opted-in events to their authors' write relays
+	if err := mirror.InitGlobalMirrorService(store); err != nil {
+		logging.Errorf("Failed to initialize outbound mirroring: %v", err)
+	}
+
+	// Import users' history from their other relays when they gain write access
+	var quota backfill.QuotaTracker
+	if manager := subscription.GetGlobalManager(); manager != nil {
+		quota = manager
+	}
+	var policy backfill.WritePolicy
+	if ac := websocket.GetAccessControl(); ac != nil {
+		policy = ac
+	}
+	if err := backfill.InitGlobalBackfillService(store, quota, policy); err != nil {
+		logging.Errorf("Failed to initialize history backfill: %v", err)
+	}
+
 	// Create and store kind 10411 event
 	if err := kind10411.CreateKind10411Event(privateKey, publicKey, store); err != nil {
 		logging.Errorf("Failed to create kind 10411 event: %v", err)
@@ -730,6 +804,9 @@ func Run(ctx context.Context, opts Options) error {
 		return nil
 	}
 
+	// Announce the relay to NIP-66 discovery clients when relay.discovery is enabled
+	kind30166.StartDiscoveryAnnouncer(ctx, privateKey, publicKey, store)
+
 	// Stream Handlers
 	download.AddDownloadHandler(listener, store, func(rootLeaf *merkle_dag.DagLeaf, pubKey *string, signature *string) bool {
 		accessControl := websocket.GetAccessControl()
@@ -819,6 +896,14 @@ func Run(ctx context.Context, opts Options) error {
 	logging.Infof("HyperDHT server started with public key: %s\n", dhtPublicKey)
 
 	// Register All Nostr Stream Handlers
+	// Pass WotCache to kind 31415 handler so it can invalidate stale WOT cache entries
+	// when permission events are updated with new wot_file tags, and to kind 1984
+	// so reporters can be weighted by follow distance.
+	var wotCacheForHandler *wot.Cache
+	if ac := websocket.GetAccessControl(); ac != nil {
+		wotCacheForHandler = ac.WotCache
+	}
+
 	// Always register all specific handlers for registered kinds
 	logging.Info("Registering all specific kind handlers...")
 	nostr.RegisterHandler("kind/0", kind0.BuildKind0Handler(store, privateKey))
@@ -836,12 +921,14 @@ func Run(ctx context.Context, opts Options) error {
 	nostr.RegisterHandler("kind/6", kind6.BuildKind6Handler(store))
 	nostr.RegisterHandler("kind/7", kind7.BuildKind7Handler(store))
 	nostr.RegisterHandler("kind/8", kind8.BuildKind8Handler(store))
-	nostr.RegisterHandler("kind/1984", kind1984.BuildKind1984Handler(store))
+	nostr.RegisterHandler("kind/1984", kind1984.BuildKind1984Handler(store, wotCacheForHandler))
 	nostr.RegisterHandler("kind/9735", kind9735.BuildKind9735Handler(store))
 	nostr.RegisterHandler("kind/9372", kind9372.BuildKind9372Handler(store))
 	nostr.RegisterHandler("kind/9373", kind9373.BuildKind9373Handler(store))
 	nostr.RegisterHandler("kind/9802", kind9802.BuildKind9802Handler(store))
 	nostr.RegisterHandler("kind/10000", kind10000.BuildKind10000Handler(store))
+	nostr.RegisterHandler("kind/28934", kind28934.BuildKind28934Handler(websocket.GetAccessControl()))
+	nostr.RegisterPrivateKind(access.InviteRequestKind)
 	nostr.RegisterHandler("kind/10001", kind10001.BuildKind10001Handler(store))
 	nostr.RegisterHandler("kind/10002", kind10002.BuildKind10002Handler(store))
 	nostr.RegisterHandler("kind/10051", kind10051.BuildKind10051Handler(store))
@@ -853,12 +940,6 @@ func Run(ctx context.Context, opts Options) error {
 	nostr.RegisterHandler("kind/30023", kind30023.BuildKind30023Handler(store))
 	nostr.RegisterHandler("kind/30078", kind30078.BuildKind30078Handler(store))
 	nostr.RegisterHandler("kind/30079", kind30079.BuildKind30079Handler(store))
-	// Pass WotCache to kind 31415 handler so it can invalidate stale WOT cache entries
-	// when permission events are updated with new wot_file tags.
-	var wotCacheForHandler *wot.Cache
-	if ac := websocket.GetAccessControl(); ac != nil {
-		wotCacheForHandler = ac.WotCache
-	}
 	nostr.RegisterHandler("kind/31415", kind16629.BuildKind31415Handler(store, wotCacheForHandler))
 	nostr.RegisterHandler("kind/16630", kind16630.BuildKind16630Handler(store))
 	nostr.RegisterHandler("kind/10010", kind10010.BuildKind10010Handler(store))
@@ -922,6 +1003,9 @@ func Run(ctx context.Context, opts Options) error {
 	// protocol registration so a recovery re-declares every handler.
 	sidecarSup.Start(ctx, listener, dhtSeed)
 
+	// Liveness and readiness checks served by the web panel
+	registerHealthChecks(store, sidecarSup)
+
 	// Web Panel
 	if config.IsEnabled("web") {
 		wg.Add(1)
@@ -1031,6 +1115,10 @@ func Run(ctx context.Context, opts Options) error {
 		}
 
 		push.StopGlobalPushService()
+		contentFeed.Stop()
+		mirror.StopGlobalMirrorService()
+		backfill.StopGlobalBackfillService()
+		lightning.StopGlobalService()
 
 		logging.Info("Closing database...")
 		if err := store.Cleanup(); err != nil {
@@ -1104,3 +1192,12 @@ func validateSubscriptionModeStartup(manager *subscription.SubscriptionManager,
 
 	return nil
 }
+
+// ollamaGenerateURL turns the configured Ollama base URL into its generate endpoint
+func ollamaGenerateURL(baseURL string) string {
+	baseURL = strings.TrimRight(baseURL, "/")
+	if baseURL == "" || strings.HasSuffix(baseURL, "/api/generate") {
+		return baseURL
+	}
+	return baseURL + "/api/generate"
+}
@@ -35,17 +35,63 @@ type sidecarSupervisor struct {
 	mu          sync.Mutex
 	client      *hsClient.Client
 	upnpCleanup func()
+	upnp        upnpMapping
 	closed      bool
 
 	listener *hsListener.HyperswarmListener
 	dhtSeed  string
+
+	// Recovery state, reported by the health endpoints
+	lostAt           time.Time
+	lastRecovery     time.Time
+	recoveries       int
+	recoveryAttempts int
+	lastError        string
+}
+
+// upnpMapping records the outcome of the last HyperDHT port mapping attempt
+type upnpMapping struct {
+	Enabled bool
+	Mapped  bool
+	Port    uint16
+	Error   string
+}
+
+// sidecarStatus is a snapshot of the supervisor state
+type sidecarStatus struct {
+	Client           *hsClient.Client
+	Connected        bool
+	LostAt           time.Time
+	LastRecovery     time.Time
+	Recoveries       int
+	RecoveryAttempts int
+	LastError        string
+	UPnP             upnpMapping
 }
 
 // newSidecarSupervisor wraps the initial sidecar client and takes ownership
 // of its UPnP cleanup function. Call Start once the listener has every
 // protocol registered; call CleanupUPnP (usually deferred) on shutdown.
-func newSidecarSupervisor(client *hsClient.Client, upnpCleanup func()) *sidecarSupervisor {
-	return &sidecarSupervisor{client: client, upnpCleanup: upnpCleanup}
+func newSidecarSupervisor(client *hsClient.Client, upnpCleanup func(), upnp upnpMapping) *sidecarSupervisor {
+	return &sidecarSupervisor{client: client, upnpCleanup: upnpCleanup, upnp: upnp}
+}
+
+// Status returns the current connection, recovery and UPnP state. Connected
+// is false from the moment the connection is lost until recovery succeeds.
+func (s *sidecarSupervisor) Status() sidecarStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return sidecarStatus{
+		Client:           s.client,
+		Connected:        s.lostAt.IsZero(),
+		LostAt:           s.lostAt,
+		LastRecovery:     s.lastRecovery,
+		Recoveries:       s.recoveries,
+		RecoveryAttempts: s.recoveryAttempts,
+		LastError:        s.lastError,
+		UPnP:             s.upnp,
+	}
 }
 
 // Start launches the supervision loop. The listener must already have every
@@ -83,6 +129,11 @@ func (s *sidecarSupervisor) run(ctx context.Context, client *hsClient.Client) {
 
 		logging.Error("Hyperswarm sidecar connection lost - starting recovery", nil)
 
+		s.mu.Lock()
+		s.lostAt = time.Now()
+		s.recoveryAttempts = 0
+		s.mu.Unlock()
+
 		recovered, ok := s.recover(ctx)
 		if !ok {
 			return
@@ -139,6 +190,11 @@ func (s *sidecarSupervisor) recover(ctx context.Context) (*hsClient.Client, bool
 			if err == nil {
 				s.mu.Lock()
 				s.client = client
+				s.lostAt = time.Time{}
+				s.lastRecovery = time.Now()
+				s.recoveries++
+				s.recoveryAttempts = attempt
+				s.lastError = ""
 				s.mu.Unlock()
 
 				s.swapUPnP(client)
@@ -154,6 +210,11 @@ func (s *sidecarSupervisor) recover(ctx context.Context) (*hsClient.Client, bool
 
 		logging.Errorf("Hyperswarm sidecar recovery attempt %d failed (retrying in %s): %v", attempt, backoff, err)
 
+		s.mu.Lock()
+		s.recoveryAttempts = attempt
+		s.lastError = err.Error()
+		s.mu.Unlock()
+
 		select {
 		case <-ctx.Done():
 			return nil, false
@@ -184,7 +245,7 @@ func (s *sidecarSupervisor) swapUPnP(client *hsClient.Client) {
 		return
 	}
 
-	cleanup := forwardSidecarDHTPort(client)
+	cleanup, mapping := forwardSidecarDHTPort(client)
 
 	s.mu.Lock()
 	if s.closed {
@@ -193,5 +254,6 @@ func (s *sidecarSupervisor) swapUPnP(client *hsClient.Client) {
 		return
 	}
 	s.upnpCleanup = cleanup
+	s.upnp = mapping
 	s.mu.Unlock()
 }